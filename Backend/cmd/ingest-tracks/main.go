@@ -0,0 +1,56 @@
+package main
+
+import (
+	"log"
+
+	"github.com/nessieaudio/ecommerce-backend/internal/audio"
+	"github.com/nessieaudio/ecommerce-backend/internal/config"
+	"github.com/nessieaudio/ecommerce-backend/internal/database"
+	"github.com/nessieaudio/ecommerce-backend/internal/migrations"
+)
+
+// One-off track ingest from the command line.
+// The server runs the same ingest on startup and every 6 hours; this is handy
+// after adding music when you don't want to wait.
+func main() {
+	// Load config
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Initialize database
+	db, err := database.InitDB(cfg.DatabasePath)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	if err := migrations.RunMigrations(db); err != nil {
+		log.Fatalf("Failed to run database migrations: %v", err)
+	}
+
+	log.Printf("Ingesting tracks from %s...", cfg.MusicDir)
+
+	library := audio.NewLibrary(db, cfg.MusicDir)
+	report, err := library.Ingest("cli")
+	if err != nil {
+		log.Fatalf("Ingest failed: %v", err)
+	}
+
+	for _, path := range report.Added {
+		log.Printf("✓ Added track: %s", path)
+	}
+	for _, path := range report.Updated {
+		log.Printf("✓ Updated track: %s", path)
+	}
+	for _, path := range report.Removed {
+		log.Printf("✓ Removed track: %s (file is gone)", path)
+	}
+	for _, e := range report.Errors {
+		log.Printf("⚠️  %s", e)
+	}
+
+	log.Printf("\n✅ Ingest complete!")
+	log.Printf("Total: %s", report.Summary())
+}
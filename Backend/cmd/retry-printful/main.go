@@ -108,18 +108,12 @@ func main() {
 			continue
 		}
 
-		// Update order with Printful ID
-		if err := orderService.UpdateOrderWithPrintful(order.ID, printfulOrderID); err != nil {
+		// Record the Printful ID; the Printful fulfillment is done
+		if err := orderService.MarkPrintfulSubmitted(order.ID, printfulOrderID); err != nil {
 			log.Printf("Failed to update order with Printful ID: %v", err)
 			continue
 		}
 
-		// Update status to fulfilled
-		if err := orderService.UpdateOrderStatus(order.ID, models.OrderStatusFulfilled); err != nil {
-			log.Printf("Failed to update order status: %v", err)
-			continue
-		}
-
 		log.Printf("✅ Order %s submitted successfully to Printful (ID: %d) after %d retries", order.ID, printfulOrderID, order.PrintfulRetryCount+1)
 	}
 
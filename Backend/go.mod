@@ -3,6 +3,7 @@ module github.com/nessieaudio/ecommerce-backend
 go 1.23
 
 require (
+	github.com/chai2010/webp v1.4.0
 	github.com/golang-migrate/migrate/v4 v4.17.1
 	github.com/google/uuid v1.6.0
 	github.com/gorilla/mux v1.8.1
@@ -0,0 +1,590 @@
+// Package audio serves the portfolio player's tracks. The catalogue is read from the
+// music directory, laid out as <genre>/<artist>/.../<track>, and tracks are streamed
+// with Range support. Tracks that are for sale only stream a preview clip.
+package audio
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nessieaudio/ecommerce-backend/internal/catalog"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+// Formats the player can stream and preview
+const (
+	FormatMP3 = "mp3"
+	FormatWAV = "wav"
+)
+
+// DefaultPreviewLength is how much of a track for sale is streamed, unless its
+// preview says otherwise
+const DefaultPreviewLength = 30 * time.Second
+
+// scanTTL is how long a scan of the music directory is used before it is redone
+const scanTTL = 5 * time.Minute
+
+// contentTypes are the formats tracks are found in
+var contentTypes = map[string]string{
+	".mp3": "audio/mpeg",
+	".wav": "audio/wav",
+}
+
+// audioExtensions are kept out of the static file server (see IsAudioFile)
+var audioExtensions = map[string]bool{
+	".mp3": true, ".wav": true, ".flac": true, ".ogg": true, ".oga": true,
+	".m4a": true, ".aac": true, ".aif": true, ".aiff": true,
+}
+
+// artworkNames are the image files, in order of preference, used as a track's artwork
+// when found in its directory or a parent up to the artist directory
+var artworkNames = []string{"cover", "folder", "artwork", "front"}
+
+var artworkExtensions = []string{".jpg", ".jpeg", ".png", ".webp"}
+
+var (
+	// ErrTrackNotFound is returned for a track ID not in the music directory
+	ErrTrackNotFound = errors.New("track not found")
+	// ErrInvalidPreview is returned for a preview window outside the track
+	ErrInvalidPreview = errors.New("preview must start within the track and have a positive length")
+	// ErrUnsupportedFormat is returned for audio files whose headers cannot be read
+	ErrUnsupportedFormat = errors.New("audio format is not supported")
+	// ErrProductNotFound is returned for a preview linked to a product that does not exist
+	ErrProductNotFound = errors.New("product not found")
+	// ErrNoArtwork is returned for tracks without artwork
+	ErrNoArtwork = errors.New("track has no artwork")
+)
+
+// IsAudioFile reports whether a file name is an audio file. The static file server
+// refuses these under the music directory, so tracks for sale are only heard as previews.
+func IsAudioFile(name string) bool {
+	return audioExtensions[strings.ToLower(filepath.Ext(name))]
+}
+
+// mediaInfo is what was read from an audio file's headers
+type mediaInfo struct {
+	format             string
+	duration           float64 // Seconds
+	sampleRate         int
+	channels           int
+	dataStart, dataEnd int64 // Byte range of the audio
+
+	seekPoints []int64   // MP3: offset of the first frame starting at or after each second
+	levels     []float32 // MP3: estimated loudness of each frame, when asked for
+
+	fmtChunk   []byte // WAV: fmt chunk, header included
+	byteRate   int64
+	blockAlign int64
+}
+
+// entry is a track found on disk
+type entry struct {
+	track   models.Track
+	file    string // Full path
+	artwork string // Full path, or "" for none
+	modTime time.Time
+	size    int64
+	media   *mediaInfo
+}
+
+// Library is the catalogue of tracks in the music directory
+type Library struct {
+	db  *sql.DB
+	dir string
+
+	mu        sync.Mutex
+	entries   []*entry
+	byID      map[string]*entry
+	scannedAt time.Time
+	media     map[string]*entry // Full path -> last scan, reused while the file is unchanged
+}
+
+// NewLibrary creates a library of the tracks in dir
+func NewLibrary(db *sql.DB, dir string) *Library {
+	return &Library{db: db, dir: dir, media: make(map[string]*entry)}
+}
+
+// Tracks returns every track in genre, artist and path order, with stream and artwork
+// URLs under baseURL, preview windows and play counts
+func (l *Library) Tracks(baseURL string) ([]models.Track, error) {
+	entries, _, err := l.scan()
+	if err != nil {
+		return nil, err
+	}
+	previews, err := l.previews()
+	if err != nil {
+		return nil, err
+	}
+	plays, err := l.plays()
+	if err != nil {
+		return nil, err
+	}
+
+	tracks := make([]models.Track, 0, len(entries))
+	for _, e := range entries {
+		tracks = append(tracks, e.withState(baseURL, previews, plays))
+	}
+	return tracks, nil
+}
+
+// Track returns one track by ID, or ErrTrackNotFound
+func (l *Library) Track(id, baseURL string) (*models.Track, error) {
+	e, err := l.find(id)
+	if err != nil {
+		return nil, err
+	}
+	previews, err := l.previews()
+	if err != nil {
+		return nil, err
+	}
+	plays, err := l.plays()
+	if err != nil {
+		return nil, err
+	}
+	t := e.withState(baseURL, previews, plays)
+	return &t, nil
+}
+
+// withState fills in a scanned track's URLs, preview and plays
+func (e *entry) withState(baseURL string, previews map[string]models.TrackPreview, plays map[string]int) models.Track {
+	t := e.track
+	t.StreamURL = baseURL + "/api/v1/audio/tracks/" + t.ID + "/stream"
+	if e.artwork != "" {
+		t.ArtworkURL = baseURL + "/api/v1/audio/tracks/" + t.ID + "/artwork"
+	}
+	if p, ok := previews[t.ID]; ok {
+		p = clampPreview(p, t.DurationSeconds)
+		t.Preview = &p
+	}
+	t.Plays = plays[t.ID]
+	return t
+}
+
+// clampPreview applies the default length and keeps the window inside the track
+func clampPreview(p models.TrackPreview, duration float64) models.TrackPreview {
+	if p.LengthSeconds <= 0 {
+		p.LengthSeconds = DefaultPreviewLength.Seconds()
+	}
+	if duration > 0 {
+		p.StartSeconds = min(p.StartSeconds, duration)
+		p.LengthSeconds = min(p.LengthSeconds, duration-p.StartSeconds)
+	}
+	return p
+}
+
+func (l *Library) find(id string) (*entry, error) {
+	_, byID, err := l.scan()
+	if err != nil {
+		return nil, err
+	}
+	e, ok := byID[id]
+	if !ok {
+		return nil, ErrTrackNotFound
+	}
+	return e, nil
+}
+
+// scan lists the music directory, reusing the last scan for scanTTL. Headers are only
+// read again for files that changed.
+func (l *Library) scan() ([]*entry, map[string]*entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.entries != nil && time.Since(l.scannedAt) < scanTTL {
+		return l.entries, l.byID, nil
+	}
+
+	entries := []*entry{}
+	err := l.walk(func(ext string) bool { return contentTypes[ext] != "" }, func(path string, parts []string, info fs.FileInfo) error {
+		e, err := l.load(path, parts, info)
+		if err != nil {
+			// One unreadable file should not take the catalogue down
+			return nil
+		}
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("scan music directory: %w", err)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i].track, entries[j].track
+		if a.Genre != b.Genre {
+			return a.Genre < b.Genre
+		}
+		if a.Artist != b.Artist {
+			return a.Artist < b.Artist
+		}
+		return a.Path < b.Path
+	})
+
+	// IDs are slugs; the rare clash gets a numeric suffix in path order
+	byID := make(map[string]*entry, len(entries))
+	media := make(map[string]*entry, len(entries))
+	for _, e := range entries {
+		base := trackID(strings.Split(e.track.Path, "/"))
+		id := base
+		for n := 2; byID[id] != nil; n++ {
+			id = base + "-" + strconv.Itoa(n)
+		}
+		e.track.ID = id
+		byID[id] = e
+		media[e.file] = e
+	}
+
+	l.entries = entries
+	l.byID = byID
+	l.media = media
+	l.scannedAt = time.Now()
+	return entries, byID, nil
+}
+
+// load describes one audio file, reading its headers unless the last scan already did
+func (l *Library) load(path string, parts []string, info fs.FileInfo) (*entry, error) {
+	if cached, ok := l.media[path]; ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		e := *cached
+		return &e, nil
+	}
+
+	media, err := readMedia(path)
+	if err != nil {
+		return nil, err
+	}
+
+	genre, artist := parts[0], parts[1]
+	return &entry{
+		track: models.Track{
+			Title:           titleFromPath(parts),
+			Genre:           genreFromFolder(genre),
+			Artist:          artist,
+			Path:            strings.Join(parts, "/"),
+			Format:          media.format,
+			DurationSeconds: math.Round(media.duration*1000) / 1000,
+		},
+		file:    path,
+		artwork: findArtwork(filepath.Dir(path), filepath.Join(l.dir, genre, artist)),
+		modTime: info.ModTime(),
+		size:    info.Size(),
+		media:   media,
+	}, nil
+}
+
+// walk calls fn for each file under <genre>/<artist>/ in the music directory whose
+// lower-cased extension is accepted. A missing music directory has no files.
+func (l *Library) walk(accept func(ext string) bool, fn func(path string, parts []string, info fs.FileInfo) error) error {
+	return filepath.WalkDir(l.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == l.dir && errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() {
+			if strings.HasPrefix(d.Name(), ".") && path != l.dir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !accept(strings.ToLower(filepath.Ext(path))) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(l.dir, path)
+		if err != nil {
+			return err
+		}
+		parts := strings.Split(filepath.ToSlash(rel), "/")
+		if len(parts) < 3 {
+			return nil // Not under <genre>/<artist>
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return fn(path, parts, info)
+	})
+}
+
+// titleFromPath is a track's title going by its file name, without an "Artist - " prefix
+func titleFromPath(parts []string) string {
+	name := parts[len(parts)-1]
+	return stripArtistPrefix(strings.TrimSuffix(name, filepath.Ext(name)), parts[1])
+}
+
+// stripArtistPrefix removes "Artist - " from the start of a title
+func stripArtistPrefix(title, artist string) string {
+	if prefix := artist + " - "; strings.HasPrefix(strings.ToLower(title), strings.ToLower(prefix)) {
+		return title[len(prefix):]
+	}
+	return title
+}
+
+// genreFromFolder reads a genre folder name. Finder shows "/" in folder names as ":" on disk.
+func genreFromFolder(name string) string {
+	return strings.ReplaceAll(name, ":", "/")
+}
+
+// trackID is the ID of a track at parts, before clashes are resolved
+func trackID(parts []string) string {
+	return catalog.Slugify(genreFromFolder(parts[0]) + " " + parts[1] + " " + titleFromPath(parts))
+}
+
+// readMedia reads an audio file's headers
+func readMedia(path string) (*mediaInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.ToLower(filepath.Ext(path)) == ".wav" {
+		return scanWAV(f, info.Size())
+	}
+	return scanMP3(f, info.Size(), false)
+}
+
+// findArtwork looks for a cover image in dir and its parents up to top
+func findArtwork(dir, top string) string {
+	for {
+		for _, name := range artworkNames {
+			for _, ext := range artworkExtensions {
+				for _, candidate := range []string{name + ext, strings.ToUpper(name[:1]) + name[1:] + ext} {
+					path := filepath.Join(dir, candidate)
+					if info, err := os.Stat(path); err == nil && !info.IsDir() {
+						return path
+					}
+				}
+			}
+		}
+		if dir == top || len(dir) <= len(top) {
+			return ""
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// Stream is an opened track or preview clip, ready to serve
+type Stream struct {
+	io.ReadSeeker
+	io.Closer
+	ContentType string
+	ModTime     time.Time
+	ETag        string // Strong ETag, quoted; differs between a track and its previews
+	Preview     bool
+}
+
+// Open opens a track for streaming: the whole file, or only the preview clip when the
+// track is for sale or preview is set. Tracks not for sale preview their first
+// DefaultPreviewLength.
+func (l *Library) Open(id string, preview bool) (*Stream, error) {
+	e, err := l.find(id)
+	if err != nil {
+		return nil, err
+	}
+	window, forSale, err := l.preview(id)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(e.file)
+	if err != nil {
+		return nil, ErrTrackNotFound
+	}
+	stream := &Stream{
+		Closer:      f,
+		ContentType: contentTypes[strings.ToLower(filepath.Ext(e.file))],
+		ModTime:     e.modTime,
+		ETag:        fmt.Sprintf(`"%x-%x"`, e.modTime.UnixNano(), e.size),
+	}
+	if !forSale && !preview {
+		stream.ReadSeeker = f
+		return stream, nil
+	}
+
+	window = clampPreview(window, e.media.duration)
+	start, end := window.StartSeconds, window.StartSeconds+window.LengthSeconds
+	stream.Preview = true
+	stream.ETag = fmt.Sprintf(`"%x-%x-%g-%g"`, e.modTime.UnixNano(), e.size, start, end)
+
+	switch e.media.format {
+	case FormatWAV:
+		header, from, to := e.media.wavClip(start, end)
+		clip := joinedReaderAt{head: header, body: io.NewSectionReader(f, from, to-from)}
+		stream.ReadSeeker = io.NewSectionReader(clip, 0, int64(len(header))+to-from)
+	default:
+		from, to := e.media.mp3Clip(start, end)
+		stream.ReadSeeker = io.NewSectionReader(f, from, to-from)
+	}
+	return stream, nil
+}
+
+// joinedReaderAt reads a generated header followed by a range of the source file
+type joinedReaderAt struct {
+	head []byte
+	body *io.SectionReader
+}
+
+func (j joinedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	if off < int64(len(j.head)) {
+		n = copy(p, j.head[off:])
+		if n == len(p) {
+			return n, nil
+		}
+	}
+	m, err := j.body.ReadAt(p[n:], max(off-int64(len(j.head)), 0))
+	return n + m, err
+}
+
+// Artwork returns the path of a track's artwork image, or ErrNoArtwork
+func (l *Library) Artwork(id string) (string, error) {
+	e, err := l.find(id)
+	if err != nil {
+		return "", err
+	}
+	if e.artwork == "" {
+		return "", ErrNoArtwork
+	}
+	return e.artwork, nil
+}
+
+// RecordPlay counts a play of a track
+func (l *Library) RecordPlay(id string) error {
+	if _, err := l.find(id); err != nil {
+		return err
+	}
+	_, err := l.db.Exec(`
+		INSERT INTO track_plays (track_id, plays, last_played_at) VALUES (?, 1, ?)
+		ON CONFLICT (track_id) DO UPDATE SET plays = plays + 1, last_played_at = excluded.last_played_at
+	`, id, time.Now())
+	if err != nil {
+		return fmt.Errorf("record play: %w", err)
+	}
+	return nil
+}
+
+// SetPreview puts a track on sale: from now on only the preview window is streamed.
+// The product it is sold as is optional. A zero length means DefaultPreviewLength.
+// Returns ErrTrackNotFound, ErrInvalidPreview or ErrProductNotFound.
+func (l *Library) SetPreview(id string, p models.TrackPreview) error {
+	e, err := l.find(id)
+	if err != nil {
+		return err
+	}
+	if p.StartSeconds < 0 || p.LengthSeconds < 0 || p.StartSeconds >= e.media.duration {
+		return ErrInvalidPreview
+	}
+	if p.ProductID != "" {
+		var exists bool
+		if err := l.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM products WHERE id = ?)`, p.ProductID).Scan(&exists); err != nil {
+			return fmt.Errorf("check product: %w", err)
+		}
+		if !exists {
+			return ErrProductNotFound
+		}
+	}
+
+	var length interface{}
+	if p.LengthSeconds > 0 {
+		length = p.LengthSeconds
+	}
+	now := time.Now()
+	_, err = l.db.Exec(`
+		INSERT INTO track_previews (track_id, product_id, start_seconds, length_seconds, created_at, updated_at)
+		VALUES (?, NULLIF(?, ''), ?, ?, ?, ?)
+		ON CONFLICT (track_id) DO UPDATE SET
+			product_id = excluded.product_id,
+			start_seconds = excluded.start_seconds,
+			length_seconds = excluded.length_seconds,
+			updated_at = excluded.updated_at
+	`, id, p.ProductID, p.StartSeconds, length, now, now)
+	if err != nil {
+		return fmt.Errorf("save preview: %w", err)
+	}
+	return nil
+}
+
+// RemovePreview takes a track off sale, so it streams in full again
+func (l *Library) RemovePreview(id string) error {
+	if _, err := l.find(id); err != nil {
+		return err
+	}
+	if _, err := l.db.Exec(`DELETE FROM track_previews WHERE track_id = ?`, id); err != nil {
+		return fmt.Errorf("remove preview: %w", err)
+	}
+	return nil
+}
+
+// preview returns a track's preview window and whether it is for sale
+func (l *Library) preview(id string) (models.TrackPreview, bool, error) {
+	var p models.TrackPreview
+	var length sql.NullFloat64
+	err := l.db.QueryRow(`
+		SELECT COALESCE(product_id, ''), start_seconds, length_seconds FROM track_previews WHERE track_id = ?
+	`, id).Scan(&p.ProductID, &p.StartSeconds, &length)
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, false, nil
+	}
+	if err != nil {
+		return p, false, fmt.Errorf("query preview: %w", err)
+	}
+	p.LengthSeconds = length.Float64
+	return p, true, nil
+}
+
+func (l *Library) previews() (map[string]models.TrackPreview, error) {
+	rows, err := l.db.Query(`SELECT track_id, COALESCE(product_id, ''), start_seconds, length_seconds FROM track_previews`)
+	if err != nil {
+		return nil, fmt.Errorf("query previews: %w", err)
+	}
+	defer rows.Close()
+
+	previews := make(map[string]models.TrackPreview)
+	for rows.Next() {
+		var id string
+		var p models.TrackPreview
+		var length sql.NullFloat64
+		if err := rows.Scan(&id, &p.ProductID, &p.StartSeconds, &length); err != nil {
+			return nil, fmt.Errorf("scan preview: %w", err)
+		}
+		p.LengthSeconds = length.Float64
+		previews[id] = p
+	}
+	return previews, rows.Err()
+}
+
+func (l *Library) plays() (map[string]int, error) {
+	rows, err := l.db.Query(`SELECT track_id, plays FROM track_plays`)
+	if err != nil {
+		return nil, fmt.Errorf("query plays: %w", err)
+	}
+	defer rows.Close()
+
+	plays := make(map[string]int)
+	for rows.Next() {
+		var id string
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, fmt.Errorf("scan plays: %w", err)
+		}
+		plays[id] = n
+	}
+	return plays, rows.Err()
+}
@@ -0,0 +1,522 @@
+package audio
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"math"
+	"mime"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+// Formats the ingest reads besides those that are streamed
+const (
+	FormatFLAC = "flac"
+	FormatOgg  = "ogg" // Ogg Vorbis
+	FormatOpus = "opus"
+)
+
+// ingestFormats are the files the ingest reads, by extension. Ogg files holding Opus
+// are told apart by their headers.
+var ingestFormats = map[string]string{
+	".mp3":  FormatMP3,
+	".wav":  FormatWAV,
+	".flac": FormatFLAC,
+	".ogg":  FormatOgg,
+	".oga":  FormatOgg,
+	".opus": FormatOpus,
+}
+
+// ErrIngestInProgress is returned when an ingest is requested while another is running
+var ErrIngestInProgress = errors.New("track ingest already in progress")
+
+// ingestMu serialises ingest runs across every Library (scheduler, admin API, CLI)
+var ingestMu sync.Mutex
+
+// IngestReport says what an ingest run changed in the tracks table
+type IngestReport struct {
+	Trigger    string    `json:"trigger"` // "startup", "scheduled", "admin", "cli"
+	Added      []string  `json:"added"`   // Track paths
+	Updated    []string  `json:"updated"`
+	Removed    []string  `json:"removed"`
+	Unchanged  int       `json:"unchanged"` // Files not modified since the last ingest
+	Errors     []string  `json:"errors,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// HasChanges reports whether the run added, updated or removed anything
+func (r *IngestReport) HasChanges() bool {
+	return len(r.Added)+len(r.Updated)+len(r.Removed) > 0
+}
+
+// Summary returns a one-line description of the run for logs
+func (r *IngestReport) Summary() string {
+	return fmt.Sprintf("tracks +%d ~%d -%d, %d unchanged, %d errors",
+		len(r.Added), len(r.Updated), len(r.Removed), r.Unchanged, len(r.Errors))
+}
+
+// ingestFile is a file found by the ingest
+type ingestFile struct {
+	path  string // Full path
+	parts []string
+	info  fs.FileInfo
+	id    string
+}
+
+// ingestedFile is what the tracks table remembers about a file
+type ingestedFile struct {
+	id      string
+	size    int64
+	modTime time.Time
+}
+
+// Ingest reads every track in the music directory into the tracks table: tags,
+// duration, sample rate, artwork and waveform. Files unchanged since the last run are
+// skipped and tracks whose files are gone are removed. One unreadable file is
+// reported and skipped; it does not fail the run.
+func (l *Library) Ingest(trigger string) (*IngestReport, error) {
+	if !ingestMu.TryLock() {
+		return nil, ErrIngestInProgress
+	}
+	defer ingestMu.Unlock()
+
+	report := &IngestReport{
+		Trigger:   trigger,
+		Added:     []string{},
+		Updated:   []string{},
+		Removed:   []string{},
+		StartedAt: time.Now(),
+	}
+
+	// Streamed tracks keep the IDs the streaming API gives them, so previews and
+	// play counts line up. Scan afresh so new files are seen.
+	l.mu.Lock()
+	l.entries = nil
+	l.mu.Unlock()
+	entries, _, err := l.scan()
+	if err != nil {
+		return nil, err
+	}
+	streamIDs := make(map[string]string, len(entries))
+	for _, e := range entries {
+		streamIDs[e.track.Path] = e.track.ID
+	}
+
+	existing, err := l.ingestedFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*ingestFile
+	err = l.walk(func(ext string) bool { return ingestFormats[ext] != "" }, func(path string, parts []string, info fs.FileInfo) error {
+		files = append(files, &ingestFile{path: path, parts: parts, info: info})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("scan music directory: %w", err)
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+
+	// IDs: streamed tracks first, then those already ingested, then new ones
+	used := make(map[string]bool)
+	for _, f := range files {
+		if id := streamIDs[strings.Join(f.parts, "/")]; id != "" {
+			f.id = id
+			used[id] = true
+		}
+	}
+	for _, f := range files {
+		if old, ok := existing[strings.Join(f.parts, "/")]; ok && f.id == "" && !used[old.id] {
+			f.id = old.id
+			used[old.id] = true
+		}
+	}
+	for _, f := range files {
+		if f.id != "" {
+			continue
+		}
+		base := trackID(f.parts)
+		f.id = base
+		for n := 2; used[f.id]; n++ {
+			f.id = base + "-" + strconv.Itoa(n)
+		}
+		used[f.id] = true
+	}
+
+	seen := make(map[string]bool, len(files))
+	for _, f := range files {
+		rel := strings.Join(f.parts, "/")
+		seen[rel] = true
+
+		old, exists := existing[rel]
+		if exists && old.id == f.id && old.size == f.info.Size() && old.modTime.Equal(f.info.ModTime()) {
+			report.Unchanged++
+			continue
+		}
+
+		track, err := l.analyze(f)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", rel, err))
+			continue
+		}
+		if err := l.saveTrack(track); err != nil {
+			return nil, err
+		}
+		if exists {
+			report.Updated = append(report.Updated, rel)
+		} else {
+			report.Added = append(report.Added, rel)
+		}
+	}
+
+	for rel := range existing {
+		if seen[rel] {
+			continue
+		}
+		if _, err := l.db.Exec(`DELETE FROM tracks WHERE path = ?`, rel); err != nil {
+			return nil, fmt.Errorf("remove track: %w", err)
+		}
+		report.Removed = append(report.Removed, rel)
+	}
+	sort.Strings(report.Removed)
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// StartScheduledIngest runs an ingest now and then every interval in the background
+func (l *Library) StartScheduledIngest(interval time.Duration) {
+	go func() {
+		l.runScheduled("startup")
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			l.runScheduled("scheduled")
+		}
+	}()
+
+	log.Printf("Track ingest scheduled (every %v)", interval)
+}
+
+// runScheduled performs a background ingest and logs the outcome
+func (l *Library) runScheduled(trigger string) {
+	report, err := l.Ingest(trigger)
+	if errors.Is(err, ErrIngestInProgress) {
+		log.Println("Track ingest skipped - another ingest is running")
+		return
+	}
+	if err != nil {
+		log.Printf("⚠️  Track ingest failed: %v", err)
+		return
+	}
+
+	for _, e := range report.Errors {
+		log.Printf("⚠️  Track ingest: %s", e)
+	}
+	if report.HasChanges() {
+		log.Printf("✅ Track ingest complete: %s", report.Summary())
+	} else {
+		log.Printf("Track ingest complete - no changes (%d tracks)", report.Unchanged)
+	}
+}
+
+// ingestedTrack is one row of the tracks table
+type ingestedTrack struct {
+	models.Track
+	artwork     []byte
+	artworkType string
+	artworkFile string // Relative to the music directory
+	size        int64
+	modTime     time.Time
+}
+
+// analyze reads a file's tags, duration and waveform. Tags fill in the title and
+// artist; the folders are the fallback, and always give the genre.
+func (l *Library) analyze(file *ingestFile) (*ingestedTrack, error) {
+	f, err := os.Open(file.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	size := file.info.Size()
+
+	format := ingestFormats[strings.ToLower(filepath.Ext(file.path))]
+	tags := newFileTags()
+	var waveform []float64
+
+	switch format {
+	case FormatMP3:
+		if err := readID3v2(f, tags); err != nil {
+			return nil, err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		media, err := scanMP3(f, size, true)
+		if err != nil {
+			return nil, err
+		}
+		readID3v1(f, size, tags)
+		tags.duration, tags.sampleRate, tags.channels = media.duration, media.sampleRate, media.channels
+		waveform = mp3Waveform(media.levels)
+	case FormatWAV:
+		media, err := scanWAV(f, size)
+		if err != nil {
+			return nil, err
+		}
+		tags.duration, tags.sampleRate, tags.channels = media.duration, media.sampleRate, media.channels
+		if waveform, err = media.wavWaveform(f); err != nil {
+			return nil, err
+		}
+		if err := readWAVTags(f, size, tags); err != nil {
+			return nil, err
+		}
+	case FormatFLAC:
+		if err := readFLACTags(f, tags); err != nil {
+			return nil, err
+		}
+	default:
+		if err := readOggTags(f, size, tags); err != nil {
+			return nil, err
+		}
+		format = tags.format
+	}
+
+	genre, artist := file.parts[0], file.parts[1]
+	track := &ingestedTrack{
+		Track: models.Track{
+			ID:              file.id,
+			Title:           tags.title,
+			Artist:          tags.artist,
+			Album:           tags.album,
+			Genre:           genreFromFolder(genre),
+			Path:            strings.Join(file.parts, "/"),
+			Format:          format,
+			DurationSeconds: math.Round(tags.duration*1000) / 1000,
+			SampleRate:      tags.sampleRate,
+			Channels:        tags.channels,
+			Waveform:        waveform,
+		},
+		artwork:     tags.artwork,
+		artworkType: tags.artworkType,
+		size:        size,
+		modTime:     file.info.ModTime(),
+	}
+	if track.Artist == "" {
+		track.Artist = artist
+	}
+	if track.Title == "" {
+		track.Title = titleFromPath(file.parts)
+	} else {
+		track.Title = stripArtistPrefix(track.Title, track.Artist)
+	}
+	if track.artwork == nil {
+		if cover := findArtwork(filepath.Dir(file.path), filepath.Join(l.dir, genre, artist)); cover != "" {
+			if rel, err := filepath.Rel(l.dir, cover); err == nil {
+				track.artworkFile = filepath.ToSlash(rel)
+			}
+		}
+	}
+	return track, nil
+}
+
+// saveTrack inserts or replaces a track by path
+func (l *Library) saveTrack(t *ingestedTrack) error {
+	var waveform interface{}
+	if t.Waveform != nil {
+		encoded, err := json.Marshal(t.Waveform)
+		if err != nil {
+			return fmt.Errorf("encode waveform: %w", err)
+		}
+		waveform = string(encoded)
+	}
+
+	_, err := l.db.Exec(`
+		INSERT INTO tracks (id, path, title, artist, album, genre, format, duration_seconds, sample_rate, channels,
+			waveform, artwork, artwork_type, artwork_file, file_size, file_modified_at, ingested_at)
+		VALUES (?, ?, ?, ?, NULLIF(?, ''), ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?)
+		ON CONFLICT (path) DO UPDATE SET
+			id = excluded.id,
+			title = excluded.title,
+			artist = excluded.artist,
+			album = excluded.album,
+			genre = excluded.genre,
+			format = excluded.format,
+			duration_seconds = excluded.duration_seconds,
+			sample_rate = excluded.sample_rate,
+			channels = excluded.channels,
+			waveform = excluded.waveform,
+			artwork = excluded.artwork,
+			artwork_type = excluded.artwork_type,
+			artwork_file = excluded.artwork_file,
+			file_size = excluded.file_size,
+			file_modified_at = excluded.file_modified_at,
+			ingested_at = excluded.ingested_at
+	`, t.ID, t.Path, t.Title, t.Artist, t.Album, t.Genre, t.Format, t.DurationSeconds, t.SampleRate, t.Channels,
+		waveform, t.artwork, t.artworkType, t.artworkFile, t.size, t.modTime, time.Now())
+	if err != nil {
+		return fmt.Errorf("save track %s: %w", t.Path, err)
+	}
+	return nil
+}
+
+// ingestedFiles returns what the tracks table holds, by path
+func (l *Library) ingestedFiles() (map[string]ingestedFile, error) {
+	rows, err := l.db.Query(`SELECT path, id, file_size, file_modified_at FROM tracks`)
+	if err != nil {
+		return nil, fmt.Errorf("query tracks: %w", err)
+	}
+	defer rows.Close()
+
+	files := make(map[string]ingestedFile)
+	for rows.Next() {
+		var path string
+		var f ingestedFile
+		if err := rows.Scan(&path, &f.id, &f.size, &f.modTime); err != nil {
+			return nil, fmt.Errorf("scan track: %w", err)
+		}
+		files[path] = f
+	}
+	return files, rows.Err()
+}
+
+// Catalogue returns the ingested tracks in genre, artist and path order, with
+// waveforms, preview windows and play counts. URLs are under baseURL.
+func (l *Library) Catalogue(baseURL string) ([]models.Track, error) {
+	return l.catalogue(baseURL, "")
+}
+
+// CatalogueTrack returns one ingested track, or ErrTrackNotFound
+func (l *Library) CatalogueTrack(id, baseURL string) (*models.Track, error) {
+	tracks, err := l.catalogue(baseURL, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(tracks) == 0 {
+		return nil, ErrTrackNotFound
+	}
+	return &tracks[0], nil
+}
+
+func (l *Library) catalogue(baseURL, id string) ([]models.Track, error) {
+	query := `
+		SELECT id, path, title, artist, COALESCE(album, ''), genre, format, duration_seconds, sample_rate, channels,
+			waveform, artwork IS NOT NULL OR artwork_file IS NOT NULL
+		FROM tracks`
+	var args []interface{}
+	if id != "" {
+		query += ` WHERE id = ?`
+		args = append(args, id)
+	}
+	query += ` ORDER BY genre, artist, path`
+
+	rows, err := l.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query tracks: %w", err)
+	}
+	defer rows.Close()
+
+	tracks := []models.Track{}
+	for rows.Next() {
+		var t models.Track
+		var waveform sql.NullString
+		var hasArtwork bool
+		if err := rows.Scan(&t.ID, &t.Path, &t.Title, &t.Artist, &t.Album, &t.Genre, &t.Format, &t.DurationSeconds,
+			&t.SampleRate, &t.Channels, &waveform, &hasArtwork); err != nil {
+			return nil, fmt.Errorf("scan track: %w", err)
+		}
+		if waveform.Valid {
+			if err := json.Unmarshal([]byte(waveform.String), &t.Waveform); err != nil {
+				return nil, fmt.Errorf("decode waveform of %s: %w", t.Path, err)
+			}
+		}
+		if hasArtwork {
+			t.ArtworkURL = baseURL + "/api/v1/tracks/" + t.ID + "/artwork"
+		}
+		if t.Format == FormatMP3 || t.Format == FormatWAV {
+			t.StreamURL = baseURL + "/api/v1/audio/tracks/" + t.ID + "/stream"
+		}
+		tracks = append(tracks, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	previews, err := l.previews()
+	if err != nil {
+		return nil, err
+	}
+	plays, err := l.plays()
+	if err != nil {
+		return nil, err
+	}
+	for i := range tracks {
+		if p, ok := previews[tracks[i].ID]; ok {
+			p = clampPreview(p, tracks[i].DurationSeconds)
+			tracks[i].Preview = &p
+		}
+		tracks[i].Plays = plays[tracks[i].ID]
+	}
+	return tracks, nil
+}
+
+// Artwork is an ingested track's cover image
+type Artwork struct {
+	io.ReadSeeker
+	ContentType string
+	ModTime     time.Time
+}
+
+// CatalogueArtwork returns an ingested track's embedded picture, or else the cover
+// image found next to it. Returns ErrTrackNotFound or ErrNoArtwork.
+func (l *Library) CatalogueArtwork(id string) (*Artwork, error) {
+	var data []byte
+	var contentType, file sql.NullString
+	var ingestedAt time.Time
+	err := l.db.QueryRow(`
+		SELECT artwork, artwork_type, artwork_file, ingested_at FROM tracks WHERE id = ?
+	`, id).Scan(&data, &contentType, &file, &ingestedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTrackNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query artwork: %w", err)
+	}
+
+	if data != nil {
+		return &Artwork{ReadSeeker: bytes.NewReader(data), ContentType: contentType.String, ModTime: ingestedAt}, nil
+	}
+	if !file.Valid {
+		return nil, ErrNoArtwork
+	}
+	path := filepath.Join(l.dir, filepath.FromSlash(file.String))
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, ErrNoArtwork // Gone since the last ingest
+	}
+	data, err = os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read artwork: %w", err)
+	}
+	return &Artwork{
+		ReadSeeker:  bytes.NewReader(data),
+		ContentType: mime.TypeByExtension(strings.ToLower(filepath.Ext(path))),
+		ModTime:     info.ModTime(),
+	}, nil
+}
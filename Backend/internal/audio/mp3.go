@@ -0,0 +1,297 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math"
+)
+
+// mp3Bitrates are in kbps, by [MPEG-1 or not][layer-1][bitrate index]
+var mp3Bitrates = [2][3][16]int{
+	{ // MPEG-1
+		{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0}, // Layer I
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},    // Layer II
+		{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},     // Layer III
+	},
+	{ // MPEG-2 and 2.5
+		{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+		{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+	},
+}
+
+// mp3SampleRates are by [MPEG-1, 2, 2.5][sample rate index]
+var mp3SampleRates = [3][3]int{
+	{44100, 48000, 32000},
+	{22050, 24000, 16000},
+	{11025, 12000, 8000},
+}
+
+// mp3Frame is what a frame header says about its frame
+type mp3Frame struct {
+	size       int // Bytes, header included
+	samples    int // Per channel
+	sampleRate int
+	channels   int
+	layer      int
+	mpeg1      bool
+	crc        bool // A 16-bit CRC follows the header
+	sideInfo   int  // Bytes of side information after the header (Layer III)
+}
+
+// parseMP3Header decodes a 4-byte frame header. Free-format and reserved values
+// are rejected, which also weeds out most false syncs.
+func parseMP3Header(b []byte) (mp3Frame, bool) {
+	if len(b) < 4 || b[0] != 0xFF || b[1]&0xE0 != 0xE0 {
+		return mp3Frame{}, false
+	}
+	versionBits := (b[1] >> 3) & 3 // 00 = 2.5, 01 = reserved, 10 = 2, 11 = 1
+	layerBits := (b[1] >> 1) & 3   // 01 = III, 10 = II, 11 = I
+	bitrateIndex := b[2] >> 4
+	sampleRateIndex := (b[2] >> 2) & 3
+	padding := int(b[2]>>1) & 1
+	mono := b[3]>>6 == 3
+	crc := b[1]&1 == 0
+	if versionBits == 1 || layerBits == 0 || bitrateIndex == 0 || bitrateIndex == 15 || sampleRateIndex == 3 {
+		return mp3Frame{}, false
+	}
+
+	version := 2 // MPEG-2.5
+	switch versionBits {
+	case 3:
+		version = 0
+	case 2:
+		version = 1
+	}
+	layer := 4 - int(layerBits)
+	table := 0
+	if version != 0 {
+		table = 1
+	}
+	bitrate := mp3Bitrates[table][layer-1][bitrateIndex] * 1000
+	f := mp3Frame{sampleRate: mp3SampleRates[version][sampleRateIndex], channels: 2, layer: layer, mpeg1: version == 0, crc: crc}
+	if mono {
+		f.channels = 1
+	}
+
+	switch {
+	case layer == 1:
+		f.samples = 384
+		f.size = (12*bitrate/f.sampleRate + padding) * 4
+	case layer == 2 || version == 0:
+		f.samples = 1152
+		f.size = 144*bitrate/f.sampleRate + padding
+	default: // Layer III, MPEG-2 and 2.5
+		f.samples = 576
+		f.size = 72*bitrate/f.sampleRate + padding
+	}
+
+	if layer == 3 {
+		switch {
+		case version == 0 && mono:
+			f.sideInfo = 17
+		case version == 0:
+			f.sideInfo = 32
+		case mono:
+			f.sideInfo = 9
+		default:
+			f.sideInfo = 17
+		}
+	}
+	return f, f.size > 4
+}
+
+// isInfoFrame reports whether a frame is a Xing/Info or VBRI header rather than audio.
+// Encoders put one first; it describes the whole file, so clips must leave it out.
+func isInfoFrame(frame []byte, f mp3Frame) bool {
+	at := 4 + f.sideInfo
+	if f.crc {
+		at += 2
+	}
+	if len(frame) >= at+4 {
+		tag := frame[at : at+4]
+		if bytes.Equal(tag, []byte("Xing")) || bytes.Equal(tag, []byte("Info")) {
+			return true
+		}
+	}
+	return len(frame) >= 40 && bytes.Equal(frame[36:40], []byte("VBRI"))
+}
+
+// id3v2Size returns the size of an ID3v2 tag at the start of header, footer included,
+// or 0 if there is none
+func id3v2Size(header []byte) int64 {
+	if len(header) < 10 || !bytes.Equal(header[:3], []byte("ID3")) {
+		return 0
+	}
+	size := syncsafe(header[6:10]) + 10
+	if header[5]&0x10 != 0 {
+		size += 10
+	}
+	return size
+}
+
+// scanMP3 walks every frame of an MP3 stream, working out its duration and a seek
+// point per second so clips can be cut on frame boundaries. Junk between frames is
+// skipped; the first frame is only trusted when another frame follows it. With
+// levels set, each frame's loudness is estimated too (see mp3FrameLevel).
+func scanMP3(r io.Reader, size int64, levels bool) (*mediaInfo, error) {
+	br := bufio.NewReaderSize(r, 64*1024)
+	info := &mediaInfo{format: FormatMP3}
+
+	var pos int64
+	if header, _ := br.Peek(10); id3v2Size(header) > 0 {
+		skip := id3v2Size(header)
+		n, err := br.Discard(int(skip))
+		pos += int64(n)
+		if err != nil {
+			return nil, ErrUnsupportedFormat
+		}
+	}
+
+	var seconds float64 // Start time of the next frame
+	first := true
+	for pos+4 <= size {
+		header, err := br.Peek(4)
+		if err != nil {
+			break
+		}
+		f, ok := parseMP3Header(header)
+		if !ok || pos+int64(f.size) > size {
+			// Resync one byte on
+			if _, err := br.Discard(1); err != nil {
+				break
+			}
+			pos++
+			continue
+		}
+
+		frame, _ := br.Peek(f.size + 4)
+		if first {
+			if len(frame) < f.size+4 {
+				break
+			}
+			if _, ok := parseMP3Header(frame[f.size:]); !ok {
+				br.Discard(1)
+				pos++
+				continue
+			}
+			first = false
+			info.sampleRate = f.sampleRate
+			info.channels = f.channels
+			if isInfoFrame(frame, f) {
+				br.Discard(f.size)
+				pos += int64(f.size)
+				continue
+			}
+		}
+		if levels {
+			frame, _ := br.Peek(f.size)
+			info.levels = append(info.levels, mp3FrameLevel(frame, f))
+		}
+
+		for float64(len(info.seekPoints)) <= seconds {
+			info.seekPoints = append(info.seekPoints, pos)
+		}
+		seconds += float64(f.samples) / float64(f.sampleRate)
+		if _, err := br.Discard(f.size); err != nil {
+			break
+		}
+		pos += int64(f.size)
+		info.dataEnd = pos
+	}
+
+	if len(info.seekPoints) == 0 {
+		return nil, ErrUnsupportedFormat
+	}
+	info.dataStart = info.seekPoints[0]
+	info.duration = seconds
+	return info, nil
+}
+
+// mp3Clip returns the byte range holding seconds [start, end) of an MP3 stream.
+// Frames are self-contained, so the range plays on its own.
+func (m *mediaInfo) mp3Clip(start, end float64) (int64, int64) {
+	from := m.seekPoints[min(int(start), len(m.seekPoints)-1)]
+	to := m.dataEnd
+	if i := int(math.Ceil(end)); i < len(m.seekPoints) {
+		to = m.seekPoints[i]
+	}
+	return from, to
+}
+
+// mp3FrameLevel estimates a Layer III frame's loudness without decoding it, from the
+// global gain of each granule and channel: the quantiser step size, which encoders
+// raise with the signal level. Each step is 1.5 dB. Granules with no coded audio are
+// silent. Other layers get a flat level.
+func mp3FrameLevel(frame []byte, f mp3Frame) float32 {
+	if f.layer != 3 {
+		return 1
+	}
+	at := 4
+	if f.crc {
+		at += 2
+	}
+	if len(frame) < at+f.sideInfo {
+		return 0
+	}
+	side := &bitReader{buf: frame[at : at+f.sideInfo]}
+
+	granules := 1
+	if f.mpeg1 {
+		granules = 2
+		side.skip(9) // main_data_begin
+		if f.channels == 1 {
+			side.skip(5) // private bits
+		} else {
+			side.skip(3)
+		}
+		side.skip(4 * f.channels) // scfsi
+	} else {
+		side.skip(8)
+		side.skip(f.channels) // private bits
+	}
+
+	var level float32
+	for gr := 0; gr < granules; gr++ {
+		for ch := 0; ch < f.channels; ch++ {
+			part23 := side.read(12)
+			side.skip(9) // big_values
+			gain := side.read(8)
+			if f.mpeg1 {
+				side.skip(4 + 1 + 22 + 3) // scalefac_compress, window switching, block/region info, flags
+			} else {
+				side.skip(9 + 1 + 22 + 2)
+			}
+			if part23 == 0 {
+				continue
+			}
+			level = max(level, float32(math.Pow(2, (float64(gain)-210)/4)))
+		}
+	}
+	return level
+}
+
+// bitReader reads big-endian bit fields
+type bitReader struct {
+	buf []byte
+	pos int // Bits
+}
+
+func (b *bitReader) read(n int) int {
+	v := 0
+	for i := 0; i < n; i++ {
+		byteAt := b.pos / 8
+		bit := 0
+		if byteAt < len(b.buf) {
+			bit = int(b.buf[byteAt]>>(7-b.pos%8)) & 1
+		}
+		v = v<<1 | bit
+		b.pos++
+	}
+	return v
+}
+
+func (b *bitReader) skip(n int) {
+	b.pos += n
+}
@@ -0,0 +1,618 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// maxTagSize caps how much of a file is read as tags, embedded artwork included
+const maxTagSize = 16 << 20
+
+// pictureFrontCover is the ID3/FLAC picture type preferred as artwork
+const pictureFrontCover = 3
+
+// fileTags is what an audio file says about itself
+type fileTags struct {
+	format               string // Set by readOggTags: ogg or opus
+	title, artist, album string
+	sampleRate, channels int
+	duration             float64 // Seconds
+
+	artwork     []byte
+	artworkType string // MIME type
+	artworkKind int    // Picture type of artwork, or -1 for none
+}
+
+func newFileTags() *fileTags {
+	return &fileTags{artworkKind: -1}
+}
+
+// setText fills in a text tag unless an earlier tag already did
+func setText(field *string, value string) {
+	if *field == "" {
+		*field = strings.TrimSpace(value)
+	}
+}
+
+// setPicture keeps a picture as the artwork, preferring the front cover over any other
+func (t *fileTags) setPicture(kind int, mimeType string, data []byte) {
+	if len(data) == 0 || t.artworkKind == pictureFrontCover {
+		return
+	}
+	if t.artworkKind >= 0 && kind != pictureFrontCover {
+		return
+	}
+	if mimeType == "" || !strings.Contains(mimeType, "/") {
+		mimeType = sniffImageType(data)
+	}
+	t.artwork = data
+	t.artworkType = mimeType
+	t.artworkKind = kind
+}
+
+// sniffImageType names the image format from its magic number
+func sniffImageType(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("\xFF\xD8\xFF")):
+		return "image/jpeg"
+	case bytes.HasPrefix(data, []byte("\x89PNG")):
+		return "image/png"
+	case bytes.HasPrefix(data, []byte("GIF8")):
+		return "image/gif"
+	case len(data) >= 12 && bytes.Equal(data[8:12], []byte("WEBP")):
+		return "image/webp"
+	}
+	return "application/octet-stream"
+}
+
+// readID3v2 reads an ID3v2 tag at the reader's position, if there is one, leaving
+// the reader just after it
+func readID3v2(r io.ReadSeeker, t *fileTags) error {
+	start, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(r, header); err != nil {
+		_, err = r.Seek(start, io.SeekStart)
+		return err
+	}
+	size := id3v2Size(header)
+	if size == 0 {
+		_, err = r.Seek(start, io.SeekStart)
+		return err
+	}
+	if size > maxTagSize {
+		_, err = r.Seek(start+size, io.SeekStart)
+		return err
+	}
+
+	tag := make([]byte, size)
+	copy(tag, header)
+	if _, err := io.ReadFull(r, tag[10:]); err != nil {
+		return ErrUnsupportedFormat
+	}
+	parseID3v2(tag, t)
+	return nil
+}
+
+// parseID3v2 reads title, artist, album and artwork from a whole ID3v2.2, 2.3 or 2.4 tag
+func parseID3v2(tag []byte, t *fileTags) {
+	if id3v2Size(tag) == 0 {
+		return
+	}
+	major, flags := tag[3], tag[5]
+	body := tag[10:min(10+int(syncsafe(tag[6:10])), len(tag))]
+	if major < 4 && flags&0x80 != 0 {
+		body = removeUnsync(body)
+	}
+	if flags&0x40 != 0 && len(body) >= 4 {
+		// Extended header: its size excludes itself in 2.3 and includes itself in 2.4
+		skip := int(binary.BigEndian.Uint32(body)) + 4
+		if major == 4 {
+			skip = int(syncsafe(body[:4]))
+		}
+		if skip < 0 || skip > len(body) {
+			return
+		}
+		body = body[skip:]
+	}
+
+	idLen, headerLen := 4, 10
+	if major == 2 {
+		idLen, headerLen = 3, 6
+	}
+	for len(body) >= headerLen && body[0] != 0 {
+		id := string(body[:idLen])
+		var size int
+		var frameFlags uint16
+		switch major {
+		case 2:
+			size = int(body[3])<<16 | int(body[4])<<8 | int(body[5])
+		case 3:
+			size = int(binary.BigEndian.Uint32(body[4:8]))
+			frameFlags = binary.BigEndian.Uint16(body[8:10])
+		default:
+			size = int(syncsafe(body[4:8]))
+			frameFlags = binary.BigEndian.Uint16(body[8:10])
+		}
+		if size < 0 || headerLen+size > len(body) {
+			return
+		}
+		data := body[headerLen : headerLen+size]
+		body = body[headerLen+size:]
+
+		switch major {
+		case 3:
+			if frameFlags&0x00C0 != 0 { // Compressed or encrypted
+				continue
+			}
+			if frameFlags&0x0020 != 0 && len(data) > 0 { // Grouping identity
+				data = data[1:]
+			}
+		case 4:
+			if frameFlags&0x000C != 0 {
+				continue
+			}
+			if frameFlags&0x0040 != 0 && len(data) > 0 {
+				data = data[1:]
+			}
+			if frameFlags&0x0001 != 0 { // Data length indicator
+				if len(data) < 4 {
+					continue
+				}
+				data = data[4:]
+			}
+			if frameFlags&0x0002 != 0 {
+				data = removeUnsync(data)
+			}
+		}
+
+		switch id {
+		case "TIT2", "TT2":
+			setText(&t.title, id3Text(data))
+		case "TPE1", "TP1":
+			setText(&t.artist, id3Text(data))
+		case "TALB", "TAL":
+			setText(&t.album, id3Text(data))
+		case "APIC":
+			parseAPIC(data, t)
+		case "PIC":
+			parsePIC(data, t)
+		}
+	}
+}
+
+// parseAPIC reads an attached picture: encoding, MIME type, picture type, description, data
+func parseAPIC(data []byte, t *fileTags) {
+	if len(data) < 2 {
+		return
+	}
+	enc := data[0]
+	end := bytes.IndexByte(data[1:], 0)
+	if end < 0 || 1+end+2 > len(data) {
+		return
+	}
+	mimeType := strings.ToLower(string(data[1 : 1+end]))
+	kind := int(data[1+end+1])
+	_, rest := splitTerminated(enc, data[1+end+2:])
+	if mimeType == "jpg" || mimeType == "png" {
+		mimeType = "" // Some taggers write the 2.2 format name here
+	}
+	t.setPicture(kind, mimeType, rest)
+}
+
+// parsePIC reads an ID3v2.2 picture, which names a 3-letter format instead of a MIME type
+func parsePIC(data []byte, t *fileTags) {
+	if len(data) < 5 {
+		return
+	}
+	_, rest := splitTerminated(data[0], data[5:])
+	t.setPicture(int(data[4]), "", rest)
+}
+
+// id3Text decodes a text frame, keeping the first of several values
+func id3Text(data []byte) string {
+	if len(data) < 2 {
+		return ""
+	}
+	value, _ := splitTerminated(data[0], data[1:])
+	return decodeID3String(data[0], value)
+}
+
+// splitTerminated splits a string in the given encoding from what follows its terminator
+func splitTerminated(enc byte, b []byte) ([]byte, []byte) {
+	if enc == 1 || enc == 2 {
+		for i := 0; i+1 < len(b); i += 2 {
+			if b[i] == 0 && b[i+1] == 0 {
+				return b[:i], b[i+2:]
+			}
+		}
+		return b, nil
+	}
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		return b[:i], b[i+1:]
+	}
+	return b, nil
+}
+
+// decodeID3String converts ISO-8859-1 (0), UTF-16 with BOM (1), UTF-16BE (2) or UTF-8 (3)
+func decodeID3String(enc byte, b []byte) string {
+	switch enc {
+	case 1, 2:
+		bigEndian := enc == 2
+		if len(b) >= 2 {
+			switch {
+			case b[0] == 0xFF && b[1] == 0xFE:
+				bigEndian, b = false, b[2:]
+			case b[0] == 0xFE && b[1] == 0xFF:
+				bigEndian, b = true, b[2:]
+			}
+		}
+		units := make([]uint16, len(b)/2)
+		for i := range units {
+			if bigEndian {
+				units[i] = binary.BigEndian.Uint16(b[2*i:])
+			} else {
+				units[i] = binary.LittleEndian.Uint16(b[2*i:])
+			}
+		}
+		return string(utf16.Decode(units))
+	case 3:
+		return strings.ToValidUTF8(string(b), "")
+	default:
+		return latin1(b)
+	}
+}
+
+func latin1(b []byte) string {
+	if utf8.Valid(b) && !bytes.ContainsFunc(b, func(r rune) bool { return r >= 0x80 && r < 0xA0 }) {
+		// Plenty of taggers write UTF-8 and call it Latin-1
+		return string(b)
+	}
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+func syncsafe(b []byte) int64 {
+	return int64(b[0]&0x7F)<<21 | int64(b[1]&0x7F)<<14 | int64(b[2]&0x7F)<<7 | int64(b[3]&0x7F)
+}
+
+// removeUnsync undoes ID3 unsynchronisation, which stuffs a zero after every 0xFF
+func removeUnsync(b []byte) []byte {
+	return bytes.ReplaceAll(b, []byte{0xFF, 0x00}, []byte{0xFF})
+}
+
+// readID3v1 reads the fixed-size tag some MP3s carry in their last 128 bytes
+func readID3v1(r io.ReaderAt, size int64, t *fileTags) {
+	if size < 128 {
+		return
+	}
+	tag := make([]byte, 128)
+	if _, err := r.ReadAt(tag, size-128); err != nil || !bytes.HasPrefix(tag, []byte("TAG")) {
+		return
+	}
+	field := func(b []byte) string {
+		return strings.TrimRight(latin1(bytes.TrimRight(b, "\x00")), " ")
+	}
+	setText(&t.title, field(tag[3:33]))
+	setText(&t.artist, field(tag[33:63]))
+	setText(&t.album, field(tag[63:93]))
+}
+
+// parseVorbisComment reads a Vorbis comment block, as used by FLAC, Ogg Vorbis and Opus
+func parseVorbisComment(b []byte, t *fileTags) {
+	next := func() ([]byte, bool) {
+		if len(b) < 4 {
+			return nil, false
+		}
+		n := int(binary.LittleEndian.Uint32(b))
+		if n < 0 || 4+n > len(b) {
+			return nil, false
+		}
+		field := b[4 : 4+n]
+		b = b[4+n:]
+		return field, true
+	}
+	if _, ok := next(); !ok { // Vendor
+		return
+	}
+	if len(b) < 4 {
+		return
+	}
+	count := int(binary.LittleEndian.Uint32(b))
+	b = b[4:]
+	for i := 0; i < count; i++ {
+		field, ok := next()
+		if !ok {
+			return
+		}
+		key, value, found := strings.Cut(string(field), "=")
+		if !found {
+			continue
+		}
+		switch strings.ToUpper(key) {
+		case "TITLE":
+			setText(&t.title, value)
+		case "ARTIST":
+			setText(&t.artist, value)
+		case "ALBUM":
+			setText(&t.album, value)
+		case "METADATA_BLOCK_PICTURE":
+			if picture, err := base64.StdEncoding.DecodeString(value); err == nil {
+				parseFLACPicture(picture, t)
+			}
+		}
+	}
+}
+
+// parseFLACPicture reads a FLAC PICTURE block: type, MIME type, description,
+// dimensions, then the image
+func parseFLACPicture(b []byte, t *fileTags) {
+	next := func() ([]byte, bool) {
+		if len(b) < 4 {
+			return nil, false
+		}
+		n := int(binary.BigEndian.Uint32(b))
+		if n < 0 || 4+n > len(b) {
+			return nil, false
+		}
+		field := b[4 : 4+n]
+		b = b[4+n:]
+		return field, true
+	}
+	if len(b) < 4 {
+		return
+	}
+	kind := int(binary.BigEndian.Uint32(b))
+	b = b[4:]
+	mimeType, ok := next()
+	if !ok {
+		return
+	}
+	if _, ok := next(); !ok { // Description
+		return
+	}
+	if len(b) < 16 {
+		return
+	}
+	b = b[16:] // Width, height, colour depth, palette size
+	data, ok := next()
+	if !ok {
+		return
+	}
+	t.setPicture(kind, string(mimeType), data)
+}
+
+// readFLACTags reads a FLAC file's STREAMINFO, Vorbis comments and pictures
+func readFLACTags(r io.ReadSeeker, t *fileTags) error {
+	if err := readID3v2(r, t); err != nil {
+		return err
+	}
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != "fLaC" {
+		return ErrUnsupportedFormat
+	}
+
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return ErrUnsupportedFormat
+		}
+		last := header[0]&0x80 != 0
+		kind := header[0] & 0x7F
+		length := int64(header[1])<<16 | int64(header[2])<<8 | int64(header[3])
+
+		switch {
+		case kind == 0 || ((kind == 4 || kind == 6) && length <= maxTagSize):
+			block := make([]byte, length)
+			if _, err := io.ReadFull(r, block); err != nil {
+				return ErrUnsupportedFormat
+			}
+			switch kind {
+			case 0:
+				if len(block) < 18 {
+					return ErrUnsupportedFormat
+				}
+				// 20 bits sample rate, 3 bits channels - 1, 5 bits bits per sample - 1, 36 bits samples
+				info := binary.BigEndian.Uint64(block[10:18])
+				t.sampleRate = int(info >> 44)
+				t.channels = int(info>>41&0x7) + 1
+				if samples := info & (1<<36 - 1); t.sampleRate > 0 {
+					t.duration = float64(samples) / float64(t.sampleRate)
+				}
+			case 4:
+				parseVorbisComment(block, t)
+			case 6:
+				parseFLACPicture(block, t)
+			}
+		default:
+			if _, err := r.Seek(length, io.SeekCurrent); err != nil {
+				return err
+			}
+		}
+		if last {
+			break
+		}
+	}
+
+	if t.sampleRate == 0 {
+		return ErrUnsupportedFormat
+	}
+	return nil
+}
+
+// readOggTags reads an Ogg Vorbis or Opus file's identification and comment headers,
+// and its duration from the granule position of the last page
+func readOggTags(r io.ReadSeeker, size int64, t *fileTags) error {
+	packets, serial, err := readOggPackets(r, 2)
+	if err != nil || len(packets) < 2 {
+		return ErrUnsupportedFormat
+	}
+	ident, comments := packets[0], packets[1]
+
+	var preSkip int64
+	granuleRate := 0
+	switch {
+	case len(ident) >= 16 && bytes.HasPrefix(ident, []byte("\x01vorbis")):
+		t.channels = int(ident[11])
+		t.sampleRate = int(binary.LittleEndian.Uint32(ident[12:16]))
+		granuleRate = t.sampleRate
+		t.format = FormatOgg
+		if bytes.HasPrefix(comments, []byte("\x03vorbis")) {
+			parseVorbisComment(comments[7:], t)
+		}
+	case len(ident) >= 16 && bytes.HasPrefix(ident, []byte("OpusHead")):
+		t.channels = int(ident[9])
+		preSkip = int64(binary.LittleEndian.Uint16(ident[10:12]))
+		t.sampleRate = int(binary.LittleEndian.Uint32(ident[12:16])) // Of the original input
+		granuleRate = 48000                                          // Opus always decodes at 48 kHz
+		t.format = FormatOpus
+		if bytes.HasPrefix(comments, []byte("OpusTags")) {
+			parseVorbisComment(comments[8:], t)
+		}
+	default:
+		return ErrUnsupportedFormat
+	}
+
+	// A page is at most 64 KiB, so the last one starts in the final 64 KiB
+	tailStart := max(size-65536, 0)
+	tail := make([]byte, size-tailStart)
+	if _, err := r.Seek(tailStart, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(r, tail); err != nil {
+		return ErrUnsupportedFormat
+	}
+	for i := bytes.LastIndex(tail, []byte("OggS")); i >= 0; i = bytes.LastIndex(tail[:i], []byte("OggS")) {
+		if i+27 > len(tail) || binary.LittleEndian.Uint32(tail[i+14:]) != serial {
+			continue
+		}
+		granule := int64(binary.LittleEndian.Uint64(tail[i+6:]))
+		if granule > 0 && granuleRate > 0 {
+			t.duration = float64(max(granule-preSkip, 0)) / float64(granuleRate)
+			break
+		}
+	}
+	return nil
+}
+
+// readOggPackets reassembles the first n packets of the first logical stream, which
+// for Vorbis and Opus are the headers. Returns the stream's serial number.
+func readOggPackets(r io.Reader, n int) ([][]byte, uint32, error) {
+	var packets [][]byte
+	var packet []byte
+	var serial uint32
+	first := true
+	header := make([]byte, 27)
+	for len(packets) < n {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, 0, err
+		}
+		if !bytes.HasPrefix(header, []byte("OggS")) {
+			return nil, 0, ErrUnsupportedFormat
+		}
+		pageSerial := binary.LittleEndian.Uint32(header[14:18])
+		if first {
+			serial, first = pageSerial, false
+		}
+		segments := make([]byte, header[26])
+		if _, err := io.ReadFull(r, segments); err != nil {
+			return nil, 0, err
+		}
+		bodySize := 0
+		for _, s := range segments {
+			bodySize += int(s)
+		}
+		body := make([]byte, bodySize)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return nil, 0, err
+		}
+		if pageSerial != serial {
+			continue // Another multiplexed stream
+		}
+
+		for _, s := range segments {
+			packet = append(packet, body[:s]...)
+			body = body[s:]
+			if len(packet) > maxTagSize {
+				return nil, 0, ErrUnsupportedFormat
+			}
+			if s < 255 {
+				packets = append(packets, packet)
+				packet = nil
+				if len(packets) == n {
+					break
+				}
+			}
+		}
+	}
+	return packets, serial, nil
+}
+
+// readWAVTags reads a WAV file's LIST/INFO chunk and any embedded ID3 tag
+func readWAVTags(r io.ReadSeeker, size int64, t *fileTags) error {
+	if _, err := r.Seek(12, io.SeekStart); err != nil {
+		return err
+	}
+	pos := int64(12)
+	chunk := make([]byte, 8)
+	for pos+8 <= size {
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			break
+		}
+		id := string(chunk[:4])
+		chunkSize := int64(binary.LittleEndian.Uint32(chunk[4:]))
+
+		if (id == "LIST" || id == "id3 " || id == "ID3 ") && chunkSize <= maxTagSize {
+			body := make([]byte, chunkSize)
+			if _, err := io.ReadFull(r, body); err != nil {
+				break
+			}
+			if id == "LIST" {
+				parseRIFFInfo(body, t)
+			} else {
+				parseID3v2(body, t)
+			}
+		}
+
+		pos += 8 + chunkSize + chunkSize%2
+		if _, err := r.Seek(pos, io.SeekStart); err != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// parseRIFFInfo reads the title, artist and album from a LIST chunk of type INFO
+func parseRIFFInfo(b []byte, t *fileTags) {
+	if !bytes.HasPrefix(b, []byte("INFO")) {
+		return
+	}
+	b = b[4:]
+	for len(b) >= 8 {
+		id := string(b[:4])
+		n := int(binary.LittleEndian.Uint32(b[4:8]))
+		if n < 0 || 8+n > len(b) {
+			return
+		}
+		value := strings.TrimRight(latin1(bytes.TrimRight(b[8:8+n], "\x00")), " ")
+		switch id {
+		case "INAM":
+			setText(&t.title, value)
+		case "IART":
+			setText(&t.artist, value)
+		case "IPRD":
+			setText(&t.album, value)
+		}
+		b = b[min(8+n+n%2, len(b)):]
+	}
+}
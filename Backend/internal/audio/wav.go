@@ -0,0 +1,83 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// scanWAV reads a RIFF/WAVE header: the fmt chunk, kept whole so clips can reuse it,
+// and where the samples are
+func scanWAV(r io.ReadSeeker, size int64) (*mediaInfo, error) {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(r, header); err != nil ||
+		!bytes.Equal(header[:4], []byte("RIFF")) || !bytes.Equal(header[8:], []byte("WAVE")) {
+		return nil, ErrUnsupportedFormat
+	}
+
+	info := &mediaInfo{format: FormatWAV}
+	pos := int64(12)
+	for pos+8 <= size {
+		chunk := make([]byte, 8)
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			break
+		}
+		id := string(chunk[:4])
+		chunkSize := int64(binary.LittleEndian.Uint32(chunk[4:]))
+		body := pos + 8
+
+		switch id {
+		case "fmt ":
+			if chunkSize < 16 || chunkSize > 1024 {
+				return nil, ErrUnsupportedFormat
+			}
+			fmtBody := make([]byte, chunkSize)
+			if _, err := io.ReadFull(r, fmtBody); err != nil {
+				return nil, ErrUnsupportedFormat
+			}
+			info.fmtChunk = append(chunk, fmtBody...)
+			info.channels = int(binary.LittleEndian.Uint16(fmtBody[2:4]))
+			info.sampleRate = int(binary.LittleEndian.Uint32(fmtBody[4:8]))
+			info.byteRate = int64(binary.LittleEndian.Uint32(fmtBody[8:12]))
+			info.blockAlign = int64(binary.LittleEndian.Uint16(fmtBody[12:14]))
+		case "data":
+			info.dataStart = body
+			info.dataEnd = min(body+chunkSize, size)
+		}
+		if info.dataEnd > 0 {
+			break
+		}
+
+		// Chunks are word aligned
+		pos = body + chunkSize + chunkSize%2
+		if _, err := r.Seek(pos, io.SeekStart); err != nil {
+			break
+		}
+	}
+
+	if info.fmtChunk == nil || info.dataEnd == 0 || info.byteRate == 0 || info.blockAlign == 0 {
+		return nil, ErrUnsupportedFormat
+	}
+	info.duration = float64(info.dataEnd-info.dataStart) / float64(info.byteRate)
+	return info, nil
+}
+
+// wavClip returns a WAV header for seconds [start, end) of the samples, and the byte
+// range of the samples to follow it
+func (m *mediaInfo) wavClip(start, end float64) ([]byte, int64, int64) {
+	offset := func(seconds float64) int64 {
+		n := int64(seconds*float64(m.byteRate)) / m.blockAlign * m.blockAlign
+		return min(m.dataStart+n, m.dataEnd)
+	}
+	from, to := offset(start), offset(end)
+	dataSize := to - from
+
+	var header bytes.Buffer
+	header.WriteString("RIFF")
+	binary.Write(&header, binary.LittleEndian, uint32(4+len(m.fmtChunk)+8+int(dataSize)))
+	header.WriteString("WAVE")
+	header.Write(m.fmtChunk)
+	header.WriteString("data")
+	binary.Write(&header, binary.LittleEndian, uint32(dataSize))
+	return header.Bytes(), from, to
+}
@@ -0,0 +1,108 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// WaveformPeaks is how many peaks a track's waveform has, evenly spread over its length
+const WaveformPeaks = 200
+
+// WAV sample formats (the fmt chunk's format tag)
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE // The real format tag starts the subformat GUID
+)
+
+// mp3Waveform downsamples per-frame levels from scanMP3 to WaveformPeaks peaks
+func mp3Waveform(levels []float32) []float64 {
+	if len(levels) == 0 {
+		return nil
+	}
+	peaks := make([]float64, WaveformPeaks)
+	for i := range peaks {
+		from := i * len(levels) / WaveformPeaks
+		to := max((i+1)*len(levels)/WaveformPeaks, from+1)
+		for _, level := range levels[from:min(to, len(levels))] {
+			peaks[i] = max(peaks[i], float64(level))
+		}
+	}
+	return normalizePeaks(peaks)
+}
+
+// wavWaveform reads every sample of a WAV file and keeps the largest absolute value,
+// across channels, in each of WaveformPeaks stretches. Returns nil for sample formats
+// other than integer PCM and 32/64-bit float.
+func (m *mediaInfo) wavWaveform(r io.ReadSeeker) ([]float64, error) {
+	if len(m.fmtChunk) < 8+16 {
+		return nil, nil
+	}
+	fmtBody := m.fmtChunk[8:]
+	format := binary.LittleEndian.Uint16(fmtBody[0:2])
+	bits := int(binary.LittleEndian.Uint16(fmtBody[14:16]))
+	if format == wavFormatExtensible && len(fmtBody) >= 26 {
+		format = binary.LittleEndian.Uint16(fmtBody[24:26])
+	}
+
+	var sample func(b []byte) float64
+	switch {
+	case format == wavFormatPCM && bits == 8:
+		sample = func(b []byte) float64 { return math.Abs(float64(int(b[0])-128)) / 128 }
+	case format == wavFormatPCM && bits == 16:
+		sample = func(b []byte) float64 { return math.Abs(float64(int16(binary.LittleEndian.Uint16(b)))) / (1 << 15) }
+	case format == wavFormatPCM && bits == 24:
+		sample = func(b []byte) float64 {
+			v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+			return math.Abs(float64(v)) / (1 << 23)
+		}
+	case format == wavFormatPCM && bits == 32:
+		sample = func(b []byte) float64 { return math.Abs(float64(int32(binary.LittleEndian.Uint32(b)))) / (1 << 31) }
+	case format == wavFormatFloat && bits == 32:
+		sample = func(b []byte) float64 { return math.Abs(float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))) }
+	case format == wavFormatFloat && bits == 64:
+		sample = func(b []byte) float64 { return math.Abs(math.Float64frombits(binary.LittleEndian.Uint64(b))) }
+	default:
+		return nil, nil
+	}
+
+	width := bits / 8
+	frames := (m.dataEnd - m.dataStart) / m.blockAlign
+	if frames == 0 || m.channels == 0 || int64(width*m.channels) > m.blockAlign {
+		return nil, nil
+	}
+	if _, err := r.Seek(m.dataStart, io.SeekStart); err != nil {
+		return nil, err
+	}
+	br := bufio.NewReaderSize(io.LimitReader(r, frames*m.blockAlign), 256*1024)
+
+	peaks := make([]float64, WaveformPeaks)
+	frame := make([]byte, m.blockAlign)
+	for n := int64(0); n < frames; n++ {
+		if _, err := io.ReadFull(br, frame); err != nil {
+			return nil, err
+		}
+		i := int(n * WaveformPeaks / frames)
+		for ch := 0; ch < m.channels; ch++ {
+			peaks[i] = max(peaks[i], sample(frame[ch*width:]))
+		}
+	}
+	return normalizePeaks(peaks), nil
+}
+
+// normalizePeaks scales peaks so the loudest is 1, rounded to keep the JSON small
+func normalizePeaks(peaks []float64) []float64 {
+	loudest := 0.0
+	for _, p := range peaks {
+		loudest = max(loudest, p)
+	}
+	if loudest == 0 {
+		return peaks
+	}
+	for i, p := range peaks {
+		peaks[i] = math.Round(p/loudest*100) / 100
+	}
+	return peaks
+}
@@ -0,0 +1,242 @@
+package catalog
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+var (
+	// ErrEmptyBundle is returned when a bundle has no components
+	ErrEmptyBundle = errors.New("a bundle needs at least one component")
+	// ErrInvalidComponent is returned when a bundle component is unknown, is itself a bundle
+	// or is a digital product
+	ErrInvalidComponent = errors.New("component must be an existing physical variant that is not a bundle")
+)
+
+// ListBundles returns every bundle, active or not, with its components in order
+func (s *Service) ListBundles() ([]models.Bundle, error) {
+	return s.queryBundles(``)
+}
+
+// GetBundle returns a bundle by product ID
+// Returns sql.ErrNoRows if there is no such bundle.
+func (s *Service) GetBundle(productID string) (*models.Bundle, error) {
+	bundles, err := s.queryBundles(`AND p.id = ?`, productID)
+	if err != nil {
+		return nil, err
+	}
+	if len(bundles) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &bundles[0], nil
+}
+
+func (s *Service) queryBundles(where string, args ...interface{}) ([]models.Bundle, error) {
+	rows, err := s.db.Query(`
+		SELECT p.id, v.id, COALESCE(p.slug, ''), p.name, COALESCE(p.description, ''), v.price,
+			COALESCE(p.image_url, ''), COALESCE(p.category, ''), p.active, p.created_at, p.updated_at
+		FROM products p
+		JOIN variants v ON v.product_id = p.id
+		WHERE p.is_bundle = 1 `+where+`
+		ORDER BY p.name
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query bundles: %w", err)
+	}
+
+	bundles := []models.Bundle{}
+	for rows.Next() {
+		var b models.Bundle
+		if err := rows.Scan(&b.ProductID, &b.VariantID, &b.Slug, &b.Name, &b.Description, &b.Price,
+			&b.ImageURL, &b.Category, &b.Active, &b.CreatedAt, &b.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan bundle: %w", err)
+		}
+		bundles = append(bundles, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range bundles {
+		components, err := s.BundleComponents(bundles[i].VariantID)
+		if err != nil {
+			return nil, err
+		}
+		bundles[i].Components = components
+	}
+	return bundles, nil
+}
+
+// BundleComponents returns what a bundle variant contains, in display order, or none
+// for a variant that is not a bundle
+func (s *Service) BundleComponents(bundleVariantID string) ([]models.BundleComponent, error) {
+	rows, err := s.db.Query(`
+		SELECT bc.component_variant_id, p.id, COALESCE(p.slug, ''), p.name, v.name, bc.quantity, v.price
+		FROM bundle_components bc
+		JOIN variants v ON v.id = bc.component_variant_id
+		JOIN products p ON p.id = v.product_id
+		WHERE bc.bundle_variant_id = ?
+		ORDER BY bc.position
+	`, bundleVariantID)
+	if err != nil {
+		return nil, fmt.Errorf("query bundle components: %w", err)
+	}
+	defer rows.Close()
+
+	components := []models.BundleComponent{}
+	for rows.Next() {
+		var c models.BundleComponent
+		if err := rows.Scan(&c.VariantID, &c.ProductID, &c.ProductSlug, &c.ProductName, &c.VariantName,
+			&c.Quantity, &c.Price); err != nil {
+			return nil, fmt.Errorf("scan bundle component: %w", err)
+		}
+		components = append(components, c)
+	}
+	return components, rows.Err()
+}
+
+// SaveBundle creates a bundle (empty ProductID) or replaces an existing one's details and
+// components. Like any local product its price is pinned, so markup rules never touch it.
+// Returns sql.ErrNoRows when updating a bundle that does not exist, ErrEmptyBundle,
+// ErrInvalidComponent or ErrCategoryNotFound.
+func (s *Service) SaveBundle(b *models.Bundle) error {
+	if len(b.Components) == 0 {
+		return ErrEmptyBundle
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkCategory(tx, b.Category); err != nil {
+		return err
+	}
+
+	components, err := resolveBundleComponents(tx, b.ProductID, b.Components)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	product := localProduct{
+		ProductID:   b.ProductID,
+		Name:        b.Name,
+		Description: b.Description,
+		VariantName: bundleVariantName(components),
+		Price:       b.Price,
+		ImageURL:    b.ImageURL,
+		Category:    b.Category,
+		Active:      b.Active,
+		IsBundle:    true,
+		ProductType: models.ProductTypePhysical,
+	}
+	var currentSlug sql.NullString
+
+	if product.ProductID == "" {
+		if err := insertLocalProduct(tx, &product, now); err != nil {
+			return fmt.Errorf("insert bundle: %w", err)
+		}
+		b.ProductID = product.ProductID
+		b.VariantID = product.VariantID
+	} else {
+		err = tx.QueryRow(`
+			SELECT p.slug, v.id FROM products p JOIN variants v ON v.product_id = p.id
+			WHERE p.id = ? AND p.is_bundle = 1
+		`, b.ProductID).Scan(&currentSlug, &product.VariantID)
+		if err != nil {
+			return err
+		}
+		b.VariantID = product.VariantID
+
+		if err := updateLocalProduct(tx, &product, now); err != nil {
+			return fmt.Errorf("update bundle: %w", err)
+		}
+	}
+
+	slug, err := setProductSlug(tx, b.ProductID, currentSlug, b.Name)
+	if err != nil {
+		return err
+	}
+	b.Slug = slug
+
+	if _, err := tx.Exec(`DELETE FROM bundle_components WHERE bundle_variant_id = ?`, b.VariantID); err != nil {
+		return fmt.Errorf("clear bundle components: %w", err)
+	}
+	for position, c := range components {
+		if _, err := tx.Exec(`
+			INSERT INTO bundle_components (bundle_variant_id, component_variant_id, quantity, position)
+			VALUES (?, ?, ?, ?)
+		`, b.VariantID, c.VariantID, c.Quantity, position); err != nil {
+			return fmt.Errorf("add bundle component: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	saved, err := s.GetBundle(b.ProductID)
+	if err != nil {
+		return err
+	}
+	*b = *saved
+	return nil
+}
+
+// resolveBundleComponents checks the components of a bundle and fills in their names.
+// Repeated variants are merged by adding up their quantities.
+func resolveBundleComponents(tx *sql.Tx, bundleProductID string, requested []models.BundleComponent) ([]models.BundleComponent, error) {
+	var components []models.BundleComponent
+	index := make(map[string]int)
+	for _, c := range requested {
+		c.VariantID = strings.TrimSpace(c.VariantID)
+		if i, ok := index[c.VariantID]; ok {
+			components[i].Quantity += c.Quantity
+			continue
+		}
+
+		var isBundle bool
+		var productType string
+		err := tx.QueryRow(`
+			SELECT p.id, COALESCE(p.slug, ''), p.name, v.name, v.price, p.is_bundle, p.product_type
+			FROM variants v JOIN products p ON p.id = v.product_id
+			WHERE v.id = ?
+		`, c.VariantID).Scan(&c.ProductID, &c.ProductSlug, &c.ProductName, &c.VariantName, &c.Price, &isBundle, &productType)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidComponent, c.VariantID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("look up bundle component: %w", err)
+		}
+		if isBundle || productType != models.ProductTypePhysical || c.ProductID == bundleProductID {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidComponent, c.VariantID)
+		}
+
+		index[c.VariantID] = len(components)
+		components = append(components, c)
+	}
+	return components, nil
+}
+
+// bundleVariantName describes a bundle's contents, e.g. "Tote Bag + Mug + 2 x Stickers".
+// It is shown after the bundle name in the cart and on the Stripe checkout page.
+func bundleVariantName(components []models.BundleComponent) string {
+	parts := make([]string, 0, len(components))
+	for _, c := range components {
+		if c.Quantity > 1 {
+			parts = append(parts, fmt.Sprintf("%d x %s", c.Quantity, c.ProductName))
+		} else {
+			parts = append(parts, c.ProductName)
+		}
+	}
+	return strings.Join(parts, " + ")
+}
@@ -0,0 +1,633 @@
+package catalog
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nessieaudio/ecommerce-backend/internal/services/printful"
+)
+
+// ErrSyncInProgress is returned when a sync is requested while another is running
+var ErrSyncInProgress = errors.New("catalog sync already in progress")
+
+// syncMu serialises sync runs across every Service (scheduler, admin API, CLI)
+var syncMu sync.Mutex
+
+// defaultCategory is the category given to newly synced products
+const defaultCategory = "merch"
+
+// productPhotosDir is the directory (under the static root) holding local product images,
+// laid out as "Product Photos/<product name>/<image file>"
+const productPhotosDir = "Product Photos"
+
+// Service keeps the local products and variants tables in step with the Printful store
+type Service struct {
+	db        *sql.DB
+	printful  *printful.Client
+	staticDir string
+}
+
+// NewService creates a new catalog sync service
+// staticDir is the site root used to look up local product photos
+func NewService(db *sql.DB, printfulClient *printful.Client, staticDir string) *Service {
+	return &Service{
+		db:        db,
+		printful:  printfulClient,
+		staticDir: staticDir,
+	}
+}
+
+// Sync pulls every store product from Printful and upserts it locally
+//
+// Products and variants are matched by Printful ID (sync product / sync variant).
+// Local price overrides and local image paths are never overwritten. Items no
+// longer in the Printful store are soft-deactivated rather than deleted, since
+// past orders still reference them.
+func (s *Service) Sync(trigger string) (*Report, error) {
+	if !syncMu.TryLock() {
+		return nil, ErrSyncInProgress
+	}
+	defer syncMu.Unlock()
+
+	report := newReport(uuid.New().String(), trigger)
+	runErr := s.sync(report)
+
+	report.FinishedAt = time.Now()
+	switch {
+	case runErr != nil:
+		report.Status = StatusFailed
+		report.Errors = append(report.Errors, runErr.Error())
+	case len(report.Errors) > 0:
+		report.Status = StatusPartial
+	default:
+		report.Status = StatusSuccess
+	}
+
+	if err := s.saveReport(report, runErr); err != nil {
+		log.Printf("⚠️  Failed to record catalog sync run: %v", err)
+	}
+
+	if runErr != nil {
+		return report, runErr
+	}
+	return report, nil
+}
+
+// sync performs a run, filling in report as it goes
+func (s *Service) sync(report *Report) error {
+	products, err := s.printful.GetProducts()
+	if err != nil {
+		return fmt.Errorf("fetch printful products: %w", err)
+	}
+
+	seenProducts := make(map[int64]bool)
+	costs := newCostLookup(s.printful)
+	for _, product := range products {
+		if product.IsIgnored {
+			continue
+		}
+
+		// Mark as seen before fetching details so a transient failure
+		// on one product never deactivates it
+		seenProducts[product.ID] = true
+
+		detail, err := s.printful.GetProduct(product.ID)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("fetch product %d (%s): %v", product.ID, product.Name, err))
+			continue
+		}
+
+		if err := s.syncProduct(detail, costs, report); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("sync product %d (%s): %v", product.ID, product.Name, err))
+		}
+	}
+
+	// An empty store almost always means a bad API key or an outage, not
+	// that everything was deleted - never wipe the whole catalog on that
+	if len(seenProducts) == 0 {
+		report.Errors = append(report.Errors, "Printful returned no products - skipping deactivation")
+		return nil
+	}
+
+	report.Errors = append(report.Errors, costs.errors...)
+
+	return s.deactivateRemovedProducts(seenProducts, report)
+}
+
+// existingProduct is the local state of a product matched by Printful ID
+type existingProduct struct {
+	id                string
+	name              string
+	price             float64
+	priceOverride     sql.NullFloat64
+	imageURL          sql.NullString
+	thumbnailURL      sql.NullString
+	category          sql.NullString
+	slug              sql.NullString
+	active            bool
+	printfulRemovedAt sql.NullTime
+}
+
+// syncProduct upserts one product and its variants in a single transaction
+func (s *Service) syncProduct(detail *printful.SyncProductDetail, costs *costLookup, report *Report) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	sp := detail.SyncProduct
+	now := time.Now()
+
+	var existing existingProduct
+	err = tx.QueryRow(`
+		SELECT id, name, price, price_override, image_url, thumbnail_url, category, slug, active, printful_removed_at
+		FROM products
+		WHERE printful_id = ?
+	`, sp.ID).Scan(&existing.id, &existing.name, &existing.price, &existing.priceOverride,
+		&existing.imageURL, &existing.thumbnailURL, &existing.category, &existing.slug, &existing.active, &existing.printfulRemovedAt)
+	isNew := errors.Is(err, sql.ErrNoRows)
+	if err != nil && !isNew {
+		return fmt.Errorf("query product: %w", err)
+	}
+
+	productID := existing.id
+	category := existing.category.String
+	if isNew {
+		productID = uuid.New().String()
+		category = defaultCategory
+	}
+
+	rule, err := resolveRule(tx, productID, category)
+	if err != nil {
+		return err
+	}
+
+	variantChanges, variantIDs, err := s.syncVariants(tx, productID, detail.SyncVariants, rule, costs, report)
+	if err != nil {
+		return err
+	}
+
+	if err := s.syncImages(tx, productID, sp.Name, detail.SyncVariants, variantIDs, report); err != nil {
+		return err
+	}
+
+	price, err := lowestVariantPrice(tx, productID)
+	if err != nil {
+		return err
+	}
+	if existing.priceOverride.Valid {
+		price = existing.priceOverride.Float64
+	}
+
+	if isNew {
+		imageURL := s.localImagePath(sp.Name)
+		if imageURL == "" {
+			imageURL = sp.ThumbnailURL // Fall back to the Printful CDN
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO products (
+				id, printful_id, name, description, price, currency,
+				image_url, thumbnail_url, category, active, synced_at,
+				created_at, updated_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, 1, ?, ?, ?)
+		`, productID, sp.ID, sp.Name, productDescription(sp.Name), price, variantCurrency(detail.SyncVariants),
+			imageURL, sp.ThumbnailURL, category, now, now, now)
+		if err != nil {
+			return fmt.Errorf("insert product: %w", err)
+		}
+		if _, err := setProductSlug(tx, productID, sql.NullString{}, sp.Name); err != nil {
+			return err
+		}
+
+		report.ProductsAdded = append(report.ProductsAdded, Change{ID: productID, PrintfulID: sp.ID, Name: sp.Name})
+		return tx.Commit()
+	}
+
+	// Local image paths ("/Product Photos/...") are ours - only replace
+	// a remote image, and prefer a local photo if one has since been added
+	imageURL := existing.imageURL.String
+	if !strings.HasPrefix(imageURL, "/") {
+		if local := s.localImagePath(sp.Name); local != "" {
+			imageURL = local
+		} else {
+			imageURL = sp.ThumbnailURL
+		}
+	}
+
+	// Reactivate products that come back to Printful, but leave
+	// products an admin switched off by hand alone
+	active := existing.active
+	if !active && existing.printfulRemovedAt.Valid {
+		active = true
+	}
+
+	var fields []string
+	if existing.name != sp.Name {
+		fields = append(fields, "name")
+	}
+	if existing.price != price {
+		fields = append(fields, "price")
+	}
+	if existing.imageURL.String != imageURL {
+		fields = append(fields, "image_url")
+	}
+	if existing.thumbnailURL.String != sp.ThumbnailURL {
+		fields = append(fields, "thumbnail_url")
+	}
+	if existing.active != active {
+		fields = append(fields, "active")
+	}
+
+	_, err = tx.Exec(`
+		UPDATE products SET
+			name = ?,
+			description = CASE WHEN COALESCE(description, '') = '' THEN ? ELSE description END,
+			price = ?,
+			image_url = ?,
+			thumbnail_url = ?,
+			active = ?,
+			printful_removed_at = NULL,
+			synced_at = ?,
+			updated_at = CASE WHEN ? THEN ? ELSE updated_at END
+		WHERE id = ?
+	`, sp.Name, productDescription(sp.Name), price, imageURL, sp.ThumbnailURL, active, now, len(fields) > 0, now, productID)
+	if err != nil {
+		return fmt.Errorf("update product: %w", err)
+	}
+
+	// A rename moves the product to a new slug; the old one redirects
+	if existing.name != sp.Name || !existing.slug.Valid {
+		if _, err := setProductSlug(tx, productID, existing.slug, sp.Name); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case len(fields) > 0:
+		report.ProductsUpdated = append(report.ProductsUpdated, Change{ID: productID, PrintfulID: sp.ID, Name: sp.Name, Fields: fields})
+	case variantChanges == 0:
+		report.Unchanged++
+	}
+
+	return tx.Commit()
+}
+
+// existingVariant is the local state of a variant matched by sync variant ID
+type existingVariant struct {
+	id                string
+	productID         string
+	name              string
+	size              sql.NullString
+	color             sql.NullString
+	price             float64
+	priceOverride     sql.NullFloat64
+	retailPrice       sql.NullFloat64
+	printfulCost      sql.NullFloat64
+	available         bool
+	printfulRemovedAt sql.NullTime
+}
+
+// syncVariants upserts a product's sync variants and deactivates the ones Printful dropped
+// Returns the number of variant changes recorded in the report and the local ID of each sync variant
+func (s *Service) syncVariants(tx *sql.Tx, productID string, variants []printful.SyncVariant, rule *PricingRule, costs *costLookup, report *Report) (int, map[int64]string, error) {
+	now := time.Now()
+	changes := 0
+	seen := make(map[int64]bool)
+	variantIDs := make(map[int64]string)
+
+	for _, sv := range variants {
+		if sv.IsIgnored {
+			continue
+		}
+		seen[sv.ID] = true
+
+		retailPrice, err := strconv.ParseFloat(sv.RetailPrice, 64)
+		if err != nil {
+			return changes, nil, fmt.Errorf("parse retail price %q for variant %d: %w", sv.RetailPrice, sv.ID, err)
+		}
+		available := variantAvailable(sv)
+
+		var existing existingVariant
+		err = tx.QueryRow(`
+			SELECT id, product_id, name, size, color, price, price_override, retail_price,
+				printful_cost, available, printful_removed_at
+			FROM variants
+			WHERE printful_variant_id = ?
+		`, sv.ID).Scan(&existing.id, &existing.productID, &existing.name, &existing.size, &existing.color,
+			&existing.price, &existing.priceOverride, &existing.retailPrice,
+			&existing.printfulCost, &existing.available, &existing.printfulRemovedAt)
+		isNew := errors.Is(err, sql.ErrNoRows)
+		if err != nil && !isNew {
+			return changes, nil, fmt.Errorf("query variant %d: %w", sv.ID, err)
+		}
+
+		// Keep the last known cost if Printful's catalog can't be reached
+		cost, ok := costs.cost(sv.VariantID)
+		if !ok {
+			cost = existing.printfulCost
+		}
+
+		if isNew {
+			variantID := uuid.New().String()
+			price := variantPrice(sql.NullFloat64{}, rule, cost, retailPrice)
+			_, err = tx.Exec(`
+				INSERT INTO variants (
+					id, product_id, printful_variant_id, printful_catalog_variant_id, name, size, color,
+					price, retail_price, printful_cost, available, created_at, updated_at
+				) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`, variantID, productID, sv.ID, sv.VariantID, sv.Name, sv.Size, sv.Color,
+				price, retailPrice, cost, available, now, now)
+			if err != nil {
+				return changes, nil, fmt.Errorf("insert variant %d: %w", sv.ID, err)
+			}
+
+			report.VariantsAdded = append(report.VariantsAdded, Change{ID: variantID, PrintfulID: sv.ID, Name: sv.Name})
+			variantIDs[sv.ID] = variantID
+			changes++
+			continue
+		}
+		variantIDs[sv.ID] = existing.id
+
+		var fields []string
+
+		// Not synced before, so its price was set by hand: keep it if Printful disagrees
+		if !existing.retailPrice.Valid && !existing.priceOverride.Valid && existing.price != retailPrice {
+			existing.priceOverride = sql.NullFloat64{Float64: existing.price, Valid: true}
+			fields = append(fields, "price_override")
+		}
+
+		price := variantPrice(existing.priceOverride, rule, cost, retailPrice)
+		if existing.productID != productID {
+			fields = append(fields, "product_id")
+		}
+		if existing.name != sv.Name {
+			fields = append(fields, "name")
+		}
+		if existing.size.String != sv.Size {
+			fields = append(fields, "size")
+		}
+		if existing.color.String != sv.Color {
+			fields = append(fields, "color")
+		}
+		if existing.price != price {
+			fields = append(fields, "price")
+		}
+		if !existing.retailPrice.Valid || existing.retailPrice.Float64 != retailPrice {
+			fields = append(fields, "retail_price")
+		}
+		if existing.printfulCost != cost {
+			fields = append(fields, "printful_cost")
+		}
+		if existing.available != available {
+			fields = append(fields, "available")
+		}
+
+		if len(fields) == 0 && !existing.printfulRemovedAt.Valid {
+			continue
+		}
+
+		_, err = tx.Exec(`
+			UPDATE variants SET
+				product_id = ?,
+				name = ?,
+				size = ?,
+				color = ?,
+				price = ?,
+				price_override = ?,
+				retail_price = ?,
+				printful_cost = ?,
+				printful_catalog_variant_id = ?,
+				available = ?,
+				printful_removed_at = NULL,
+				updated_at = ?
+			WHERE id = ?
+		`, productID, sv.Name, sv.Size, sv.Color, price, existing.priceOverride, retailPrice, cost, sv.VariantID, available, now, existing.id)
+		if err != nil {
+			return changes, nil, fmt.Errorf("update variant %d: %w", sv.ID, err)
+		}
+
+		if len(fields) > 0 {
+			report.VariantsUpdated = append(report.VariantsUpdated, Change{ID: existing.id, PrintfulID: sv.ID, Name: sv.Name, Fields: fields})
+			changes++
+		}
+	}
+
+	deactivated, err := deactivateRemovedVariants(tx, productID, seen, report)
+	return changes + deactivated, variantIDs, err
+}
+
+// deactivateRemovedVariants marks a product's variants that Printful no longer lists as unavailable
+func deactivateRemovedVariants(tx *sql.Tx, productID string, seen map[int64]bool, report *Report) (int, error) {
+	rows, err := tx.Query(`
+		SELECT id, printful_variant_id, name
+		FROM variants
+		WHERE product_id = ? AND printful_removed_at IS NULL
+	`, productID)
+	if err != nil {
+		return 0, fmt.Errorf("query variants: %w", err)
+	}
+
+	var removed []Change
+	for rows.Next() {
+		var c Change
+		if err := rows.Scan(&c.ID, &c.PrintfulID, &c.Name); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan variant: %w", err)
+		}
+		if !seen[c.PrintfulID] {
+			removed = append(removed, c)
+		}
+	}
+	rows.Close()
+
+	now := time.Now()
+	for _, c := range removed {
+		_, err := tx.Exec(`
+			UPDATE variants SET available = 0, printful_removed_at = ?, updated_at = ?
+			WHERE id = ?
+		`, now, now, c.ID)
+		if err != nil {
+			return 0, fmt.Errorf("deactivate variant %s: %w", c.ID, err)
+		}
+		report.VariantsDeactivated = append(report.VariantsDeactivated, c)
+	}
+
+	return len(removed), nil
+}
+
+// deactivateRemovedProducts soft-deactivates products that are no longer in the Printful store
+// Bundles and digital products are local products and are never deactivated by a sync.
+func (s *Service) deactivateRemovedProducts(seen map[int64]bool, report *Report) error {
+	rows, err := s.db.Query(`
+		SELECT id, printful_id, name
+		FROM products
+		WHERE printful_removed_at IS NULL AND is_bundle = 0 AND product_type = 'physical'
+	`)
+	if err != nil {
+		return fmt.Errorf("query products: %w", err)
+	}
+
+	var removed []Change
+	for rows.Next() {
+		var c Change
+		if err := rows.Scan(&c.ID, &c.PrintfulID, &c.Name); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan product: %w", err)
+		}
+		if !seen[c.PrintfulID] {
+			removed = append(removed, c)
+		}
+	}
+	rows.Close()
+
+	now := time.Now()
+	for _, c := range removed {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return fmt.Errorf("begin transaction: %w", err)
+		}
+
+		_, err = tx.Exec(`
+			UPDATE products SET active = 0, printful_removed_at = ?, updated_at = ?
+			WHERE id = ?
+		`, now, now, c.ID)
+		if err == nil {
+			_, err = deactivateRemovedVariants(tx, c.ID, map[int64]bool{}, report)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			tx.Rollback()
+			report.Errors = append(report.Errors, fmt.Sprintf("deactivate product %s (%s): %v", c.ID, c.Name, err))
+			continue
+		}
+
+		report.ProductsDeactivated = append(report.ProductsDeactivated, c)
+	}
+
+	return nil
+}
+
+// variantAvailable reports whether Printful can currently fulfil a sync variant
+func variantAvailable(sv printful.SyncVariant) bool {
+	switch sv.AvailabilityStatus {
+	case "", "active":
+		return true
+	default: // discontinued, out_of_stock, temporary_out_of_stock
+		return false
+	}
+}
+
+// lowestVariantPrice returns the cheapest available variant price (the "from" price) of a product
+func lowestVariantPrice(tx *sql.Tx, productID string) (float64, error) {
+	var lowest sql.NullFloat64
+	err := tx.QueryRow(`
+		SELECT MIN(price) FROM variants
+		WHERE product_id = ? AND available = 1
+	`, productID).Scan(&lowest)
+	if err != nil {
+		return 0, fmt.Errorf("query lowest variant price: %w", err)
+	}
+	return lowest.Float64, nil
+}
+
+// variantCurrency returns the currency Printful prices the product's variants in
+func variantCurrency(variants []printful.SyncVariant) string {
+	for _, sv := range variants {
+		if sv.Currency != "" {
+			return sv.Currency
+		}
+	}
+	return "USD"
+}
+
+// localImagePath returns the URL of a product's first local photo, or "" if it has none
+func (s *Service) localImagePath(productName string) string {
+	if images := s.LocalImages(productName); len(images) > 0 {
+		return images[0]
+	}
+	return ""
+}
+
+// StartScheduledSync runs a sync immediately and then on a fixed interval
+func (s *Service) StartScheduledSync(interval time.Duration) {
+	go func() {
+		s.runScheduled("startup")
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			s.runScheduled("scheduled")
+		}
+	}()
+
+	log.Printf("Catalog sync scheduled (every %v)", interval)
+}
+
+// runScheduled performs a background sync and logs the outcome
+func (s *Service) runScheduled(trigger string) {
+	report, err := s.Sync(trigger)
+	if errors.Is(err, ErrSyncInProgress) {
+		log.Println("Catalog sync skipped - another sync is running")
+		return
+	}
+	if err != nil {
+		log.Printf("⚠️  Catalog sync failed: %v", err)
+		return
+	}
+
+	if report.HasChanges() {
+		log.Printf("✅ Catalog sync complete: %s", report.Summary())
+	} else {
+		log.Printf("Catalog sync complete - no changes (%d products)", report.Unchanged)
+	}
+	for _, e := range report.Errors {
+		log.Printf("⚠️  Catalog sync: %s", e)
+	}
+}
+
+// costLookup fetches Printful catalog costs once per catalog variant during a sync run
+type costLookup struct {
+	client *printful.Client
+	costs  map[int64]sql.NullFloat64
+	errors []string
+}
+
+func newCostLookup(client *printful.Client) *costLookup {
+	return &costLookup{client: client, costs: make(map[int64]sql.NullFloat64)}
+}
+
+// cost returns the Printful cost of a catalog variant; ok is false if it could not be fetched
+func (c *costLookup) cost(catalogVariantID int64) (sql.NullFloat64, bool) {
+	if catalogVariantID == 0 {
+		return sql.NullFloat64{}, false
+	}
+	if cost, seen := c.costs[catalogVariantID]; seen {
+		return cost, cost.Valid
+	}
+
+	var cost sql.NullFloat64
+	variant, err := c.client.GetCatalogVariant(catalogVariantID)
+	if err == nil {
+		var price float64
+		price, err = strconv.ParseFloat(variant.Price, 64)
+		cost = sql.NullFloat64{Float64: price, Valid: err == nil}
+	}
+	if err != nil {
+		c.errors = append(c.errors, fmt.Sprintf("fetch cost for catalog variant %d: %v", catalogVariantID, err))
+	}
+
+	c.costs[catalogVariantID] = cost
+	return cost, cost.Valid
+}
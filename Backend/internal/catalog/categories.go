@@ -0,0 +1,327 @@
+package catalog
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+var (
+	// ErrParentNotFound is returned when a category's parent does not exist
+	ErrParentNotFound = errors.New("parent category not found")
+	// ErrCategoryCycle is returned when a category would become its own ancestor
+	ErrCategoryCycle = errors.New("category cannot be nested under itself or its descendants")
+	// ErrCategoryInUse is returned when deleting a category that still has products or subcategories
+	ErrCategoryInUse = errors.New("category still has products or subcategories")
+	// ErrCategoryNotFound is returned when assigning a product to an unknown category
+	ErrCategoryNotFound = errors.New("category not found")
+)
+
+// CategoryNode is a category with its subcategories, for navigation
+type CategoryNode struct {
+	models.Category
+	ProductCount int             `json:"product_count"` // Active products directly in this category
+	Children     []*CategoryNode `json:"children"`
+}
+
+// ListCategories returns every category, parents before children within each level's sort order
+func (s *Service) ListCategories() ([]models.Category, error) {
+	rows, err := s.db.Query(`
+		SELECT id, parent_id, slug, name, description, sort_order, created_at, updated_at
+		FROM categories
+		ORDER BY sort_order, name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query categories: %w", err)
+	}
+	defer rows.Close()
+
+	categories := []models.Category{}
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, *c)
+	}
+
+	return categories, rows.Err()
+}
+
+// CategoryTree returns the categories nested under their parents
+func (s *Service) CategoryTree() ([]*CategoryNode, error) {
+	categories, err := s.ListCategories()
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	rows, err := s.db.Query(`
+		SELECT category, COUNT(*) FROM products
+		WHERE active = 1 AND category IS NOT NULL
+		GROUP BY category
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("count category products: %w", err)
+	}
+	for rows.Next() {
+		var slug string
+		var n int
+		if err := rows.Scan(&slug, &n); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan category count: %w", err)
+		}
+		counts[slug] = n
+	}
+	rows.Close()
+
+	nodes := make(map[string]*CategoryNode, len(categories))
+	for _, c := range categories {
+		nodes[c.ID] = &CategoryNode{Category: c, ProductCount: counts[c.Slug], Children: []*CategoryNode{}}
+	}
+
+	roots := []*CategoryNode{}
+	for _, c := range categories {
+		node := nodes[c.ID]
+		if c.ParentID != nil {
+			if parent, ok := nodes[*c.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+
+	return roots, nil
+}
+
+// CreateCategory adds a category; the slug defaults to one derived from the name
+func (s *Service) CreateCategory(c *models.Category) error {
+	if c.Slug == "" {
+		c.Slug = Slugify(c.Name)
+	}
+	if c.ParentID != nil {
+		if err := s.checkCategoryExists(*c.ParentID); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	c.ID = uuid.New().String()
+	c.CreatedAt = now
+	c.UpdatedAt = now
+
+	_, err := s.db.Exec(`
+		INSERT INTO categories (id, parent_id, slug, name, description, sort_order, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, c.ID, c.ParentID, c.Slug, c.Name, c.Description, c.SortOrder, now, now)
+	if isUniqueViolation(err) {
+		return ErrSlugTaken
+	}
+	if err != nil {
+		return fmt.Errorf("insert category: %w", err)
+	}
+
+	return nil
+}
+
+// CategoryUpdate holds the editable fields of a category; nil fields are left unchanged
+type CategoryUpdate struct {
+	Name        *string `json:"name"`
+	Slug        *string `json:"slug"`
+	Description *string `json:"description"`
+	ParentID    *string `json:"parent_id"` // "" moves the category to the top level
+	SortOrder   *int    `json:"sort_order"`
+}
+
+// UpdateCategory edits a category. Renaming the slug also moves its products and
+// category pricing rule to the new slug.
+// Returns sql.ErrNoRows if the category does not exist.
+func (s *Service) UpdateCategory(id string, update CategoryUpdate) (*models.Category, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	current, err := scanCategory(tx.QueryRow(`
+		SELECT id, parent_id, slug, name, description, sort_order, created_at, updated_at
+		FROM categories WHERE id = ?
+	`, id))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sets := []string{"updated_at = ?"}
+	args := []interface{}{now}
+
+	if update.Name != nil {
+		sets = append(sets, "name = ?")
+		args = append(args, strings.TrimSpace(*update.Name))
+	}
+	if update.Description != nil {
+		sets = append(sets, "description = ?")
+		args = append(args, *update.Description)
+	}
+	if update.SortOrder != nil {
+		sets = append(sets, "sort_order = ?")
+		args = append(args, *update.SortOrder)
+	}
+	if update.ParentID != nil {
+		var parentID *string
+		if *update.ParentID != "" {
+			parentID = update.ParentID
+			if err := checkCategoryParent(tx, id, *parentID); err != nil {
+				return nil, err
+			}
+		}
+		sets = append(sets, "parent_id = ?")
+		args = append(args, parentID)
+	}
+	if update.Slug != nil && *update.Slug != current.Slug {
+		sets = append(sets, "slug = ?")
+		args = append(args, *update.Slug)
+
+		if _, err := tx.Exec(`UPDATE products SET category = ?, updated_at = ? WHERE category = ?`,
+			*update.Slug, now, current.Slug); err != nil {
+			return nil, fmt.Errorf("move category products: %w", err)
+		}
+		if _, err := tx.Exec(`UPDATE pricing_rules SET target = ?, updated_at = ? WHERE scope = ? AND target = ?`,
+			*update.Slug, now, ScopeCategory, current.Slug); err != nil {
+			return nil, fmt.Errorf("move category pricing rule: %w", err)
+		}
+	}
+
+	args = append(args, id)
+	_, err = tx.Exec(`UPDATE categories SET `+strings.Join(sets, ", ")+` WHERE id = ?`, args...)
+	if isUniqueViolation(err) {
+		return nil, ErrSlugTaken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("update category: %w", err)
+	}
+
+	updated, err := scanCategory(tx.QueryRow(`
+		SELECT id, parent_id, slug, name, description, sort_order, created_at, updated_at
+		FROM categories WHERE id = ?
+	`, id))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit transaction: %w", err)
+	}
+	return updated, nil
+}
+
+// DeleteCategory removes an empty category
+// Returns sql.ErrNoRows if it does not exist, ErrCategoryInUse if products or subcategories still use it.
+func (s *Service) DeleteCategory(id string) error {
+	var slug string
+	if err := s.db.QueryRow(`SELECT slug FROM categories WHERE id = ?`, id).Scan(&slug); err != nil {
+		return err
+	}
+
+	var inUse bool
+	err := s.db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM categories WHERE parent_id = ?)
+			OR EXISTS (SELECT 1 FROM products WHERE category = ?)
+	`, id, slug).Scan(&inUse)
+	if err != nil {
+		return fmt.Errorf("check category usage: %w", err)
+	}
+	if inUse {
+		return ErrCategoryInUse
+	}
+
+	if _, err := s.db.Exec(`DELETE FROM categories WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("delete category: %w", err)
+	}
+	return nil
+}
+
+// SetProductCategory moves a product into a category and reprices it under that category's rules
+// Returns sql.ErrNoRows if the product does not exist, ErrCategoryNotFound for an unknown slug.
+func (s *Service) SetProductCategory(productID, slug string) error {
+	var exists int
+	err := s.db.QueryRow(`SELECT 1 FROM categories WHERE slug = ?`, slug).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrCategoryNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("look up category: %w", err)
+	}
+
+	result, err := s.db.Exec(`UPDATE products SET category = ?, updated_at = ? WHERE id = ?`, slug, time.Now(), productID)
+	if err != nil {
+		return fmt.Errorf("update product category: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+
+	if _, err := s.Reprice(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *Service) checkCategoryExists(id string) error {
+	var exists int
+	err := s.db.QueryRow(`SELECT 1 FROM categories WHERE id = ?`, id).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrParentNotFound
+	}
+	return err
+}
+
+// checkCategoryParent verifies parentID exists and is not id or one of its descendants
+func checkCategoryParent(tx *sql.Tx, id, parentID string) error {
+	for current := parentID; ; {
+		if current == id {
+			return ErrCategoryCycle
+		}
+		var next sql.NullString
+		err := tx.QueryRow(`SELECT parent_id FROM categories WHERE id = ?`, current).Scan(&next)
+		if errors.Is(err, sql.ErrNoRows) {
+			if current == parentID {
+				return ErrParentNotFound
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("check category parent: %w", err)
+		}
+		if !next.Valid {
+			return nil
+		}
+		current = next.String
+	}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCategory(row rowScanner) (*models.Category, error) {
+	var c models.Category
+	var parentID sql.NullString
+	if err := row.Scan(&c.ID, &parentID, &c.Slug, &c.Name, &c.Description, &c.SortOrder,
+		&c.CreatedAt, &c.UpdatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scan category: %w", err)
+	}
+	if parentID.Valid {
+		c.ParentID = &parentID.String
+	}
+	return &c, nil
+}
@@ -0,0 +1,301 @@
+package catalog
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+var (
+	// ErrInvalidWindow is returned when a collection ends before it starts
+	ErrInvalidWindow = errors.New("ends_at must be after starts_at")
+	// ErrProductNotFound is returned when a collection lists an unknown product
+	ErrProductNotFound = errors.New("product not found")
+)
+
+// CollectionVisible reports whether a collection is shown publicly at t
+func CollectionVisible(c *models.Collection, t time.Time) bool {
+	if !c.Active {
+		return false
+	}
+	if c.StartsAt != nil && t.Before(*c.StartsAt) {
+		return false
+	}
+	if c.EndsAt != nil && !t.Before(*c.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// ListCollections returns every collection, visible or not, with its products in order
+func (s *Service) ListCollections() ([]models.Collection, error) {
+	return s.queryCollections(``)
+}
+
+// ListVisibleCollections returns the collections shown publicly right now
+// Visibility windows are checked in Go; SQLite comparisons on stored Go times are unreliable.
+func (s *Service) ListVisibleCollections() ([]models.Collection, error) {
+	collections, err := s.queryCollections(`WHERE active = 1`)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	visible := []models.Collection{}
+	for i := range collections {
+		if CollectionVisible(&collections[i], now) {
+			visible = append(visible, collections[i])
+		}
+	}
+	return visible, nil
+}
+
+// GetCollection returns a collection by ID
+// Returns sql.ErrNoRows if it does not exist.
+func (s *Service) GetCollection(id string) (*models.Collection, error) {
+	collections, err := s.queryCollections(`WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(collections) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &collections[0], nil
+}
+
+// GetVisibleCollection returns a publicly visible collection by slug
+// Returns sql.ErrNoRows if it does not exist, is inactive or is outside its window.
+func (s *Service) GetVisibleCollection(slug string) (*models.Collection, error) {
+	collections, err := s.queryCollections(`WHERE slug = ?`, slug)
+	if err != nil {
+		return nil, err
+	}
+	if len(collections) == 0 || !CollectionVisible(&collections[0], time.Now()) {
+		return nil, sql.ErrNoRows
+	}
+	return &collections[0], nil
+}
+
+func (s *Service) queryCollections(where string, args ...interface{}) ([]models.Collection, error) {
+	rows, err := s.db.Query(`
+		SELECT id, slug, name, description, image_url, active, starts_at, ends_at, created_at, updated_at
+		FROM collections
+		`+where+`
+		ORDER BY name
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query collections: %w", err)
+	}
+
+	collections := []models.Collection{}
+	for rows.Next() {
+		var c models.Collection
+		var startsAt, endsAt sql.NullTime
+		if err := rows.Scan(&c.ID, &c.Slug, &c.Name, &c.Description, &c.ImageURL, &c.Active,
+			&startsAt, &endsAt, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan collection: %w", err)
+		}
+		if startsAt.Valid {
+			c.StartsAt = &startsAt.Time
+		}
+		if endsAt.Valid {
+			c.EndsAt = &endsAt.Time
+		}
+		c.ProductIDs = []string{}
+		collections = append(collections, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range collections {
+		ids, err := s.collectionProductIDs(collections[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		collections[i].ProductIDs = ids
+	}
+
+	return collections, nil
+}
+
+func (s *Service) collectionProductIDs(collectionID string) ([]string, error) {
+	rows, err := s.db.Query(`
+		SELECT product_id FROM collection_products
+		WHERE collection_id = ?
+		ORDER BY position
+	`, collectionID)
+	if err != nil {
+		return nil, fmt.Errorf("query collection products: %w", err)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan collection product: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// SaveCollection creates a collection (empty ID) or replaces an existing one's details
+// The slug defaults to one derived from the name. A non-nil ProductIDs also replaces the
+// product list, in that order. Returns sql.ErrNoRows when updating a collection that does
+// not exist, ErrProductNotFound for unknown products.
+func (s *Service) SaveCollection(c *models.Collection) error {
+	if c.Slug == "" {
+		c.Slug = Slugify(c.Name)
+	}
+	if c.StartsAt != nil && c.EndsAt != nil && !c.EndsAt.After(*c.StartsAt) {
+		return ErrInvalidWindow
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	c.UpdatedAt = now
+
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+		c.CreatedAt = now
+		_, err = tx.Exec(`
+			INSERT INTO collections (id, slug, name, description, image_url, active, starts_at, ends_at, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, c.ID, c.Slug, c.Name, c.Description, c.ImageURL, c.Active, c.StartsAt, c.EndsAt, now, now)
+	} else {
+		var result sql.Result
+		result, err = tx.Exec(`
+			UPDATE collections
+			SET slug = ?, name = ?, description = ?, image_url = ?, active = ?, starts_at = ?, ends_at = ?, updated_at = ?
+			WHERE id = ?
+		`, c.Slug, c.Name, c.Description, c.ImageURL, c.Active, c.StartsAt, c.EndsAt, now, c.ID)
+		if err == nil {
+			if n, _ := result.RowsAffected(); n == 0 {
+				return sql.ErrNoRows
+			}
+		}
+	}
+	if isUniqueViolation(err) {
+		return ErrSlugTaken
+	}
+	if err != nil {
+		return fmt.Errorf("save collection: %w", err)
+	}
+
+	if c.ProductIDs != nil {
+		if err := setCollectionProducts(tx, c.ID, c.ProductIDs); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	ids, err := s.collectionProductIDs(c.ID)
+	if err != nil {
+		return err
+	}
+	c.ProductIDs = ids
+	return nil
+}
+
+// SetCollectionProducts replaces a collection's products; their order is the display order
+// Returns sql.ErrNoRows if the collection does not exist, ErrProductNotFound for unknown products.
+func (s *Service) SetCollectionProducts(collectionID string, productIDs []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists int
+	if err := tx.QueryRow(`SELECT 1 FROM collections WHERE id = ?`, collectionID).Scan(&exists); err != nil {
+		return err
+	}
+
+	if err := setCollectionProducts(tx, collectionID, productIDs); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE collections SET updated_at = ? WHERE id = ?`, time.Now(), collectionID); err != nil {
+		return fmt.Errorf("touch collection: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+func setCollectionProducts(tx *sql.Tx, collectionID string, productIDs []string) error {
+	if _, err := tx.Exec(`DELETE FROM collection_products WHERE collection_id = ?`, collectionID); err != nil {
+		return fmt.Errorf("clear collection products: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	position := 0
+	for _, productID := range productIDs {
+		productID = strings.TrimSpace(productID)
+		if seen[productID] {
+			continue
+		}
+		seen[productID] = true
+
+		var exists int
+		err := tx.QueryRow(`SELECT 1 FROM products WHERE id = ?`, productID).Scan(&exists)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %s", ErrProductNotFound, productID)
+		}
+		if err != nil {
+			return fmt.Errorf("look up product: %w", err)
+		}
+
+		if _, err := tx.Exec(`
+			INSERT INTO collection_products (collection_id, product_id, position) VALUES (?, ?, ?)
+		`, collectionID, productID, position); err != nil {
+			return fmt.Errorf("add collection product: %w", err)
+		}
+		position++
+	}
+	return nil
+}
+
+// DeleteCollection removes a collection and its product list (the products themselves are kept)
+// Returns sql.ErrNoRows if it does not exist.
+func (s *Service) DeleteCollection(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM collection_products WHERE collection_id = ?`, id); err != nil {
+		return fmt.Errorf("delete collection products: %w", err)
+	}
+	result, err := tx.Exec(`DELETE FROM collections WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete collection: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
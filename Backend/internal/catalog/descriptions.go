@@ -0,0 +1,50 @@
+package catalog
+
+// productDescription returns the shop copy for a synced product. Printful's sync
+// API has no descriptions, so products without copy here are described by name.
+func productDescription(productName string) string {
+	descriptionMap := map[string]string{
+		"Nessie Audio Unisex t-shirt": `The Unisex Staple T-Shirt feels soft and light with just the right amount of stretch. It's comfortable and flattering for all. We can't compliment this shirt enough–it's one of our crowd favorites, and it's sure to be your next favorite too!
+
+Disclaimer: The fabric is slightly sheer and may appear see-through, especially in lighter colors or under certain lighting conditions.`,
+		"Nessie Audio Unisex Champion hoodie": `A classic hoodie that combines Champion's signature quality with everyday comfort. The cotton-poly blend makes it soft and durable, while the two-ply hood and snug rib-knit cuffs lock in warmth. Champion's double Dry® technology keeps the wearer dry on the move, and the kangaroo pocket keeps essentials handy.
+
+Disclaimer: Size up for a looser fit.`,
+		"Nessie Audio Black Glossy Mug": `Sturdy and sleek in glossy black—this mug is a cupboard essential for a morning java or afternoon tea. 
+
+- Ceramic
+- 11 oz mug dimensions: 3.85″ × 3.35″ (9.8 cm × 8.5 cm)
+- 15 oz mug dimensions: 4.7″ × 3.35″ (12 cm × 8.5 cm)
+- Lead and BPA-free material
+- Dishwasher and microwave safe`,
+		"Hardcover bound Nessie Audio notebook": `Whether crafting a masterpiece or brainstorming the next big idea, the Hardcover Bound Notebook will inspire your inner wordsmith. The notebook features 80 lined, cream-colored pages, a built-in elastic closure, and a matching ribbon page marker. Plus, the expandable inner pocket is perfect for storing loose notes and business cards to never lose track of important information. 
+
+- Cover material: UltraHyde hardcover paper
+- Size: 5.5" × 8.5" (13.97 cm × 21.59 cm)
+- Weight: 10.9 oz (309 g)
+- 80 pages of lined, cream-colored paper
+- Matching elastic closure and ribbon marker
+- Expandable inner pocket`,
+		"Nessie Audio Eco Tote Bag": `There's nothing trendier than being eco-friendly! 
+
+- 100% certified organic cotton 3/1 twill
+- Fabric weight: 8 oz/yd² (272 g/m²)
+- Dimensions: 16″ × 14 ½″ × 5″ (40.6 cm × 35.6 cm × 12.7 cm)
+- Weight limit: 30 lbs (13.6 kg)
+- 1″ (2.5 cm) wide dual straps, 24.5″ (62.2 cm) length
+- Open main compartment
+- The fabric of this product holds certifications for its organic cotton content under GOTS (Global Organic Textile Standard) and OCS (Organic Content Standard)
+- The fabric of this product is OEKO-TEX Standard 100 certified and PETA-Approved Vegan`,
+		"Nessie Audio Bubble-free stickers": `Available in four sizes and there are no order minimums, so you can get a single sticker or a whole stack — the world is your oyster.
+
+- High opacity film that's impossible to see through
+- Durable vinyl
+- 95µ thickness
+- Fast and easy bubble-free application`,
+	}
+
+	if desc, ok := descriptionMap[productName]; ok {
+		return desc
+	}
+	return productName // Fallback to product name if no custom description
+}
@@ -0,0 +1,211 @@
+package catalog
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+// ErrNoFiles is returned when a digital product has no files
+var ErrNoFiles = errors.New("a digital product needs at least one file")
+
+// digitalVariantName is shown after a digital product's name in the cart and on the
+// Stripe checkout page
+const digitalVariantName = "Digital download"
+
+// ListDigitalProducts returns every digital product, active or not, with its files in order
+func (s *Service) ListDigitalProducts() ([]models.DigitalProduct, error) {
+	return s.queryDigitalProducts(``)
+}
+
+// GetDigitalProduct returns a digital product by product ID
+// Returns sql.ErrNoRows if there is no such product.
+func (s *Service) GetDigitalProduct(productID string) (*models.DigitalProduct, error) {
+	products, err := s.queryDigitalProducts(`AND p.id = ?`, productID)
+	if err != nil {
+		return nil, err
+	}
+	if len(products) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &products[0], nil
+}
+
+func (s *Service) queryDigitalProducts(where string, args ...interface{}) ([]models.DigitalProduct, error) {
+	args = append([]interface{}{models.ProductTypeDigital}, args...)
+	rows, err := s.db.Query(`
+		SELECT p.id, v.id, COALESCE(p.slug, ''), p.name, COALESCE(p.description, ''), v.price,
+			COALESCE(p.image_url, ''), COALESCE(p.category, ''), p.active, p.created_at, p.updated_at
+		FROM products p
+		JOIN variants v ON v.product_id = p.id
+		WHERE p.product_type = ? `+where+`
+		ORDER BY p.name
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query digital products: %w", err)
+	}
+
+	products := []models.DigitalProduct{}
+	for rows.Next() {
+		var p models.DigitalProduct
+		if err := rows.Scan(&p.ProductID, &p.VariantID, &p.Slug, &p.Name, &p.Description, &p.Price,
+			&p.ImageURL, &p.Category, &p.Active, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan digital product: %w", err)
+		}
+		products = append(products, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range products {
+		files, err := s.DigitalFiles(products[i].ProductID)
+		if err != nil {
+			return nil, err
+		}
+		products[i].Files = files
+	}
+	return products, nil
+}
+
+// DigitalFiles returns the files currently sold with a product, in display order
+func (s *Service) DigitalFiles(productID string) ([]models.DigitalFile, error) {
+	rows, err := s.db.Query(`
+		SELECT id, file_path, file_name, content_type, size_bytes
+		FROM digital_files
+		WHERE product_id = ? AND removed_at IS NULL
+		ORDER BY position
+	`, productID)
+	if err != nil {
+		return nil, fmt.Errorf("query digital files: %w", err)
+	}
+	defer rows.Close()
+
+	files := []models.DigitalFile{}
+	for rows.Next() {
+		var f models.DigitalFile
+		if err := rows.Scan(&f.ID, &f.Path, &f.FileName, &f.ContentType, &f.SizeBytes); err != nil {
+			return nil, fmt.Errorf("scan digital file: %w", err)
+		}
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
+
+// SaveDigitalProduct creates a digital product (empty ProductID) or replaces an existing
+// one's details and files. Files are matched by path; a file left out is no longer sold,
+// but buyers who already have it keep their downloads. The files must already have been
+// checked against the downloads directory. Returns sql.ErrNoRows when updating a product
+// that does not exist, ErrNoFiles or ErrCategoryNotFound.
+func (s *Service) SaveDigitalProduct(d *models.DigitalProduct) error {
+	if len(d.Files) == 0 {
+		return ErrNoFiles
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkCategory(tx, d.Category); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	product := localProduct{
+		ProductID:   d.ProductID,
+		Name:        d.Name,
+		Description: d.Description,
+		VariantName: digitalVariantName,
+		Price:       d.Price,
+		ImageURL:    d.ImageURL,
+		Category:    d.Category,
+		Active:      d.Active,
+		ProductType: models.ProductTypeDigital,
+	}
+	var currentSlug sql.NullString
+
+	if product.ProductID == "" {
+		if err := insertLocalProduct(tx, &product, now); err != nil {
+			return fmt.Errorf("insert digital product: %w", err)
+		}
+		d.ProductID = product.ProductID
+	} else {
+		err = tx.QueryRow(`
+			SELECT p.slug, v.id FROM products p JOIN variants v ON v.product_id = p.id
+			WHERE p.id = ? AND p.product_type = ?
+		`, d.ProductID, models.ProductTypeDigital).Scan(&currentSlug, &product.VariantID)
+		if err != nil {
+			return err
+		}
+
+		if err := updateLocalProduct(tx, &product, now); err != nil {
+			return fmt.Errorf("update digital product: %w", err)
+		}
+	}
+
+	if _, err := setProductSlug(tx, d.ProductID, currentSlug, d.Name); err != nil {
+		return err
+	}
+	if err := replaceDigitalFiles(tx, d.ProductID, d.Files, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	saved, err := s.GetDigitalProduct(d.ProductID)
+	if err != nil {
+		return err
+	}
+	*d = *saved
+	return nil
+}
+
+// replaceDigitalFiles makes files the product's files, in order. Rows are kept for
+// files that are dropped, because download grants point at them.
+func replaceDigitalFiles(tx *sql.Tx, productID string, files []models.DigitalFile, now time.Time) error {
+	if _, err := tx.Exec(`
+		UPDATE digital_files SET removed_at = ? WHERE product_id = ? AND removed_at IS NULL
+	`, now, productID); err != nil {
+		return fmt.Errorf("clear digital files: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	position := 0
+	for _, f := range files {
+		if seen[f.Path] {
+			continue
+		}
+		seen[f.Path] = true
+
+		result, err := tx.Exec(`
+			UPDATE digital_files
+			SET file_name = ?, content_type = ?, size_bytes = ?, position = ?, removed_at = NULL
+			WHERE product_id = ? AND file_path = ?
+		`, f.FileName, f.ContentType, f.SizeBytes, position, productID, f.Path)
+		if err != nil {
+			return fmt.Errorf("update digital file: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			_, err = tx.Exec(`
+				INSERT INTO digital_files (
+					id, product_id, file_path, file_name, content_type, size_bytes, position, created_at
+				) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			`, uuid.New().String(), productID, f.Path, f.FileName, f.ContentType, f.SizeBytes, position, now)
+			if err != nil {
+				return fmt.Errorf("add digital file: %w", err)
+			}
+		}
+		position++
+	}
+	return nil
+}
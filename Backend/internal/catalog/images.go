@@ -0,0 +1,369 @@
+package catalog
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+	"github.com/nessieaudio/ecommerce-backend/internal/services/printful"
+)
+
+// ErrVariantNotInProduct is returned when an image is tied to another product's variant
+var ErrVariantNotInProduct = errors.New("variant does not belong to product")
+
+// printfulImageSortBase keeps Printful mockups after our own photos in the gallery
+const printfulImageSortBase = 100
+
+// ListImages returns a product's gallery in display order
+func (s *Service) ListImages(productID string) ([]models.ProductImage, error) {
+	return s.queryImages(`WHERE product_id = ?`, productID)
+}
+
+// ListImagesMissingAltText returns every image without alt text, for accessibility audits
+func (s *Service) ListImagesMissingAltText() ([]models.ProductImage, error) {
+	return s.queryImages(`WHERE TRIM(alt_text) = ''`)
+}
+
+func (s *Service) queryImages(where string, args ...interface{}) ([]models.ProductImage, error) {
+	rows, err := s.db.Query(`
+		SELECT id, product_id, variant_id, url, alt_text, sort_order, source, created_at, updated_at
+		FROM product_images
+		`+where+`
+		ORDER BY product_id, sort_order, created_at
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query product images: %w", err)
+	}
+	defer rows.Close()
+
+	images := []models.ProductImage{}
+	for rows.Next() {
+		var img models.ProductImage
+		var variantID sql.NullString
+		if err := rows.Scan(&img.ID, &img.ProductID, &variantID, &img.URL, &img.AltText,
+			&img.SortOrder, &img.Source, &img.CreatedAt, &img.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan product image: %w", err)
+		}
+		if variantID.Valid {
+			img.VariantID = &variantID.String
+		}
+		images = append(images, img)
+	}
+
+	return images, rows.Err()
+}
+
+// AddImage adds an admin-managed image to a product's gallery
+// Returns sql.ErrNoRows if the product does not exist
+func (s *Service) AddImage(img *models.ProductImage) error {
+	if err := s.checkImageTarget(img.ProductID, img.VariantID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	img.ID = uuid.New().String()
+	img.Source = models.ImageSourceAdmin
+	img.CreatedAt = now
+	img.UpdatedAt = now
+
+	_, err := s.db.Exec(`
+		INSERT INTO product_images (id, product_id, variant_id, url, alt_text, sort_order, source, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, img.ID, img.ProductID, img.VariantID, img.URL, img.AltText, img.SortOrder, img.Source, now, now)
+	if err != nil {
+		return fmt.Errorf("insert product image: %w", err)
+	}
+
+	return nil
+}
+
+// ImageUpdate holds the editable fields of an image; nil fields are left unchanged
+type ImageUpdate struct {
+	AltText   *string `json:"alt_text"`
+	SortOrder *int    `json:"sort_order"`
+	VariantID *string `json:"variant_id"` // "" detaches the image from its variant
+}
+
+// UpdateImage edits an image's alt text, position or variant association
+// Returns sql.ErrNoRows if the image does not exist
+func (s *Service) UpdateImage(imageID string, update ImageUpdate) (*models.ProductImage, error) {
+	var productID string
+	if err := s.db.QueryRow(`SELECT product_id FROM product_images WHERE id = ?`, imageID).Scan(&productID); err != nil {
+		return nil, err
+	}
+
+	sets := []string{"updated_at = ?"}
+	args := []interface{}{time.Now()}
+
+	if update.AltText != nil {
+		sets = append(sets, "alt_text = ?")
+		args = append(args, strings.TrimSpace(*update.AltText))
+	}
+	if update.SortOrder != nil {
+		sets = append(sets, "sort_order = ?")
+		args = append(args, *update.SortOrder)
+	}
+	if update.VariantID != nil {
+		var variantID *string
+		if *update.VariantID != "" {
+			variantID = update.VariantID
+		}
+		if err := s.checkImageTarget(productID, variantID); err != nil {
+			return nil, err
+		}
+		sets = append(sets, "variant_id = ?")
+		args = append(args, variantID)
+	}
+
+	args = append(args, imageID)
+	if _, err := s.db.Exec(`UPDATE product_images SET `+strings.Join(sets, ", ")+` WHERE id = ?`, args...); err != nil {
+		return nil, fmt.Errorf("update product image: %w", err)
+	}
+
+	images, err := s.queryImages(`WHERE id = ?`, imageID)
+	if err != nil {
+		return nil, err
+	}
+	if len(images) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &images[0], nil
+}
+
+// DeleteImage removes an image from its gallery
+// Returns sql.ErrNoRows if the image does not exist. Synced images come back on the
+// next catalog sync while their file or mockup still exists.
+func (s *Service) DeleteImage(imageID string) error {
+	result, err := s.db.Exec(`DELETE FROM product_images WHERE id = ?`, imageID)
+	if err != nil {
+		return fmt.Errorf("delete product image: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// checkImageTarget verifies the product exists and the variant, if any, belongs to it
+func (s *Service) checkImageTarget(productID string, variantID *string) error {
+	var exists int
+	if err := s.db.QueryRow(`SELECT 1 FROM products WHERE id = ?`, productID).Scan(&exists); err != nil {
+		return err
+	}
+
+	if variantID == nil {
+		return nil
+	}
+
+	err := s.db.QueryRow(`SELECT 1 FROM variants WHERE id = ? AND product_id = ?`, *variantID, productID).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrVariantNotInProduct
+	}
+	return err
+}
+
+// syncImages refreshes a product's local photos and Printful mockups in its gallery
+// Admin-added images are never touched; alt text and ordering edits on synced images are kept.
+// variantIDs maps Printful sync variant IDs to local variant IDs.
+func (s *Service) syncImages(tx *sql.Tx, productID, productName string, variants []printful.SyncVariant, variantIDs map[int64]string, report *Report) error {
+	now := time.Now()
+
+	var synced []string                          // IDs of every synced image
+	localByURL := make(map[string]string)        // url -> image ID
+	printfulByVariant := make(map[string]string) // variant ID -> image ID
+	rows, err := tx.Query(`
+		SELECT id, source, COALESCE(variant_id, ''), url
+		FROM product_images
+		WHERE product_id = ? AND source IN (?, ?)
+	`, productID, models.ImageSourceLocal, models.ImageSourcePrintful)
+	if err != nil {
+		return fmt.Errorf("query product images: %w", err)
+	}
+	for rows.Next() {
+		var id, source, variantID, url string
+		if err := rows.Scan(&id, &source, &variantID, &url); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan product image: %w", err)
+		}
+		synced = append(synced, id)
+		if source == models.ImageSourceLocal {
+			localByURL[url] = id
+		} else {
+			printfulByVariant[variantID] = id
+		}
+	}
+	rows.Close()
+
+	keep := make(map[string]bool)
+
+	// Local photos, in filename order, ahead of any mockups
+	for i, url := range s.LocalImages(productName) {
+		if id, ok := localByURL[url]; ok {
+			keep[id] = true
+			continue
+		}
+
+		id := uuid.New().String()
+		_, err := tx.Exec(`
+			INSERT INTO product_images (id, product_id, url, alt_text, sort_order, source, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, id, productID, url, localImageAltText(productName, url), i, models.ImageSourceLocal, now, now)
+		if err != nil {
+			return fmt.Errorf("insert local image: %w", err)
+		}
+		keep[id] = true
+		report.ImagesAdded++
+	}
+
+	// One Printful mockup per variant, so selecting a variant can show its colour
+	for i, sv := range variants {
+		variantID, ok := variantIDs[sv.ID]
+		if !ok {
+			continue
+		}
+		url := previewURL(sv)
+		if url == "" {
+			continue
+		}
+
+		if id, ok := printfulByVariant[variantID]; ok {
+			keep[id] = true
+			_, err := tx.Exec(`
+				UPDATE product_images SET url = ?, updated_at = ?
+				WHERE id = ? AND url != ?
+			`, url, now, id, url)
+			if err != nil {
+				return fmt.Errorf("update mockup image: %w", err)
+			}
+			continue
+		}
+
+		id := uuid.New().String()
+		_, err := tx.Exec(`
+			INSERT INTO product_images (id, product_id, variant_id, url, alt_text, sort_order, source, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, id, productID, variantID, url, mockupAltText(productName, sv), printfulImageSortBase+i,
+			models.ImageSourcePrintful, now, now)
+		if err != nil {
+			return fmt.Errorf("insert mockup image: %w", err)
+		}
+		keep[id] = true
+		report.ImagesAdded++
+	}
+
+	// Drop synced images whose file or mockup has gone
+	for _, id := range synced {
+		if keep[id] {
+			continue
+		}
+		if _, err := tx.Exec(`DELETE FROM product_images WHERE id = ?`, id); err != nil {
+			return fmt.Errorf("remove stale image: %w", err)
+		}
+		report.ImagesRemoved++
+	}
+
+	return nil
+}
+
+// previewURL returns the mockup image of a sync variant, or "" if it has none
+func previewURL(sv printful.SyncVariant) string {
+	for _, f := range sv.Files {
+		if f.Type == "preview" && f.PreviewURL != "" {
+			return f.PreviewURL
+		}
+	}
+	return ""
+}
+
+// mockupAltText describes a variant mockup, e.g. "Nessie Audio Unisex t-shirt in Black"
+func mockupAltText(productName string, sv printful.SyncVariant) string {
+	if sv.Color != "" {
+		return productName + " in " + sv.Color
+	}
+	if sv.Size != "" {
+		return productName + ", " + sv.Size
+	}
+	return productName
+}
+
+// localImageAltText builds default alt text from a photo filename,
+// e.g. "unisex-staple-t-shirt-black-back-6947058beaf9f.jpg" -> "<product>, black back"
+func localImageAltText(productName, url string) string {
+	name := strings.TrimSuffix(filepath.Base(url), filepath.Ext(url))
+	words := strings.Split(name, "-")
+
+	// Printful mockup exports end in a hex hash
+	if n := len(words); n > 1 && isHexHash(words[n-1]) {
+		words = words[:n-1]
+	}
+
+	// Drop the words already in the product name ("unisex", "t", "shirt", ...)
+	productWords := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(productName), func(r rune) bool {
+		return r == ' ' || r == '-'
+	}) {
+		productWords[w] = true
+	}
+
+	var descriptive []string
+	for _, w := range words {
+		if w != "" && !productWords[strings.ToLower(w)] {
+			descriptive = append(descriptive, w)
+		}
+	}
+
+	if len(descriptive) == 0 {
+		return productName
+	}
+	return productName + ", " + strings.Join(descriptive, " ")
+}
+
+func isHexHash(s string) bool {
+	if len(s) < 8 {
+		return false
+	}
+	for _, r := range s {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// LocalImages returns the URLs of a product's photos in "<staticDir>/Product Photos/<product name>/",
+// sorted by filename
+func (s *Service) LocalImages(productName string) []string {
+	if s.staticDir == "" {
+		return nil
+	}
+
+	entries, err := os.ReadDir(filepath.Join(s.staticDir, productPhotosDir, productName))
+	if err != nil {
+		return nil
+	}
+
+	var images []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".jpg", ".jpeg", ".png", ".webp":
+			images = append(images, entry.Name())
+		}
+	}
+	sort.Strings(images)
+
+	urls := make([]string, len(images))
+	for i, name := range images {
+		urls[i] = "/" + productPhotosDir + "/" + productName + "/" + name
+	}
+	return urls
+}
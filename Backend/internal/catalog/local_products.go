@@ -0,0 +1,85 @@
+package catalog
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// localProduct is a product defined in the store rather than synced from Printful
+// (printful_id 0), with the single variant that is added to carts. Bundles and
+// digital products are stored this way. The price is pinned as a price override,
+// so markup rules never touch it.
+type localProduct struct {
+	ProductID   string
+	VariantID   string
+	Name        string
+	Description string
+	VariantName string
+	Price       float64
+	ImageURL    string
+	Category    string
+	Active      bool
+	IsBundle    bool
+	ProductType string
+}
+
+// checkCategory returns ErrCategoryNotFound unless slug is empty or a known category
+func checkCategory(tx *sql.Tx, slug string) error {
+	if slug == "" {
+		return nil
+	}
+	var exists int
+	err := tx.QueryRow(`SELECT 1 FROM categories WHERE slug = ?`, slug).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrCategoryNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("look up category: %w", err)
+	}
+	return nil
+}
+
+// insertLocalProduct creates the product and its variant, filling in their IDs
+func insertLocalProduct(tx *sql.Tx, p *localProduct, now time.Time) error {
+	p.ProductID = uuid.New().String()
+	p.VariantID = uuid.New().String()
+	_, err := tx.Exec(`
+		INSERT INTO products (
+			id, printful_id, name, description, price, price_override, currency,
+			image_url, thumbnail_url, category, active, is_bundle, product_type, created_at, updated_at
+		) VALUES (?, 0, ?, ?, ?, ?, 'USD', ?, ?, ?, ?, ?, ?, ?, ?)
+	`, p.ProductID, p.Name, p.Description, p.Price, p.Price,
+		p.ImageURL, p.ImageURL, p.Category, p.Active, p.IsBundle, p.ProductType, now, now)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO variants (
+			id, product_id, printful_variant_id, name, price, price_override,
+			available, track_inventory, created_at, updated_at
+		) VALUES (?, ?, 0, ?, ?, ?, ?, 0, ?, ?)
+	`, p.VariantID, p.ProductID, p.VariantName, p.Price, p.Price, p.Active, now, now)
+	return err
+}
+
+// updateLocalProduct replaces the product's and its variant's details
+func updateLocalProduct(tx *sql.Tx, p *localProduct, now time.Time) error {
+	_, err := tx.Exec(`
+		UPDATE products
+		SET name = ?, description = ?, price = ?, price_override = ?, image_url = ?, thumbnail_url = ?,
+			category = ?, active = ?, updated_at = ?
+		WHERE id = ?
+	`, p.Name, p.Description, p.Price, p.Price, p.ImageURL, p.ImageURL, p.Category, p.Active, now, p.ProductID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		UPDATE variants SET name = ?, price = ?, price_override = ?, available = ?, updated_at = ?
+		WHERE id = ?
+	`, p.VariantName, p.Price, p.Price, p.Active, now, p.VariantID)
+	return err
+}
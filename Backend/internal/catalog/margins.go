@@ -0,0 +1,103 @@
+package catalog
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+
+	"github.com/nessieaudio/ecommerce-backend/internal/money"
+)
+
+// VariantMargin is the per-unit margin of a variant at current prices
+type VariantMargin struct {
+	VariantID     string   `json:"variant_id"`
+	ProductID     string   `json:"product_id"`
+	ProductName   string   `json:"product_name"`
+	VariantName   string   `json:"variant_name"`
+	Price         float64  `json:"price"`
+	PriceSource   string   `json:"price_source"` // "override", "rule" or "retail"
+	PrintfulCost  *float64 `json:"printful_cost"`
+	RetailPrice   *float64 `json:"retail_price"`             // Printful's suggested retail price
+	Margin        *float64 `json:"margin,omitempty"`         // Price - cost (before Stripe fees and shipping)
+	MarginPercent *float64 `json:"margin_percent,omitempty"` // Margin as a percentage of price
+	Available     bool     `json:"available"`
+}
+
+// GetVariantMargins returns margins for every variant, or for one product if productID is set
+// Variants whose Printful cost is not known yet have no margin.
+func (s *Service) GetVariantMargins(productID string) ([]VariantMargin, error) {
+	query := `
+		SELECT v.id, v.product_id, p.name, v.name, v.price, v.price_override,
+			v.printful_cost, v.retail_price, v.available, COALESCE(p.category, '')
+		FROM variants v
+		JOIN products p ON v.product_id = p.id
+	`
+	var args []interface{}
+	if productID != "" {
+		query += ` WHERE v.product_id = ?`
+		args = append(args, productID)
+	}
+	query += ` ORDER BY p.name, v.price`
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query variant margins: %w", err)
+	}
+
+	type marginRow struct {
+		margin   VariantMargin
+		override sql.NullFloat64
+		category string
+	}
+	var results []marginRow
+	for rows.Next() {
+		var r marginRow
+		var cost, retail sql.NullFloat64
+		if err := rows.Scan(&r.margin.VariantID, &r.margin.ProductID, &r.margin.ProductName, &r.margin.VariantName,
+			&r.margin.Price, &r.override, &cost, &retail, &r.margin.Available, &r.category); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan variant margin: %w", err)
+		}
+		if cost.Valid {
+			r.margin.PrintfulCost = &cost.Float64
+		}
+		if retail.Valid {
+			r.margin.RetailPrice = &retail.Float64
+		}
+		results = append(results, r)
+	}
+	rows.Close()
+
+	margins := make([]VariantMargin, 0, len(results))
+	for _, r := range results {
+		m := r.margin
+
+		switch {
+		case r.override.Valid:
+			m.PriceSource = "override"
+		default:
+			rule, err := resolveRule(s.db, m.ProductID, r.category)
+			if err != nil {
+				return nil, err
+			}
+			if rule != nil && m.PrintfulCost != nil {
+				m.PriceSource = "rule"
+			} else {
+				m.PriceSource = "retail"
+			}
+		}
+
+		if m.PrintfulCost != nil {
+			margin := money.RoundCents(m.Price - *m.PrintfulCost)
+			m.Margin = &margin
+			if m.Price > 0 {
+				percent := math.Round(margin/m.Price*1000) / 10
+				m.MarginPercent = &percent
+			}
+		}
+
+		margins = append(margins, m)
+	}
+
+	return margins, nil
+}
@@ -0,0 +1,340 @@
+package catalog
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Pricing rule scopes, in order of precedence
+const (
+	ScopeProduct  = "product"
+	ScopeCategory = "category"
+	ScopeDefault  = "default"
+)
+
+// Markup types
+const (
+	MarkupPercent = "percent" // cost * (1 + value/100)
+	MarkupFixed   = "fixed"   // cost + value
+)
+
+// Psychological rounding modes. Prices are only ever rounded up.
+const (
+	RoundingNone    = "none"     // 17.23 -> 17.23
+	RoundingCharm99 = "charm_99" // 17.23 -> 17.99
+	RoundingCharm95 = "charm_95" // 17.23 -> 17.95
+	RoundingWhole   = "whole"    // 17.23 -> 18.00
+)
+
+// PricingRule derives a variant price from its Printful cost
+type PricingRule struct {
+	ID          string    `json:"id"`
+	Scope       string    `json:"scope"`
+	Target      string    `json:"target"` // Product ID or category name, "" for default
+	MarkupType  string    `json:"markup_type"`
+	MarkupValue float64   `json:"markup_value"`
+	Rounding    string    `json:"rounding"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Validate checks that the rule is well formed
+func (r *PricingRule) Validate() error {
+	switch r.Scope {
+	case ScopeProduct, ScopeCategory:
+		if r.Target == "" {
+			return fmt.Errorf("target is required for %s rules", r.Scope)
+		}
+	case ScopeDefault:
+		if r.Target != "" {
+			return errors.New("default rules cannot have a target")
+		}
+	default:
+		return fmt.Errorf("scope must be one of %s, %s, %s", ScopeProduct, ScopeCategory, ScopeDefault)
+	}
+
+	switch r.MarkupType {
+	case MarkupPercent, MarkupFixed:
+	default:
+		return fmt.Errorf("markup_type must be %s or %s", MarkupPercent, MarkupFixed)
+	}
+	if r.MarkupValue < 0 {
+		return errors.New("markup_value must not be negative")
+	}
+
+	switch r.Rounding {
+	case RoundingNone, RoundingCharm99, RoundingCharm95, RoundingWhole:
+	default:
+		return fmt.Errorf("rounding must be one of %s, %s, %s, %s", RoundingNone, RoundingCharm99, RoundingCharm95, RoundingWhole)
+	}
+
+	return nil
+}
+
+// Apply returns the marked-up, rounded price for a Printful cost
+func (r *PricingRule) Apply(cost float64) float64 {
+	price := cost
+	switch r.MarkupType {
+	case MarkupPercent:
+		price = cost * (1 + r.MarkupValue/100)
+	case MarkupFixed:
+		price = cost + r.MarkupValue
+	}
+	return RoundPrice(price, r.Rounding)
+}
+
+// RoundPrice rounds a price up to the next psychological price point
+func RoundPrice(price float64, rounding string) float64 {
+	// Work in whole cents; the epsilon absorbs float noise like 17.000000001
+	cents := int64(math.Ceil(price*100 - 1e-6))
+
+	switch rounding {
+	case RoundingCharm99:
+		cents = charmCents(cents, 99)
+	case RoundingCharm95:
+		cents = charmCents(cents, 95)
+	case RoundingWhole:
+		cents = (cents + 99) / 100 * 100
+	}
+
+	return float64(cents) / 100
+}
+
+// charmCents rounds cents up to the next price ending in .<ending>
+func charmCents(cents, ending int64) int64 {
+	rounded := cents/100*100 + ending
+	if rounded < cents {
+		rounded += 100
+	}
+	return rounded
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx
+type queryRower interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// resolveRule returns the rule that prices a product: product rule, then
+// category rule, then the default rule. Returns nil if no rule applies.
+func resolveRule(q queryRower, productID, category string) (*PricingRule, error) {
+	var rule PricingRule
+	err := q.QueryRow(`
+		SELECT id, scope, target, markup_type, markup_value, rounding, created_at, updated_at
+		FROM pricing_rules
+		WHERE (scope = 'product' AND target = ?)
+			OR (scope = 'category' AND target = ?)
+			OR scope = 'default'
+		ORDER BY CASE scope WHEN 'product' THEN 0 WHEN 'category' THEN 1 ELSE 2 END
+		LIMIT 1
+	`, productID, category).Scan(&rule.ID, &rule.Scope, &rule.Target, &rule.MarkupType,
+		&rule.MarkupValue, &rule.Rounding, &rule.CreatedAt, &rule.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("resolve pricing rule: %w", err)
+	}
+	return &rule, nil
+}
+
+// variantPrice picks a variant's selling price: our override, then the
+// markup rule applied to Printful's cost, then Printful's retail price
+func variantPrice(override sql.NullFloat64, rule *PricingRule, cost sql.NullFloat64, retail float64) float64 {
+	if override.Valid {
+		return override.Float64
+	}
+	if rule != nil && cost.Valid {
+		return rule.Apply(cost.Float64)
+	}
+	return retail
+}
+
+// ListPricingRules returns all markup rules in precedence order
+func (s *Service) ListPricingRules() ([]PricingRule, error) {
+	rows, err := s.db.Query(`
+		SELECT id, scope, target, markup_type, markup_value, rounding, created_at, updated_at
+		FROM pricing_rules
+		ORDER BY CASE scope WHEN 'product' THEN 0 WHEN 'category' THEN 1 ELSE 2 END, target
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query pricing rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []PricingRule{}
+	for rows.Next() {
+		var rule PricingRule
+		if err := rows.Scan(&rule.ID, &rule.Scope, &rule.Target, &rule.MarkupType,
+			&rule.MarkupValue, &rule.Rounding, &rule.CreatedAt, &rule.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan pricing rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// SavePricingRule creates a rule, replacing any existing rule for the same scope and target,
+// then reprices the catalog
+func (s *Service) SavePricingRule(rule *PricingRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	rule.ID = uuid.New().String()
+	rule.CreatedAt = now
+	rule.UpdatedAt = now
+
+	err := s.db.QueryRow(`
+		INSERT INTO pricing_rules (id, scope, target, markup_type, markup_value, rounding, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (scope, target) DO UPDATE SET
+			markup_type = excluded.markup_type,
+			markup_value = excluded.markup_value,
+			rounding = excluded.rounding,
+			updated_at = excluded.updated_at
+		RETURNING id, created_at
+	`, rule.ID, rule.Scope, rule.Target, rule.MarkupType, rule.MarkupValue, rule.Rounding, now, now).
+		Scan(&rule.ID, &rule.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("save pricing rule: %w", err)
+	}
+
+	if _, err := s.Reprice(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DeletePricingRule removes a rule and reprices the catalog
+// Returns sql.ErrNoRows if the rule does not exist
+func (s *Service) DeletePricingRule(id string) error {
+	result, err := s.db.Exec(`DELETE FROM pricing_rules WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete pricing rule: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+
+	if _, err := s.Reprice(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SetVariantPriceOverride pins a variant's price (nil clears the override) and reprices it
+// Returns sql.ErrNoRows if the variant does not exist
+func (s *Service) SetVariantPriceOverride(variantID string, price *float64) error {
+	var override sql.NullFloat64
+	if price != nil {
+		override = sql.NullFloat64{Float64: *price, Valid: true}
+	}
+
+	result, err := s.db.Exec(`
+		UPDATE variants SET price_override = ?, updated_at = ? WHERE id = ?
+	`, override, time.Now(), variantID)
+	if err != nil {
+		return fmt.Errorf("update price override: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+
+	if _, err := s.Reprice(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Reprice recomputes every variant and product price from overrides, rules and stored
+// Printful costs without calling Printful. Returns the number of variants whose price changed.
+func (s *Service) Reprice() (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT v.id, v.product_id, COALESCE(p.category, ''), v.price,
+			v.price_override, v.printful_cost, COALESCE(v.retail_price, v.price)
+		FROM variants v
+		JOIN products p ON v.product_id = p.id
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("query variants: %w", err)
+	}
+
+	type repricedVariant struct {
+		id, productID, category string
+		price, retail           float64
+		override, cost          sql.NullFloat64
+	}
+	var variants []repricedVariant
+	for rows.Next() {
+		var v repricedVariant
+		if err := rows.Scan(&v.id, &v.productID, &v.category, &v.price, &v.override, &v.cost, &v.retail); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan variant: %w", err)
+		}
+		variants = append(variants, v)
+	}
+	rows.Close()
+
+	now := time.Now()
+	changed := 0
+	touchedProducts := make(map[string]bool)
+	for _, v := range variants {
+		rule, err := resolveRule(tx, v.productID, v.category)
+		if err != nil {
+			return 0, err
+		}
+
+		price := variantPrice(v.override, rule, v.cost, v.retail)
+		if price == v.price {
+			continue
+		}
+
+		if _, err := tx.Exec(`UPDATE variants SET price = ?, updated_at = ? WHERE id = ?`, price, now, v.id); err != nil {
+			return 0, fmt.Errorf("update variant price: %w", err)
+		}
+		changed++
+		touchedProducts[v.productID] = true
+	}
+
+	for productID := range touchedProducts {
+		if err := updateProductPrice(tx, productID); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return changed, nil
+}
+
+// updateProductPrice refreshes a product's "from" price unless it has an override
+func updateProductPrice(tx *sql.Tx, productID string) error {
+	price, err := lowestVariantPrice(tx, productID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE products SET price = ?, updated_at = ?
+		WHERE id = ? AND price_override IS NULL AND price != ?
+	`, price, time.Now(), productID, price)
+	if err != nil {
+		return fmt.Errorf("update product price: %w", err)
+	}
+	return nil
+}
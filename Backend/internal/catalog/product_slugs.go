@@ -0,0 +1,154 @@
+package catalog
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fallbackProductSlug is used for names with no letters or digits
+const fallbackProductSlug = "product"
+
+// ResolveProduct finds an active product by UUID or slug
+// movedTo is the current slug when ref is a slug the product used before a rename,
+// so callers can redirect to it. Returns sql.ErrNoRows if nothing matches.
+func (s *Service) ResolveProduct(ref string) (productID, movedTo string, err error) {
+	if _, parseErr := uuid.Parse(ref); parseErr == nil {
+		err = s.db.QueryRow(`SELECT id FROM products WHERE id = ? AND active = 1`, ref).Scan(&productID)
+		if err == nil || !errors.Is(err, sql.ErrNoRows) {
+			return productID, "", err
+		}
+	}
+
+	err = s.db.QueryRow(`SELECT id FROM products WHERE slug = ? AND active = 1`, ref).Scan(&productID)
+	if err == nil {
+		return productID, "", nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", "", fmt.Errorf("look up product slug: %w", err)
+	}
+
+	var slug sql.NullString
+	err = s.db.QueryRow(`
+		SELECT p.id, p.slug
+		FROM product_slug_redirects r
+		JOIN products p ON p.id = r.product_id
+		WHERE r.slug = ? AND p.active = 1
+	`, ref).Scan(&productID, &slug)
+	if err != nil {
+		return "", "", err
+	}
+	return productID, slug.String, nil
+}
+
+// BackfillProductSlugs gives a slug to every product that does not have one yet
+// Returns the number of products updated.
+func (s *Service) BackfillProductSlugs() (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT id, name FROM products WHERE slug IS NULL ORDER BY created_at, id`)
+	if err != nil {
+		return 0, fmt.Errorf("query products without slug: %w", err)
+	}
+	type pending struct{ id, name string }
+	var products []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.name); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan product: %w", err)
+		}
+		products = append(products, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, p := range products {
+		if _, err := setProductSlug(tx, p.id, sql.NullString{}, p.name); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit transaction: %w", err)
+	}
+	return len(products), nil
+}
+
+// setProductSlug points a product at the slug for name. A replaced slug becomes a
+// redirect to the product. Returns the product's slug.
+func setProductSlug(tx *sql.Tx, productID string, current sql.NullString, name string) (string, error) {
+	base := Slugify(name)
+	if base == "" {
+		base = fallbackProductSlug
+	}
+
+	slug, err := uniqueProductSlug(tx, productID, base)
+	if err != nil {
+		return "", err
+	}
+	if current.Valid && current.String == slug {
+		return slug, nil
+	}
+
+	now := time.Now()
+
+	// A product renamed back to an earlier name reclaims its old slug
+	if _, err := tx.Exec(`DELETE FROM product_slug_redirects WHERE slug = ?`, slug); err != nil {
+		return "", fmt.Errorf("reclaim product slug: %w", err)
+	}
+	if current.Valid {
+		if _, err := tx.Exec(`
+			INSERT OR REPLACE INTO product_slug_redirects (slug, product_id, created_at) VALUES (?, ?, ?)
+		`, current.String, productID, now); err != nil {
+			return "", fmt.Errorf("record product slug redirect: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE products SET slug = ? WHERE id = ?`, slug, productID); err != nil {
+		return "", fmt.Errorf("update product slug: %w", err)
+	}
+	return slug, nil
+}
+
+// uniqueProductSlug returns base, or base-2, base-3, ... if another product uses it or
+// used it before a rename
+func uniqueProductSlug(q queryRower, productID, base string) (string, error) {
+	for n := 1; ; n++ {
+		slug := base
+		if n > 1 {
+			slug = base + "-" + strconv.Itoa(n)
+		}
+
+		var taken bool
+		err := q.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM products WHERE slug = ? AND id != ?)
+				OR EXISTS (SELECT 1 FROM product_slug_redirects WHERE slug = ? AND product_id != ?)
+		`, slug, productID, slug, productID).Scan(&taken)
+		if err != nil {
+			return "", fmt.Errorf("check product slug: %w", err)
+		}
+		if !taken {
+			return slug, nil
+		}
+	}
+}
+
+// ProductPagePath is the storefront path of a product page, by slug when it has one
+func ProductPagePath(id, slug string) string {
+	if slug != "" {
+		return "/product-detail?slug=" + url.QueryEscape(slug)
+	}
+	return "/product-detail?id=" + url.QueryEscape(id)
+}
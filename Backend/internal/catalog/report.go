@@ -0,0 +1,122 @@
+package catalog
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Change describes a single product or variant touched by a sync run
+type Change struct {
+	ID         string   `json:"id"`          // Local UUID
+	PrintfulID int64    `json:"printful_id"` // Printful sync product / sync variant ID
+	Name       string   `json:"name"`
+	Fields     []string `json:"fields,omitempty"` // Changed fields (updates only)
+}
+
+// Report is the diff produced by a sync run
+type Report struct {
+	RunID               string    `json:"run_id"`
+	Trigger             string    `json:"trigger"` // "startup", "scheduled", "admin", "cli"
+	Status              string    `json:"status"`  // "success", "partial", "failed"
+	ProductsAdded       []Change  `json:"products_added"`
+	ProductsUpdated     []Change  `json:"products_updated"`
+	ProductsDeactivated []Change  `json:"products_deactivated"`
+	VariantsAdded       []Change  `json:"variants_added"`
+	VariantsUpdated     []Change  `json:"variants_updated"`
+	VariantsDeactivated []Change  `json:"variants_deactivated"`
+	ImagesAdded         int       `json:"images_added"`
+	ImagesRemoved       int       `json:"images_removed"`
+	Unchanged           int       `json:"unchanged"` // Products seen with no changes
+	Errors              []string  `json:"errors,omitempty"`
+	StartedAt           time.Time `json:"started_at"`
+	FinishedAt          time.Time `json:"finished_at"`
+}
+
+// Sync run statuses
+const (
+	StatusSuccess = "success"
+	StatusPartial = "partial" // Completed, but some products could not be fetched or saved
+	StatusFailed  = "failed"
+)
+
+func newReport(runID, trigger string) *Report {
+	return &Report{
+		RunID:               runID,
+		Trigger:             trigger,
+		ProductsAdded:       []Change{},
+		ProductsUpdated:     []Change{},
+		ProductsDeactivated: []Change{},
+		VariantsAdded:       []Change{},
+		VariantsUpdated:     []Change{},
+		VariantsDeactivated: []Change{},
+		StartedAt:           time.Now(),
+	}
+}
+
+// HasChanges reports whether the run added, updated or deactivated anything
+func (r *Report) HasChanges() bool {
+	return len(r.ProductsAdded)+len(r.ProductsUpdated)+len(r.ProductsDeactivated)+
+		len(r.VariantsAdded)+len(r.VariantsUpdated)+len(r.VariantsDeactivated)+
+		r.ImagesAdded+r.ImagesRemoved > 0
+}
+
+// Summary returns a one-line description of the run for logs
+func (r *Report) Summary() string {
+	return fmt.Sprintf("products +%d ~%d -%d, variants +%d ~%d -%d, images +%d -%d, %d unchanged, %d errors",
+		len(r.ProductsAdded), len(r.ProductsUpdated), len(r.ProductsDeactivated),
+		len(r.VariantsAdded), len(r.VariantsUpdated), len(r.VariantsDeactivated),
+		r.ImagesAdded, r.ImagesRemoved, r.Unchanged, len(r.Errors))
+}
+
+// saveReport records a finished run in catalog_sync_runs
+func (s *Service) saveReport(report *Report, runErr error) error {
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("marshal report: %w", err)
+	}
+
+	var errMsg sql.NullString
+	if runErr != nil {
+		errMsg = sql.NullString{String: runErr.Error(), Valid: true}
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO catalog_sync_runs (
+			id, trigger, status,
+			products_added, products_updated, products_deactivated,
+			variants_added, variants_updated, variants_deactivated,
+			report, error, started_at, finished_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, report.RunID, report.Trigger, report.Status,
+		len(report.ProductsAdded), len(report.ProductsUpdated), len(report.ProductsDeactivated),
+		len(report.VariantsAdded), len(report.VariantsUpdated), len(report.VariantsDeactivated),
+		string(reportJSON), errMsg, report.StartedAt, report.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("save sync run: %w", err)
+	}
+
+	return nil
+}
+
+// LastReport returns the report of the most recent sync run
+// Returns sql.ErrNoRows if the catalog has never been synced
+func (s *Service) LastReport() (*Report, error) {
+	var reportJSON string
+	err := s.db.QueryRow(`
+		SELECT report FROM catalog_sync_runs
+		ORDER BY started_at DESC
+		LIMIT 1
+	`).Scan(&reportJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	var report Report
+	if err := json.Unmarshal([]byte(reportJSON), &report); err != nil {
+		return nil, fmt.Errorf("decode sync report: %w", err)
+	}
+
+	return &report, nil
+}
@@ -0,0 +1,40 @@
+package catalog
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// ErrSlugTaken is returned when a slug is already used by another category or collection
+var ErrSlugTaken = errors.New("slug is already in use")
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Slugify turns a name into a URL slug, e.g. "Tour 2026: Cosmic Lung" -> "tour-2026-cosmic-lung"
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+			dash = false
+		case b.Len() > 0 && !dash:
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// ValidSlug reports whether s is a lowercase, dash-separated slug
+func ValidSlug(s string) bool {
+	return len(s) <= 100 && slugPattern.MatchString(s)
+}
+
+// isUniqueViolation reports whether err is a SQLite UNIQUE constraint failure
+func isUniqueViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE")
+}
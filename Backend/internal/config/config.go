@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/joho/godotenv"
@@ -18,6 +20,19 @@ type Config struct {
 	// Database
 	DatabasePath string
 
+	// Static site root (HTML pages, Product Photos)
+	StaticDir string
+
+	// Resized product photo cache (see internal/imaging)
+	ImageCacheDir string
+
+	// Files sold as digital downloads (see internal/downloads). Must not be
+	// under StaticDir, or the files could be fetched without paying.
+	DigitalFilesDir string
+
+	// Portfolio tracks, laid out as <genre>/<artist>/... (see internal/audio)
+	MusicDir string
+
 	// Printful
 	PrintfulAPIKey        string
 	PrintfulAPIURL        string
@@ -45,6 +60,21 @@ type Config struct {
 	SMTPFromName  string
 	AdminEmail    string
 
+	// Email transport: "smtp", "file" (capture into a maildir at EmailCaptureDir)
+	// or "none". Empty picks smtp when credentials are set and none otherwise.
+	EmailTransport  string
+	EmailCaptureDir string
+
+	// Admin API (Bearer token for /api/v1/admin endpoints)
+	AdminAPIKey string
+
+	// Newsletter campaigns are sent at most this many emails a minute, to stay
+	// under the SMTP provider's limits
+	NewsletterSendsPerMinute int
+
+	// HMAC key for links sent by email, e.g. review invitations (see internal/signing)
+	LinkSigningSecret string
+
 	// Logging
 	LogLevel string
 }
@@ -128,6 +158,33 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvInt retrieves a positive integer environment variable or returns a default value
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Printf("WARNING: %s must be a positive whole number, using %d", key, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+// getStaticDir returns the directory the site's static files are served from
+// In Docker (production): /app/static
+// In local dev (CWD is Backend/): ../
+func getStaticDir() string {
+	if dir := os.Getenv("STATIC_DIR"); dir != "" {
+		return dir
+	}
+	if _, err := os.Stat("/app/static"); err == nil {
+		return "/app/static"
+	}
+	return ".."
+}
+
 // getStripeSuccessURL returns the appropriate success URL based on environment
 func getStripeSuccessURL(cfg *Config) string {
 	// Check if explicitly set in environment
@@ -212,6 +269,10 @@ func Load() (*Config, error) {
 		Port:                  getEnv("PORT", "8080"),
 		Env:                   detectedEnv,
 		DatabasePath:          databasePath,
+		StaticDir:             getStaticDir(),
+		ImageCacheDir:         getEnv("IMAGE_CACHE_DIR", filepath.Join(filepath.Dir(databasePath), "image-cache")),
+		DigitalFilesDir:       getEnv("DIGITAL_FILES_DIR", filepath.Join(filepath.Dir(databasePath), "downloads")),
+		MusicDir:              getEnv("MUSIC_DIR", filepath.Join(getStaticDir(), "Music")),
 		PrintfulAPIKey:        getEnv("PRINTFUL_API_KEY", ""),
 		PrintfulAPIURL:        getEnv("PRINTFUL_API_URL", "https://api.printful.com"),
 		PrintfulWebhookSecret: getEnv("PRINTFUL_WEBHOOK_SECRET", ""),
@@ -227,6 +288,8 @@ func Load() (*Config, error) {
 		SMTPFromEmail:         getEnv("SMTP_FROM_EMAIL", ""),
 		SMTPFromName:          getEnv("SMTP_FROM_NAME", "Nessie Audio"),
 		AdminEmail:            getEnv("ADMIN_EMAIL", ""),
+		AdminAPIKey:           getEnv("ADMIN_API_KEY", ""),
+		LinkSigningSecret:     getEnv("LINK_SIGNING_SECRET", ""),
 		LogLevel:              getEnv("LOG_LEVEL", "info"),
 	}
 
@@ -234,6 +297,12 @@ func Load() (*Config, error) {
 	cfg.StripeSuccessURL = getStripeSuccessURL(cfg)
 	cfg.StripeCancelURL = getStripeCancelURL(cfg)
 
+	cfg.EmailTransport = strings.ToLower(getEnv("EMAIL_TRANSPORT", ""))
+	cfg.EmailCaptureDir = getEnv("EMAIL_CAPTURE_DIR", "mail")
+
+	// Campaign send rate; lower it for providers with daily caps such as Gmail
+	cfg.NewsletterSendsPerMinute = getEnvInt("NEWSLETTER_SENDS_PER_MINUTE", 20)
+
 	// Auto-detect CORS allowed origins based on environment
 	cfg.AllowedOrigins = getAllowedOrigins(cfg)
 
@@ -251,6 +320,11 @@ func (c *Config) Validate() error {
 	if c.Port == "" {
 		return fmt.Errorf("PORT is required")
 	}
+	switch c.EmailTransport {
+	case "", "smtp", "file", "none":
+	default:
+		return fmt.Errorf("EMAIL_TRANSPORT must be smtp, file or none, got %q", c.EmailTransport)
+	}
 
 	// Log warnings for missing API keys
 	if c.PrintfulAPIKey == "" {
@@ -259,6 +333,9 @@ func (c *Config) Validate() error {
 	if c.StripeSecretKey == "" {
 		log.Println("WARNING: STRIPE_SECRET_KEY not set - payment processing will not work")
 	}
+	if c.AdminAPIKey == "" {
+		log.Println("WARNING: ADMIN_API_KEY not set - admin endpoints will reject all requests")
+	}
 
 	return nil
 }
@@ -0,0 +1,340 @@
+// Package downloads delivers digital products: it issues a download grant per purchased
+// file when an order is paid, and resolves the signed, expiring, count-limited links
+// to those files.
+package downloads
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+	"github.com/nessieaudio/ecommerce-backend/internal/signing"
+)
+
+// linkPurpose binds download tokens to downloads so other signed links cannot be reused
+const linkPurpose = "download"
+
+// GrantTTL is how long a buyer can download a purchased file
+const GrantTTL = 30 * 24 * time.Hour
+
+// MaxDownloads is how many times each purchased file can be downloaded
+const MaxDownloads = 5
+
+// ResumeWindow is how long after a counted download a ranged request may resume it
+// without using up another download
+const ResumeWindow = 24 * time.Hour
+
+var (
+	// ErrLinkExpired is returned for a download link past its grant's expiry
+	ErrLinkExpired = errors.New("download link has expired")
+	// ErrLimitReached is returned when a grant has no downloads left
+	ErrLimitReached = errors.New("download limit reached")
+	// ErrFileMissing is returned when a file is not in the downloads directory
+	ErrFileMissing = errors.New("file is not available")
+	// ErrInvalidPath is returned for a file path outside the downloads directory
+	ErrInvalidPath = errors.New("path must be relative to the downloads directory")
+)
+
+// Service issues and resolves downloads
+type Service struct {
+	db     *sql.DB
+	signer *signing.Signer
+	dir    string
+}
+
+// NewService creates a download service serving files from dir
+func NewService(db *sql.DB, signer *signing.Signer, dir string) *Service {
+	return &Service{db: db, signer: signer, dir: dir}
+}
+
+// link is what a download token carries
+type link struct {
+	GrantID string `json:"g"`
+}
+
+// DescribeFile checks that path names a file in the downloads directory and returns
+// its size and content type, ready to attach to a digital product
+func (s *Service) DescribeFile(path string) (*models.DigitalFile, error) {
+	full, err := s.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(full)
+	if err != nil || info.IsDir() {
+		return nil, fmt.Errorf("%w: %s", ErrFileMissing, path)
+	}
+
+	contentType := mime.TypeByExtension(strings.ToLower(filepath.Ext(full)))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return &models.DigitalFile{
+		Path:        filepath.ToSlash(filepath.Clean(path)),
+		FileName:    filepath.Base(full),
+		ContentType: contentType,
+		SizeBytes:   info.Size(),
+	}, nil
+}
+
+// resolve turns a stored relative path into a path inside the downloads directory
+func (s *Service) resolve(path string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(path))
+	if path == "" || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", ErrInvalidPath
+	}
+	return filepath.Join(s.dir, clean), nil
+}
+
+// Issue grants the buyer of each digital item in a paid order access to the product's
+// files. Grants already issued are left alone, so this is safe to call again.
+// Returns how many grants were added.
+func (s *Service) Issue(orderID string) (int, error) {
+	rows, err := s.db.Query(`
+		SELECT oi.id, f.id, COALESCE(o.customer_email, '')
+		FROM order_items oi
+		JOIN orders o ON o.id = oi.order_id
+		JOIN products p ON p.id = oi.product_id
+		JOIN digital_files f ON f.product_id = p.id AND f.removed_at IS NULL
+		WHERE oi.order_id = ? AND p.product_type = ?
+		ORDER BY oi.created_at, f.position
+	`, orderID, models.ProductTypeDigital)
+	if err != nil {
+		return 0, fmt.Errorf("query purchased files: %w", err)
+	}
+
+	type purchase struct{ itemID, fileID, email string }
+	var purchases []purchase
+	for rows.Next() {
+		var p purchase
+		if err := rows.Scan(&p.itemID, &p.fileID, &p.email); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scan purchased file: %w", err)
+		}
+		purchases = append(purchases, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	issued := 0
+	for _, p := range purchases {
+		result, err := s.db.Exec(`
+			INSERT OR IGNORE INTO download_grants (
+				id, order_id, order_item_id, file_id, email, max_downloads, expires_at, created_at
+			) VALUES (?, ?, ?, ?, lower(?), ?, ?, ?)
+		`, uuid.New().String(), orderID, p.itemID, p.fileID, p.email, MaxDownloads, now.Add(GrantTTL), now)
+		if err != nil {
+			return issued, fmt.Errorf("insert download grant: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			issued++
+		}
+	}
+	return issued, nil
+}
+
+// ForOrder returns an order's downloads, with links under baseURL. Expired grants
+// are listed without a URL.
+func (s *Service) ForOrder(orderID, baseURL string) ([]models.Download, error) {
+	rows, err := s.db.Query(`
+		SELECT g.id, g.order_item_id, oi.product_name, f.file_name, f.size_bytes,
+			g.downloads, g.max_downloads, g.expires_at
+		FROM download_grants g
+		JOIN order_items oi ON oi.id = g.order_item_id
+		JOIN digital_files f ON f.id = g.file_id
+		WHERE g.order_id = ?
+		ORDER BY oi.created_at, f.position
+	`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("query downloads: %w", err)
+	}
+	defer rows.Close()
+
+	downloads := []models.Download{}
+	for rows.Next() {
+		var d models.Download
+		var grantID string
+		var used, limit int
+		if err := rows.Scan(&grantID, &d.OrderItemID, &d.ProductName, &d.FileName, &d.SizeBytes,
+			&used, &limit, &d.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("scan download: %w", err)
+		}
+
+		if used < limit {
+			d.DownloadsRemaining = limit - used
+		}
+		if ttl := time.Until(d.ExpiresAt); ttl > 0 {
+			token, err := s.signer.Sign(linkPurpose, link{GrantID: grantID}, ttl)
+			if err != nil {
+				return nil, err
+			}
+			d.URL = baseURL + "/api/v1/downloads/" + token
+		}
+		downloads = append(downloads, d)
+	}
+	return downloads, rows.Err()
+}
+
+// File is an opened download, ready to stream
+type File struct {
+	*os.File
+	Name        string
+	ContentType string
+	ModTime     time.Time
+
+	grantID string
+	offset  int64 // Where the response body starts in the file
+	partial bool  // Whether the body is one contiguous part of the file
+}
+
+// requestedStart returns the offset a single-range request starts at, following
+// http.ServeContent: a suffix range counts back from the end, and a range that
+// doesn't match If-Range is ignored. ranged is false when the whole file (or a
+// multipart response) would be sent.
+func requestedStart(r *http.Request, size int64, modTime time.Time) (start int64, ranged bool) {
+	spec, ok := strings.CutPrefix(strings.TrimSpace(r.Header.Get("Range")), "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, false
+	}
+	if ifRange := r.Header.Get("If-Range"); ifRange != "" {
+		t, err := http.ParseTime(ifRange)
+		if err != nil || !modTime.Truncate(time.Second).Equal(t) {
+			return 0, false
+		}
+	}
+
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, false
+	}
+	if first == "" {
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, false
+		}
+		return max(size-n, 0), true
+	}
+	n, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// Open resolves the download link a request was made with and opens its file.
+// A GET uses up one of the grant's downloads unless it resumes the last counted
+// one: a ranged GET within ResumeWindow that continues from no further than the
+// bytes already sent, while the file hasn't been sent in full. A HEAD never
+// counts. Returns signing.ErrInvalidToken, sql.ErrNoRows, ErrLinkExpired,
+// ErrLimitReached or ErrFileMissing.
+func (s *Service) Open(token string, r *http.Request) (*File, error) {
+	var l link
+	if err := s.signer.Verify(linkPurpose, token, &l); err != nil {
+		if errors.Is(err, signing.ErrExpiredToken) {
+			return nil, ErrLinkExpired
+		}
+		return nil, err
+	}
+
+	var used, limit int
+	var served int64
+	var expiresAt time.Time
+	var lastDownloadedAt sql.NullTime
+	var path string
+	file := &File{grantID: l.GrantID}
+	err := s.db.QueryRow(`
+		SELECT g.downloads, g.max_downloads, g.expires_at, g.last_downloaded_at, g.served_bytes,
+			f.file_path, f.file_name, f.content_type
+		FROM download_grants g
+		JOIN digital_files f ON f.id = g.file_id
+		WHERE g.id = ?
+	`, l.GrantID).Scan(&used, &limit, &expiresAt, &lastDownloadedAt, &served, &path, &file.Name, &file.ContentType)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if now.After(expiresAt) {
+		return nil, ErrLinkExpired
+	}
+	resumable := lastDownloadedAt.Valid && now.Sub(lastDownloadedAt.Time) <= ResumeWindow
+	if r.Method == http.MethodHead && used >= limit && !resumable {
+		return nil, ErrLimitReached
+	}
+
+	// Open before counting, so a missing file never costs the buyer a download
+	full, err := s.resolve(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrFileMissing, path)
+	}
+	f, err := os.Open(full)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrFileMissing, path)
+	}
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		f.Close()
+		return nil, fmt.Errorf("%w: %s", ErrFileMissing, path)
+	}
+	file.File = f
+	file.ModTime = info.ModTime()
+	if r.Method == http.MethodHead {
+		return file, nil
+	}
+
+	start, ranged := requestedStart(r, info.Size(), file.ModTime)
+	file.offset = start
+	file.partial = ranged || r.Header.Get("Range") == ""
+	switch {
+	case ranged && start >= info.Size():
+		// Unsatisfiable: ServeContent answers 416 without sending anything
+		return file, nil
+	case ranged && start > 0 && resumable && start <= served && served < info.Size():
+		// Continues the counted download
+		return file, nil
+	}
+
+	if used >= limit {
+		f.Close()
+		return nil, ErrLimitReached
+	}
+	// Conditional update, so concurrent requests cannot go past the limit
+	result, err := s.db.Exec(`
+		UPDATE download_grants SET downloads = downloads + 1, last_downloaded_at = ?, served_bytes = ?
+		WHERE id = ? AND downloads < max_downloads
+	`, now, start, l.GrantID)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("count download: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		f.Close()
+		return nil, ErrLimitReached
+	}
+	return file, nil
+}
+
+// RecordServed notes that written bytes of file were sent, so that a later
+// ranged request can resume from there
+func (s *Service) RecordServed(file *File, written int64) error {
+	if written == 0 || !file.partial {
+		return nil
+	}
+	_, err := s.db.Exec(`UPDATE download_grants SET served_bytes = MAX(served_bytes, ?) WHERE id = ?`,
+		file.offset+written, file.grantID)
+	if err != nil {
+		return fmt.Errorf("record served bytes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+// Package engagements tracks booking work from inquiry to paid deposit: the
+// engagement itself, the quotes sent for it and the deposits paid against them.
+package engagements
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+var (
+	// ErrInquiryNotFound is returned when creating an engagement from an unknown inquiry
+	ErrInquiryNotFound = errors.New("inquiry not found")
+	// ErrAlreadyEngaged is returned when an inquiry already has an engagement
+	ErrAlreadyEngaged = errors.New("inquiry already has an engagement")
+	// ErrInvalidStatus is returned for an unknown engagement status
+	ErrInvalidStatus = errors.New("status must be open, quoted, booked, completed or cancelled")
+	// ErrClosed is returned when quoting or paying for an engagement that is
+	// already booked, completed or cancelled
+	ErrClosed = errors.New("engagement is no longer open for quotes")
+)
+
+// Service manages engagements and their quotes
+type Service struct {
+	db *sql.DB
+}
+
+// NewService creates an engagement service
+func NewService(db *sql.DB) *Service {
+	return &Service{db: db}
+}
+
+// CreateFromInquiry starts an engagement for an inquiry, copying the client's details
+// Returns ErrInquiryNotFound or ErrAlreadyEngaged.
+func (s *Service) CreateFromInquiry(inquiryID string) (*models.Engagement, error) {
+	e := &models.Engagement{InquiryID: &inquiryID}
+	err := s.db.QueryRow(`SELECT name, email, project_type FROM inquiries WHERE id = ?`, inquiryID).
+		Scan(&e.Name, &e.Email, &e.ProjectType)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrInquiryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get inquiry: %w", err)
+	}
+
+	var exists bool
+	if err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM engagements WHERE inquiry_id = ?)`, inquiryID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("check engagement: %w", err)
+	}
+	if exists {
+		return nil, ErrAlreadyEngaged
+	}
+
+	if err := s.Create(e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Create stores a new engagement, filling in its ID, status and timestamps
+func (s *Service) Create(e *models.Engagement) error {
+	now := time.Now()
+	e.ID = uuid.New().String()
+	e.Status = models.EngagementStatusOpen
+	e.CreatedAt = now
+	e.UpdatedAt = now
+
+	_, err := s.db.Exec(`
+		INSERT INTO engagements (id, inquiry_id, name, email, project_type, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, e.ID, e.InquiryID, e.Name, e.Email, e.ProjectType, e.Status, now, now)
+	if err != nil {
+		return fmt.Errorf("insert engagement: %w", err)
+	}
+	return nil
+}
+
+// List returns engagements with a status, or all of them for "", newest first.
+// Quotes are left out; Get includes them.
+func (s *Service) List(status string) ([]models.Engagement, error) {
+	if status == "" {
+		return s.query(`ORDER BY created_at DESC`)
+	}
+	return s.query(`WHERE status = ? ORDER BY created_at DESC`, status)
+}
+
+// Get returns an engagement by ID with its quotes, newest first
+// Returns sql.ErrNoRows if it does not exist.
+func (s *Service) Get(id string) (*models.Engagement, error) {
+	list, err := s.query(`WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	e := &list[0]
+
+	e.Quotes, err = s.queryQuotes(`WHERE engagement_id = ? ORDER BY created_at DESC`, id)
+	if err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (s *Service) query(where string, args ...interface{}) ([]models.Engagement, error) {
+	rows, err := s.db.Query(`
+		SELECT id, inquiry_id, name, email, project_type, status, created_at, updated_at
+		FROM engagements
+		`+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query engagements: %w", err)
+	}
+	defer rows.Close()
+
+	list := []models.Engagement{}
+	for rows.Next() {
+		var e models.Engagement
+		var inquiryID sql.NullString
+		if err := rows.Scan(&e.ID, &inquiryID, &e.Name, &e.Email, &e.ProjectType, &e.Status,
+			&e.CreatedAt, &e.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan engagement: %w", err)
+		}
+		if inquiryID.Valid {
+			e.InquiryID = &inquiryID.String
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+// SetStatus moves an engagement to a status
+// Returns sql.ErrNoRows if it does not exist, ErrInvalidStatus for an unknown status.
+func (s *Service) SetStatus(id, status string) (*models.Engagement, error) {
+	switch status {
+	case models.EngagementStatusOpen, models.EngagementStatusQuoted, models.EngagementStatusBooked,
+		models.EngagementStatusCompleted, models.EngagementStatusCancelled:
+	default:
+		return nil, ErrInvalidStatus
+	}
+
+	result, err := s.db.Exec(`UPDATE engagements SET status = ?, updated_at = ? WHERE id = ?`, status, time.Now(), id)
+	if err != nil {
+		return nil, fmt.Errorf("update engagement: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return s.Get(id)
+}
+
+// open reports whether an engagement can still be quoted and paid
+func open(status string) bool {
+	return status == models.EngagementStatusOpen || status == models.EngagementStatusQuoted
+}
@@ -0,0 +1,307 @@
+package engagements
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+	"github.com/nessieaudio/ecommerce-backend/internal/money"
+)
+
+const (
+	// DefaultDepositPercent of the total is due up front when a quote doesn't set a deposit
+	DefaultDepositPercent = 50
+	// DefaultValidity is how long a quote can be accepted when it doesn't set an expiry
+	DefaultValidity = 14 * 24 * time.Hour
+)
+
+var (
+	// ErrInvalidDeposit is returned for a deposit above the quote's total
+	ErrInvalidDeposit = errors.New("deposit must not be more than the total")
+	// ErrQuoteExpired is returned when sending or paying a quote past its expiry
+	ErrQuoteExpired = errors.New("quote has expired")
+	// ErrQuotePaid is returned when sending or paying a quote whose deposit is paid
+	ErrQuotePaid = errors.New("deposit has already been paid")
+)
+
+// QuoteStatus works out a quote's status from its timestamps
+func QuoteStatus(q *models.Quote, now time.Time) string {
+	switch {
+	case q.DepositPaidAt != nil:
+		return models.QuoteStatusPaid
+	case !now.Before(q.ExpiresAt):
+		return models.QuoteStatusExpired
+	case q.SentAt != nil:
+		return models.QuoteStatusSent
+	default:
+		return models.QuoteStatusDraft
+	}
+}
+
+// DepositFor returns percent of total, rounded to the cent
+func DepositFor(total float64, percent int) float64 {
+	return money.RoundCents(total * float64(percent) / 100)
+}
+
+// CreateQuote stores a draft quote for an engagement. The total is worked out
+// from the items; ID, token, status and timestamps are filled in.
+// Returns sql.ErrNoRows for an unknown engagement, ErrClosed or ErrInvalidDeposit.
+func (s *Service) CreateQuote(engagementID string, q *models.Quote) error {
+	var status string
+	err := s.db.QueryRow(`SELECT status FROM engagements WHERE id = ?`, engagementID).Scan(&status)
+	if err != nil {
+		return err
+	}
+	if !open(status) {
+		return ErrClosed
+	}
+
+	q.Total = 0
+	for _, item := range q.Items {
+		q.Total += float64(item.Quantity) * item.UnitPrice
+	}
+	q.Total = money.RoundCents(q.Total)
+	q.Deposit = money.RoundCents(q.Deposit)
+	if q.Deposit > q.Total {
+		return ErrInvalidDeposit
+	}
+
+	now := time.Now()
+	q.ID = uuid.New().String()
+	q.EngagementID = engagementID
+	q.Token = uuid.New().String()
+	q.CreatedAt = now
+	q.UpdatedAt = now
+	q.Status = QuoteStatus(q, now)
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO quotes (id, engagement_id, token, currency, total, deposit, note, expires_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, q.ID, q.EngagementID, q.Token, q.Currency, q.Total, q.Deposit, q.Note, q.ExpiresAt, now, now)
+	if err != nil {
+		return fmt.Errorf("insert quote: %w", err)
+	}
+	for i, item := range q.Items {
+		_, err := tx.Exec(`
+			INSERT INTO quote_items (id, quote_id, position, description, quantity, unit_price)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, uuid.New().String(), q.ID, i, item.Description, item.Quantity, item.UnitPrice)
+		if err != nil {
+			return fmt.Errorf("insert quote item: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetQuote returns a quote by ID with its items
+// Returns sql.ErrNoRows if it does not exist.
+func (s *Service) GetQuote(id string) (*models.Quote, error) {
+	return s.getQuote(`WHERE id = ?`, id)
+}
+
+// GetQuoteByToken returns the quote a client's link points to, with its items
+// Returns sql.ErrNoRows for an unknown token.
+func (s *Service) GetQuoteByToken(token string) (*models.Quote, error) {
+	return s.getQuote(`WHERE token = ?`, token)
+}
+
+func (s *Service) getQuote(where string, args ...interface{}) (*models.Quote, error) {
+	list, err := s.queryQuotes(where, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &list[0], nil
+}
+
+func (s *Service) queryQuotes(where string, args ...interface{}) ([]models.Quote, error) {
+	rows, err := s.db.Query(`
+		SELECT id, engagement_id, token, currency, total, deposit, note, expires_at, sent_at,
+			stripe_session_id, stripe_payment_intent_id, deposit_paid_at, created_at, updated_at
+		FROM quotes
+		`+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query quotes: %w", err)
+	}
+
+	now := time.Now()
+	list := []models.Quote{}
+	for rows.Next() {
+		var q models.Quote
+		var sentAt, paidAt sql.NullTime
+		var sessionID, paymentIntentID sql.NullString
+		if err := rows.Scan(&q.ID, &q.EngagementID, &q.Token, &q.Currency, &q.Total, &q.Deposit, &q.Note,
+			&q.ExpiresAt, &sentAt, &sessionID, &paymentIntentID, &paidAt, &q.CreatedAt, &q.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan quote: %w", err)
+		}
+		if sentAt.Valid {
+			q.SentAt = &sentAt.Time
+		}
+		if paidAt.Valid {
+			q.DepositPaidAt = &paidAt.Time
+		}
+		q.StripeSessionID = sessionID.String
+		q.StripePaymentIntentID = paymentIntentID.String
+		q.Status = QuoteStatus(&q, now)
+		list = append(list, q)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+
+	// Items are read once the quote rows are closed, so SQLite's single
+	// connection isn't held by two result sets
+	for i := range list {
+		if list[i].Items, err = s.quoteItems(list[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+func (s *Service) quoteItems(quoteID string) ([]models.QuoteItem, error) {
+	rows, err := s.db.Query(`
+		SELECT description, quantity, unit_price FROM quote_items WHERE quote_id = ? ORDER BY position
+	`, quoteID)
+	if err != nil {
+		return nil, fmt.Errorf("query quote items: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.QuoteItem{}
+	for rows.Next() {
+		var item models.QuoteItem
+		if err := rows.Scan(&item.Description, &item.Quantity, &item.UnitPrice); err != nil {
+			return nil, fmt.Errorf("scan quote item: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// Payable returns a quote and its engagement if the quote's deposit can be paid now
+// Returns sql.ErrNoRows for an unknown token, ErrQuotePaid, ErrQuoteExpired or ErrClosed.
+func (s *Service) Payable(token string) (*models.Quote, *models.Engagement, error) {
+	q, err := s.GetQuoteByToken(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := CanSend(q); err != nil {
+		return nil, nil, err
+	}
+
+	list, err := s.query(`WHERE id = ?`, q.EngagementID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(list) == 0 || !open(list[0].Status) {
+		return nil, nil, ErrClosed
+	}
+	return q, &list[0], nil
+}
+
+// CanSend returns ErrQuotePaid or ErrQuoteExpired for a quote that can no longer
+// be sent or paid
+func CanSend(q *models.Quote) error {
+	switch q.Status {
+	case models.QuoteStatusPaid:
+		return ErrQuotePaid
+	case models.QuoteStatusExpired:
+		return ErrQuoteExpired
+	}
+	return nil
+}
+
+// MarkSent records that a quote was emailed to the client, moving an open
+// engagement to quoted. Sending again updates sent_at.
+// Returns sql.ErrNoRows if it does not exist, ErrQuotePaid or ErrQuoteExpired.
+func (s *Service) MarkSent(id string) (*models.Quote, error) {
+	q, err := s.GetQuote(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := CanSend(q); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if _, err := s.db.Exec(`UPDATE quotes SET sent_at = ?, updated_at = ? WHERE id = ?`, now, now, id); err != nil {
+		return nil, fmt.Errorf("update quote: %w", err)
+	}
+	_, err = s.db.Exec(`UPDATE engagements SET status = ?, updated_at = ? WHERE id = ? AND status = ?`,
+		models.EngagementStatusQuoted, now, q.EngagementID, models.EngagementStatusOpen)
+	if err != nil {
+		return nil, fmt.Errorf("update engagement: %w", err)
+	}
+
+	return s.GetQuote(id)
+}
+
+// SetCheckoutSession records the Stripe session started for a quote's deposit
+func (s *Service) SetCheckoutSession(id, sessionID string) error {
+	_, err := s.db.Exec(`UPDATE quotes SET stripe_session_id = ?, updated_at = ? WHERE id = ?`, sessionID, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("update quote: %w", err)
+	}
+	return nil
+}
+
+// RecordDeposit marks a quote's deposit paid and books its engagement.
+// first is false if the deposit was already recorded, e.g. for a redelivered webhook.
+// Returns sql.ErrNoRows if the quote does not exist.
+func (s *Service) RecordDeposit(id, sessionID, paymentIntentID string) (q *models.Quote, first bool, err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, false, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var engagementID string
+	var paidAt sql.NullTime
+	err = tx.QueryRow(`SELECT engagement_id, deposit_paid_at FROM quotes WHERE id = ?`, id).Scan(&engagementID, &paidAt)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !paidAt.Valid {
+		now := time.Now()
+		_, err := tx.Exec(`
+			UPDATE quotes
+			SET deposit_paid_at = ?, stripe_session_id = ?, stripe_payment_intent_id = ?, updated_at = ?
+			WHERE id = ?
+		`, now, sessionID, paymentIntentID, now, id)
+		if err != nil {
+			return nil, false, fmt.Errorf("update quote: %w", err)
+		}
+		_, err = tx.Exec(`UPDATE engagements SET status = ?, updated_at = ? WHERE id = ?`,
+			models.EngagementStatusBooked, now, engagementID)
+		if err != nil {
+			return nil, false, fmt.Errorf("update engagement: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	q, err = s.GetQuote(id)
+	if err != nil {
+		return nil, false, err
+	}
+	return q, !paidAt.Valid, nil
+}
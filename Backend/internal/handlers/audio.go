@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/nessieaudio/ecommerce-backend/internal/audio"
+	apierrors "github.com/nessieaudio/ecommerce-backend/internal/errors"
+	"github.com/nessieaudio/ecommerce-backend/internal/middleware"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+// GetTracks lists the portfolio tracks
+// GET /api/v1/audio/tracks?genre=Metal&artist=Cosmic%20Lung
+//
+// Both filters are optional and case-insensitive.
+func (h *Handler) GetTracks(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	tracks, err := h.audio.Tracks(h.getAPIBaseURL())
+	if err != nil {
+		h.respondAudioError(w, err, requestID)
+		return
+	}
+
+	filtered := filterTracks(tracks, r.URL.Query().Get("genre"), r.URL.Query().Get("artist"))
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"tracks": filtered,
+		"count":  len(filtered),
+	})
+}
+
+// filterTracks keeps the tracks in a genre and by an artist, ignoring case; an empty
+// filter matches every track
+func filterTracks(tracks []models.Track, genre, artist string) []models.Track {
+	filtered := make([]models.Track, 0, len(tracks))
+	for _, t := range tracks {
+		if genre != "" && !strings.EqualFold(t.Genre, genre) {
+			continue
+		}
+		if artist != "" && !strings.EqualFold(t.Artist, artist) {
+			continue
+		}
+		filtered = append(filtered, t)
+	}
+	return filtered
+}
+
+// GetTrack returns one track
+// GET /api/v1/audio/tracks/{id}
+func (h *Handler) GetTrack(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	track, err := h.audio.Track(mux.Vars(r)["id"], h.getAPIBaseURL())
+	if err != nil {
+		h.respondAudioError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, track)
+}
+
+// StreamTrack streams a track, or only its preview clip if it is for sale
+// GET /api/v1/audio/tracks/{id}/stream
+//
+// Supports Range requests, so the player can seek.
+func (h *Handler) StreamTrack(w http.ResponseWriter, r *http.Request) {
+	h.streamTrack(w, r, false)
+}
+
+// StreamTrackPreview streams a track's preview clip, whether or not it is for sale
+// GET /api/v1/audio/tracks/{id}/preview
+func (h *Handler) StreamTrackPreview(w http.ResponseWriter, r *http.Request) {
+	h.streamTrack(w, r, true)
+}
+
+func (h *Handler) streamTrack(w http.ResponseWriter, r *http.Request, preview bool) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	stream, err := h.audio.Open(mux.Vars(r)["id"], preview)
+	if err != nil {
+		h.respondAudioError(w, err, requestID)
+		return
+	}
+	defer stream.Close()
+
+	w.Header().Set("Content-Type", stream.ContentType)
+	w.Header().Set("ETag", stream.ETag)
+	// Short-lived: putting a track on sale must cut it to the preview soon after
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	h.liftWriteDeadline(w, requestID)
+	http.ServeContent(w, r, "", stream.ModTime, stream)
+}
+
+// GetTrackArtwork serves a track's cover image
+// GET /api/v1/audio/tracks/{id}/artwork
+func (h *Handler) GetTrackArtwork(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	path, err := h.audio.Artwork(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondAudioError(w, err, requestID)
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		apierrors.RespondNotFound(w, "Artwork", requestID)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		h.logger.Error("Failed to stat artwork [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	h.liftWriteDeadline(w, requestID)
+	http.ServeContent(w, r, info.Name(), info.ModTime(), f)
+}
+
+// RecordTrackPlay counts a play of a track; the player calls it when playback starts
+// POST /api/v1/audio/tracks/{id}/plays
+func (h *Handler) RecordTrackPlay(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	if err := h.audio.RecordPlay(mux.Vars(r)["id"]); err != nil {
+		h.respondAudioError(w, err, requestID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetTrackPreview puts a track on sale, so only its preview clip is streamed
+// PUT /api/v1/admin/audio/tracks/{id}/preview
+//
+// Request: { "product_id": "...", "start_seconds": 45, "length_seconds": 30 }
+// product_id is optional; a missing or zero length_seconds means the default of 30 seconds.
+func (h *Handler) SetTrackPreview(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	id := mux.Vars(r)["id"]
+
+	var req models.TrackPreview
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+	req.ProductID = strings.TrimSpace(req.ProductID)
+
+	if err := h.audio.SetPreview(id, req); err != nil {
+		h.respondAudioError(w, err, requestID)
+		return
+	}
+
+	track, err := h.audio.Track(id, h.getAPIBaseURL())
+	if err != nil {
+		h.respondAudioError(w, err, requestID)
+		return
+	}
+	apierrors.RespondJSON(w, http.StatusOK, track)
+}
+
+// DeleteTrackPreview takes a track off sale, so it streams in full again
+// DELETE /api/v1/admin/audio/tracks/{id}/preview
+func (h *Handler) DeleteTrackPreview(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	if err := h.audio.RemovePreview(mux.Vars(r)["id"]); err != nil {
+		h.respondAudioError(w, err, requestID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetIngestedTracks lists the tracks read by the ingest, with waveforms and durations
+// from the files themselves
+// GET /api/v1/tracks?genre=Metal&artist=Cosmic%20Lung
+//
+// Both filters are optional and case-insensitive.
+func (h *Handler) GetIngestedTracks(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	tracks, err := h.audio.Catalogue(h.getAPIBaseURL())
+	if err != nil {
+		h.respondAudioError(w, err, requestID)
+		return
+	}
+
+	filtered := filterTracks(tracks, r.URL.Query().Get("genre"), r.URL.Query().Get("artist"))
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"tracks": filtered,
+		"count":  len(filtered),
+	})
+}
+
+// GetIngestedTrack returns one ingested track
+// GET /api/v1/tracks/{id}
+func (h *Handler) GetIngestedTrack(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	track, err := h.audio.CatalogueTrack(mux.Vars(r)["id"], h.getAPIBaseURL())
+	if err != nil {
+		h.respondAudioError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, track)
+}
+
+// GetIngestedTrackArtwork serves an ingested track's embedded picture or cover image
+// GET /api/v1/tracks/{id}/artwork
+func (h *Handler) GetIngestedTrackArtwork(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	artwork, err := h.audio.CatalogueArtwork(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondAudioError(w, err, requestID)
+		return
+	}
+
+	w.Header().Set("Content-Type", artwork.ContentType)
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	http.ServeContent(w, r, "", artwork.ModTime, artwork)
+}
+
+// IngestTracks reads the music directory into the tracks table now
+// POST /api/v1/admin/audio/ingest
+//
+// The server also ingests at startup and every 6 hours. Unchanged files are skipped.
+func (h *Handler) IngestTracks(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	report, err := h.audio.Ingest("admin")
+	if errors.Is(err, audio.ErrIngestInProgress) {
+		apierrors.RespondError(w, http.StatusConflict, "A track ingest is already running", apierrors.ErrCodeConflict, nil, requestID)
+		return
+	}
+	if err != nil {
+		h.respondAudioError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, report)
+}
+
+// StartTrackIngest ingests the music directory now and then every interval
+func (h *Handler) StartTrackIngest(interval time.Duration) {
+	h.audio.StartScheduledIngest(interval)
+}
+
+// respondAudioError maps audio library errors to API responses
+func (h *Handler) respondAudioError(w http.ResponseWriter, err error, requestID string) {
+	switch {
+	case errors.Is(err, audio.ErrTrackNotFound):
+		apierrors.RespondNotFound(w, "Track", requestID)
+	case errors.Is(err, audio.ErrNoArtwork):
+		apierrors.RespondNotFound(w, "Artwork", requestID)
+	case errors.Is(err, audio.ErrInvalidPreview):
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "start_seconds", Message: err.Error()}}, requestID)
+	case errors.Is(err, audio.ErrProductNotFound):
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "product_id", Message: err.Error()}}, requestID)
+	default:
+		h.logger.Error("Audio operation failed [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/nessieaudio/ecommerce-backend/internal/catalog"
+	apierrors "github.com/nessieaudio/ecommerce-backend/internal/errors"
+	"github.com/nessieaudio/ecommerce-backend/internal/middleware"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+// GetAdminBundles lists every bundle with its components
+// GET /api/v1/admin/bundles
+func (h *Handler) GetAdminBundles(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	bundles, err := catalogService.ListBundles()
+	if err != nil {
+		h.logger.Error("Failed to fetch bundles [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"bundles": bundles,
+		"count":   len(bundles),
+	})
+}
+
+// GetAdminBundle returns one bundle by product ID
+// GET /api/v1/admin/bundles/{id}
+func (h *Handler) GetAdminBundle(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	bundle, err := catalogService.GetBundle(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondBundleError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, bundle)
+}
+
+// BundleRequest is the editable part of a bundle
+type BundleRequest struct {
+	Name        string                   `json:"name"`
+	Description string                   `json:"description"`
+	Price       float64                  `json:"price"` // What the whole bundle sells for
+	ImageURL    string                   `json:"image_url"`
+	Category    string                   `json:"category"` // Category slug, optional
+	Active      *bool                    `json:"active"`   // Defaults to true
+	Components  []BundleComponentRequest `json:"components"`
+}
+
+// BundleComponentRequest is one variant in a bundle
+type BundleComponentRequest struct {
+	VariantID string `json:"variant_id"`
+	Quantity  int    `json:"quantity"` // Defaults to 1
+}
+
+// CreateBundle adds a bundle product
+// POST /api/v1/admin/bundles
+//
+// Request: { "name": "Tour Bundle", "price": 45, "components": [{ "variant_id": "...", "quantity": 1 }, ...] }
+func (h *Handler) CreateBundle(w http.ResponseWriter, r *http.Request) {
+	h.saveBundle(w, r, "")
+}
+
+// UpdateBundle replaces a bundle's details and components
+// PUT /api/v1/admin/bundles/{id}
+//
+// Set "active": false to take a bundle off sale; past orders keep their components.
+func (h *Handler) UpdateBundle(w http.ResponseWriter, r *http.Request) {
+	h.saveBundle(w, r, mux.Vars(r)["id"])
+}
+
+func (h *Handler) saveBundle(w http.ResponseWriter, r *http.Request, productID string) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	var req BundleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+
+	var validationErrors []apierrors.ValidationError
+	if req.Name == "" {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "name", Message: "is required"})
+	}
+	if req.Price <= 0 {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "price", Message: "must be greater than 0"})
+	}
+	if len(req.Components) == 0 {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "components", Message: "is required"})
+	}
+
+	components := make([]models.BundleComponent, 0, len(req.Components))
+	for _, c := range req.Components {
+		if c.Quantity == 0 {
+			c.Quantity = 1
+		}
+		if c.VariantID == "" || c.Quantity < 1 {
+			validationErrors = append(validationErrors, apierrors.ValidationError{Field: "components", Message: "each component needs a variant_id and a positive quantity"})
+			break
+		}
+		components = append(components, models.BundleComponent{VariantID: c.VariantID, Quantity: c.Quantity})
+	}
+	if len(validationErrors) > 0 {
+		apierrors.RespondValidationError(w, validationErrors, requestID)
+		return
+	}
+
+	bundle := models.Bundle{
+		ProductID:   productID,
+		Name:        req.Name,
+		Description: req.Description,
+		Price:       req.Price,
+		ImageURL:    req.ImageURL,
+		Category:    req.Category,
+		Active:      req.Active == nil || *req.Active,
+		Components:  components,
+	}
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	if err := catalogService.SaveBundle(&bundle); err != nil {
+		h.respondBundleError(w, err, requestID)
+		return
+	}
+
+	status := http.StatusOK
+	if productID == "" {
+		status = http.StatusCreated
+	}
+	apierrors.RespondJSON(w, status, bundle)
+}
+
+// respondBundleError maps bundle service errors to API responses
+func (h *Handler) respondBundleError(w http.ResponseWriter, err error, requestID string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		apierrors.RespondNotFound(w, "Bundle", requestID)
+	case errors.Is(err, catalog.ErrEmptyBundle), errors.Is(err, catalog.ErrInvalidComponent):
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "components", Message: err.Error()}}, requestID)
+	case errors.Is(err, catalog.ErrCategoryNotFound):
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "category", Message: err.Error()}}, requestID)
+	default:
+		h.logger.Error("Bundle operation failed [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+	}
+}
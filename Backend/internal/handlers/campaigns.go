@@ -0,0 +1,604 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	apierrors "github.com/nessieaudio/ecommerce-backend/internal/errors"
+	"github.com/nessieaudio/ecommerce-backend/internal/middleware"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+	"github.com/nessieaudio/ecommerce-backend/internal/newsletter"
+	"github.com/nessieaudio/ecommerce-backend/internal/services/email"
+)
+
+const (
+	maxCampaignBlocks   = 50
+	maxInfoRows         = 20
+	defaultCampaignIcon = "&#127926;" // Musical notes
+)
+
+// trackingPixel is a transparent 1x1 GIF
+var trackingPixel = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// CampaignRequest is a campaign's content and segment
+type CampaignRequest struct {
+	Subject string                 `json:"subject"`
+	Title   string                 `json:"title"` // Heading of the email
+	Icon    string                 `json:"icon"`  // Emoji or HTML entity above the heading
+	Blocks  []models.CampaignBlock `json:"blocks"`
+
+	// Segment of subscribed members; empty fields match everyone
+	Genre     string `json:"genre"`
+	City      string `json:"city"`
+	Purchaser *bool  `json:"purchaser"`
+}
+
+// GetAdminCampaigns lists campaigns with their delivery stats, newest first
+// GET /api/v1/admin/campaigns?status=sent
+//
+// status is optional: draft, scheduled, sending, sent or cancelled.
+func (h *Handler) GetAdminCampaigns(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	status := r.URL.Query().Get("status")
+	if status != "" && !newsletter.ValidStatus(status) {
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "status", Message: "must be draft, scheduled, sending, sent or cancelled"}}, requestID)
+		return
+	}
+
+	list, err := newsletter.NewService(h.db).ListCampaigns(status)
+	if err != nil {
+		h.logger.Error("Failed to fetch campaigns [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"campaigns": list,
+		"count":     len(list),
+	})
+}
+
+// GetAdminCampaign returns a campaign with its stats and the clicks on each link
+// GET /api/v1/admin/campaigns/{id}
+func (h *Handler) GetAdminCampaign(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	campaign, err := newsletter.NewService(h.db).GetCampaign(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondCampaignError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, campaign)
+}
+
+// CreateCampaign stores a draft campaign
+// POST /api/v1/admin/campaigns
+//
+// Request: { "subject": "New EP out now", "title": "Out Now", "blocks": [{ "type": "text", "text": "..." },
+// { "type": "button", "text": "Listen", "url": "https://..." }], "genre": "Metal" }
+func (h *Handler) CreateCampaign(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	var req CampaignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+
+	campaign, validationErrors := campaignFromRequest(&req)
+	if len(validationErrors) > 0 {
+		apierrors.RespondValidationError(w, validationErrors, requestID)
+		return
+	}
+
+	service := newsletter.NewService(h.db)
+	if err := service.CreateCampaign(campaign); err != nil {
+		h.respondCampaignError(w, err, requestID)
+		return
+	}
+	created, err := service.GetCampaign(campaign.ID)
+	if err != nil {
+		h.respondCampaignError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusCreated, created)
+}
+
+// UpdateCampaign replaces a draft campaign's content and segment
+// PUT /api/v1/admin/campaigns/{id}
+//
+// Request: the same as CreateCampaign. Scheduled campaigns must be cancelled first.
+func (h *Handler) UpdateCampaign(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	var req CampaignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+
+	campaign, validationErrors := campaignFromRequest(&req)
+	if len(validationErrors) > 0 {
+		apierrors.RespondValidationError(w, validationErrors, requestID)
+		return
+	}
+	campaign.ID = mux.Vars(r)["id"]
+
+	service := newsletter.NewService(h.db)
+	if err := service.UpdateCampaign(campaign); err != nil {
+		h.respondCampaignError(w, err, requestID)
+		return
+	}
+	updated, err := service.GetCampaign(campaign.ID)
+	if err != nil {
+		h.respondCampaignError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, updated)
+}
+
+// DeleteCampaign removes a draft campaign
+// DELETE /api/v1/admin/campaigns/{id}
+func (h *Handler) DeleteCampaign(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	campaignID := mux.Vars(r)["id"]
+
+	if err := newsletter.NewService(h.db).DeleteCampaign(campaignID); err != nil {
+		h.respondCampaignError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Campaign deleted",
+		"id":      campaignID,
+	})
+}
+
+// campaignFromRequest validates a campaign request, trimming its text
+func campaignFromRequest(req *CampaignRequest) (*models.Campaign, []apierrors.ValidationError) {
+	var validationErrors []apierrors.ValidationError
+	invalid := func(field, message string) {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: field, Message: message})
+	}
+	within := func(value string, min, max int) bool {
+		n := utf8.RuneCountInString(value)
+		return n >= min && n <= max
+	}
+
+	c := &models.Campaign{
+		Subject:   strings.TrimSpace(req.Subject),
+		Title:     strings.TrimSpace(req.Title),
+		Icon:      strings.TrimSpace(req.Icon),
+		Blocks:    []models.CampaignBlock{},
+		Genre:     strings.TrimSpace(req.Genre),
+		City:      strings.TrimSpace(req.City),
+		Purchaser: req.Purchaser,
+	}
+	if c.Icon == "" {
+		c.Icon = defaultCampaignIcon
+	}
+
+	if !within(c.Subject, 1, 200) {
+		invalid("subject", "must be between 1 and 200 characters")
+	}
+	if !within(c.Title, 1, 100) {
+		invalid("title", "must be between 1 and 100 characters")
+	}
+	if !within(c.Icon, 1, 32) {
+		invalid("icon", "must be at most 32 characters")
+	}
+	if !within(c.Genre, 0, 50) {
+		invalid("genre", "must be at most 50 characters")
+	}
+	if !within(c.City, 0, 100) {
+		invalid("city", "must be at most 100 characters")
+	}
+	if len(req.Blocks) == 0 || len(req.Blocks) > maxCampaignBlocks {
+		invalid("blocks", fmt.Sprintf("must have between 1 and %d blocks", maxCampaignBlocks))
+		return c, validationErrors
+	}
+
+	for i, in := range req.Blocks {
+		field := fmt.Sprintf("blocks[%d]", i)
+		block := models.CampaignBlock{Type: in.Type}
+		switch in.Type {
+		case models.CampaignBlockText:
+			block.Text = strings.TrimSpace(in.Text)
+			if !within(block.Text, 1, 10000) {
+				invalid(field+".text", "must be between 1 and 10000 characters")
+			}
+		case models.CampaignBlockNote:
+			block.Text = strings.TrimSpace(in.Text)
+			if !within(block.Text, 1, 2000) {
+				invalid(field+".text", "must be between 1 and 2000 characters")
+			}
+		case models.CampaignBlockInfo:
+			block.Title = strings.TrimSpace(in.Title)
+			if !within(block.Title, 1, 100) {
+				invalid(field+".title", "must be between 1 and 100 characters")
+			}
+			if len(in.Rows) == 0 || len(in.Rows) > maxInfoRows {
+				invalid(field+".rows", fmt.Sprintf("must have between 1 and %d rows", maxInfoRows))
+			}
+			for _, row := range in.Rows {
+				row.Label = strings.TrimSpace(row.Label)
+				row.Value = strings.TrimSpace(row.Value)
+				if !within(row.Label, 1, 200) || !within(row.Value, 0, 200) {
+					invalid(field+".rows", "labels must be between 1 and 200 characters, values at most 200")
+					break
+				}
+				block.Rows = append(block.Rows, row)
+			}
+		case models.CampaignBlockButton:
+			block.Text = strings.TrimSpace(in.Text)
+			block.URL = strings.TrimSpace(in.URL)
+			if !within(block.Text, 1, 100) {
+				invalid(field+".text", "must be between 1 and 100 characters")
+			}
+			if u, err := url.Parse(block.URL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || len(block.URL) > 2000 {
+				invalid(field+".url", "must be an http or https URL")
+			}
+		default:
+			invalid(field+".type", "must be text, info, note or button")
+		}
+		c.Blocks = append(c.Blocks, block)
+	}
+
+	return c, validationErrors
+}
+
+// PreviewCampaign renders a campaign as subscribers will see it
+// GET /api/v1/admin/campaigns/{id}/preview
+//
+// Links go straight to their targets and nothing is tracked.
+func (h *Handler) PreviewCampaign(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	campaign, err := newsletter.NewService(h.db).GetCampaign(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondCampaignError(w, err, requestID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte(h.renderCampaign(campaign, "", "")))
+}
+
+// TestCampaignRequest names who gets a test send
+type TestCampaignRequest struct {
+	Email string `json:"email"` // Defaults to ADMIN_EMAIL
+}
+
+// SendTestCampaign emails a campaign to one address, untracked, with "[Test]"
+// before its subject
+// POST /api/v1/admin/campaigns/{id}/test
+//
+// Request: { "email": "me@example.com" }
+func (h *Handler) SendTestCampaign(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	var req TestCampaignRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+			return
+		}
+	}
+	to := strings.TrimSpace(req.Email)
+	if to == "" {
+		to = h.config.AdminEmail
+	}
+	if len(to) > 254 || !contactEmailPattern.MatchString(to) {
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "email", Message: "must be a valid email address (ADMIN_EMAIL is used when empty)"}}, requestID)
+		return
+	}
+
+	campaign, err := newsletter.NewService(h.db).GetCampaign(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondCampaignError(w, err, requestID)
+		return
+	}
+
+	// Sent right away rather than queued, so a delivery problem shows up here
+	if err := h.emailClient.SendNow(to, "[Test] "+campaign.Subject, h.renderCampaign(campaign, "", ""), nil); err != nil {
+		h.logger.Error("Failed to send test campaign [request_id: "+requestID+"]", err)
+		apierrors.RespondError(w, http.StatusBadGateway, "Failed to send the test email", apierrors.ErrCodeExternalAPIError, nil, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Test email sent",
+		"email":   to,
+	})
+}
+
+// ScheduleCampaignRequest sets when a campaign goes out
+type ScheduleCampaignRequest struct {
+	SendAt *time.Time `json:"send_at"` // Defaults to now
+}
+
+// ScheduleCampaign queues a draft campaign, or moves a scheduled one
+// POST /api/v1/admin/campaigns/{id}/schedule
+//
+// Request: { "send_at": "2026-11-01T17:00:00Z" }
+func (h *Handler) ScheduleCampaign(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	var req ScheduleCampaignRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+			return
+		}
+	}
+	sendAt := time.Now()
+	if req.SendAt != nil && req.SendAt.After(sendAt) {
+		sendAt = *req.SendAt
+	}
+
+	service := newsletter.NewService(h.db)
+	campaignID := mux.Vars(r)["id"]
+	if err := service.Schedule(campaignID, sendAt); err != nil {
+		h.respondCampaignError(w, err, requestID)
+		return
+	}
+	campaign, err := service.GetCampaign(campaignID)
+	if err != nil {
+		h.respondCampaignError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, campaign)
+}
+
+// CancelCampaign stops a campaign. A scheduled campaign goes back to draft; one
+// that is sending stops and its remaining recipients are skipped.
+// POST /api/v1/admin/campaigns/{id}/cancel
+func (h *Handler) CancelCampaign(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	service := newsletter.NewService(h.db)
+	campaignID := mux.Vars(r)["id"]
+	if err := service.Cancel(campaignID); err != nil {
+		h.respondCampaignError(w, err, requestID)
+		return
+	}
+	campaign, err := service.GetCampaign(campaignID)
+	if err != nil {
+		h.respondCampaignError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, campaign)
+}
+
+// GetCampaignRecipients lists a campaign's recipients with their delivery status,
+// opens and clicks
+// GET /api/v1/admin/campaigns/{id}/recipients?status=failed
+func (h *Handler) GetCampaignRecipients(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	status := r.URL.Query().Get("status")
+	if status != "" && !newsletter.ValidRecipientStatus(status) {
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "status", Message: "must be pending, sent, failed or skipped"}}, requestID)
+		return
+	}
+
+	service := newsletter.NewService(h.db)
+	campaignID := mux.Vars(r)["id"]
+	if _, err := service.GetCampaign(campaignID); err != nil {
+		h.respondCampaignError(w, err, requestID)
+		return
+	}
+	list, err := service.Recipients(campaignID, status)
+	if err != nil {
+		h.respondCampaignError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"recipients": list,
+		"count":      len(list),
+	})
+}
+
+// respondCampaignError maps newsletter campaign errors to API responses
+func (h *Handler) respondCampaignError(w http.ResponseWriter, err error, requestID string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		apierrors.RespondNotFound(w, "Campaign", requestID)
+	case errors.Is(err, newsletter.ErrNotDraft), errors.Is(err, newsletter.ErrAlreadySent):
+		apierrors.RespondError(w, http.StatusConflict, err.Error(), apierrors.ErrCodeConflict, nil, requestID)
+	default:
+		h.logger.Error("Campaign request failed [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+	}
+}
+
+// TrackCampaignOpen records an open from the pixel in a campaign email
+// GET /api/v1/email/open/{token}.gif
+func (h *Handler) TrackCampaignOpen(w http.ResponseWriter, r *http.Request) {
+	if err := newsletter.NewService(h.db).RecordOpen(mux.Vars(r)["token"]); err != nil {
+		log.Printf("Failed to record campaign open: %v", err)
+	}
+
+	w.Header().Set("Content-Type", "image/gif")
+	w.Header().Set("Cache-Control", "no-store, max-age=0")
+	w.Write(trackingPixel)
+}
+
+// TrackCampaignClick records a click on a campaign link and redirects to it
+// GET /api/v1/email/click/{token}/{link}
+//
+// Targets come from the campaign, never the URL, so this can't be used as an open redirect.
+func (h *Handler) TrackCampaignClick(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	vars := mux.Vars(r)
+
+	target, err := newsletter.NewService(h.db).RecordClick(vars["token"], vars["link"])
+	if errors.Is(err, sql.ErrNoRows) {
+		apierrors.RespondNotFound(w, "Link", requestID)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to record campaign click [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// renderCampaign builds a campaign's HTML with the email layout. For a recipient,
+// links go through the click redirect and the open pixel is added; with no
+// recipient token (previews and test sends) links are left as they are.
+func (h *Handler) renderCampaign(c *models.Campaign, recipientToken, unsubscribeToken string) string {
+	apiBase := h.getAPIBaseURL() + "/api/v1/email"
+	linkIDs := make(map[int]string, len(c.Links))
+	for _, link := range c.Links {
+		linkIDs[link.Position] = link.ID
+	}
+	paragraphs := func(text string) string {
+		return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>")
+	}
+
+	var content strings.Builder
+	for i, block := range c.Blocks {
+		switch block.Type {
+		case models.CampaignBlockText:
+			for _, p := range strings.Split(strings.ReplaceAll(block.Text, "\r\n", "\n"), "\n\n") {
+				if p = strings.TrimSpace(p); p != "" {
+					content.WriteString(`<p style="font-size:16px;">` + paragraphs(p) + `</p>`)
+				}
+			}
+		case models.CampaignBlockNote:
+			content.WriteString(email.NoteBox(paragraphs(block.Text), false))
+		case models.CampaignBlockInfo:
+			var rows strings.Builder
+			for _, row := range block.Rows {
+				rows.WriteString(email.DetailRow(html.EscapeString(row.Label), html.EscapeString(row.Value)))
+			}
+			content.WriteString(email.InfoBox(html.EscapeString(block.Title), rows.String()))
+		case models.CampaignBlockButton:
+			href := block.URL
+			if linkID, ok := linkIDs[i]; ok && recipientToken != "" {
+				href = apiBase + "/click/" + url.PathEscape(recipientToken) + "/" + url.PathEscape(linkID)
+			}
+			content.WriteString(email.CTAButton(html.EscapeString(block.Text), html.EscapeString(href)))
+		}
+	}
+
+	unsubscribeURL := h.getBaseURL() + "/subscribe"
+	if unsubscribeToken != "" {
+		unsubscribeURL = h.subscribePageURL("unsubscribe", unsubscribeToken)
+	}
+	content.WriteString(email.NoteBox(fmt.Sprintf(`You're getting this because you're on the %s mailing list. <a href="%s">Unsubscribe</a> at any time.`,
+		brandName, html.EscapeString(unsubscribeURL)), false))
+	if recipientToken != "" {
+		content.WriteString(fmt.Sprintf(`<img src="%s/open/%s.gif" width="1" height="1" alt="" style="display:block;width:1px;height:1px;border:0;">`,
+			apiBase, url.PathEscape(recipientToken)))
+	}
+
+	return email.EmailLayout(html.EscapeString(c.Title), c.Icon, content.String(), false)
+}
+
+// StartCampaignSender sends due campaign emails one at a time, at most
+// perMinute a minute, to stay under the SMTP provider's limits
+func (h *Handler) StartCampaignSender(perMinute int) {
+	go func() {
+		ticker := time.NewTicker(time.Minute / time.Duration(perMinute))
+		defer ticker.Stop()
+
+		for range ticker.C {
+			h.SendNextCampaignEmail()
+		}
+	}()
+
+	log.Printf("Campaign sender started (%d emails per minute)", perMinute)
+}
+
+// SendNextCampaignEmail sends the campaign email that is due next. With none due,
+// it starts any scheduled campaigns whose time has come instead.
+func (h *Handler) SendNextCampaignEmail() {
+	service := newsletter.NewService(h.db)
+	now := time.Now()
+
+	delivery, err := service.NextDelivery(now)
+	// Recipients who unsubscribed since the campaign started are skipped without
+	// using up a send
+	for err == nil && !delivery.Subscribed {
+		if err := service.RecordSkipped(delivery.Recipient.ID); err != nil {
+			log.Printf("Failed to skip campaign recipient %s: %v", delivery.Recipient.ID, err)
+			return
+		}
+		h.finishCampaignIfDone(service, delivery.Recipient.CampaignID)
+		delivery, err = service.NextDelivery(now)
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		if started, err := service.StartDue(now); err != nil {
+			log.Printf("Failed to start scheduled campaigns: %v", err)
+		} else if started > 0 {
+			log.Printf("Started sending %d scheduled campaign(s)", started)
+		}
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to find the next campaign email: %v", err)
+		return
+	}
+
+	recipient := delivery.Recipient
+	campaign, err := service.GetCampaign(recipient.CampaignID)
+	if err != nil {
+		log.Printf("Failed to load campaign %s: %v", recipient.CampaignID, err)
+		return
+	}
+
+	htmlBody := h.renderCampaign(campaign, recipient.Token, delivery.UnsubscribeToken)
+	unsubscribeURL := h.getAPIBaseURL() + "/api/v1/unsubscribe/" + url.PathEscape(delivery.UnsubscribeToken)
+	// Campaigns keep their own per-recipient retries and throttle, so they
+	// bypass the email queue
+	sendErr := h.emailClient.SendNow(recipient.Email, campaign.Subject, htmlBody, email.ListHeaders(unsubscribeURL))
+	if sendErr != nil {
+		retry, err := service.RecordFailure(delivery, sendErr, email.IsTransient(sendErr))
+		if err != nil {
+			log.Printf("Failed to record campaign failure for %s: %v", recipient.Email, err)
+		} else if retry {
+			log.Printf("Campaign %s to %s failed, will retry: %v", campaign.ID, recipient.Email, sendErr)
+		} else {
+			log.Printf("Campaign %s to %s failed: %v", campaign.ID, recipient.Email, sendErr)
+		}
+	} else if err := service.RecordSent(recipient.ID); err != nil {
+		log.Printf("Failed to record campaign email to %s as sent: %v", recipient.Email, err)
+	}
+
+	h.finishCampaignIfDone(service, campaign.ID)
+}
+
+func (h *Handler) finishCampaignIfDone(service *newsletter.Service, campaignID string) {
+	if done, err := service.FinishIfDone(campaignID); err != nil {
+		log.Printf("Failed to finish campaign %s: %v", campaignID, err)
+	} else if done {
+		log.Printf("Campaign %s sent", campaignID)
+	}
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/nessieaudio/ecommerce-backend/internal/catalog"
+	apierrors "github.com/nessieaudio/ecommerce-backend/internal/errors"
+	"github.com/nessieaudio/ecommerce-backend/internal/middleware"
+)
+
+// SyncCatalog pulls the Printful store into the local catalog and returns the diff report
+// POST /api/v1/admin/catalog/sync
+func (h *Handler) SyncCatalog(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	report, err := catalogService.Sync("admin")
+	if errors.Is(err, catalog.ErrSyncInProgress) {
+		apierrors.RespondError(w, http.StatusConflict, "A catalog sync is already running", apierrors.ErrCodeConflict, nil, requestID)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Catalog sync failed [request_id: "+requestID+"]", err)
+		apierrors.RespondError(w, http.StatusBadGateway, "Catalog sync failed", apierrors.ErrCodeExternalAPIError, report, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, report)
+}
+
+// GetLastCatalogSync returns the report of the most recent catalog sync
+// GET /api/v1/admin/catalog/sync
+func (h *Handler) GetLastCatalogSync(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	report, err := catalogService.LastReport()
+	if errors.Is(err, sql.ErrNoRows) {
+		apierrors.RespondNotFound(w, "Catalog sync run", requestID)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch last catalog sync [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, report)
+}
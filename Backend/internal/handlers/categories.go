@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/nessieaudio/ecommerce-backend/internal/catalog"
+	apierrors "github.com/nessieaudio/ecommerce-backend/internal/errors"
+	"github.com/nessieaudio/ecommerce-backend/internal/middleware"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+// GetCategories returns the category tree for navigation
+// GET /api/v1/categories
+func (h *Handler) GetCategories(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	tree, err := catalogService.CategoryTree()
+	if err != nil {
+		h.logger.Error("Failed to fetch categories [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"categories": tree,
+	})
+}
+
+// GetAdminCategories lists every category flat, with parent IDs
+// GET /api/v1/admin/categories
+func (h *Handler) GetAdminCategories(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	categories, err := catalogService.ListCategories()
+	if err != nil {
+		h.logger.Error("Failed to fetch categories [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"categories": categories,
+		"count":      len(categories),
+	})
+}
+
+// CreateCategory adds a category
+// POST /api/v1/admin/categories
+//
+// Request: { "name": "Apparel", "slug": "apparel", "parent_id": null, "description": "", "sort_order": 0 }
+func (h *Handler) CreateCategory(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	var category models.Category
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+	category.Name = strings.TrimSpace(category.Name)
+
+	var validationErrors []apierrors.ValidationError
+	if category.Name == "" {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "name", Message: "is required"})
+	}
+	if category.Slug != "" && !catalog.ValidSlug(category.Slug) {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "slug", Message: "must be lowercase letters, digits and dashes"})
+	}
+	if len(validationErrors) > 0 {
+		apierrors.RespondValidationError(w, validationErrors, requestID)
+		return
+	}
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	if err := catalogService.CreateCategory(&category); err != nil {
+		h.respondCategoryError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusCreated, category)
+}
+
+// UpdateCategory edits a category's name, slug, parent or position
+// PUT /api/v1/admin/categories/{id}
+//
+// Request: { "name": "Tees", "parent_id": "<apparel id>" } - omitted fields are unchanged, "" parent_id moves to top level
+func (h *Handler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	categoryID := mux.Vars(r)["id"]
+
+	var update catalog.CategoryUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+
+	var validationErrors []apierrors.ValidationError
+	if update.Name != nil && strings.TrimSpace(*update.Name) == "" {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "name", Message: "must not be empty"})
+	}
+	if update.Slug != nil && !catalog.ValidSlug(*update.Slug) {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "slug", Message: "must be lowercase letters, digits and dashes"})
+	}
+	if len(validationErrors) > 0 {
+		apierrors.RespondValidationError(w, validationErrors, requestID)
+		return
+	}
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	category, err := catalogService.UpdateCategory(categoryID, update)
+	if err != nil {
+		h.respondCategoryError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, category)
+}
+
+// DeleteCategory removes a category that has no products or subcategories
+// DELETE /api/v1/admin/categories/{id}
+func (h *Handler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	categoryID := mux.Vars(r)["id"]
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	if err := catalogService.DeleteCategory(categoryID); err != nil {
+		h.respondCategoryError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Category deleted",
+		"id":      categoryID,
+	})
+}
+
+// UpdateProductCategoryRequest moves a product into a category
+type UpdateProductCategoryRequest struct {
+	Category string `json:"category"` // Category slug
+}
+
+// UpdateProductCategory sets a product's category and reprices it under that category's rules
+// PUT /api/v1/admin/products/{id}/category
+//
+// Request: { "category": "apparel" }
+func (h *Handler) UpdateProductCategory(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	productID := mux.Vars(r)["id"]
+
+	var req UpdateProductCategoryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	err := catalogService.SetProductCategory(productID, req.Category)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierrors.RespondNotFound(w, "Product", requestID)
+		return
+	}
+	if err != nil {
+		h.respondCategoryError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]string{
+		"message":    "Product category updated",
+		"product_id": productID,
+		"category":   req.Category,
+	})
+}
+
+// respondCategoryError maps category service errors to API responses
+func (h *Handler) respondCategoryError(w http.ResponseWriter, err error, requestID string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		apierrors.RespondNotFound(w, "Category", requestID)
+	case errors.Is(err, catalog.ErrSlugTaken):
+		apierrors.RespondError(w, http.StatusConflict, err.Error(), apierrors.ErrCodeConflict, nil, requestID)
+	case errors.Is(err, catalog.ErrCategoryInUse):
+		apierrors.RespondError(w, http.StatusConflict, err.Error(), apierrors.ErrCodeConflict, nil, requestID)
+	case errors.Is(err, catalog.ErrParentNotFound):
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "parent_id", Message: err.Error()}}, requestID)
+	case errors.Is(err, catalog.ErrCategoryCycle):
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "parent_id", Message: err.Error()}}, requestID)
+	case errors.Is(err, catalog.ErrCategoryNotFound):
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "category", Message: err.Error()}}, requestID)
+	default:
+		h.logger.Error("Category operation failed [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+	}
+}
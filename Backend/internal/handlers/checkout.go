@@ -1,11 +1,19 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"time"
 
+	"github.com/nessieaudio/ecommerce-backend/internal/inventory"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
 	"github.com/nessieaudio/ecommerce-backend/internal/services/stripe"
+	"github.com/nessieaudio/ecommerce-backend/internal/tour"
+	"github.com/nessieaudio/ecommerce-backend/internal/wishlist"
 )
 
 // CreateCheckoutRequest represents checkout initiation request
@@ -20,8 +28,9 @@ type CreateCheckoutResponse struct {
 
 // CartCheckoutRequest represents a cart-based checkout request
 type CartCheckoutRequest struct {
-	Items []CartCheckoutItem `json:"items"`
-	Email string             `json:"email"`
+	Items         []CartCheckoutItem `json:"items"`
+	Email         string             `json:"email"`
+	WishlistToken string             `json:"wishlist_token"` // Optional; an anonymous wishlist is attached to Email
 }
 
 // CartCheckoutItem represents a single item in the cart
@@ -69,10 +78,19 @@ func (h *Handler) CreateCheckout(w http.ResponseWriter, r *http.Request) {
 
 	// Build line items for Stripe
 	var lineItems []stripe.CheckoutLineItem
+	digitalOnly := true
 	for _, item := range items {
+		if item.FulfillmentChannel != models.FulfillmentChannelDigital {
+			digitalOnly = false
+		}
+		variantName := item.VariantName
+		if item.PreorderShipDate != nil {
+			variantName = preorderLabel(variantName, *item.PreorderShipDate)
+		}
+
 		lineItems = append(lineItems, stripe.CheckoutLineItem{
 			ProductName: item.ProductName,
-			VariantName: item.VariantName,
+			VariantName: variantName,
 			Quantity:    int64(item.Quantity),
 			UnitPrice:   int64(item.UnitPrice * 100), // Convert to cents
 		})
@@ -80,9 +98,11 @@ func (h *Handler) CreateCheckout(w http.ResponseWriter, r *http.Request) {
 
 	// Create Stripe checkout session
 	sessionID, err := h.stripeClient.CreateCheckoutSession(&stripe.CheckoutSessionRequest{
-		OrderID:       order.ID,
-		CustomerEmail: customerEmail,
-		LineItems:     lineItems,
+		OrderID:           order.ID,
+		CustomerEmail:     customerEmail,
+		LineItems:         lineItems,
+		DigitalOnly:       digitalOnly,
+		PromotionsConsent: true, // "Subscribe" checkbox for the mailing list
 	})
 
 	if err != nil {
@@ -122,8 +142,17 @@ func (h *Handler) CreateCartCheckout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	inventoryService := inventory.NewService(h.db)
+	drops, err := tour.NewService(h.db).Availability(time.Now())
+	if err != nil {
+		log.Printf("Failed to load merch drops: %v", err)
+		respondError(w, http.StatusInternalServerError, "Failed to check availability")
+		return
+	}
+
 	// Build line items by querying database for each cart item
 	var lineItems []stripe.CheckoutLineItem
+	digitalOnly := true
 	for _, cartItem := range req.Items {
 		// Validate quantity
 		if cartItem.Quantity < 1 {
@@ -136,13 +165,16 @@ func (h *Handler) CreateCartCheckout(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Get product name
-		var productName string
-		err := h.db.QueryRow("SELECT name FROM products WHERE id = ?", cartItem.ProductID).Scan(&productName)
+		var productName, productType string
+		err := h.db.QueryRow("SELECT name, product_type FROM products WHERE id = ?", cartItem.ProductID).Scan(&productName, &productType)
 		if err != nil {
 			log.Printf("Failed to get product %s: %v", cartItem.ProductID, err)
 			respondError(w, http.StatusBadRequest, "Invalid product")
 			return
 		}
+		if productType != models.ProductTypeDigital {
+			digitalOnly = false
+		}
 
 		// Get variant name and price (only available variants)
 		var variantName string
@@ -155,6 +187,27 @@ func (h *Handler) CreateCartCheckout(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// Tour exclusives can only be bought around their show
+		if !drops.CanBuy(cartItem.ProductID, cartItem.VariantID) {
+			respondError(w, http.StatusConflict, fmt.Sprintf("%s - %s is a tour exclusive and is not on sale right now", productName, variantName))
+			return
+		}
+
+		// Reject out-of-stock items up front; label pre-orders with their ship date
+		stockCheck, err := inventoryService.CheckStock(cartItem.VariantID, cartItem.Quantity)
+		if err != nil {
+			log.Printf("Failed to check stock for variant %s: %v", cartItem.VariantID, err)
+			respondError(w, http.StatusInternalServerError, "Failed to check stock")
+			return
+		}
+		if !stockCheck.Available {
+			respondError(w, http.StatusConflict, fmt.Sprintf("%s - %s is out of stock", productName, variantName))
+			return
+		}
+		if stockCheck.Preorder {
+			variantName = preorderLabel(variantName, *stockCheck.ExpectedShipDate)
+		}
+
 		lineItems = append(lineItems, stripe.CheckoutLineItem{
 			ProductName: productName,
 			VariantName: variantName,
@@ -167,9 +220,11 @@ func (h *Handler) CreateCartCheckout(w http.ResponseWriter, r *http.Request) {
 
 	// Create Stripe checkout session
 	sessionID, err := h.stripeClient.CreateCheckoutSession(&stripe.CheckoutSessionRequest{
-		OrderID:       "", // No order created yet
-		CustomerEmail: req.Email,
-		LineItems:     lineItems,
+		OrderID:           "", // No order created yet
+		CustomerEmail:     req.Email,
+		LineItems:         lineItems,
+		DigitalOnly:       digitalOnly,
+		PromotionsConsent: true, // "Subscribe" checkbox for the mailing list
 	})
 
 	if err != nil {
@@ -178,7 +233,36 @@ func (h *Handler) CreateCartCheckout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.WishlistToken != "" && req.Email != "" {
+		h.promoteWishlist(req.WishlistToken, req.Email)
+	}
+
 	respondJSON(w, http.StatusOK, CreateCheckoutResponse{
 		SessionID: sessionID,
 	})
 }
+
+// promoteWishlist attaches a still-anonymous wishlist to the customer checking out
+// Failures are logged only; they must not block checkout.
+func (h *Handler) promoteWishlist(token, email string) {
+	wishlistService := wishlist.NewService(h.db)
+	list, err := wishlistService.Get(token)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Failed to load wishlist for checkout: %v", err)
+		}
+		return
+	}
+	if list.Email != "" {
+		return
+	}
+	if _, err := wishlistService.Promote(token, email, false); err != nil {
+		log.Printf("Failed to attach wishlist %s to %s: %v", list.ID, email, err)
+	}
+}
+
+// preorderLabel appends the expected ship date to a variant name so the
+// customer sees it on the Stripe checkout page
+func preorderLabel(variantName string, shipDate time.Time) string {
+	return fmt.Sprintf("%s (Pre-order, ships by %s)", variantName, shipDate.Format("Jan 2, 2006"))
+}
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/nessieaudio/ecommerce-backend/internal/catalog"
+	apierrors "github.com/nessieaudio/ecommerce-backend/internal/errors"
+	"github.com/nessieaudio/ecommerce-backend/internal/middleware"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+// CollectionResponse is a public collection with its products in display order
+type CollectionResponse struct {
+	Slug        string            `json:"slug"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	ImageURL    string            `json:"image_url,omitempty"`
+	EndsAt      *time.Time        `json:"ends_at,omitempty"` // Set for limited-time collections
+	Products    []ProductResponse `json:"products"`
+}
+
+// GetCollections lists the collections visible right now, without their products
+// GET /api/v1/collections
+func (h *Handler) GetCollections(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	collections, err := catalogService.ListVisibleCollections()
+	if err != nil {
+		h.logger.Error("Failed to fetch collections [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	response := make([]CollectionResponse, 0, len(collections))
+	for _, c := range collections {
+		response = append(response, CollectionResponse{
+			Slug:        c.Slug,
+			Name:        c.Name,
+			Description: c.Description,
+			ImageURL:    c.ImageURL,
+			EndsAt:      c.EndsAt,
+			Products:    []ProductResponse{},
+		})
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"collections": response,
+	})
+}
+
+// GetCollection returns a visible collection with its active products in their manual order
+// GET /api/v1/collections/{slug}
+func (h *Handler) GetCollection(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	slug := mux.Vars(r)["slug"]
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	collection, err := catalogService.GetVisibleCollection(slug)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierrors.RespondNotFound(w, "Collection", requestID)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch collection [request_id: "+requestID+", slug: "+slug+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	products, err := h.getProductSummaries(collection.ProductIDs)
+	if err != nil {
+		h.logger.Error("Failed to fetch collection products [request_id: "+requestID+", slug: "+slug+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, CollectionResponse{
+		Slug:        collection.Slug,
+		Name:        collection.Name,
+		Description: collection.Description,
+		ImageURL:    collection.ImageURL,
+		EndsAt:      collection.EndsAt,
+		Products:    products,
+	})
+}
+
+// GetAdminCollections lists every collection, including hidden and scheduled ones
+// GET /api/v1/admin/collections
+func (h *Handler) GetAdminCollections(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	collections, err := catalogService.ListCollections()
+	if err != nil {
+		h.logger.Error("Failed to fetch collections [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"collections": collections,
+		"count":       len(collections),
+	})
+}
+
+// GetAdminCollection returns one collection by ID
+// GET /api/v1/admin/collections/{id}
+func (h *Handler) GetAdminCollection(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	collectionID := mux.Vars(r)["id"]
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	collection, err := catalogService.GetCollection(collectionID)
+	if err != nil {
+		h.respondCollectionError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, collection)
+}
+
+// CollectionRequest is the editable part of a collection
+type CollectionRequest struct {
+	Name        string     `json:"name"`
+	Slug        string     `json:"slug"` // Defaults to a slug of the name
+	Description string     `json:"description"`
+	ImageURL    string     `json:"image_url"`
+	Active      *bool      `json:"active"` // Defaults to true
+	StartsAt    *time.Time `json:"starts_at"`
+	EndsAt      *time.Time `json:"ends_at"`
+	ProductIDs  []string   `json:"product_ids"` // Optional; replaces the product list in this order
+}
+
+// CreateCollection adds a collection
+// POST /api/v1/admin/collections
+//
+// Request: { "name": "Tour 2026", "starts_at": "2026-03-01T00:00:00Z", "ends_at": null, "product_ids": ["...", "..."] }
+func (h *Handler) CreateCollection(w http.ResponseWriter, r *http.Request) {
+	h.saveCollection(w, r, "")
+}
+
+// UpdateCollection replaces a collection's details, and its products if product_ids is given
+// PUT /api/v1/admin/collections/{id}
+func (h *Handler) UpdateCollection(w http.ResponseWriter, r *http.Request) {
+	h.saveCollection(w, r, mux.Vars(r)["id"])
+}
+
+func (h *Handler) saveCollection(w http.ResponseWriter, r *http.Request, collectionID string) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	var req CollectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+
+	var validationErrors []apierrors.ValidationError
+	if req.Name == "" {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "name", Message: "is required"})
+	}
+	if req.Slug != "" && !catalog.ValidSlug(req.Slug) {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "slug", Message: "must be lowercase letters, digits and dashes"})
+	}
+	if len(validationErrors) > 0 {
+		apierrors.RespondValidationError(w, validationErrors, requestID)
+		return
+	}
+
+	collection := models.Collection{
+		ID:          collectionID,
+		Slug:        req.Slug,
+		Name:        req.Name,
+		Description: req.Description,
+		ImageURL:    req.ImageURL,
+		Active:      req.Active == nil || *req.Active,
+		StartsAt:    req.StartsAt,
+		EndsAt:      req.EndsAt,
+		ProductIDs:  req.ProductIDs,
+	}
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	if err := catalogService.SaveCollection(&collection); err != nil {
+		h.respondCollectionError(w, err, requestID)
+		return
+	}
+
+	status := http.StatusOK
+	if collectionID == "" {
+		status = http.StatusCreated
+	}
+	apierrors.RespondJSON(w, status, collection)
+}
+
+// SetCollectionProductsRequest orders a collection's products
+type SetCollectionProductsRequest struct {
+	ProductIDs []string `json:"product_ids"`
+}
+
+// SetCollectionProducts replaces a collection's products; the array order is the display order
+// PUT /api/v1/admin/collections/{id}/products
+//
+// Request: { "product_ids": ["<first>", "<second>"] }
+func (h *Handler) SetCollectionProducts(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	collectionID := mux.Vars(r)["id"]
+
+	var req SetCollectionProductsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	if err := catalogService.SetCollectionProducts(collectionID, req.ProductIDs); err != nil {
+		h.respondCollectionError(w, err, requestID)
+		return
+	}
+
+	collection, err := catalogService.GetCollection(collectionID)
+	if err != nil {
+		h.respondCollectionError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, collection)
+}
+
+// DeleteCollection removes a collection; its products are not affected
+// DELETE /api/v1/admin/collections/{id}
+func (h *Handler) DeleteCollection(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	collectionID := mux.Vars(r)["id"]
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	if err := catalogService.DeleteCollection(collectionID); err != nil {
+		h.respondCollectionError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Collection deleted",
+		"id":      collectionID,
+	})
+}
+
+// respondCollectionError maps collection service errors to API responses
+func (h *Handler) respondCollectionError(w http.ResponseWriter, err error, requestID string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		apierrors.RespondNotFound(w, "Collection", requestID)
+	case errors.Is(err, catalog.ErrSlugTaken):
+		apierrors.RespondError(w, http.StatusConflict, err.Error(), apierrors.ErrCodeConflict, nil, requestID)
+	case errors.Is(err, catalog.ErrInvalidWindow):
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "ends_at", Message: err.Error()}}, requestID)
+	case errors.Is(err, catalog.ErrProductNotFound):
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "product_ids", Message: err.Error()}}, requestID)
+	default:
+		h.logger.Error("Collection operation failed [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+	}
+}
@@ -0,0 +1,250 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	apierrors "github.com/nessieaudio/ecommerce-backend/internal/errors"
+	"github.com/nessieaudio/ecommerce-backend/internal/inquiries"
+	"github.com/nessieaudio/ecommerce-backend/internal/middleware"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+	"github.com/nessieaudio/ecommerce-backend/internal/services/email"
+)
+
+// Booking form rules, kept in step with form-validation.js
+var (
+	contactEmailPattern = regexp.MustCompile(`^[^\s@]+@[^\s@]+\.[^\s@]+$`)
+	contactNamePattern  = regexp.MustCompile(`^[a-zA-Z0-9\s.,\-!?'":;()]+$`)
+)
+
+// ContactRequest is a booking inquiry from the form on the home page
+type ContactRequest struct {
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	ProjectType string `json:"project_type"`
+	Deadline    string `json:"deadline"` // YYYY-MM-DD
+	Message     string `json:"message"`
+
+	// Honeypot: hidden from people, so only bots fill it in
+	Website string `json:"website"`
+}
+
+// contactReceived is the answer to every accepted submission, including those
+// dropped by the honeypot, so bots can't tell the difference
+var contactReceived = map[string]string{
+	"message": "Thanks! Your booking request has been sent. We'll get back to you soon.",
+}
+
+// SubmitContact stores a booking inquiry, notifies the admin and acknowledges it to the sender
+// POST /api/v1/contact
+//
+// Request: { "name": "Ada", "email": "ada@example.com", "project_type": "Mixing",
+// "deadline": "2026-12-01", "message": "Three songs, stems ready..." }
+func (h *Handler) SubmitContact(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	var req ContactRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+
+	if req.Website != "" {
+		log.Printf("Contact form honeypot filled; dropping submission [request_id: %s]", requestID)
+		apierrors.RespondJSON(w, http.StatusCreated, contactReceived)
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	req.Email = strings.TrimSpace(req.Email)
+	req.ProjectType = strings.TrimSpace(req.ProjectType)
+	req.Deadline = strings.TrimSpace(req.Deadline)
+	req.Message = strings.TrimSpace(req.Message)
+
+	if validationErrors := validateContact(&req, time.Now()); len(validationErrors) > 0 {
+		apierrors.RespondValidationError(w, validationErrors, requestID)
+		return
+	}
+
+	inquiry := models.Inquiry{
+		Name:        req.Name,
+		Email:       req.Email,
+		ProjectType: req.ProjectType,
+		Deadline:    req.Deadline,
+		Message:     req.Message,
+	}
+	if err := inquiries.NewService(h.db).Create(&inquiry); err != nil {
+		h.logger.Error("Failed to store inquiry [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	go h.sendInquiryEmails(inquiry)
+
+	apierrors.RespondJSON(w, http.StatusCreated, contactReceived)
+}
+
+// validateContact applies the booking form's rules. Lengths are counted in
+// characters after trimming, as the browser does.
+func validateContact(req *ContactRequest, now time.Time) []apierrors.ValidationError {
+	var validationErrors []apierrors.ValidationError
+	invalid := func(field, message string) {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: field, Message: message})
+	}
+	length := utf8.RuneCountInString
+
+	switch {
+	case req.Name == "":
+		invalid("name", "is required")
+	case length(req.Name) < 2 || length(req.Name) > 100:
+		invalid("name", "must be between 2 and 100 characters")
+	case !contactNamePattern.MatchString(req.Name):
+		invalid("name", "contains invalid characters")
+	}
+
+	switch {
+	case req.Email == "":
+		invalid("email", "is required")
+	case length(req.Email) > 254 || !contactEmailPattern.MatchString(req.Email):
+		invalid("email", "must be a valid email address")
+	}
+
+	switch {
+	case req.ProjectType == "":
+		invalid("project_type", "is required")
+	case length(req.ProjectType) < 3 || length(req.ProjectType) > 200:
+		invalid("project_type", "must be between 3 and 200 characters")
+	}
+
+	// "Today" is the sender's, so accept any date that is still today somewhere
+	earliest := now.UTC().Add(-12 * time.Hour).Format("2006-01-02")
+	if req.Deadline == "" {
+		invalid("deadline", "is required")
+	} else if deadline, err := time.Parse("2006-01-02", req.Deadline); err != nil {
+		invalid("deadline", "must be a date such as 2026-12-01")
+	} else if deadline.Format("2006-01-02") < earliest {
+		invalid("deadline", "must be today or in the future")
+	}
+
+	switch {
+	case req.Message == "":
+		invalid("message", "is required")
+	case length(req.Message) < 10 || length(req.Message) > 2000:
+		invalid("message", "must be between 10 and 2000 characters")
+	}
+
+	return validationErrors
+}
+
+// sendInquiryEmails notifies the admin of a new inquiry, with replies going to the
+// sender, and lets the sender know it arrived. Failures are only logged; the
+// inquiry is stored either way.
+func (h *Handler) sendInquiryEmails(inquiry models.Inquiry) {
+	message := strings.ReplaceAll(html.EscapeString(inquiry.Message), "\n", "<br>")
+
+	if h.config.AdminEmail != "" {
+		contentHTML := fmt.Sprintf(`<p style="font-size:16px;">A new booking request came in through the website. Reply to this email to answer %s directly.</p>%s%s`,
+			html.EscapeString(inquiry.Name),
+			email.InfoBox("Booking Request",
+				email.DetailRow("Name:", html.EscapeString(inquiry.Name))+
+					email.DetailRow("Email:", html.EscapeString(inquiry.Email))+
+					email.DetailRow("Project type:", html.EscapeString(inquiry.ProjectType))+
+					email.DetailRow("Deadline:", html.EscapeString(inquiry.Deadline))+
+					email.DetailRow("Received:", inquiry.CreatedAt.Format("2006-01-02 15:04:05 MST"))),
+			email.NoteBox(message, false),
+		)
+		htmlBody := email.EmailLayout("New Booking Request", "&#127908;", contentHTML, true)
+		subject := "New booking request: " + inquiry.ProjectType + " from " + inquiry.Name
+
+		if err := h.emailClient.SendHTMLEmailReplyTo(h.config.AdminEmail, inquiry.Email, subject, htmlBody); err != nil {
+			log.Printf("Failed to send inquiry %s to admin: %v", inquiry.ID, err)
+		}
+	}
+
+	contentHTML := fmt.Sprintf(`<p style="font-size:16px;">%s,</p>
+<p style="font-size:16px;">Thanks for getting in touch about your %s project. We've received your booking request and will reply within a few days.</p>%s`,
+		greetingFor(inquiry.Name),
+		html.EscapeString(inquiry.ProjectType),
+		email.NoteBox(message, false),
+	)
+	htmlBody := email.EmailLayout("Request Received", "&#10003;", contentHTML, false)
+
+	if err := h.emailClient.SendHTMLEmail(inquiry.Email, "We got your booking request", htmlBody); err != nil {
+		log.Printf("Failed to acknowledge inquiry %s: %v", inquiry.ID, err)
+	}
+}
+
+// GetAdminInquiries lists booking inquiries by status, newest first
+// GET /api/v1/admin/inquiries?status=new
+//
+// status defaults to new (the inbox); "all" lists every inquiry.
+func (h *Handler) GetAdminInquiries(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = models.InquiryStatusNew
+	case "all":
+		status = ""
+	}
+
+	list, err := inquiries.NewService(h.db).List(status)
+	if err != nil {
+		h.logger.Error("Failed to fetch inquiries [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"inquiries": list,
+		"count":     len(list),
+	})
+}
+
+// UpdateInquiryRequest moves an inquiry to a status
+type UpdateInquiryRequest struct {
+	Status string `json:"status"` // new, replied or archived
+	Note   string `json:"note"`   // Internal
+}
+
+// UpdateInquiry marks an inquiry replied or archived
+// PUT /api/v1/admin/inquiries/{id}
+//
+// Request: { "status": "replied", "note": "Quoted for 3 songs" }
+func (h *Handler) UpdateInquiry(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	inquiryID := mux.Vars(r)["id"]
+
+	var req UpdateInquiryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+
+	inquiry, err := inquiries.NewService(h.db).SetStatus(inquiryID, req.Status, strings.TrimSpace(req.Note))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		apierrors.RespondNotFound(w, "Inquiry", requestID)
+		return
+	case errors.Is(err, inquiries.ErrInvalidStatus):
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "status", Message: err.Error()}}, requestID)
+		return
+	case err != nil:
+		h.logger.Error("Failed to update inquiry [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, inquiry)
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/nessieaudio/ecommerce-backend/internal/catalog"
+	"github.com/nessieaudio/ecommerce-backend/internal/downloads"
+	apierrors "github.com/nessieaudio/ecommerce-backend/internal/errors"
+	"github.com/nessieaudio/ecommerce-backend/internal/middleware"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+	"github.com/nessieaudio/ecommerce-backend/internal/signing"
+)
+
+// DownloadFile streams a purchased file from a signed download link
+// GET /api/v1/downloads/{token}
+//
+// Supports Range requests, so an interrupted download can be resumed from where it
+// stopped without using up another download.
+func (h *Handler) DownloadFile(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	downloadService := downloads.NewService(h.db, h.signer, h.config.DigitalFilesDir)
+	file, err := downloadService.Open(mux.Vars(r)["token"], r)
+	if err != nil {
+		h.respondDownloadError(w, err, requestID)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Name}))
+	w.Header().Set("Cache-Control", "private, no-store")
+
+	h.liftWriteDeadline(w, requestID)
+	cw := &countingWriter{ResponseWriter: w}
+	http.ServeContent(cw, r, file.Name, file.ModTime, file)
+	if cw.status == http.StatusOK || cw.status == http.StatusPartialContent {
+		if err := downloadService.RecordServed(file, cw.written); err != nil {
+			h.logger.Error("Failed to record download progress [request_id: "+requestID+"]", err)
+		}
+	}
+}
+
+// liftWriteDeadline removes the server's WriteTimeout for this response, so that
+// streaming a large file over a slow connection is not cut off
+func (h *Handler) liftWriteDeadline(w http.ResponseWriter, requestID string) {
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		h.logger.Error("Failed to lift write deadline [request_id: "+requestID+"]", err)
+	}
+}
+
+// countingWriter records the status and how many body bytes were written
+type countingWriter struct {
+	http.ResponseWriter
+	status  int
+	written int64
+}
+
+func (cw *countingWriter) WriteHeader(status int) {
+	if cw.status == 0 {
+		cw.status = status
+	}
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	n, err := cw.ResponseWriter.Write(p)
+	cw.written += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (cw *countingWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// getAPIBaseURL is where emailed links to API endpoints point: the site itself, except
+// in local development where the API has its own port
+func (h *Handler) getAPIBaseURL() string {
+	if h.config.Env == "production" || h.config.Env == "staging" {
+		return h.getBaseURL()
+	}
+	return "http://localhost:" + h.config.Port
+}
+
+// GetAdminDigitalProducts lists every digital product with its files
+// GET /api/v1/admin/digital-products
+func (h *Handler) GetAdminDigitalProducts(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	products, err := catalogService.ListDigitalProducts()
+	if err != nil {
+		h.logger.Error("Failed to fetch digital products [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"products": products,
+		"count":    len(products),
+	})
+}
+
+// GetAdminDigitalProduct returns one digital product by product ID
+// GET /api/v1/admin/digital-products/{id}
+func (h *Handler) GetAdminDigitalProduct(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	product, err := catalogService.GetDigitalProduct(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondDigitalProductError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, product)
+}
+
+// DigitalProductRequest is the editable part of a digital product
+type DigitalProductRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Price       float64  `json:"price"`
+	ImageURL    string   `json:"image_url"`
+	Category    string   `json:"category"` // Category slug, optional
+	Active      *bool    `json:"active"`   // Defaults to true
+	Files       []string `json:"files"`    // Paths relative to DIGITAL_FILES_DIR, in display order
+}
+
+// CreateDigitalProduct adds a digital product
+// POST /api/v1/admin/digital-products
+//
+// Request: { "name": "Cosmic Lung - Intro (MP3)", "price": 1.5, "files": ["cosmic-lung/Intro.mp3"] }
+func (h *Handler) CreateDigitalProduct(w http.ResponseWriter, r *http.Request) {
+	h.saveDigitalProduct(w, r, "")
+}
+
+// UpdateDigitalProduct replaces a digital product's details and files
+// PUT /api/v1/admin/digital-products/{id}
+//
+// Buyers keep their downloads of files that are dropped.
+func (h *Handler) UpdateDigitalProduct(w http.ResponseWriter, r *http.Request) {
+	h.saveDigitalProduct(w, r, mux.Vars(r)["id"])
+}
+
+func (h *Handler) saveDigitalProduct(w http.ResponseWriter, r *http.Request, productID string) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	var req DigitalProductRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+
+	var validationErrors []apierrors.ValidationError
+	if req.Name == "" {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "name", Message: "is required"})
+	}
+	if req.Price <= 0 {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "price", Message: "must be greater than 0"})
+	}
+	if len(req.Files) == 0 {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "files", Message: "is required"})
+	}
+
+	downloadService := downloads.NewService(h.db, h.signer, h.config.DigitalFilesDir)
+	files := make([]models.DigitalFile, 0, len(req.Files))
+	for _, path := range req.Files {
+		file, err := downloadService.DescribeFile(strings.TrimSpace(path))
+		if err != nil {
+			validationErrors = append(validationErrors, apierrors.ValidationError{Field: "files", Message: err.Error()})
+			break
+		}
+		files = append(files, *file)
+	}
+	if len(validationErrors) > 0 {
+		apierrors.RespondValidationError(w, validationErrors, requestID)
+		return
+	}
+
+	product := models.DigitalProduct{
+		ProductID:   productID,
+		Name:        req.Name,
+		Description: req.Description,
+		Price:       req.Price,
+		ImageURL:    req.ImageURL,
+		Category:    req.Category,
+		Active:      req.Active == nil || *req.Active,
+		Files:       files,
+	}
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	if err := catalogService.SaveDigitalProduct(&product); err != nil {
+		h.respondDigitalProductError(w, err, requestID)
+		return
+	}
+
+	status := http.StatusOK
+	if productID == "" {
+		status = http.StatusCreated
+	}
+	apierrors.RespondJSON(w, status, product)
+}
+
+// respondDownloadError maps download link errors to API responses
+func (h *Handler) respondDownloadError(w http.ResponseWriter, err error, requestID string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, signing.ErrInvalidToken):
+		apierrors.RespondNotFound(w, "Download", requestID)
+	case errors.Is(err, downloads.ErrLinkExpired):
+		apierrors.RespondError(w, http.StatusGone, "Download link has expired", apierrors.ErrCodeForbidden, nil, requestID)
+	case errors.Is(err, downloads.ErrLimitReached):
+		apierrors.RespondError(w, http.StatusForbidden, "Download limit reached", apierrors.ErrCodeForbidden, nil, requestID)
+	default:
+		// Includes ErrFileMissing: a sold file gone from disk needs fixing on our side
+		h.logger.Error("Download failed [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+	}
+}
+
+// respondDigitalProductError maps digital product service errors to API responses
+func (h *Handler) respondDigitalProductError(w http.ResponseWriter, err error, requestID string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		apierrors.RespondNotFound(w, "Digital product", requestID)
+	case errors.Is(err, catalog.ErrNoFiles):
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "files", Message: err.Error()}}, requestID)
+	case errors.Is(err, catalog.ErrCategoryNotFound):
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "category", Message: err.Error()}}, requestID)
+	default:
+		h.logger.Error("Digital product operation failed [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+	}
+}
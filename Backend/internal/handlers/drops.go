@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	apierrors "github.com/nessieaudio/ecommerce-backend/internal/errors"
+	"github.com/nessieaudio/ecommerce-backend/internal/middleware"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+	"github.com/nessieaudio/ecommerce-backend/internal/tour"
+)
+
+// DropResponse is a drop with its window worked out from the show
+type DropResponse struct {
+	models.Drop
+	OpensAt  time.Time `json:"opens_at"` // In the venue's timezone
+	ClosesAt time.Time `json:"closes_at"`
+	Open     bool      `json:"open"`
+}
+
+// DropRequest ties a product or variant to a show
+type DropRequest struct {
+	ShowID           string `json:"show_id"`
+	ProductID        string `json:"product_id"`
+	VariantID        string `json:"variant_id"`         // Optional; empty = the whole product
+	OpensHoursBefore *int   `json:"opens_hours_before"` // Defaults to 48
+	ClosesHoursAfter *int   `json:"closes_hours_after"` // Defaults to 24
+}
+
+// GetAdminDrops lists every tour-exclusive drop with its sale window
+// GET /api/v1/admin/drops
+func (h *Handler) GetAdminDrops(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	tourService := tour.NewService(h.db)
+
+	drops, err := tourService.ListDrops()
+	if err != nil {
+		h.respondDropError(w, err, requestID)
+		return
+	}
+	shows, err := tourService.ListAll()
+	if err != nil {
+		h.respondDropError(w, err, requestID)
+		return
+	}
+	showsByID := make(map[string]*models.Show, len(shows))
+	for i := range shows {
+		showsByID[shows[i].ID] = &shows[i]
+	}
+
+	now := time.Now()
+	response := make([]DropResponse, 0, len(drops))
+	for _, drop := range drops {
+		d := DropResponse{Drop: drop}
+		if show, ok := showsByID[drop.ShowID]; ok {
+			d.OpensAt, d.ClosesAt = tour.DropWindow(show, &drop)
+			d.Open = tour.DropOpen(show, &drop, now)
+		}
+		response = append(response, d)
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"drops": response,
+	})
+}
+
+// CreateDrop makes a product, or one variant, a tour exclusive sold around a show
+// POST /api/v1/admin/drops
+//
+// Request: { "show_id": "...", "product_id": "...", "variant_id": "...",
+// "opens_hours_before": 48, "closes_hours_after": 24 }
+func (h *Handler) CreateDrop(w http.ResponseWriter, r *http.Request) {
+	h.saveDrop(w, r, "")
+}
+
+// UpdateDrop replaces a drop
+// PUT /api/v1/admin/drops/{id}
+func (h *Handler) UpdateDrop(w http.ResponseWriter, r *http.Request) {
+	h.saveDrop(w, r, mux.Vars(r)["id"])
+}
+
+func (h *Handler) saveDrop(w http.ResponseWriter, r *http.Request, dropID string) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	var req DropRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+
+	var validationErrors []apierrors.ValidationError
+	if strings.TrimSpace(req.ShowID) == "" {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "show_id", Message: "is required"})
+	}
+	if strings.TrimSpace(req.ProductID) == "" {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "product_id", Message: "is required"})
+	}
+	if len(validationErrors) > 0 {
+		apierrors.RespondValidationError(w, validationErrors, requestID)
+		return
+	}
+
+	drop := models.Drop{
+		ID:               dropID,
+		ShowID:           strings.TrimSpace(req.ShowID),
+		ProductID:        strings.TrimSpace(req.ProductID),
+		VariantID:        strings.TrimSpace(req.VariantID),
+		OpensHoursBefore: tour.DefaultOpensHoursBefore,
+		ClosesHoursAfter: tour.DefaultClosesHoursAfter,
+	}
+	if req.OpensHoursBefore != nil {
+		drop.OpensHoursBefore = *req.OpensHoursBefore
+	}
+	if req.ClosesHoursAfter != nil {
+		drop.ClosesHoursAfter = *req.ClosesHoursAfter
+	}
+
+	if err := tour.NewService(h.db).SaveDrop(&drop); err != nil {
+		h.respondDropError(w, err, requestID)
+		return
+	}
+
+	status := http.StatusOK
+	if dropID == "" {
+		status = http.StatusCreated
+	}
+	apierrors.RespondJSON(w, status, drop)
+}
+
+// DeleteDrop removes a drop; the product or variant goes back on general sale
+// unless it has other drops
+// DELETE /api/v1/admin/drops/{id}
+func (h *Handler) DeleteDrop(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	dropID := mux.Vars(r)["id"]
+
+	if err := tour.NewService(h.db).DeleteDrop(dropID); err != nil {
+		h.respondDropError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Drop deleted",
+		"id":      dropID,
+	})
+}
+
+// respondDropError maps drop errors to API responses
+func (h *Handler) respondDropError(w http.ResponseWriter, err error, requestID string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		apierrors.RespondNotFound(w, "Drop", requestID)
+	case errors.Is(err, tour.ErrShowNotFound):
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "show_id", Message: err.Error()}}, requestID)
+	case errors.Is(err, tour.ErrProductNotFound):
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "product_id", Message: err.Error()}}, requestID)
+	case errors.Is(err, tour.ErrVariantNotInProduct):
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "variant_id", Message: err.Error()}}, requestID)
+	case errors.Is(err, tour.ErrInvalidDropWindow):
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "opens_hours_before", Message: err.Error()}}, requestID)
+	default:
+		h.logger.Error("Drop operation failed [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	apierrors "github.com/nessieaudio/ecommerce-backend/internal/errors"
+	"github.com/nessieaudio/ecommerce-backend/internal/middleware"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+	"github.com/nessieaudio/ecommerce-backend/internal/services/email"
+)
+
+// emailListLimit caps how many queued emails an admin listing returns
+const emailListLimit = 200
+
+// GetAdminEmails lists emails in the outgoing queue by status, newest first
+// GET /api/v1/admin/emails?status=dead
+//
+// status defaults to dead (the dead-letter list of emails that failed
+// permanently or ran out of retries); "all" lists every email. counts covers the
+// whole queue.
+func (h *Handler) GetAdminEmails(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = models.EmailStatusDead
+	case "all":
+		status = ""
+	case models.EmailStatusPending, models.EmailStatusSent, models.EmailStatusDead:
+	default:
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "status", Message: "must be pending, sent, dead or all"}}, requestID)
+		return
+	}
+
+	queue := h.emailClient.Queue()
+	list, err := queue.List(status, emailListLimit)
+	if err != nil {
+		h.logger.Error("Failed to fetch queued emails [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+	counts, err := queue.Counts()
+	if err != nil {
+		h.logger.Error("Failed to count queued emails [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"emails": list,
+		"count":  len(list),
+		"counts": counts,
+	})
+}
+
+// ResendEmail puts a dead email back in the queue with a fresh set of retries
+// POST /api/v1/admin/emails/{id}/resend
+func (h *Handler) ResendEmail(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	id := mux.Vars(r)["id"]
+
+	err := h.emailClient.Queue().Resend(id)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		apierrors.RespondNotFound(w, "Email", requestID)
+		return
+	case errors.Is(err, email.ErrNotDead):
+		apierrors.RespondError(w, http.StatusConflict, err.Error(), apierrors.ErrCodeConflict, nil, requestID)
+		return
+	case err != nil:
+		h.logger.Error("Failed to resend email [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Email queued for resending",
+		"id":      id,
+	})
+}
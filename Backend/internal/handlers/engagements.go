@@ -0,0 +1,539 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"github.com/nessieaudio/ecommerce-backend/internal/engagements"
+	apierrors "github.com/nessieaudio/ecommerce-backend/internal/errors"
+	"github.com/nessieaudio/ecommerce-backend/internal/middleware"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+	"github.com/nessieaudio/ecommerce-backend/internal/money"
+	"github.com/nessieaudio/ecommerce-backend/internal/services/email"
+	"github.com/nessieaudio/ecommerce-backend/internal/services/stripe"
+	stripeLib "github.com/stripe/stripe-go/v76"
+)
+
+const (
+	maxQuoteItems     = 50
+	maxQuoteValidDays = 90
+	minDeposit        = 0.50 // Stripe's smallest USD charge
+)
+
+// CreateEngagementRequest starts an engagement from an inquiry, or from the
+// client's details when the work came in some other way
+type CreateEngagementRequest struct {
+	InquiryID   string `json:"inquiry_id"`
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	ProjectType string `json:"project_type"`
+}
+
+// GetAdminEngagements lists engagements, newest first
+// GET /api/v1/admin/engagements?status=booked
+//
+// status is optional: open, quoted, booked, completed or cancelled.
+func (h *Handler) GetAdminEngagements(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	list, err := engagements.NewService(h.db).List(r.URL.Query().Get("status"))
+	if err != nil {
+		h.logger.Error("Failed to fetch engagements [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"engagements": list,
+		"count":       len(list),
+	})
+}
+
+// GetAdminEngagement returns an engagement with its quotes
+// GET /api/v1/admin/engagements/{id}
+func (h *Handler) GetAdminEngagement(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	engagement, err := engagements.NewService(h.db).Get(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondEngagementError(w, err, "Engagement", requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, engagement)
+}
+
+// CreateEngagement starts an engagement
+// POST /api/v1/admin/engagements
+//
+// Request: { "inquiry_id": "..." } or { "name": "Ada", "email": "ada@example.com", "project_type": "Mixing" }
+func (h *Handler) CreateEngagement(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	var req CreateEngagementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+
+	service := engagements.NewService(h.db)
+	var engagement *models.Engagement
+	var err error
+	if req.InquiryID != "" {
+		engagement, err = service.CreateFromInquiry(req.InquiryID)
+	} else {
+		engagement = &models.Engagement{
+			Name:        strings.TrimSpace(req.Name),
+			Email:       strings.TrimSpace(req.Email),
+			ProjectType: strings.TrimSpace(req.ProjectType),
+		}
+		if validationErrors := validateEngagement(engagement); len(validationErrors) > 0 {
+			apierrors.RespondValidationError(w, validationErrors, requestID)
+			return
+		}
+		err = service.Create(engagement)
+	}
+	if err != nil {
+		h.respondEngagementError(w, err, "Engagement", requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusCreated, engagement)
+}
+
+// validateEngagement checks the client's details of an engagement made without an inquiry
+func validateEngagement(e *models.Engagement) []apierrors.ValidationError {
+	var validationErrors []apierrors.ValidationError
+	if e.Name == "" || utf8.RuneCountInString(e.Name) > 100 {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "name", Message: "must be between 1 and 100 characters"})
+	}
+	if len(e.Email) > 254 || !contactEmailPattern.MatchString(e.Email) {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "email", Message: "must be a valid email address"})
+	}
+	if e.ProjectType == "" || utf8.RuneCountInString(e.ProjectType) > 200 {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "project_type", Message: "must be between 1 and 200 characters"})
+	}
+	return validationErrors
+}
+
+// UpdateEngagementRequest moves an engagement to a status
+type UpdateEngagementRequest struct {
+	Status string `json:"status"`
+}
+
+// UpdateEngagement sets an engagement's status, e.g. to completed or cancelled
+// PUT /api/v1/admin/engagements/{id}
+//
+// Request: { "status": "completed" }
+func (h *Handler) UpdateEngagement(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	var req UpdateEngagementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+
+	engagement, err := engagements.NewService(h.db).SetStatus(mux.Vars(r)["id"], req.Status)
+	if err != nil {
+		h.respondEngagementError(w, err, "Engagement", requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, engagement)
+}
+
+// CreateQuoteRequest prices an engagement
+type CreateQuoteRequest struct {
+	Items []models.QuoteItem `json:"items"`
+
+	// The deposit is Deposit if set, else DepositPercent of the total (default 50)
+	Deposit        float64 `json:"deposit"`
+	DepositPercent int     `json:"deposit_percent"`
+
+	ExpiresInDays int    `json:"expires_in_days"` // Default 14
+	Note          string `json:"note"`            // Shown to the client
+	Send          bool   `json:"send"`            // Email the quote straight away
+}
+
+// CreateQuote adds a quote to an engagement, optionally emailing it to the client
+// POST /api/v1/admin/engagements/{id}/quotes
+//
+// Request: { "items": [{ "description": "Mixing", "quantity": 3, "unit_price": 250 }],
+// "deposit_percent": 50, "expires_in_days": 14, "note": "...", "send": true }
+func (h *Handler) CreateQuote(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	var req CreateQuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+
+	quote, validationErrors := quoteFromRequest(&req, time.Now())
+	if len(validationErrors) > 0 {
+		apierrors.RespondValidationError(w, validationErrors, requestID)
+		return
+	}
+
+	service := engagements.NewService(h.db)
+	if err := service.CreateQuote(mux.Vars(r)["id"], quote); err != nil {
+		h.respondEngagementError(w, err, "Engagement", requestID)
+		return
+	}
+
+	// If the email fails the quote is still created, as a draft to send again
+	if req.Send {
+		if sent, err := h.sendQuote(service, quote.ID); err != nil {
+			h.logger.Error("Failed to send quote [request_id: "+requestID+"]", err)
+		} else {
+			quote = sent
+		}
+	}
+
+	apierrors.RespondJSON(w, http.StatusCreated, quote)
+}
+
+// quoteFromRequest validates a quote request and builds the quote it describes
+func quoteFromRequest(req *CreateQuoteRequest, now time.Time) (*models.Quote, []apierrors.ValidationError) {
+	var validationErrors []apierrors.ValidationError
+	invalid := func(field, message string) {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: field, Message: message})
+	}
+
+	if len(req.Items) == 0 || len(req.Items) > maxQuoteItems {
+		invalid("items", fmt.Sprintf("must have between 1 and %d items", maxQuoteItems))
+	}
+	var total float64
+	for i := range req.Items {
+		item := &req.Items[i]
+		item.Description = strings.TrimSpace(item.Description)
+		field := fmt.Sprintf("items[%d]", i)
+		if item.Description == "" || utf8.RuneCountInString(item.Description) > 200 {
+			invalid(field+".description", "must be between 1 and 200 characters")
+		}
+		if item.Quantity < 1 || item.Quantity > 1000 {
+			invalid(field+".quantity", "must be between 1 and 1000")
+		}
+		if item.UnitPrice < 0 || math.IsNaN(item.UnitPrice) || math.IsInf(item.UnitPrice, 0) {
+			invalid(field+".unit_price", "must not be negative")
+		}
+		total += float64(item.Quantity) * item.UnitPrice
+	}
+
+	deposit := req.Deposit
+	switch {
+	case req.Deposit < 0:
+		invalid("deposit", "must not be negative")
+	case req.Deposit > 0 && req.DepositPercent != 0:
+		invalid("deposit_percent", "can't be set together with deposit")
+	case req.Deposit == 0 && (req.DepositPercent < 0 || req.DepositPercent > 100):
+		invalid("deposit_percent", "must be between 1 and 100")
+	case req.Deposit == 0:
+		percent := req.DepositPercent
+		if percent == 0 {
+			percent = engagements.DefaultDepositPercent
+		}
+		deposit = engagements.DepositFor(total, percent)
+	}
+	if len(validationErrors) == 0 && deposit < minDeposit {
+		invalid("deposit", fmt.Sprintf("must be at least %.2f", minDeposit))
+	}
+
+	validity := engagements.DefaultValidity
+	if req.ExpiresInDays != 0 {
+		if req.ExpiresInDays < 1 || req.ExpiresInDays > maxQuoteValidDays {
+			invalid("expires_in_days", fmt.Sprintf("must be between 1 and %d", maxQuoteValidDays))
+		}
+		validity = time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	}
+
+	note := strings.TrimSpace(req.Note)
+	if utf8.RuneCountInString(note) > 2000 {
+		invalid("note", "must be at most 2000 characters")
+	}
+
+	return &models.Quote{
+		Items:     req.Items,
+		Currency:  "USD",
+		Deposit:   deposit,
+		Note:      note,
+		ExpiresAt: now.Add(validity),
+	}, validationErrors
+}
+
+// SendQuote emails a quote's link to the client; sending again resends it
+// POST /api/v1/admin/quotes/{id}/send
+func (h *Handler) SendQuote(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	quote, err := h.sendQuote(engagements.NewService(h.db), mux.Vars(r)["id"])
+	if err != nil {
+		h.respondEngagementError(w, err, "Quote", requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, quote)
+}
+
+// sendQuote emails a quote to the client, then marks it sent
+func (h *Handler) sendQuote(service *engagements.Service, quoteID string) (*models.Quote, error) {
+	quote, err := service.GetQuote(quoteID)
+	if err != nil {
+		return nil, err
+	}
+	if err := engagements.CanSend(quote); err != nil {
+		return nil, err
+	}
+	engagement, err := service.Get(quote.EngagementID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.sendQuoteEmail(engagement, quote); err != nil {
+		return nil, fmt.Errorf("email quote: %w", err)
+	}
+	return service.MarkSent(quoteID)
+}
+
+// sendQuoteEmail emails the client a summary of a quote and the link to pay its deposit
+func (h *Handler) sendQuoteEmail(engagement *models.Engagement, quote *models.Quote) error {
+	var rows strings.Builder
+	for _, item := range quote.Items {
+		label := html.EscapeString(item.Description)
+		if item.Quantity > 1 {
+			label += fmt.Sprintf(" &times; %d", item.Quantity)
+		}
+		rows.WriteString(email.DetailRow(label+":", fmt.Sprintf("$%.2f", float64(item.Quantity)*item.UnitPrice)))
+	}
+	rows.WriteString(email.DetailRow("Total:", fmt.Sprintf("$%.2f", quote.Total)))
+	rows.WriteString(email.DetailRow("Deposit to book:", fmt.Sprintf("$%.2f", quote.Deposit)))
+
+	note := ""
+	if quote.Note != "" {
+		note = email.NoteBox(strings.ReplaceAll(html.EscapeString(quote.Note), "\n", "<br>"), false)
+	}
+
+	contentHTML := fmt.Sprintf(`<p style="font-size:16px;">%s,</p>
+<p style="font-size:16px;">Here's our quote for your %s project. Paying the deposit books the work in. The quote is valid until %s.</p>%s%s%s`,
+		greetingFor(engagement.Name),
+		html.EscapeString(engagement.ProjectType),
+		quote.ExpiresAt.Format("January 2, 2006"),
+		email.InfoBox("Quote", rows.String()),
+		note,
+		email.CTAButton("View quote and pay deposit", h.quoteURL(quote.Token)),
+	)
+	htmlBody := email.EmailLayout("Your Quote", "&#127908;", contentHTML, false)
+
+	return h.emailClient.SendHTMLEmail(engagement.Email, "Your quote from "+brandName+": "+engagement.ProjectType, htmlBody)
+}
+
+// quoteURL is the page a client views and pays a quote on
+func (h *Handler) quoteURL(token string) string {
+	return h.getBaseURL() + "/quote?token=" + url.QueryEscape(token)
+}
+
+// greetingFor greets someone by their first name, escaped for HTML
+func greetingFor(name string) string {
+	if fields := strings.Fields(name); len(fields) > 0 {
+		return "Hi " + html.EscapeString(fields[0])
+	}
+	return "Hi"
+}
+
+// QuoteView is a quote as its client sees it
+type QuoteView struct {
+	Name          string             `json:"name"`
+	ProjectType   string             `json:"project_type"`
+	Items         []models.QuoteItem `json:"items"`
+	Currency      string             `json:"currency"`
+	Total         float64            `json:"total"`
+	Deposit       float64            `json:"deposit"`
+	BalanceDue    float64            `json:"balance_due"` // After the deposit
+	Note          string             `json:"note,omitempty"`
+	Status        string             `json:"status"` // draft, sent, paid, expired
+	ExpiresAt     time.Time          `json:"expires_at"`
+	DepositPaidAt *time.Time         `json:"deposit_paid_at,omitempty"`
+}
+
+// GetQuote returns the quote a client's link points to
+// GET /api/v1/quotes/{token}
+func (h *Handler) GetQuote(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	service := engagements.NewService(h.db)
+	quote, err := service.GetQuoteByToken(mux.Vars(r)["token"])
+	if err != nil {
+		h.respondEngagementError(w, err, "Quote", requestID)
+		return
+	}
+	engagement, err := service.Get(quote.EngagementID)
+	if err != nil {
+		h.respondEngagementError(w, err, "Quote", requestID)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	apierrors.RespondJSON(w, http.StatusOK, QuoteView{
+		Name:          engagement.Name,
+		ProjectType:   engagement.ProjectType,
+		Items:         quote.Items,
+		Currency:      quote.Currency,
+		Total:         quote.Total,
+		Deposit:       quote.Deposit,
+		BalanceDue:    money.RoundCents(quote.Total - quote.Deposit),
+		Note:          quote.Note,
+		Status:        quote.Status,
+		ExpiresAt:     quote.ExpiresAt,
+		DepositPaidAt: quote.DepositPaidAt,
+	})
+}
+
+// CreateQuoteCheckout starts a Stripe checkout for a quote's deposit
+// POST /api/v1/quotes/{token}/checkout
+//
+// Response: { "session_id": "cs_test_..." }
+func (h *Handler) CreateQuoteCheckout(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	token := mux.Vars(r)["token"]
+
+	service := engagements.NewService(h.db)
+	quote, engagement, err := service.Payable(token)
+	if err != nil {
+		h.respondEngagementError(w, err, "Quote", requestID)
+		return
+	}
+
+	sessionID, err := h.stripeClient.CreateCheckoutSession(&stripe.CheckoutSessionRequest{
+		Type: stripe.SessionTypeDeposit,
+		Metadata: map[string]string{
+			"quote_id":      quote.ID,
+			"engagement_id": engagement.ID,
+		},
+		CustomerEmail: engagement.Email,
+		LineItems: []stripe.CheckoutLineItem{{
+			ProductName: "Deposit: " + engagement.ProjectType,
+			Quantity:    1,
+			UnitPrice:   int64(math.Round(quote.Deposit * 100)), // Convert to cents
+		}},
+		DigitalOnly: true,
+		SuccessURL:  h.quoteURL(token) + "&paid=1",
+		CancelURL:   h.quoteURL(token),
+	})
+	if err != nil {
+		h.logger.Error("Failed to create deposit checkout [request_id: "+requestID+"]", err)
+		apierrors.RespondError(w, http.StatusBadGateway, "Failed to create checkout session", apierrors.ErrCodeExternalAPIError, nil, requestID)
+		return
+	}
+
+	if err := service.SetCheckoutSession(quote.ID, sessionID); err != nil {
+		log.Printf("Failed to record checkout session %s for quote %s: %v", sessionID, quote.ID, err)
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, CreateCheckoutResponse{SessionID: sessionID})
+}
+
+// handleDepositCompleted records a paid quote deposit, booking the engagement
+// Called from the Stripe webhook for checkout sessions of type deposit.
+func (h *Handler) handleDepositCompleted(session *stripeLib.CheckoutSession) {
+	quoteID := session.Metadata["quote_id"]
+	paymentIntentID := ""
+	if session.PaymentIntent != nil {
+		paymentIntentID = session.PaymentIntent.ID
+	}
+
+	service := engagements.NewService(h.db)
+	quote, first, err := service.RecordDeposit(quoteID, session.ID, paymentIntentID)
+	if err != nil {
+		h.logger.Critical("Failed to record deposit for quote "+quoteID, err, map[string]interface{}{
+			"session_id":        session.ID,
+			"payment_intent_id": paymentIntentID,
+		})
+		return
+	}
+	if !first {
+		log.Printf("Deposit for quote %s already recorded, skipping", quoteID)
+		return
+	}
+	log.Printf("Deposit paid for quote %s, engagement %s booked", quote.ID, quote.EngagementID)
+
+	engagement, err := service.Get(quote.EngagementID)
+	if err != nil {
+		log.Printf("Failed to get engagement %s: %v", quote.EngagementID, err)
+		return
+	}
+	go h.sendDepositEmails(engagement, quote, float64(session.AmountTotal)/100)
+}
+
+// sendDepositEmails lets the admin know a deposit came in and sends the client a receipt
+func (h *Handler) sendDepositEmails(engagement *models.Engagement, quote *models.Quote, paid float64) {
+	details := email.DetailRow("Client:", html.EscapeString(engagement.Name)) +
+		email.DetailRow("Email:", html.EscapeString(engagement.Email)) +
+		email.DetailRow("Project type:", html.EscapeString(engagement.ProjectType)) +
+		email.DetailRow("Deposit paid:", fmt.Sprintf("$%.2f", paid)) +
+		email.DetailRow("Quote total:", fmt.Sprintf("$%.2f", quote.Total)) +
+		email.DetailRow("Balance due:", fmt.Sprintf("$%.2f", quote.Total-quote.Deposit))
+
+	if h.config.AdminEmail != "" {
+		contentHTML := fmt.Sprintf(`<p style="font-size:16px;">%s paid the deposit on their quote. The engagement is now booked.</p>%s`,
+			html.EscapeString(engagement.Name),
+			email.InfoBox("Deposit Details", details+email.DetailRow("Payment Intent:", quote.StripePaymentIntentID)),
+		)
+		htmlBody := email.EmailLayout("Deposit Paid", "&#128176;", contentHTML, true)
+		subject := fmt.Sprintf("Deposit paid: $%.2f from %s", paid, engagement.Name)
+
+		if err := h.emailClient.SendHTMLEmailReplyTo(h.config.AdminEmail, engagement.Email, subject, htmlBody); err != nil {
+			log.Printf("Failed to send deposit alert for quote %s: %v", quote.ID, err)
+		}
+	}
+
+	contentHTML := fmt.Sprintf(`<p style="font-size:16px;">%s,</p>
+<p style="font-size:16px;">Thanks, we've received your deposit and your %s project is booked in. We'll be in touch about next steps.</p>%s%s`,
+		greetingFor(engagement.Name),
+		html.EscapeString(engagement.ProjectType),
+		email.InfoBox("Receipt", details),
+		email.CTAButton("View your quote", h.quoteURL(quote.Token)),
+	)
+	htmlBody := email.EmailLayout("Deposit Received", "&#10003;", contentHTML, false)
+
+	if err := h.emailClient.SendHTMLEmail(engagement.Email, "Your "+brandName+" booking is confirmed", htmlBody); err != nil {
+		log.Printf("Failed to send deposit receipt for quote %s: %v", quote.ID, err)
+	}
+}
+
+// respondEngagementError maps engagement service errors to API responses
+// resource names what wasn't found for sql.ErrNoRows.
+func (h *Handler) respondEngagementError(w http.ResponseWriter, err error, resource, requestID string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		apierrors.RespondNotFound(w, resource, requestID)
+	case errors.Is(err, engagements.ErrInquiryNotFound):
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "inquiry_id", Message: err.Error()}}, requestID)
+	case errors.Is(err, engagements.ErrInvalidStatus):
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "status", Message: err.Error()}}, requestID)
+	case errors.Is(err, engagements.ErrInvalidDeposit):
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "deposit", Message: err.Error()}}, requestID)
+	case errors.Is(err, engagements.ErrAlreadyEngaged):
+		apierrors.RespondError(w, http.StatusConflict, "This inquiry already has an engagement", apierrors.ErrCodeConflict, nil, requestID)
+	case errors.Is(err, engagements.ErrClosed):
+		apierrors.RespondError(w, http.StatusConflict, "This engagement is no longer open for quotes", apierrors.ErrCodeConflict, nil, requestID)
+	case errors.Is(err, engagements.ErrQuoteExpired):
+		apierrors.RespondError(w, http.StatusConflict, "This quote has expired", apierrors.ErrCodeConflict, nil, requestID)
+	case errors.Is(err, engagements.ErrQuotePaid):
+		apierrors.RespondError(w, http.StatusConflict, "The deposit on this quote has already been paid", apierrors.ErrCodeConflict, nil, requestID)
+	default:
+		h.logger.Error("Engagement request failed [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+	}
+}
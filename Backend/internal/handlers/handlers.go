@@ -8,13 +8,17 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/nessieaudio/ecommerce-backend/internal/audio"
 	"github.com/nessieaudio/ecommerce-backend/internal/config"
+	"github.com/nessieaudio/ecommerce-backend/internal/imaging"
 	"github.com/nessieaudio/ecommerce-backend/internal/logger"
 	"github.com/nessieaudio/ecommerce-backend/internal/middleware"
 	"github.com/nessieaudio/ecommerce-backend/internal/services/email"
 	"github.com/nessieaudio/ecommerce-backend/internal/services/order"
 	"github.com/nessieaudio/ecommerce-backend/internal/services/printful"
 	"github.com/nessieaudio/ecommerce-backend/internal/services/stripe"
+	"github.com/nessieaudio/ecommerce-backend/internal/signing"
+	"github.com/nessieaudio/ecommerce-backend/internal/sitemap"
 )
 
 // Handler holds all dependencies for HTTP handlers
@@ -25,6 +29,10 @@ type Handler struct {
 	stripeClient   *stripe.Client
 	orderService   *order.Service
 	emailClient    *email.Client
+	imageService   *imaging.Service
+	sitemap        *sitemap.Generator
+	audio          *audio.Library
+	signer         *signing.Signer
 	logger         *logger.Logger
 }
 
@@ -38,15 +46,20 @@ func NewHandler(
 	emailClient *email.Client,
 	appLogger *logger.Logger,
 ) *Handler {
-	return &Handler{
+	h := &Handler{
 		db:             db,
 		config:         cfg,
 		printfulClient: printfulClient,
 		stripeClient:   stripeClient,
 		orderService:   orderService,
 		emailClient:    emailClient,
+		imageService:   imaging.NewService(cfg.StaticDir, cfg.ImageCacheDir),
+		audio:          audio.NewLibrary(db, cfg.MusicDir),
+		signer:         signing.NewSigner(cfg.LinkSigningSecret),
 		logger:         appLogger,
 	}
+	h.sitemap = sitemap.NewGenerator(db, cfg.StaticDir, h.getBaseURL())
+	return h
 }
 
 // RegisterRoutes registers all API routes with appropriate rate limiting
@@ -63,21 +76,82 @@ func (h *Handler) RegisterRoutes(r *mux.Router) {
 	// General API - moderate limits (60 tokens, refill 1/sec = ~60/min)
 	generalLimiter := middleware.RateLimit(60, 1.0)
 
+	// Contact form - very strict (3 tokens, refill 1 per 5 min) since each one sends email
+	contactLimiter := middleware.RateLimit(3, 1.0/300)
+
 	// API v1 routes
 	api := r.PathPrefix("/api/v1").Subrouter()
 
 	// Products - Public read endpoints (higher limit)
 	api.Handle("/products", publicLimiter(http.HandlerFunc(h.GetProducts))).Methods("GET")
 	api.Handle("/products/{id}", publicLimiter(http.HandlerFunc(h.GetProduct))).Methods("GET")
+	api.Handle("/products/{id}/structured-data", publicLimiter(http.HandlerFunc(h.GetProductStructuredData))).Methods("GET")
+
+	// Reviews - Strict limits (submitted from the link in a review invitation)
+	api.Handle("/products/{id}/reviews", checkoutLimiter(http.HandlerFunc(h.SubmitReview))).Methods("POST")
+
+	// Wishlists - keyed by the private token, or the share token for the read-only view
+	api.Handle("/wishlists", generalLimiter(http.HandlerFunc(h.CreateWishlist))).Methods("POST")
+	api.Handle("/wishlists/shared/{share_token}", publicLimiter(http.HandlerFunc(h.GetSharedWishlist))).Methods("GET")
+	api.Handle("/wishlists/{token}", publicLimiter(http.HandlerFunc(h.GetWishlist))).Methods("GET")
+	api.Handle("/wishlists/{token}", generalLimiter(http.HandlerFunc(h.UpdateWishlist))).Methods("PUT")
+	api.Handle("/wishlists/{token}/items", generalLimiter(http.HandlerFunc(h.AddWishlistItem))).Methods("POST")
+	api.Handle("/wishlists/{token}/items/{variant_id}", generalLimiter(http.HandlerFunc(h.RemoveWishlistItem))).Methods("DELETE")
+
+	// Categories and collections - Public read endpoints
+	api.Handle("/categories", publicLimiter(http.HandlerFunc(h.GetCategories))).Methods("GET")
+	api.Handle("/collections", publicLimiter(http.HandlerFunc(h.GetCollections))).Methods("GET")
+	api.Handle("/collections/{slug}", publicLimiter(http.HandlerFunc(h.GetCollection))).Methods("GET")
+
+	// Resized product photos (widths from imaging.Widths only)
+	api.Handle("/images/{width:[0-9]+}/{path:.+}", publicLimiter(http.HandlerFunc(h.GetResizedImage))).Methods("GET", "HEAD")
+
+	// Portfolio player: catalogue, streams (previews only for tracks on sale) and play counts
+	api.Handle("/audio/tracks", publicLimiter(http.HandlerFunc(h.GetTracks))).Methods("GET")
+	api.Handle("/audio/tracks/{id}", publicLimiter(http.HandlerFunc(h.GetTrack))).Methods("GET")
+	api.Handle("/audio/tracks/{id}/stream", publicLimiter(http.HandlerFunc(h.StreamTrack))).Methods("GET", "HEAD")
+	api.Handle("/audio/tracks/{id}/preview", publicLimiter(http.HandlerFunc(h.StreamTrackPreview))).Methods("GET", "HEAD")
+	api.Handle("/audio/tracks/{id}/artwork", publicLimiter(http.HandlerFunc(h.GetTrackArtwork))).Methods("GET", "HEAD")
+	api.Handle("/audio/tracks/{id}/plays", generalLimiter(http.HandlerFunc(h.RecordTrackPlay))).Methods("POST")
+
+	// Ingested track metadata: tags, durations and waveforms read from the files
+	api.Handle("/tracks", publicLimiter(http.HandlerFunc(h.GetIngestedTracks))).Methods("GET")
+	api.Handle("/tracks/{id}", publicLimiter(http.HandlerFunc(h.GetIngestedTrack))).Methods("GET")
+	api.Handle("/tracks/{id}/artwork", publicLimiter(http.HandlerFunc(h.GetIngestedTrackArtwork))).Methods("GET", "HEAD")
+
+	// Tour dates - Public read endpoints
+	api.Handle("/shows", publicLimiter(http.HandlerFunc(h.GetShows))).Methods("GET")
+	api.Handle("/shows/structured-data", publicLimiter(http.HandlerFunc(h.GetShowsStructuredData))).Methods("GET")
+	api.Handle("/shows/{id}.ics", publicLimiter(http.HandlerFunc(h.GetShowCalendar))).Methods("GET", "HEAD")
+	api.Handle("/shows/{id}", publicLimiter(http.HandlerFunc(h.GetShow))).Methods("GET")
+
+	// Booking inquiries from the home page form
+	api.Handle("/contact", contactLimiter(http.HandlerFunc(h.SubmitContact))).Methods("POST", "OPTIONS")
+
+	// Mailing list: double opt-in sign-up and one-click unsubscribe (RFC 8058)
+	api.Handle("/subscribe", contactLimiter(http.HandlerFunc(h.Subscribe))).Methods("POST", "OPTIONS")
+	api.Handle("/subscribe/confirm/{token}", generalLimiter(http.HandlerFunc(h.ConfirmSubscription))).Methods("POST", "OPTIONS")
+	api.Handle("/unsubscribe/{token}", generalLimiter(http.HandlerFunc(h.Unsubscribe))).Methods("POST", "OPTIONS")
+
+	// Campaign open pixel and click redirects
+	api.Handle("/email/open/{token}.gif", publicLimiter(http.HandlerFunc(h.TrackCampaignOpen))).Methods("GET")
+	api.Handle("/email/click/{token}/{link}", publicLimiter(http.HandlerFunc(h.TrackCampaignClick))).Methods("GET")
 
 	// Orders - Moderate limits
 	api.Handle("/orders", checkoutLimiter(http.HandlerFunc(h.CreateOrder))).Methods("POST")
 	api.Handle("/orders/{id}", generalLimiter(http.HandlerFunc(h.GetOrder))).Methods("GET")
 
+	// Digital downloads (signed links from the confirmation email and order view)
+	api.Handle("/downloads/{token}", generalLimiter(http.HandlerFunc(h.DownloadFile))).Methods("GET", "HEAD")
+
 	// Checkout - Strict limits (most important to protect)
 	api.Handle("/checkout", checkoutLimiter(http.HandlerFunc(h.CreateCheckout))).Methods("POST", "OPTIONS")
 	api.Handle("/cart/checkout", checkoutLimiter(http.HandlerFunc(h.CreateCartCheckout))).Methods("POST", "OPTIONS")
 
+	// Booking quotes: the client's view of a quote and checkout for its deposit
+	api.Handle("/quotes/{token}", publicLimiter(http.HandlerFunc(h.GetQuote))).Methods("GET")
+	api.Handle("/quotes/{token}/checkout", checkoutLimiter(http.HandlerFunc(h.CreateQuoteCheckout))).Methods("POST", "OPTIONS")
+
 	// Config - General limits
 	api.Handle("/config", generalLimiter(http.HandlerFunc(h.GetConfig))).Methods("GET")
 
@@ -88,6 +162,83 @@ func (h *Handler) RegisterRoutes(r *mux.Router) {
 	api.Handle("/inventory/{variant_id}", generalLimiter(http.HandlerFunc(h.UpdateVariantInventory))).Methods("PUT")
 	api.Handle("/inventory/{variant_id}/check", generalLimiter(http.HandlerFunc(h.CheckVariantStock))).Methods("GET")
 
+	// Admin - Bearer token (ADMIN_API_KEY) plus general limits
+	admin := api.PathPrefix("/admin").Subrouter()
+	admin.Use(middleware.AdminAuth(h.config.AdminAPIKey))
+	admin.Use(generalLimiter)
+
+	admin.HandleFunc("/inventory/{variant_id}/preorder", h.UpdateVariantPreorder).Methods("PUT")
+	admin.HandleFunc("/preorders", h.GetHeldPreorders).Methods("GET")
+	admin.HandleFunc("/orders/{id}/release", h.ReleasePreorder).Methods("POST")
+	admin.HandleFunc("/catalog/sync", h.SyncCatalog).Methods("POST")
+	admin.HandleFunc("/catalog/sync", h.GetLastCatalogSync).Methods("GET")
+	admin.HandleFunc("/margins", h.GetMargins).Methods("GET")
+	admin.HandleFunc("/pricing-rules", h.GetPricingRules).Methods("GET")
+	admin.HandleFunc("/pricing-rules", h.SavePricingRule).Methods("POST")
+	admin.HandleFunc("/pricing-rules/{id}", h.DeletePricingRule).Methods("DELETE")
+	admin.HandleFunc("/variants/{id}/price", h.UpdateVariantPrice).Methods("PUT")
+	admin.HandleFunc("/reports/profit", h.GetProfitReport).Methods("GET")
+	admin.HandleFunc("/products/{id}/images", h.GetProductImages).Methods("GET")
+	admin.HandleFunc("/products/{id}/images", h.AddProductImage).Methods("POST")
+	admin.HandleFunc("/images/missing-alt", h.GetImagesMissingAltText).Methods("GET")
+	admin.HandleFunc("/images/{id}", h.UpdateProductImage).Methods("PUT")
+	admin.HandleFunc("/images/{id}", h.DeleteProductImage).Methods("DELETE")
+	admin.HandleFunc("/categories", h.GetAdminCategories).Methods("GET")
+	admin.HandleFunc("/categories", h.CreateCategory).Methods("POST")
+	admin.HandleFunc("/categories/{id}", h.UpdateCategory).Methods("PUT")
+	admin.HandleFunc("/categories/{id}", h.DeleteCategory).Methods("DELETE")
+	admin.HandleFunc("/products/{id}/category", h.UpdateProductCategory).Methods("PUT")
+	admin.HandleFunc("/collections", h.GetAdminCollections).Methods("GET")
+	admin.HandleFunc("/collections", h.CreateCollection).Methods("POST")
+	admin.HandleFunc("/collections/{id}", h.GetAdminCollection).Methods("GET")
+	admin.HandleFunc("/collections/{id}", h.UpdateCollection).Methods("PUT")
+	admin.HandleFunc("/collections/{id}", h.DeleteCollection).Methods("DELETE")
+	admin.HandleFunc("/collections/{id}/products", h.SetCollectionProducts).Methods("PUT")
+	admin.HandleFunc("/bundles", h.GetAdminBundles).Methods("GET")
+	admin.HandleFunc("/bundles", h.CreateBundle).Methods("POST")
+	admin.HandleFunc("/bundles/{id}", h.GetAdminBundle).Methods("GET")
+	admin.HandleFunc("/bundles/{id}", h.UpdateBundle).Methods("PUT")
+	admin.HandleFunc("/digital-products", h.GetAdminDigitalProducts).Methods("GET")
+	admin.HandleFunc("/digital-products", h.CreateDigitalProduct).Methods("POST")
+	admin.HandleFunc("/digital-products/{id}", h.GetAdminDigitalProduct).Methods("GET")
+	admin.HandleFunc("/digital-products/{id}", h.UpdateDigitalProduct).Methods("PUT")
+	admin.HandleFunc("/reviews", h.GetAdminReviews).Methods("GET")
+	admin.HandleFunc("/reviews/{id}", h.ModerateReview).Methods("PUT")
+	admin.HandleFunc("/reviews/{id}", h.DeleteReview).Methods("DELETE")
+	admin.HandleFunc("/audio/tracks/{id}/preview", h.SetTrackPreview).Methods("PUT")
+	admin.HandleFunc("/audio/tracks/{id}/preview", h.DeleteTrackPreview).Methods("DELETE")
+	admin.HandleFunc("/audio/ingest", h.IngestTracks).Methods("POST")
+	admin.HandleFunc("/shows", h.GetAdminShows).Methods("GET")
+	admin.HandleFunc("/shows", h.CreateShow).Methods("POST")
+	admin.HandleFunc("/shows/{id}", h.UpdateShow).Methods("PUT")
+	admin.HandleFunc("/shows/{id}", h.DeleteShow).Methods("DELETE")
+	admin.HandleFunc("/inquiries", h.GetAdminInquiries).Methods("GET")
+	admin.HandleFunc("/inquiries/{id}", h.UpdateInquiry).Methods("PUT")
+	admin.HandleFunc("/engagements", h.GetAdminEngagements).Methods("GET")
+	admin.HandleFunc("/engagements", h.CreateEngagement).Methods("POST")
+	admin.HandleFunc("/engagements/{id}", h.GetAdminEngagement).Methods("GET")
+	admin.HandleFunc("/engagements/{id}", h.UpdateEngagement).Methods("PUT")
+	admin.HandleFunc("/engagements/{id}/quotes", h.CreateQuote).Methods("POST")
+	admin.HandleFunc("/quotes/{id}/send", h.SendQuote).Methods("POST")
+	admin.HandleFunc("/subscribers", h.GetAdminSubscribers).Methods("GET")
+	admin.HandleFunc("/subscribers/export", h.ExportSubscribers).Methods("GET")
+	admin.HandleFunc("/campaigns", h.GetAdminCampaigns).Methods("GET")
+	admin.HandleFunc("/campaigns", h.CreateCampaign).Methods("POST")
+	admin.HandleFunc("/campaigns/{id}", h.GetAdminCampaign).Methods("GET")
+	admin.HandleFunc("/campaigns/{id}", h.UpdateCampaign).Methods("PUT")
+	admin.HandleFunc("/campaigns/{id}", h.DeleteCampaign).Methods("DELETE")
+	admin.HandleFunc("/campaigns/{id}/preview", h.PreviewCampaign).Methods("GET")
+	admin.HandleFunc("/campaigns/{id}/test", h.SendTestCampaign).Methods("POST")
+	admin.HandleFunc("/campaigns/{id}/schedule", h.ScheduleCampaign).Methods("POST")
+	admin.HandleFunc("/campaigns/{id}/cancel", h.CancelCampaign).Methods("POST")
+	admin.HandleFunc("/campaigns/{id}/recipients", h.GetCampaignRecipients).Methods("GET")
+	admin.HandleFunc("/emails", h.GetAdminEmails).Methods("GET")
+	admin.HandleFunc("/emails/{id}/resend", h.ResendEmail).Methods("POST")
+	admin.HandleFunc("/drops", h.GetAdminDrops).Methods("GET")
+	admin.HandleFunc("/drops", h.CreateDrop).Methods("POST")
+	admin.HandleFunc("/drops/{id}", h.UpdateDrop).Methods("PUT")
+	admin.HandleFunc("/drops/{id}", h.DeleteDrop).Methods("DELETE")
+
 	// Webhooks - NO rate limiting (Stripe/Printful need reliable delivery)
 	r.HandleFunc("/webhooks/stripe", h.HandleStripeWebhook).Methods("POST")
 	r.HandleFunc("/webhooks/printful/{token}", h.HandlePrintfulWebhook).Methods("POST")
@@ -95,8 +246,14 @@ func (h *Handler) RegisterRoutes(r *mux.Router) {
 	// Health check - NO rate limiting (used for monitoring)
 	r.HandleFunc("/health", h.HealthCheck).Methods("GET")
 
-	// Sitemap - NO rate limiting (used by search engines)
-	r.HandleFunc("/sitemap.xml", h.GetSitemap).Methods("GET")
+	// Sitemap and robots.txt - NO rate limiting (used by search engines)
+	r.HandleFunc("/sitemap.xml", h.GetSitemap).Methods("GET", "HEAD")
+	r.HandleFunc("/sitemap-{n:[0-9]+}.xml", h.GetSitemapPart).Methods("GET", "HEAD")
+	r.HandleFunc("/robots.txt", h.GetRobotsTxt).Methods("GET", "HEAD")
+
+	// Tour feeds - NO rate limiting (polled by calendar apps and feed readers)
+	r.HandleFunc("/tour.ics", h.GetTourCalendar).Methods("GET", "HEAD")
+	r.HandleFunc("/tour.rss", h.GetTourRSS).Methods("GET", "HEAD")
 }
 
 // respondJSON writes a JSON response
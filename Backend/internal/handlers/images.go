@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/nessieaudio/ecommerce-backend/internal/catalog"
+	apierrors "github.com/nessieaudio/ecommerce-backend/internal/errors"
+	"github.com/nessieaudio/ecommerce-backend/internal/middleware"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+// GetProductImages lists a product's gallery, including source and sort order
+// GET /api/v1/admin/products/{id}/images
+func (h *Handler) GetProductImages(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	productID := mux.Vars(r)["id"]
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	images, err := catalogService.ListImages(productID)
+	if err != nil {
+		h.logger.Error("Failed to fetch product images [request_id: "+requestID+", product_id: "+productID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"images": images,
+		"count":  len(images),
+	})
+}
+
+// GetImagesMissingAltText lists gallery images without alt text
+// GET /api/v1/admin/images/missing-alt
+func (h *Handler) GetImagesMissingAltText(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	images, err := catalogService.ListImagesMissingAltText()
+	if err != nil {
+		h.logger.Error("Failed to fetch images missing alt text [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"images": images,
+		"count":  len(images),
+	})
+}
+
+// AddProductImageRequest adds an image to a product's gallery
+type AddProductImageRequest struct {
+	URL       string  `json:"url"`
+	AltText   string  `json:"alt_text"`
+	SortOrder int     `json:"sort_order"`
+	VariantID *string `json:"variant_id"`
+}
+
+// AddProductImage adds an image to a product's gallery
+// POST /api/v1/admin/products/{id}/images
+//
+// Request: { "url": "/Product Photos/...", "alt_text": "...", "sort_order": 1, "variant_id": null }
+func (h *Handler) AddProductImage(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	productID := mux.Vars(r)["id"]
+
+	var req AddProductImageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+
+	var validationErrors []apierrors.ValidationError
+	if strings.TrimSpace(req.URL) == "" {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "url", Message: "is required"})
+	}
+	if strings.TrimSpace(req.AltText) == "" {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "alt_text", Message: "is required"})
+	}
+	if len(validationErrors) > 0 {
+		apierrors.RespondValidationError(w, validationErrors, requestID)
+		return
+	}
+
+	image := models.ProductImage{
+		ProductID: productID,
+		VariantID: req.VariantID,
+		URL:       strings.TrimSpace(req.URL),
+		AltText:   strings.TrimSpace(req.AltText),
+		SortOrder: req.SortOrder,
+	}
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	err := catalogService.AddImage(&image)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierrors.RespondNotFound(w, "Product", requestID)
+		return
+	}
+	if h.respondImageError(w, err, requestID) {
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusCreated, image)
+}
+
+// UpdateProductImage edits an image's alt text, sort order or variant
+// PUT /api/v1/admin/images/{id}
+//
+// Request: { "alt_text": "...", "sort_order": 2, "variant_id": "" }
+func (h *Handler) UpdateProductImage(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	imageID := mux.Vars(r)["id"]
+
+	var update catalog.ImageUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	image, err := catalogService.UpdateImage(imageID, update)
+	if h.respondImageError(w, err, requestID) {
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, image)
+}
+
+// DeleteProductImage removes an image from a gallery
+// DELETE /api/v1/admin/images/{id}
+func (h *Handler) DeleteProductImage(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	imageID := mux.Vars(r)["id"]
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	err := catalogService.DeleteImage(imageID)
+	if h.respondImageError(w, err, requestID) {
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Image deleted",
+		"id":      imageID,
+	})
+}
+
+// respondImageError writes the response for a failed image operation
+// Returns false (and writes nothing) if err is nil
+func (h *Handler) respondImageError(w http.ResponseWriter, err error, requestID string) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, sql.ErrNoRows):
+		apierrors.RespondNotFound(w, "Product image", requestID)
+	case errors.Is(err, catalog.ErrVariantNotInProduct):
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{
+			{Field: "variant_id", Message: err.Error()},
+		}, requestID)
+	default:
+		h.logger.Error("Product image operation failed [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+	}
+	return true
+}
@@ -36,15 +36,15 @@ func (h *Handler) GetInventoryStatus(w http.ResponseWriter, r *http.Request) {
 	defer rows.Close()
 
 	type InventoryItem struct {
-		VariantID         string  `json:"variant_id"`
-		VariantName       string  `json:"variant_name"`
-		ProductID         string  `json:"product_id"`
-		ProductName       string  `json:"product_name"`
-		StockQuantity     *int    `json:"stock_quantity"`
-		LowStockThreshold int     `json:"low_stock_threshold"`
-		TrackInventory    bool    `json:"track_inventory"`
-		Available         bool    `json:"available"`
-		Status            string  `json:"status"` // "in_stock", "low_stock", "out_of_stock"
+		VariantID         string `json:"variant_id"`
+		VariantName       string `json:"variant_name"`
+		ProductID         string `json:"product_id"`
+		ProductName       string `json:"product_name"`
+		StockQuantity     *int   `json:"stock_quantity"`
+		LowStockThreshold int    `json:"low_stock_threshold"`
+		TrackInventory    bool   `json:"track_inventory"`
+		Available         bool   `json:"available"`
+		Status            string `json:"status"` // "in_stock", "low_stock", "out_of_stock", "backordered"
 	}
 
 	var items []InventoryItem
@@ -73,7 +73,9 @@ func (h *Handler) GetInventoryStatus(w http.ResponseWriter, r *http.Request) {
 			item.StockQuantity = &qty
 
 			// Determine status
-			if qty == 0 {
+			if qty < 0 {
+				item.Status = "backordered" // Pre-order units sold past zero
+			} else if qty == 0 {
 				item.Status = "out_of_stock"
 			} else if qty <= item.LowStockThreshold {
 				item.Status = "low_stock"
@@ -183,11 +185,13 @@ func (h *Handler) CheckVariantStock(w http.ResponseWriter, r *http.Request) {
 	}
 
 	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"variant_id":       stockCheck.VariantID,
-		"requested_qty":    stockCheck.RequestedQty,
-		"available":        stockCheck.Available,
-		"stock_quantity":   stockCheck.StockQuantity,
-		"track_inventory":  stockCheck.TrackInventory,
+		"variant_id":         stockCheck.VariantID,
+		"requested_qty":      stockCheck.RequestedQty,
+		"available":          stockCheck.Available,
+		"stock_quantity":     stockCheck.StockQuantity,
+		"track_inventory":    stockCheck.TrackInventory,
+		"preorder":           stockCheck.Preorder,
+		"expected_ship_date": stockCheck.ExpectedShipDate,
 	})
 }
 
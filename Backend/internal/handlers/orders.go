@@ -8,7 +8,10 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/nessieaudio/ecommerce-backend/internal/downloads"
 	"github.com/nessieaudio/ecommerce-backend/internal/models"
+	"github.com/nessieaudio/ecommerce-backend/internal/services/order"
+	"github.com/nessieaudio/ecommerce-backend/internal/tour"
 )
 
 // CreateOrderRequest represents the request to create an order
@@ -52,6 +55,12 @@ func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	drops, err := tour.NewService(h.db).Availability(time.Now())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to check availability")
+		return
+	}
+
 	// Calculate total and prepare order items
 	var totalAmount float64
 	orderItems := make([]models.OrderItem, 0, len(req.Items))
@@ -59,14 +68,14 @@ func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 	for _, item := range req.Items {
 		// Get variant details
 		var variantPrice float64
-		var productName, variantName string
+		var productID, productName, variantName, productType string
 
 		err := h.db.QueryRow(`
-			SELECT v.price, p.name, v.name
+			SELECT v.price, p.id, p.name, v.name, p.product_type
 			FROM variants v
 			JOIN products p ON v.product_id = p.id
 			WHERE v.id = ? AND v.available = 1
-		`, item.VariantID).Scan(&variantPrice, &productName, &variantName)
+		`, item.VariantID).Scan(&variantPrice, &productID, &productName, &variantName, &productType)
 
 		if err == sql.ErrNoRows {
 			respondError(w, http.StatusBadRequest, "Variant not available")
@@ -76,21 +85,26 @@ func (h *Handler) CreateOrder(w http.ResponseWriter, r *http.Request) {
 			respondError(w, http.StatusInternalServerError, "Failed to fetch variant")
 			return
 		}
+		if !drops.CanBuy(productID, item.VariantID) {
+			respondError(w, http.StatusConflict, productName+" - "+variantName+" is a tour exclusive and is not on sale right now")
+			return
+		}
 
 		itemTotal := variantPrice * float64(item.Quantity)
 		totalAmount += itemTotal
 
 		orderItems = append(orderItems, models.OrderItem{
-			ID:          uuid.New().String(),
-			OrderID:     "", // Will be set below
-			ProductID:   item.ProductID,
-			VariantID:   item.VariantID,
-			Quantity:    item.Quantity,
-			UnitPrice:   variantPrice,
-			TotalPrice:  itemTotal,
-			ProductName: productName,
-			VariantName: variantName,
-			CreatedAt:   time.Now(),
+			ID:                 uuid.New().String(),
+			OrderID:            "", // Will be set below
+			ProductID:          item.ProductID,
+			VariantID:          item.VariantID,
+			Quantity:           item.Quantity,
+			UnitPrice:          variantPrice,
+			TotalPrice:         itemTotal,
+			ProductName:        productName,
+			VariantName:        variantName,
+			FulfillmentChannel: order.ChannelForProductType(productType),
+			CreatedAt:          time.Now(),
 		})
 	}
 
@@ -144,9 +158,25 @@ func (h *Handler) GetOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Links for the digital items, once the order is paid
+	downloadService := downloads.NewService(h.db, h.signer, h.config.DigitalFilesDir)
+	orderDownloads, err := downloadService.ForOrder(orderID, h.getAPIBaseURL())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch downloads")
+		return
+	}
+
+	fulfillments, err := h.orderService.GetFulfillments(orderID)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to fetch fulfillments")
+		return
+	}
+
 	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"order": order,
-		"items": items,
+		"order":        order,
+		"items":        items,
+		"fulfillments": fulfillments,
+		"downloads":    orderDownloads,
 	})
 }
 
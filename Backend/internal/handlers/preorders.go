@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	apierrors "github.com/nessieaudio/ecommerce-backend/internal/errors"
+	"github.com/nessieaudio/ecommerce-backend/internal/inventory"
+	"github.com/nessieaudio/ecommerce-backend/internal/middleware"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+// UpdatePreorderRequest configures pre-order selling for a variant
+type UpdatePreorderRequest struct {
+	PreorderEnabled  bool   `json:"preorder_enabled"`
+	PreorderLimit    int    `json:"preorder_limit"`
+	PreorderShipDate string `json:"preorder_ship_date"` // YYYY-MM-DD
+}
+
+// UpdateVariantPreorder enables or disables pre-orders for a tracked variant
+// PUT /api/v1/admin/inventory/{variant_id}/preorder
+//
+// Request: { "preorder_enabled": true, "preorder_limit": 50, "preorder_ship_date": "2026-03-01" }
+func (h *Handler) UpdateVariantPreorder(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	variantID := mux.Vars(r)["variant_id"]
+
+	var req UpdatePreorderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+
+	var validationErrors []apierrors.ValidationError
+	if req.PreorderLimit < 0 {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "preorder_limit", Message: "must not be negative"})
+	}
+
+	var shipDate *time.Time
+	if req.PreorderShipDate != "" {
+		parsed, err := time.Parse("2006-01-02", req.PreorderShipDate)
+		if err != nil {
+			validationErrors = append(validationErrors, apierrors.ValidationError{Field: "preorder_ship_date", Message: "must be a date in YYYY-MM-DD format"})
+		} else {
+			shipDate = &parsed
+		}
+	} else if req.PreorderEnabled {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "preorder_ship_date", Message: "required when preorder_enabled is true"})
+	}
+
+	if len(validationErrors) > 0 {
+		apierrors.RespondValidationError(w, validationErrors, requestID)
+		return
+	}
+
+	inventoryService := inventory.NewService(h.db)
+	err := inventoryService.UpdatePreorder(variantID, req.PreorderEnabled, req.PreorderLimit, shipDate)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierrors.RespondNotFound(w, "Variant", requestID)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to update preorder settings [request_id: "+requestID+", variant_id: "+variantID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"message":            "Pre-order settings updated successfully",
+		"variant_id":         variantID,
+		"preorder_enabled":   req.PreorderEnabled,
+		"preorder_limit":     req.PreorderLimit,
+		"preorder_ship_date": shipDate,
+	})
+}
+
+// GetHeldPreorders lists paid orders held from Printful until their pre-order release
+// GET /api/v1/admin/preorders
+func (h *Handler) GetHeldPreorders(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	held, err := h.orderService.GetHeldOrders()
+	if err != nil {
+		h.logger.Error("Failed to fetch held preorders [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"held_orders": held,
+		"count":       len(held),
+	})
+}
+
+// ReleasePreorder releases a held pre-order and submits it to Printful immediately
+// POST /api/v1/admin/orders/{id}/release
+func (h *Handler) ReleasePreorder(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	orderID := mux.Vars(r)["id"]
+
+	order, err := h.orderService.GetOrder(orderID)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierrors.RespondNotFound(w, "Order", requestID)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch order for release [request_id: "+requestID+", order_id: "+orderID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	if order.FulfillmentHoldUntil == nil {
+		apierrors.RespondError(w, http.StatusConflict, "Order is not held", apierrors.ErrCodeConflict, nil, requestID)
+		return
+	}
+	// The Printful fulfillment is what is held; other channels are fulfilled already
+	fulfillment, err := h.orderService.GetFulfillment(orderID, models.FulfillmentChannelPrintful)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		h.logger.Error("Failed to fetch fulfillment for release [request_id: "+requestID+", order_id: "+orderID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+	if err != nil || fulfillment.Status != models.FulfillmentStatusPending {
+		apierrors.RespondError(w, http.StatusConflict, "Only paid orders awaiting Printful can be released", apierrors.ErrCodeConflict, nil, requestID)
+		return
+	}
+
+	if err := h.releasePreorder(orderID); err != nil {
+		h.logger.Error("Failed to release preorder [request_id: "+requestID+", order_id: "+orderID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]string{
+		"message":  "Order released for fulfillment",
+		"order_id": orderID,
+	})
+}
+
+// releasePreorder clears an order's hold and submits it to Printful in the background
+func (h *Handler) releasePreorder(orderID string) error {
+	if err := h.orderService.ReleaseFulfillmentHold(orderID); err != nil {
+		return err
+	}
+
+	log.Printf("Pre-order %s released - submitting to Printful", orderID)
+	go h.submitOrderToPrintful(orderID)
+	return nil
+}
+
+// ReleaseDuePreorders submits every held order whose release date has arrived
+func (h *Handler) ReleaseDuePreorders() {
+	due, err := h.orderService.GetReleasableOrders(time.Now())
+	if err != nil {
+		log.Printf("Failed to check for releasable preorders: %v", err)
+		return
+	}
+
+	for _, held := range due {
+		if err := h.releasePreorder(held.OrderID); err != nil {
+			log.Printf("Failed to release preorder %s: %v", held.OrderID, err)
+		}
+	}
+}
+
+// StartPreorderReleaser checks for due pre-orders on a fixed interval
+func (h *Handler) StartPreorderReleaser(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			h.ReleaseDuePreorders()
+		}
+	}()
+
+	log.Printf("Pre-order releaser started (every %v)", interval)
+}
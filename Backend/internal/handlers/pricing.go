@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/nessieaudio/ecommerce-backend/internal/catalog"
+	apierrors "github.com/nessieaudio/ecommerce-backend/internal/errors"
+	"github.com/nessieaudio/ecommerce-backend/internal/middleware"
+)
+
+// GetMargins returns per-variant margins against Printful cost
+// GET /api/v1/admin/margins?product_id={id}
+func (h *Handler) GetMargins(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	margins, err := catalogService.GetVariantMargins(r.URL.Query().Get("product_id"))
+	if err != nil {
+		h.logger.Error("Failed to fetch margins [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"variants": margins,
+		"count":    len(margins),
+	})
+}
+
+// GetPricingRules lists markup rules in precedence order
+// GET /api/v1/admin/pricing-rules
+func (h *Handler) GetPricingRules(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	rules, err := catalogService.ListPricingRules()
+	if err != nil {
+		h.logger.Error("Failed to fetch pricing rules [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"rules": rules,
+		"count": len(rules),
+	})
+}
+
+// SavePricingRule creates or replaces the markup rule for a scope/target and reprices the catalog
+// POST /api/v1/admin/pricing-rules
+//
+// Request: { "scope": "category", "target": "merch", "markup_type": "percent", "markup_value": 40, "rounding": "charm_99" }
+func (h *Handler) SavePricingRule(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	var rule catalog.PricingRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+	if rule.Rounding == "" {
+		rule.Rounding = catalog.RoundingNone
+	}
+
+	if err := rule.Validate(); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, err.Error(), apierrors.ErrCodeValidation, nil, requestID)
+		return
+	}
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	if err := catalogService.SavePricingRule(&rule); err != nil {
+		h.logger.Error("Failed to save pricing rule [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, rule)
+}
+
+// DeletePricingRule removes a markup rule and reprices the catalog
+// DELETE /api/v1/admin/pricing-rules/{id}
+func (h *Handler) DeletePricingRule(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ruleID := mux.Vars(r)["id"]
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	err := catalogService.DeletePricingRule(ruleID)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierrors.RespondNotFound(w, "Pricing rule", requestID)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to delete pricing rule [request_id: "+requestID+", rule_id: "+ruleID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Pricing rule deleted",
+		"id":      ruleID,
+	})
+}
+
+// UpdateVariantPriceRequest pins or clears a variant's price override
+type UpdateVariantPriceRequest struct {
+	PriceOverride *float64 `json:"price_override"` // null = price from markup rules / Printful retail
+}
+
+// UpdateVariantPrice sets or clears a variant's local price override
+// PUT /api/v1/admin/variants/{id}/price
+//
+// Request: { "price_override": 24.99 } or { "price_override": null }
+func (h *Handler) UpdateVariantPrice(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	variantID := mux.Vars(r)["id"]
+
+	var req UpdateVariantPriceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+
+	if req.PriceOverride != nil && *req.PriceOverride <= 0 {
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{
+			{Field: "price_override", Message: "must be greater than zero"},
+		}, requestID)
+		return
+	}
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	err := catalogService.SetVariantPriceOverride(variantID, req.PriceOverride)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierrors.RespondNotFound(w, "Variant", requestID)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to update variant price [request_id: "+requestID+", variant_id: "+variantID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"message":        "Variant price updated successfully",
+		"variant_id":     variantID,
+		"price_override": req.PriceOverride,
+	})
+}
+
+// GetProfitReport returns revenue, Printful cost and profit for orders in a date range
+// GET /api/v1/admin/reports/profit?from=2026-01-01&to=2026-02-01
+//
+// Defaults to the last 30 days. "to" is exclusive.
+func (h *Handler) GetProfitReport(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -30)
+
+	var validationErrors []apierrors.ValidationError
+	if v := r.URL.Query().Get("from"); v != "" {
+		parsed, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			validationErrors = append(validationErrors, apierrors.ValidationError{Field: "from", Message: "must be a date in YYYY-MM-DD format"})
+		}
+		from = parsed
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		parsed, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			validationErrors = append(validationErrors, apierrors.ValidationError{Field: "to", Message: "must be a date in YYYY-MM-DD format"})
+		}
+		to = parsed
+	}
+	if len(validationErrors) > 0 {
+		apierrors.RespondValidationError(w, validationErrors, requestID)
+		return
+	}
+
+	report, err := h.orderService.GetProfitReport(from, to)
+	if err != nil {
+		h.logger.Error("Failed to build profit report [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, report)
+}
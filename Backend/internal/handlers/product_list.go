@@ -0,0 +1,341 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	apierrors "github.com/nessieaudio/ecommerce-backend/internal/errors"
+)
+
+// Product list sort orders (GET /api/v1/products?sort=)
+const (
+	sortNewest    = "newest"
+	sortPriceAsc  = "price_asc"
+	sortPriceDesc = "price_desc"
+	sortName      = "name"
+	sortRelevance = "relevance" // Search results only; the default when q is set
+)
+
+const (
+	defaultProductPageSize = 50
+	maxProductPageSize     = 100
+	maxSearchTerms         = 10
+)
+
+// productSortSpec is the SQL a sort order compiles to
+type productSortSpec struct {
+	key  string // Expression ordered on and compared against the cursor
+	desc bool
+}
+
+var productSorts = map[string]productSortSpec{
+	sortNewest:    {key: "p.created_at", desc: true},
+	sortPriceAsc:  {key: "COALESCE(vp.min_price, p.price)"},
+	sortPriceDesc: {key: "COALESCE(vp.min_price, p.price)", desc: true},
+	sortName:      {key: "p.name COLLATE NOCASE"},
+	sortRelevance: {key: "products_fts.rank"}, // bm25: lower is a better match
+}
+
+// productListParams are the parsed query parameters of GET /api/v1/products
+type productListParams struct {
+	Search   string // FTS5 query built from q
+	Category string
+	MinPrice *float64
+	MaxPrice *float64
+	InStock  bool
+	Sort     string
+	Limit    int
+	Cursor   *productCursor
+
+	// Tour-exclusive merch outside its sale window, from tour.Availability rather
+	// than the query string
+	ClosedProducts []string
+	ClosedVariants []string
+}
+
+// productCursor points just past the last product of a page
+// Clients treat it as opaque; it is only valid for the sort order it was issued for.
+type productCursor struct {
+	Sort  string      `json:"s"`
+	Value interface{} `json:"v"` // Sort key of the last product: string or number
+	ID    string      `json:"id"`
+}
+
+func (c *productCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeProductCursor(s string) (*productCursor, bool) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, false
+	}
+	var c productCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == "" {
+		return nil, false
+	}
+
+	// The key type must match the sort order, or the comparison silently goes wrong
+	switch c.Value.(type) {
+	case float64:
+		if c.Sort != sortPriceAsc && c.Sort != sortPriceDesc && c.Sort != sortRelevance {
+			return nil, false
+		}
+	case string:
+		if c.Sort != sortNewest && c.Sort != sortName {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+	return &c, true
+}
+
+// parseProductListParams reads and validates the product list query parameters
+func parseProductListParams(r *http.Request) (*productListParams, []apierrors.ValidationError) {
+	query := r.URL.Query()
+	params := &productListParams{
+		Category: strings.TrimSpace(query.Get("category")),
+		Limit:    defaultProductPageSize,
+	}
+	var validationErrors []apierrors.ValidationError
+
+	if q := strings.TrimSpace(query.Get("q")); q != "" {
+		if len(q) > 200 {
+			validationErrors = append(validationErrors, apierrors.ValidationError{Field: "q", Message: "must be at most 200 characters"})
+		}
+		params.Search = ftsQuery(q)
+	}
+
+	for _, field := range []struct {
+		name  string
+		value **float64
+	}{{"min_price", &params.MinPrice}, {"max_price", &params.MaxPrice}} {
+		v := query.Get(field.name)
+		if v == "" {
+			continue
+		}
+		price, err := strconv.ParseFloat(v, 64)
+		if err != nil || price < 0 {
+			validationErrors = append(validationErrors, apierrors.ValidationError{Field: field.name, Message: "must be a non-negative number"})
+			continue
+		}
+		*field.value = &price
+	}
+	if params.MinPrice != nil && params.MaxPrice != nil && *params.MinPrice > *params.MaxPrice {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "max_price", Message: "must not be less than min_price"})
+	}
+
+	if v := query.Get("in_stock"); v != "" {
+		inStock, err := strconv.ParseBool(v)
+		if err != nil {
+			validationErrors = append(validationErrors, apierrors.ValidationError{Field: "in_stock", Message: "must be true or false"})
+		}
+		params.InStock = inStock
+	}
+
+	params.Sort = query.Get("sort")
+	switch {
+	case params.Sort == "" && params.Search != "":
+		params.Sort = sortRelevance
+	case params.Sort == "":
+		params.Sort = sortNewest
+	case params.Sort == sortRelevance && params.Search == "":
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "sort", Message: "relevance requires a search query (q)"})
+	default:
+		if _, ok := productSorts[params.Sort]; !ok {
+			validationErrors = append(validationErrors, apierrors.ValidationError{
+				Field:   "sort",
+				Message: "must be one of newest, price_asc, price_desc, name, relevance",
+			})
+		}
+	}
+
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxProductPageSize {
+			validationErrors = append(validationErrors, apierrors.ValidationError{
+				Field:   "limit",
+				Message: "must be between 1 and " + strconv.Itoa(maxProductPageSize),
+			})
+		}
+		params.Limit = limit
+	}
+
+	if v := query.Get("cursor"); v != "" {
+		cursor, ok := decodeProductCursor(v)
+		if !ok || cursor.Sort != params.Sort {
+			validationErrors = append(validationErrors, apierrors.ValidationError{Field: "cursor", Message: "is invalid for this sort order"})
+		}
+		params.Cursor = cursor
+	}
+
+	return params, validationErrors
+}
+
+// ftsQuery turns free text into an FTS5 query that matches every word as a prefix,
+// e.g. `black hood` -> `"black"* "hood"*`. Quoting each term keeps FTS5 operators
+// and syntax in user input from being interpreted.
+func ftsQuery(q string) string {
+	words := strings.FieldsFunc(q, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) > maxSearchTerms {
+		words = words[:maxSearchTerms]
+	}
+
+	terms := make([]string, len(words))
+	for i, w := range words {
+		terms[i] = `"` + w + `"*`
+	}
+	return strings.Join(terms, " ")
+}
+
+// variantInStockSQL is true for a row of variants with a unit on hand. A bundle
+// is in stock when every component is on sale and has enough for one bundle.
+const variantInStockSQL = `CASE WHEN EXISTS (
+		SELECT 1 FROM bundle_components bc WHERE bc.bundle_variant_id = variants.id
+	) THEN NOT EXISTS (
+		SELECT 1 FROM bundle_components bc
+		JOIN variants c ON c.id = bc.component_variant_id
+		JOIN products cp ON cp.id = c.product_id
+		WHERE bc.bundle_variant_id = variants.id AND (
+			c.available != 1 OR cp.active != 1
+			OR (COALESCE(c.track_inventory, 0) = 1 AND COALESCE(c.stock_quantity, 0) < bc.quantity)
+		)
+	) ELSE COALESCE(track_inventory, 0) = 0 OR stock_quantity > 0 END`
+
+// dropFilterSQL hides tour-exclusive merch outside its window. variantSQL is
+// added to the variant aggregate's conditions, and its arguments come first;
+// productSQL is added to the product conditions, hiding closed product drops and
+// products whose available variants are all closed variant drops.
+func dropFilterSQL(closedProducts, closedVariants []string) (variantSQL, productSQL string, variantArgs, productArgs []interface{}) {
+	var conditions []string
+	if len(closedProducts) > 0 {
+		conditions = append(conditions, "p.id NOT IN ("+placeholderList(len(closedProducts))+")")
+		for _, id := range closedProducts {
+			productArgs = append(productArgs, id)
+		}
+	}
+	if len(closedVariants) > 0 {
+		variantSQL = " AND id NOT IN (" + placeholderList(len(closedVariants)) + ")"
+		for _, id := range closedVariants {
+			variantArgs = append(variantArgs, id)
+		}
+		conditions = append(conditions, `(vp.product_id IS NOT NULL OR NOT EXISTS (
+			SELECT 1 FROM variants v WHERE v.product_id = p.id AND v.available = 1
+		))`)
+	}
+	return variantSQL, strings.Join(conditions, " AND "), variantArgs, productArgs
+}
+
+// placeholderList returns n comma-separated SQL placeholders
+func placeholderList(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// buildProductListQuery returns the SQL and arguments for one page of products
+// Variant price ranges and stock come from a single aggregate join. One row more
+// than the page size is fetched to tell whether there is a next page.
+func buildProductListQuery(params *productListParams) (string, []interface{}) {
+	spec := productSorts[params.Sort]
+	variantDropSQL, productDropSQL, variantArgs, productArgs := dropFilterSQL(params.ClosedProducts, params.ClosedVariants)
+
+	query := `
+		SELECT p.id, p.slug, p.name, p.description, p.price, p.currency, p.image_url, p.thumbnail_url, p.category,
+			vp.min_price, vp.max_price, COALESCE(vp.in_stock, 0), ` + sortKeyColumn(params.Sort, spec) + `
+		FROM products p
+		LEFT JOIN (
+			SELECT product_id, MIN(price) AS min_price, MAX(price) AS max_price,
+				MAX(` + variantInStockSQL + `) AS in_stock
+			FROM variants
+			WHERE available = 1` + variantDropSQL + `
+			GROUP BY product_id
+		) vp ON vp.product_id = p.id`
+
+	var where []string
+	args := variantArgs
+	where = append(where, "p.active = 1")
+	if productDropSQL != "" {
+		where = append(where, productDropSQL)
+		args = append(args, productArgs...)
+	}
+
+	if params.Search != "" {
+		query += `
+		JOIN products_fts ON products_fts.product_id = p.id`
+		where = append(where, "products_fts MATCH ?")
+		args = append(args, params.Search)
+	}
+	if params.Category != "" {
+		// A category matches its subcategories too
+		where = append(where, `(p.category = ? COLLATE NOCASE OR p.category IN (
+			WITH RECURSIVE tree(id, slug) AS (
+				SELECT id, slug FROM categories WHERE slug = ? COLLATE NOCASE
+				UNION ALL
+				SELECT c.id, c.slug FROM categories c JOIN tree ON c.parent_id = tree.id
+			)
+			SELECT slug FROM tree
+		))`)
+		args = append(args, params.Category, params.Category)
+	}
+	if params.MinPrice != nil {
+		where = append(where, "COALESCE(vp.min_price, p.price) >= ?")
+		args = append(args, *params.MinPrice)
+	}
+	if params.MaxPrice != nil {
+		where = append(where, "COALESCE(vp.min_price, p.price) <= ?")
+		args = append(args, *params.MaxPrice)
+	}
+	if params.InStock {
+		where = append(where, "vp.in_stock = 1")
+	}
+
+	dir, cmp := "ASC", ">"
+	if spec.desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	if params.Cursor != nil {
+		where = append(where, "("+spec.key+" "+cmp+" ? OR ("+spec.key+" = ? AND p.id "+cmp+" ?))")
+		args = append(args, params.Cursor.Value, params.Cursor.Value, params.Cursor.ID)
+	}
+
+	query += `
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY ` + spec.key + ` ` + dir + `, p.id ` + dir + `
+		LIMIT ?`
+	args = append(args, params.Limit+1)
+
+	return query, args
+}
+
+// sortKeyColumn selects the raw sort key so it can be echoed back in the cursor
+// created_at is cast to TEXT so it round-trips exactly as stored rather than as a parsed time.
+func sortKeyColumn(sort string, spec productSortSpec) string {
+	switch sort {
+	case sortNewest:
+		return "CAST(p.created_at AS TEXT)"
+	case sortName:
+		return "p.name"
+	default:
+		return spec.key
+	}
+}
+
+// cursorValue normalises a scanned sort key to the JSON types decodeProductCursor accepts
+func cursorValue(key interface{}) interface{} {
+	switch v := key.(type) {
+	case []byte:
+		return string(v)
+	case int64:
+		return float64(v)
+	default:
+		return v
+	}
+}
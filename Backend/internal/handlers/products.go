@@ -2,21 +2,32 @@ package handlers
 
 import (
 	"database/sql"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/nessieaudio/ecommerce-backend/internal/catalog"
 	apierrors "github.com/nessieaudio/ecommerce-backend/internal/errors"
+	"github.com/nessieaudio/ecommerce-backend/internal/inventory"
 	"github.com/nessieaudio/ecommerce-backend/internal/middleware"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+	"github.com/nessieaudio/ecommerce-backend/internal/reviews"
+	"github.com/nessieaudio/ecommerce-backend/internal/tour"
 )
 
 // GetProductsResponse represents the products API response
 type GetProductsResponse struct {
-	Products []ProductResponse `json:"products"`
+	Products   []ProductResponse `json:"products"`
+	NextCursor string            `json:"next_cursor,omitempty"` // Pass as ?cursor= to fetch the next page
 }
 
 // ProductResponse represents a product in API responses
 type ProductResponse struct {
 	ID           string            `json:"id"`
+	Slug         string            `json:"slug"`
 	Name         string            `json:"name"`
 	Description  string            `json:"description"`
 	Price        float64           `json:"price"`
@@ -24,9 +35,39 @@ type ProductResponse struct {
 	MaxPrice     float64           `json:"max_price,omitempty"`
 	Currency     string            `json:"currency"`
 	ImageURL     string            `json:"image_url"`
+	ImageSrcset  string            `json:"image_srcset,omitempty"` // Resized widths of image_url, for <img srcset>
 	ThumbnailURL string            `json:"thumbnail_url"`
 	Category     string            `json:"category"`
+	Images       []ImageResponse   `json:"images,omitempty"`
 	Variants     []VariantResponse `json:"variants,omitempty"`
+	Rating       *reviews.Summary  `json:"rating,omitempty"`  // Approved reviews only; detail responses
+	Reviews      []ReviewResponse  `json:"reviews,omitempty"` // Newest first; detail responses
+
+	// What a bundle contains, in display order; detail responses
+	Bundle []models.BundleComponent `json:"bundle,omitempty"`
+
+	// Digital products: the files a buyer can download; detail responses
+	Digital bool                  `json:"digital,omitempty"`
+	Files   []DigitalFileResponse `json:"files,omitempty"`
+
+	// Tour-exclusive products: the show they are sold around and its sale window.
+	// The list only includes them while the window is open.
+	Drop *models.DropStatus `json:"drop,omitempty"`
+}
+
+// DigitalFileResponse is a file included with a digital product
+type DigitalFileResponse struct {
+	FileName  string `json:"file_name"`
+	SizeBytes int64  `json:"size_bytes"`
+}
+
+// ImageResponse represents one gallery image
+type ImageResponse struct {
+	ID         string   `json:"id"`
+	URL        string   `json:"url"`
+	AltText    string   `json:"alt_text"`
+	Srcset     string   `json:"srcset,omitempty"`      // Resized widths, for <img srcset>; empty for remote mockups
+	VariantIDs []string `json:"variant_ids,omitempty"` // Variants this image shows; empty = whole product
 }
 
 // VariantResponse represents a product variant
@@ -38,19 +79,43 @@ type VariantResponse struct {
 	Color     string  `json:"color"`
 	Price     float64 `json:"price"`
 	Available bool    `json:"available"`
+	InStock   bool    `json:"in_stock"`           // False once tracked stock runs out (see preorder)
+	ImageID   string  `json:"image_id,omitempty"` // Gallery image to show when this variant is selected
+
+	// Pre-order state: set when on-hand stock is gone and further units ship later
+	Preorder         bool       `json:"preorder,omitempty"`
+	ExpectedShipDate *time.Time `json:"expected_ship_date,omitempty"`
+
+	// Tour-exclusive variants: available is false outside the sale window
+	Drop *models.DropStatus `json:"drop,omitempty"`
 }
 
-// GetProducts returns all active products
-// GET /api/v1/products
+// GetProducts returns active products, optionally searched, filtered and sorted, a page at a time
+// GET /api/v1/products?q=hoodie&category=merch&min_price=10&max_price=50&in_stock=true&sort=price_asc&limit=20&cursor=...
+//
+// sort: newest (default), price_asc, price_desc, name, or relevance (default when q is set).
+// Prices filter and sort on the lowest available variant price. Tour-exclusive
+// products and variants are left out while their sale window is closed.
 func (h *Handler) GetProducts(w http.ResponseWriter, r *http.Request) {
 	requestID := middleware.GetRequestID(r.Context())
 
-	// Query products from database
-	rows, err := h.db.Query(`
-		SELECT id, name, description, price, currency, image_url, thumbnail_url, category
-		FROM products WHERE active = 1
-		ORDER BY created_at DESC
-	`)
+	params, validationErrors := parseProductListParams(r)
+	if len(validationErrors) > 0 {
+		apierrors.RespondValidationError(w, validationErrors, requestID)
+		return
+	}
+
+	drops, err := tour.NewService(h.db).Availability(time.Now())
+	if err != nil {
+		h.logger.Error("Failed to load merch drops [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+	params.ClosedProducts = drops.ClosedProducts()
+	params.ClosedVariants = drops.ClosedVariants()
+
+	query, args := buildProductListQuery(params)
+	rows, err := h.db.Query(query, args...)
 	if err != nil {
 		h.logger.Error("Failed to query products [request_id: "+requestID+"]", err)
 		apierrors.RespondInternalError(w, requestID)
@@ -58,37 +123,38 @@ func (h *Handler) GetProducts(w http.ResponseWriter, r *http.Request) {
 	}
 	defer rows.Close()
 
-	var products []ProductResponse
+	products := []ProductResponse{}
+	var sortKeys []interface{}
 	for rows.Next() {
 		var p ProductResponse
-		var description, imageURL, thumbnailURL, category sql.NullString
-		if err := rows.Scan(&p.ID, &p.Name, &description, &p.Price, &p.Currency,
-			&imageURL, &thumbnailURL, &category); err != nil {
+		var slug, description, imageURL, thumbnailURL, category sql.NullString
+		var minPrice, maxPrice sql.NullFloat64
+		var inStock bool
+		var sortKey interface{}
+		if err := rows.Scan(&p.ID, &slug, &p.Name, &description, &p.Price, &p.Currency,
+			&imageURL, &thumbnailURL, &category, &minPrice, &maxPrice, &inStock, &sortKey); err != nil {
 			h.logger.Error("Failed to scan product row [request_id: "+requestID+"]", err)
 			apierrors.RespondInternalError(w, requestID)
 			return
 		}
 
+		p.Slug = slug.String
 		p.Description = description.String
 		p.ImageURL = imageURL.String
+		p.ImageSrcset = h.imageService.Srcset(p.ImageURL)
 		p.ThumbnailURL = thumbnailURL.String
 		p.Category = category.String
 
-		// Get min and max prices from variants
-		var minPrice, maxPrice sql.NullFloat64
-		err := h.db.QueryRow(`
-			SELECT MIN(price), MAX(price)
-			FROM variants
-			WHERE product_id = ? AND available = 1
-		`, p.ID).Scan(&minPrice, &maxPrice)
-
-		if err == nil && minPrice.Valid && maxPrice.Valid {
+		if minPrice.Valid && maxPrice.Valid {
 			p.MinPrice = minPrice.Float64
 			p.MaxPrice = maxPrice.Float64
 		}
-		// Silently ignore price range errors - not critical
+		if p.Drop = drops.ProductStatus(p.ID); p.Drop != nil {
+			p.Drop.SoldOut = !inStock
+		}
 
 		products = append(products, p)
+		sortKeys = append(sortKeys, sortKey)
 	}
 
 	// Check for errors during iteration
@@ -98,31 +164,114 @@ func (h *Handler) GetProducts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	apierrors.RespondJSON(w, http.StatusOK, GetProductsResponse{Products: products})
+	response := GetProductsResponse{Products: products}
+
+	// The query fetches one extra row; if it came back there is another page
+	if len(products) > params.Limit {
+		last := params.Limit - 1
+		response.Products = products[:params.Limit]
+		response.NextCursor = (&productCursor{
+			Sort:  params.Sort,
+			Value: cursorValue(sortKeys[last]),
+			ID:    products[last].ID,
+		}).encode()
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, response)
+}
+
+// getProductSummaries returns the active products among ids, in the order given,
+// with the same fields as the product list
+func (h *Handler) getProductSummaries(ids []string) ([]ProductResponse, error) {
+	products := []ProductResponse{}
+	if len(ids) == 0 {
+		return products, nil
+	}
+
+	drops, err := tour.NewService(h.db).Availability(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	variantDropSQL, productDropSQL, args, productArgs := dropFilterSQL(drops.ClosedProducts(), drops.ClosedVariants())
+	if productDropSQL != "" {
+		productDropSQL = " AND " + productDropSQL
+	}
+
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, productArgs...)
+
+	rows, err := h.db.Query(`
+		SELECT p.id, p.slug, p.name, p.description, p.price, p.currency, p.image_url, p.thumbnail_url, p.category,
+			vp.min_price, vp.max_price, COALESCE(vp.in_stock, 0)
+		FROM products p
+		LEFT JOIN (
+			SELECT product_id, MIN(price) AS min_price, MAX(price) AS max_price,
+				MAX(`+variantInStockSQL+`) AS in_stock
+			FROM variants
+			WHERE available = 1`+variantDropSQL+`
+			GROUP BY product_id
+		) vp ON vp.product_id = p.id
+		WHERE p.active = 1 AND p.id IN (`+placeholderList(len(ids))+`)`+productDropSQL+`
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byID := make(map[string]ProductResponse, len(ids))
+	for rows.Next() {
+		var p ProductResponse
+		var slug, description, imageURL, thumbnailURL, category sql.NullString
+		var minPrice, maxPrice sql.NullFloat64
+		var inStock bool
+		if err := rows.Scan(&p.ID, &slug, &p.Name, &description, &p.Price, &p.Currency,
+			&imageURL, &thumbnailURL, &category, &minPrice, &maxPrice, &inStock); err != nil {
+			return nil, err
+		}
+
+		p.Slug = slug.String
+		p.Description = description.String
+		p.ImageURL = imageURL.String
+		p.ImageSrcset = h.imageService.Srcset(p.ImageURL)
+		p.ThumbnailURL = thumbnailURL.String
+		p.Category = category.String
+		if minPrice.Valid && maxPrice.Valid {
+			p.MinPrice = minPrice.Float64
+			p.MaxPrice = maxPrice.Float64
+		}
+		if p.Drop = drops.ProductStatus(p.ID); p.Drop != nil {
+			p.Drop.SoldOut = !inStock
+		}
+		byID[p.ID] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		if p, ok := byID[id]; ok {
+			products = append(products, p)
+		}
+	}
+	return products, nil
 }
 
 // GetProduct returns a single product with variants
 // GET /api/v1/products/{id}
+//
+// {id} is the product's UUID or slug. A slug the product had before a rename
+// answers 301 with the current URL.
 func (h *Handler) GetProduct(w http.ResponseWriter, r *http.Request) {
 	requestID := middleware.GetRequestID(r.Context())
-	vars := mux.Vars(r)
-	productID := vars["id"]
 
-	// Validate product ID
-	if productID == "" {
-		apierrors.RespondError(w, http.StatusBadRequest, "Product ID is required", apierrors.ErrCodeBadRequest, nil, requestID)
+	productID, ok := h.resolveProductRef(w, r, requestID)
+	if !ok {
 		return
 	}
 
-	// Get product
-	var product ProductResponse
-	var description, imageURL, thumbnailURL, category sql.NullString
-	err := h.db.QueryRow(`
-		SELECT id, name, description, price, currency, image_url, thumbnail_url, category
-		FROM products WHERE id = ? AND active = 1
-	`, productID).Scan(&product.ID, &product.Name, &description, &product.Price,
-		&product.Currency, &imageURL, &thumbnailURL, &category)
-
+	product, err := h.getProductDetail(productID)
 	if err == sql.ErrNoRows {
 		apierrors.RespondNotFound(w, "Product", requestID)
 		return
@@ -133,43 +282,251 @@ func (h *Handler) GetProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	apierrors.RespondJSON(w, http.StatusOK, product)
+}
+
+// resolveProductRef turns the {id} route variable (UUID or slug) into a product ID
+// It writes the response itself - 404, or a 301 to the current slug for a retired
+// one - and returns false when the caller should stop.
+func (h *Handler) resolveProductRef(w http.ResponseWriter, r *http.Request, requestID string) (string, bool) {
+	ref := mux.Vars(r)["id"]
+
+	// Validate product ID
+	if ref == "" {
+		apierrors.RespondError(w, http.StatusBadRequest, "Product ID is required", apierrors.ErrCodeBadRequest, nil, requestID)
+		return "", false
+	}
+
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	productID, movedTo, err := catalogService.ResolveProduct(ref)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierrors.RespondNotFound(w, "Product", requestID)
+		return "", false
+	}
+	if err != nil {
+		h.logger.Error("Failed to resolve product [request_id: "+requestID+", ref: "+ref+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return "", false
+	}
+
+	if movedTo != "" {
+		location := *r.URL
+		if i := strings.Index(r.URL.Path, "/products/"+ref); i >= 0 {
+			location.Path = r.URL.Path[:i] + "/products/" + movedTo + r.URL.Path[i+len("/products/"+ref):]
+		}
+		location.RawPath = ""
+		http.Redirect(w, r, location.String(), http.StatusMovedPermanently)
+		return "", false
+	}
+
+	return productID, true
+}
+
+// getProductDetail loads an active product with its available variants and gallery
+// A tour-exclusive product is returned outside its sale window too, so links to it
+// keep working, but with its variants unavailable and the window in drop.
+// Returns sql.ErrNoRows if the product does not exist or is inactive.
+func (h *Handler) getProductDetail(productID string) (*ProductResponse, error) {
+	var product ProductResponse
+	var slug, description, imageURL, thumbnailURL, category sql.NullString
+	var isBundle bool
+	var productType string
+	err := h.db.QueryRow(`
+		SELECT id, slug, name, description, price, currency, image_url, thumbnail_url, category, is_bundle, product_type
+		FROM products WHERE id = ? AND active = 1
+	`, productID).Scan(&product.ID, &slug, &product.Name, &description, &product.Price,
+		&product.Currency, &imageURL, &thumbnailURL, &category, &isBundle, &productType)
+	if err != nil {
+		return nil, err
+	}
+
+	product.Slug = slug.String
 	product.Description = description.String
 	product.ImageURL = imageURL.String
+	product.ImageSrcset = h.imageService.Srcset(product.ImageURL)
 	product.ThumbnailURL = thumbnailURL.String
 	product.Category = category.String
 
 	// Get variants
 	rows, err := h.db.Query(`
-		SELECT id, product_id, name, COALESCE(size, ''), COALESCE(color, ''), price, available
+		SELECT id, product_id, name, COALESCE(size, ''), COALESCE(color, ''), price, available,
+			COALESCE(track_inventory, 0), stock_quantity, COALESCE(preorder_enabled, 0), preorder_ship_date
 		FROM variants WHERE product_id = ? AND available = 1
 		ORDER BY name
 	`, productID)
 	if err != nil {
-		h.logger.Error("Failed to fetch variants [request_id: "+requestID+", product_id: "+productID+"]", err)
-		apierrors.RespondInternalError(w, requestID)
-		return
+		return nil, fmt.Errorf("query variants: %w", err)
 	}
 	defer rows.Close()
 
 	var variants []VariantResponse
 	for rows.Next() {
 		var v VariantResponse
-		if err := rows.Scan(&v.ID, &v.ProductID, &v.Name, &v.Size, &v.Color, &v.Price, &v.Available); err != nil {
-			h.logger.Error("Error scanning variant [request_id: "+requestID+", product_id: "+productID+"]", err)
-			apierrors.RespondInternalError(w, requestID)
-			return
+		var trackInventory, preorderEnabled bool
+		var stockQty sql.NullInt64
+		var preorderShipDate sql.NullTime
+		if err := rows.Scan(&v.ID, &v.ProductID, &v.Name, &v.Size, &v.Color, &v.Price, &v.Available,
+			&trackInventory, &stockQty, &preorderEnabled, &preorderShipDate); err != nil {
+			return nil, fmt.Errorf("scan variant: %w", err)
+		}
+
+		v.InStock = !trackInventory || (stockQty.Valid && stockQty.Int64 > 0)
+
+		// The next unit sold is a pre-order once on-hand stock is exhausted
+		if trackInventory && preorderEnabled && preorderShipDate.Valid && stockQty.Valid && stockQty.Int64 <= 0 {
+			v.Preorder = true
+			v.ExpectedShipDate = &preorderShipDate.Time
 		}
+
 		variants = append(variants, v)
 	}
 
 	// Check for errors during iteration
 	if err := rows.Err(); err != nil {
-		h.logger.Error("Error iterating variants [request_id: "+requestID+", product_id: "+productID+"]", err)
-		apierrors.RespondInternalError(w, requestID)
-		return
+		return nil, fmt.Errorf("iterate variants: %w", err)
 	}
 
 	product.Variants = variants
 
-	apierrors.RespondJSON(w, http.StatusOK, product)
+	// A bundle's stock comes from its components
+	if isBundle && len(variants) > 0 {
+		if err := h.applyBundleStock(&product); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := h.applyDrops(&product); err != nil {
+		return nil, err
+	}
+
+	if productType == models.ProductTypeDigital {
+		catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+		files, err := catalogService.DigitalFiles(product.ID)
+		if err != nil {
+			return nil, err
+		}
+		product.Digital = true
+		for _, f := range files {
+			product.Files = append(product.Files, DigitalFileResponse{FileName: f.FileName, SizeBytes: f.SizeBytes})
+		}
+	}
+
+	images, err := h.getProductGallery(&product)
+	if err != nil {
+		return nil, fmt.Errorf("query product images: %w", err)
+	}
+	product.Images = images
+
+	product.Reviews, product.Rating, err = h.getProductReviews(product.ID)
+	if err != nil {
+		return nil, fmt.Errorf("query product reviews: %w", err)
+	}
+
+	return &product, nil
+}
+
+// applyDrops sets the drop state of a tour-exclusive product and its variants, and
+// marks variants unavailable outside their sale window
+func (h *Handler) applyDrops(product *ProductResponse) error {
+	drops, err := tour.NewService(h.db).Availability(time.Now())
+	if err != nil {
+		return fmt.Errorf("load merch drops: %w", err)
+	}
+
+	product.Drop = drops.ProductStatus(product.ID)
+	productSoldOut := true
+	for i := range product.Variants {
+		v := &product.Variants[i]
+		v.Available = drops.CanBuy(product.ID, v.ID)
+		if v.Drop = drops.VariantStatus(v.ID); v.Drop != nil {
+			v.Drop.SoldOut = !v.InStock && !v.Preorder
+		}
+		if v.InStock || v.Preorder {
+			productSoldOut = false
+		}
+	}
+	if product.Drop != nil {
+		product.Drop.SoldOut = productSoldOut
+	}
+	return nil
+}
+
+// applyBundleStock fills in a bundle product's contents and sets its variant's stock
+// state from the components
+func (h *Handler) applyBundleStock(product *ProductResponse) error {
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	inventoryService := inventory.NewService(h.db)
+
+	for i := range product.Variants {
+		v := &product.Variants[i]
+		check, err := inventoryService.CheckStock(v.ID, 1)
+		if err != nil {
+			return fmt.Errorf("check bundle stock: %w", err)
+		}
+		v.InStock = check.Available && !check.Preorder
+		v.Preorder = check.Preorder
+		v.ExpectedShipDate = check.ExpectedShipDate
+	}
+
+	components, err := catalogService.BundleComponents(product.Variants[0].ID)
+	if err != nil {
+		return err
+	}
+	product.Bundle = components
+	return nil
+}
+
+// getProductGallery builds a product's gallery and points each variant at its image
+// Mockups shared by several variants (e.g. every size of one colour) appear once.
+func (h *Handler) getProductGallery(product *ProductResponse) ([]ImageResponse, error) {
+	rows, err := h.db.Query(`
+		SELECT id, variant_id, url, alt_text
+		FROM product_images
+		WHERE product_id = ?
+		ORDER BY sort_order, created_at
+	`, product.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var images []ImageResponse
+	byURL := make(map[string]int)
+	variantImage := make(map[string]string)
+
+	for rows.Next() {
+		var id, url, altText string
+		var variantID sql.NullString
+		if err := rows.Scan(&id, &variantID, &url, &altText); err != nil {
+			return nil, err
+		}
+
+		i, seen := byURL[url]
+		if !seen {
+			i = len(images)
+			byURL[url] = i
+			images = append(images, ImageResponse{ID: id, URL: url, AltText: altText, Srcset: h.imageService.Srcset(url)})
+		}
+
+		if variantID.Valid {
+			images[i].VariantIDs = append(images[i].VariantIDs, variantID.String)
+			if _, ok := variantImage[variantID.String]; !ok {
+				variantImage[variantID.String] = images[i].ID
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Products not yet synced into the gallery still show their primary image
+	if len(images) == 0 && product.ImageURL != "" {
+		images = append(images, ImageResponse{URL: product.ImageURL, AltText: product.Name, Srcset: product.ImageSrcset})
+	}
+
+	for i := range product.Variants {
+		product.Variants[i].ImageID = variantImage[product.Variants[i].ID]
+	}
+
+	return images, nil
 }
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	apierrors "github.com/nessieaudio/ecommerce-backend/internal/errors"
+	"github.com/nessieaudio/ecommerce-backend/internal/imaging"
+	"github.com/nessieaudio/ecommerce-backend/internal/middleware"
+)
+
+// GetResizedImage serves a product photo scaled to one of the allowed widths
+// GET /api/v1/images/{width}/Product Photos/{product}/{file}?v={version}&format=jpeg|png|webp
+//
+// URLs carrying the current source version (as emitted in srcset) are cached as immutable;
+// a changed photo gets a new version and therefore a new URL. Without a format, WebP is
+// served to browsers whose Accept header lists it.
+func (h *Handler) GetResizedImage(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	vars := mux.Vars(r)
+
+	width, err := strconv.Atoi(vars["width"])
+	if err != nil || !imaging.IsAllowedWidth(width) {
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{
+			{Field: "width", Message: fmt.Sprintf("must be one of %v", imaging.Widths)},
+		}, requestID)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = imaging.NegotiateFormat(r.Header.Get("Accept"))
+		w.Header().Set("Vary", "Accept")
+	}
+
+	resized, err := h.imageService.Resize("/"+vars["path"], width, format)
+	if errors.Is(err, imaging.ErrSourceNotFound) {
+		apierrors.RespondNotFound(w, "Image", requestID)
+		return
+	}
+	if errors.Is(err, imaging.ErrInvalidFormat) {
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{
+			{Field: "format", Message: "must be " + imaging.FormatJPEG + ", " + imaging.FormatPNG + " or " + imaging.FormatWebP},
+		}, requestID)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to resize image [request_id: "+requestID+", path: "+vars["path"]+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	f, err := os.Open(resized.Path)
+	if err != nil {
+		h.logger.Error("Failed to open resized image [request_id: "+requestID+", path: "+vars["path"]+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", resized.ContentType)
+	w.Header().Set("ETag", resized.ETag)
+	if r.URL.Query().Get("v") == resized.Version {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		// Unversioned or stale URL: the content can change, so revalidate via the ETag
+		w.Header().Set("Cache-Control", "public, max-age=3600")
+	}
+
+	// ServeContent handles If-None-Match / If-Modified-Since and Range requests
+	http.ServeContent(w, r, "", resized.ModTime, f)
+}
@@ -0,0 +1,307 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/nessieaudio/ecommerce-backend/internal/catalog"
+	apierrors "github.com/nessieaudio/ecommerce-backend/internal/errors"
+	"github.com/nessieaudio/ecommerce-backend/internal/middleware"
+	"github.com/nessieaudio/ecommerce-backend/internal/reviews"
+	"github.com/nessieaudio/ecommerce-backend/internal/services/email"
+	"github.com/nessieaudio/ecommerce-backend/internal/signing"
+)
+
+// maxReviewBodyLength keeps reviews to a readable size
+const maxReviewBodyLength = 5000
+
+// ReviewResponse is an approved review as shown on the product page
+type ReviewResponse struct {
+	ID           string    `json:"id"`
+	ReviewerName string    `json:"reviewer_name"`
+	Rating       int       `json:"rating"`
+	Fit          string    `json:"fit,omitempty"`
+	Body         string    `json:"body"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// SubmitReviewRequest is a review sent from the link in a review invitation
+type SubmitReviewRequest struct {
+	Token        string `json:"token"` // From the invitation link
+	Rating       int    `json:"rating"`
+	Fit          string `json:"fit"` // runs_small, true_to_size, runs_large or empty
+	Body         string `json:"body"`
+	ReviewerName string `json:"reviewer_name"` // Defaults to the buyer's first name
+}
+
+// SubmitReview records a verified purchaser's review for moderation
+// POST /api/v1/products/{id}/reviews
+//
+// Request: { "token": "<from the invitation email>", "rating": 5, "fit": "runs_small", "body": "Great hoodie" }
+func (h *Handler) SubmitReview(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	ref := mux.Vars(r)["id"]
+
+	var req SubmitReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+
+	var validationErrors []apierrors.ValidationError
+	if req.Token == "" {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "token", Message: "is required"})
+	}
+	if req.Rating < 1 || req.Rating > 5 {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "rating", Message: "must be between 1 and 5"})
+	}
+	if !reviews.ValidFit(req.Fit) {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "fit", Message: "must be runs_small, true_to_size or runs_large"})
+	}
+	if len(req.Body) > maxReviewBodyLength {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "body", Message: fmt.Sprintf("must be at most %d characters", maxReviewBodyLength)})
+	}
+	if len(req.ReviewerName) > 100 {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "reviewer_name", Message: "must be at most 100 characters"})
+	}
+	if len(validationErrors) > 0 {
+		apierrors.RespondValidationError(w, validationErrors, requestID)
+		return
+	}
+
+	// Old slugs still identify the product; there is nothing to redirect on a POST
+	catalogService := catalog.NewService(h.db, h.printfulClient, h.config.StaticDir)
+	productID, _, err := catalogService.ResolveProduct(ref)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierrors.RespondNotFound(w, "Product", requestID)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to resolve product [request_id: "+requestID+", ref: "+ref+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	reviewService := reviews.NewService(h.db, h.signer)
+	review, err := reviewService.Submit(productID, req.Token, reviews.Submission{
+		Rating:       req.Rating,
+		Fit:          req.Fit,
+		Body:         req.Body,
+		ReviewerName: req.ReviewerName,
+	})
+	if err != nil {
+		h.respondReviewError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusCreated, map[string]string{
+		"message": "Thanks! Your review will appear once it has been approved.",
+		"id":      review.ID,
+		"status":  review.Status,
+	})
+}
+
+// GetAdminReviews lists reviews by moderation status
+// GET /api/v1/admin/reviews?status=pending
+//
+// status defaults to pending (the moderation queue); "all" lists every review.
+func (h *Handler) GetAdminReviews(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = "pending"
+	case "all":
+		status = ""
+	}
+
+	reviewService := reviews.NewService(h.db, h.signer)
+	list, err := reviewService.List(status)
+	if err != nil {
+		h.logger.Error("Failed to fetch reviews [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"reviews": list,
+		"count":   len(list),
+	})
+}
+
+// ModerateReviewRequest approves or rejects a review
+type ModerateReviewRequest struct {
+	Status string `json:"status"` // approved, rejected or pending
+	Note   string `json:"note"`   // Internal, never shown publicly
+}
+
+// ModerateReview approves or rejects a review
+// PUT /api/v1/admin/reviews/{id}
+//
+// Request: { "status": "approved" }
+func (h *Handler) ModerateReview(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	reviewID := mux.Vars(r)["id"]
+
+	var req ModerateReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+
+	reviewService := reviews.NewService(h.db, h.signer)
+	review, err := reviewService.Moderate(reviewID, req.Status, req.Note)
+	if err != nil {
+		h.respondReviewError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, review)
+}
+
+// DeleteReview removes a review
+// DELETE /api/v1/admin/reviews/{id}
+func (h *Handler) DeleteReview(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	reviewID := mux.Vars(r)["id"]
+
+	reviewService := reviews.NewService(h.db, h.signer)
+	if err := reviewService.Delete(reviewID); err != nil {
+		h.respondReviewError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Review deleted",
+		"id":      reviewID,
+	})
+}
+
+// respondReviewError maps review service errors to API responses
+func (h *Handler) respondReviewError(w http.ResponseWriter, err error, requestID string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		apierrors.RespondNotFound(w, "Review", requestID)
+	case errors.Is(err, signing.ErrInvalidToken):
+		apierrors.RespondError(w, http.StatusForbidden, "Review link is not valid", apierrors.ErrCodeForbidden, nil, requestID)
+	case errors.Is(err, signing.ErrExpiredToken):
+		apierrors.RespondError(w, http.StatusForbidden, "Review link has expired", apierrors.ErrCodeForbidden, nil, requestID)
+	case errors.Is(err, reviews.ErrNotVerifiedPurchase):
+		apierrors.RespondError(w, http.StatusForbidden, err.Error(), apierrors.ErrCodeForbidden, nil, requestID)
+	case errors.Is(err, reviews.ErrAlreadyReviewed):
+		apierrors.RespondError(w, http.StatusConflict, err.Error(), apierrors.ErrCodeConflict, nil, requestID)
+	case errors.Is(err, reviews.ErrInvalidStatus):
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "status", Message: err.Error()}}, requestID)
+	default:
+		h.logger.Error("Review operation failed [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+	}
+}
+
+// getProductReviews returns a product's approved reviews and their summary
+func (h *Handler) getProductReviews(productID string) ([]ReviewResponse, *reviews.Summary, error) {
+	reviewService := reviews.NewService(h.db, h.signer)
+
+	approved, err := reviewService.Approved(productID)
+	if err != nil {
+		return nil, nil, err
+	}
+	summary, err := reviewService.Summarize(productID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var response []ReviewResponse
+	for _, r := range approved {
+		response = append(response, ReviewResponse{
+			ID:           r.ID,
+			ReviewerName: r.ReviewerName,
+			Rating:       r.Rating,
+			Fit:          r.Fit,
+			Body:         r.Body,
+			CreatedAt:    r.CreatedAt,
+		})
+	}
+	return response, summary, nil
+}
+
+// StartReviewInviter emails review invitations for shipped orders on a fixed interval
+func (h *Handler) StartReviewInviter(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			h.SendDueReviewInvites()
+		}
+	}()
+
+	log.Printf("Review inviter started (every %v)", interval)
+}
+
+// SendDueReviewInvites emails every buyer whose order shipped reviews.InviteDelay ago
+// a signed review link per product
+func (h *Handler) SendDueReviewInvites() {
+	reviewService := reviews.NewService(h.db, h.signer)
+	due, err := reviewService.DueInvites(time.Now())
+	if err != nil {
+		log.Printf("Failed to check for due review invitations: %v", err)
+		return
+	}
+
+	for _, invite := range due {
+		if len(invite.Products) > 0 {
+			if err := h.sendReviewInvite(reviewService, invite); err != nil {
+				log.Printf("Failed to send review invitation for order %s: %v", invite.OrderID, err)
+				continue
+			}
+		}
+		if err := reviewService.MarkInvited(invite.OrderID); err != nil {
+			log.Printf("Failed to mark review invitation for order %s: %v", invite.OrderID, err)
+		}
+	}
+}
+
+// sendReviewInvite emails one buyer a review link for each product in their order
+func (h *Handler) sendReviewInvite(reviewService *reviews.Service, invite reviews.PendingInvite) error {
+	baseURL := h.getBaseURL()
+
+	var buttons strings.Builder
+	for _, product := range invite.Products {
+		token, err := reviewService.InviteToken(reviews.Invite{
+			OrderID:   invite.OrderID,
+			ProductID: product.ID,
+			Email:     invite.Email,
+		})
+		if err != nil {
+			return err
+		}
+		link := baseURL + catalog.ProductPagePath(product.ID, product.Slug) + "&review=" + url.QueryEscape(token) + "#reviews"
+		buttons.WriteString(email.CTAButton("Review "+html.EscapeString(product.Name), link))
+	}
+
+	greeting := "Hi"
+	if fields := strings.Fields(invite.Name); len(fields) > 0 {
+		greeting = "Hi " + html.EscapeString(fields[0])
+	}
+
+	contentHTML := fmt.Sprintf(`<p style="font-size:16px;">%s,</p>
+<p style="font-size:16px;">Your Nessie Audio order should have arrived by now. How did it turn out? A quick rating (and, for clothing, how it fits) helps other fans choose.</p>%s%s`,
+		greeting,
+		buttons.String(),
+		email.NoteBox(fmt.Sprintf("These links are just for you and work for %d days.", int(reviews.InviteTTL.Hours()/24)), false),
+	)
+	htmlBody := email.EmailLayout("How was your order?", "&#11088;", contentHTML, false)
+
+	return h.emailClient.SendHTMLEmail(invite.Email, "How was your Nessie Audio order?", htmlBody)
+}
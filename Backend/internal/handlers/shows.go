@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/nessieaudio/ecommerce-backend/internal/catalog"
+	apierrors "github.com/nessieaudio/ecommerce-backend/internal/errors"
+	"github.com/nessieaudio/ecommerce-backend/internal/middleware"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+	"github.com/nessieaudio/ecommerce-backend/internal/tour"
+)
+
+// GetShows lists tour dates
+// GET /api/v1/shows?upcoming=true
+//
+// upcoming=true lists the shows that are not over yet, soonest first; upcoming=false
+// lists the archived ones, most recent first. Without it, every show in date order.
+func (h *Handler) GetShows(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	tourService := tour.NewService(h.db)
+	var shows []models.Show
+	var err error
+	switch r.URL.Query().Get("upcoming") {
+	case "true", "1":
+		shows, err = tourService.ListUpcoming()
+	case "false", "0":
+		shows, err = tourService.ListPast()
+	case "":
+		shows, err = tourService.ListAll()
+	default:
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "upcoming", Message: "must be true or false"}}, requestID)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch shows [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"shows": shows,
+		"count": len(shows),
+	})
+}
+
+// GetShow returns one tour date
+// GET /api/v1/shows/{id}
+func (h *Handler) GetShow(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	show, err := tour.NewService(h.db).Get(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondShowError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, show)
+}
+
+// GetShowsStructuredData returns schema.org MusicEvent JSON-LD for the upcoming shows
+// GET /api/v1/shows/structured-data
+func (h *Handler) GetShowsStructuredData(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	shows, err := tour.NewService(h.db).ListUpcoming()
+	if err != nil {
+		h.logger.Error("Failed to fetch shows [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	events := make([]MusicEventJSONLD, 0, len(shows))
+	for i := range shows {
+		events = append(events, h.musicEventJSONLD(&shows[i]))
+	}
+
+	w.Header().Set("Content-Type", "application/ld+json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(events)
+}
+
+// GetAdminShows lists every show, archived or not
+// GET /api/v1/admin/shows
+func (h *Handler) GetAdminShows(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	shows, err := tour.NewService(h.db).ListAll()
+	if err != nil {
+		h.logger.Error("Failed to fetch shows [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"shows": shows,
+		"count": len(shows),
+	})
+}
+
+// ShowRequest is the editable part of a show
+type ShowRequest struct {
+	Venue       string   `json:"venue"`
+	City        string   `json:"city"`
+	Region      string   `json:"region"`
+	Country     string   `json:"country"` // ISO 3166-1 alpha-2
+	Latitude    *float64 `json:"latitude"`
+	Longitude   *float64 `json:"longitude"`
+	StartsAt    string   `json:"starts_at"` // Wall-clock time at the venue, or RFC 3339
+	Timezone    string   `json:"timezone"`  // IANA zone of the venue
+	TicketURL   string   `json:"ticket_url"`
+	Status      string   `json:"status"` // Defaults to on_sale
+	SupportActs []string `json:"support_acts"`
+}
+
+// CreateShow adds a tour date
+// POST /api/v1/admin/shows
+//
+// Request: { "venue": "The Rusty Stage", "city": "Portland", "region": "OR", "country": "US",
+// "starts_at": "2026-03-14T20:00", "timezone": "America/Los_Angeles", "ticket_url": "https://..." }
+func (h *Handler) CreateShow(w http.ResponseWriter, r *http.Request) {
+	h.saveShow(w, r, "")
+}
+
+// UpdateShow replaces a tour date
+// PUT /api/v1/admin/shows/{id}
+func (h *Handler) UpdateShow(w http.ResponseWriter, r *http.Request) {
+	h.saveShow(w, r, mux.Vars(r)["id"])
+}
+
+func (h *Handler) saveShow(w http.ResponseWriter, r *http.Request, showID string) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	var req ShowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+	req.Venue = strings.TrimSpace(req.Venue)
+	req.City = strings.TrimSpace(req.City)
+	req.Country = strings.ToUpper(strings.TrimSpace(req.Country))
+	req.TicketURL = strings.TrimSpace(req.TicketURL)
+
+	var validationErrors []apierrors.ValidationError
+	if req.Venue == "" {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "venue", Message: "is required"})
+	}
+	if req.City == "" {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "city", Message: "is required"})
+	}
+	if len(req.Country) != 2 {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "country", Message: "must be a two-letter ISO country code"})
+	}
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "latitude", Message: "latitude and longitude must be given together"})
+	} else if req.Latitude != nil && (*req.Latitude < -90 || *req.Latitude > 90 || *req.Longitude < -180 || *req.Longitude > 180) {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "latitude", Message: "coordinates are out of range"})
+	}
+	startsAt, err := tour.ParseStart(req.StartsAt, req.Timezone)
+	if errors.Is(err, tour.ErrInvalidTimezone) {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "timezone", Message: err.Error()})
+	} else if err != nil {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "starts_at", Message: "must be a date and time such as 2026-03-14T20:00"})
+	}
+	if req.TicketURL != "" {
+		if u, err := url.Parse(req.TicketURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			validationErrors = append(validationErrors, apierrors.ValidationError{Field: "ticket_url", Message: "must be an http(s) URL"})
+		}
+	}
+	if req.Status != "" && !tour.ValidStatus(req.Status) {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "status", Message: "must be on_sale, sold_out or cancelled"})
+	}
+	if len(validationErrors) > 0 {
+		apierrors.RespondValidationError(w, validationErrors, requestID)
+		return
+	}
+
+	supportActs := []string{}
+	for _, act := range req.SupportActs {
+		if act = strings.TrimSpace(act); act != "" {
+			supportActs = append(supportActs, act)
+		}
+	}
+
+	show := models.Show{
+		ID:          showID,
+		Venue:       req.Venue,
+		City:        req.City,
+		Region:      strings.TrimSpace(req.Region),
+		Country:     req.Country,
+		Latitude:    req.Latitude,
+		Longitude:   req.Longitude,
+		StartsAt:    startsAt,
+		Timezone:    req.Timezone,
+		TicketURL:   req.TicketURL,
+		Status:      req.Status,
+		SupportActs: supportActs,
+	}
+	if err := tour.NewService(h.db).Save(&show); err != nil {
+		h.respondShowError(w, err, requestID)
+		return
+	}
+
+	status := http.StatusOK
+	if showID == "" {
+		status = http.StatusCreated
+	}
+	apierrors.RespondJSON(w, status, show)
+}
+
+// DeleteShow removes a tour date. Cancelled shows are better kept with status
+// cancelled, so fans who saw the date find out.
+// DELETE /api/v1/admin/shows/{id}
+func (h *Handler) DeleteShow(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	showID := mux.Vars(r)["id"]
+
+	if err := tour.NewService(h.db).Delete(showID); err != nil {
+		h.respondShowError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Show deleted",
+		"id":      showID,
+	})
+}
+
+// respondShowError maps tour service errors to API responses
+func (h *Handler) respondShowError(w http.ResponseWriter, err error, requestID string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		apierrors.RespondNotFound(w, "Show", requestID)
+	case errors.Is(err, tour.ErrShowHasDrops):
+		apierrors.RespondError(w, http.StatusConflict, err.Error(), apierrors.ErrCodeConflict, nil, requestID)
+	default:
+		h.logger.Error("Show operation failed [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+	}
+}
+
+// calendarHistory is how far back the calendar feed goes, so subscribers keep
+// recent shows in their calendars after they are archived
+const calendarHistory = 365 * 24 * time.Hour
+
+// GetTourCalendar serves the tour as an iCalendar feed to subscribe to
+// GET /tour.ics
+//
+// Upcoming shows and those of the past year, cancelled ones included so
+// subscribed calendars mark them cancelled.
+func (h *Handler) GetTourCalendar(w http.ResponseWriter, r *http.Request) {
+	shows, err := tour.NewService(h.db).ListSince(time.Now().Add(-calendarHistory))
+	if err != nil {
+		h.logger.Error("Failed to fetch shows for the tour calendar", err)
+		http.Error(w, "Failed to generate calendar", http.StatusInternalServerError)
+		return
+	}
+
+	body := h.tourCalendar().ICS(shows)
+	serveFeed(w, r, "text/calendar; charset=utf-8", body)
+}
+
+// GetShowCalendar serves one show as an .ics download, for "Add to calendar"
+// GET /api/v1/shows/{id}.ics
+func (h *Handler) GetShowCalendar(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	show, err := tour.NewService(h.db).Get(mux.Vars(r)["id"])
+	if err != nil {
+		h.respondShowError(w, err, requestID)
+		return
+	}
+
+	filename := "nessie-audio-" + show.StartsAt.Format("2006-01-02") + "-" + catalog.Slugify(show.Venue) + ".ics"
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	serveFeed(w, r, "text/calendar; charset=utf-8", h.tourCalendar().ICS([]models.Show{*show}))
+}
+
+// GetTourRSS serves the upcoming shows as an RSS feed
+// GET /tour.rss
+func (h *Handler) GetTourRSS(w http.ResponseWriter, r *http.Request) {
+	shows, err := tour.NewService(h.db).ListUpcoming()
+	if err != nil {
+		h.logger.Error("Failed to fetch shows for the tour feed", err)
+		http.Error(w, "Failed to generate feed", http.StatusInternalServerError)
+		return
+	}
+
+	feed := tour.Feed{
+		Title:       brandName + " Tour Dates",
+		Description: "Upcoming " + brandName + " shows",
+		PageURL:     h.getBaseURL() + "/tour",
+		FeedURL:     h.getBaseURL() + "/tour.rss",
+	}
+	body, err := feed.RSS(shows)
+	if err != nil {
+		h.logger.Error("Failed to generate the tour feed", err)
+		http.Error(w, "Failed to generate feed", http.StatusInternalServerError)
+		return
+	}
+	serveFeed(w, r, "application/rss+xml; charset=utf-8", body)
+}
+
+func (h *Handler) tourCalendar() tour.Calendar {
+	return tour.Calendar{Name: brandName + " Tour", PageURL: h.getBaseURL() + "/tour"}
+}
+
+// serveFeed writes a generated feed with a strong ETag of its body, so pollers
+// get 304 Not Modified until a show changes. There is no Last-Modified: deleting
+// a show changes the feed without leaving a newer timestamp behind.
+func serveFeed(w http.ResponseWriter, r *http.Request, contentType string, body []byte) {
+	sum := sha256.Sum256(body)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "public, max-age=900")
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(body))
+}
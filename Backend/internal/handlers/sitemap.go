@@ -1,99 +1,85 @@
 package handlers
 
 import (
-	"encoding/xml"
-	"fmt"
-	"log"
+	"bytes"
+	"errors"
 	"net/http"
-	"time"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/nessieaudio/ecommerce-backend/internal/sitemap"
 )
 
-// URL represents a single URL entry in the sitemap
-type URL struct {
-	Loc        string  `xml:"loc"`
-	LastMod    string  `xml:"lastmod,omitempty"`
-	ChangeFreq string  `xml:"changefreq,omitempty"`
-	Priority   float64 `xml:"priority,omitempty"`
+// GetSitemap serves sitemap.xml - the sitemap itself, or a sitemap index once the
+// site outgrows one file (see sitemap.MaxURLsPerFile)
+// GET /sitemap.xml
+func (h *Handler) GetSitemap(w http.ResponseWriter, r *http.Request) {
+	h.serveSitemap(w, r, "sitemap.xml")
 }
 
-// URLSet represents the root element of the sitemap
-type URLSet struct {
-	XMLName xml.Name `xml:"urlset"`
-	XMLNS   string   `xml:"xmlns,attr"`
-	URLs    []URL    `xml:"url"`
+// GetSitemapPart serves one part of a split sitemap
+// GET /sitemap-{n}.xml
+func (h *Handler) GetSitemapPart(w http.ResponseWriter, r *http.Request) {
+	h.serveSitemap(w, r, "sitemap-"+mux.Vars(r)["n"]+".xml")
 }
 
-// GetSitemap generates and serves the sitemap.xml
-func (h *Handler) GetSitemap(w http.ResponseWriter, r *http.Request) {
-	// Determine base URL based on environment
-	baseURL := h.getBaseURL()
-
-	// Create sitemap structure
-	sitemap := URLSet{
-		XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9",
-		URLs:  []URL{},
-	}
-
-	// Current timestamp for lastmod
-	now := time.Now().Format("2006-01-02")
-
-	// Add static pages
-	staticPages := []struct {
-		path       string
-		changefreq string
-		priority   float64
-	}{
-		{"/home", "monthly", 1.0},          // Homepage - highest priority
-		{"/portfolio", "weekly", 0.9},      // Portfolio - high priority
-		{"/merch", "daily", 0.9},           // Merch/shop - high priority, changes often
-		{"/tour", "weekly", 0.8},           // Tour dates - medium-high priority
-		{"/about", "monthly", 0.7},         // About page
-		{"/gallery", "monthly", 0.7},       // Gallery
-		{"/nessie-digital", "monthly", 0.7}, // Nessie Digital
-		{"/contact", "monthly", 0.6},       // Contact page
-		{"/cart", "yearly", 0.3},           // Cart - low priority, functional page
-	}
-
-	for _, page := range staticPages {
-		sitemap.URLs = append(sitemap.URLs, URL{
-			Loc:        baseURL + page.path,
-			LastMod:    now,
-			ChangeFreq: page.changefreq,
-			Priority:   page.priority,
-		})
+// serveSitemap writes a cached sitemap document; conditional requests are answered
+// from its ETag and Last-Modified
+func (h *Handler) serveSitemap(w http.ResponseWriter, r *http.Request, name string) {
+	file, err := h.sitemap.Get(name)
+	if errors.Is(err, sitemap.ErrNotFound) {
+		http.NotFound(w, r)
+		return
 	}
-
-	// Add dynamic product pages from database
-	products, err := h.getProductsForSitemap()
 	if err != nil {
-		log.Printf("Error fetching products for sitemap: %v", err)
-		// Continue anyway with static pages
-	} else {
-		for _, productID := range products {
-			sitemap.URLs = append(sitemap.URLs, URL{
-				Loc:        fmt.Sprintf("%s/product-detail?id=%s", baseURL, productID),
-				LastMod:    now,
-				ChangeFreq: "weekly",
-				Priority:   0.8, // Product pages are important for e-commerce
-			})
-		}
+		h.logger.Error("Failed to generate sitemap", err)
+		http.Error(w, "Failed to generate sitemap", http.StatusInternalServerError)
+		return
 	}
 
-	// Set headers for XML response
 	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
-	w.WriteHeader(http.StatusOK)
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Header().Set("ETag", file.ETag)
+	http.ServeContent(w, r, name, file.ModTime, bytes.NewReader(file.Body))
+}
 
-	// Write XML declaration
-	w.Write([]byte(xml.Header))
+// robotsDisallow are the paths crawlers are asked to skip
+var robotsDisallow = []string{
+	// Cart-related pages (not useful for search results)
+	"/cart",
+	"/cart-success",
+	"/cart-cancel",
+	// Wishlists are private or shared by link only
+	"/wishlist",
+	// Booking quotes are only for the client they were sent to
+	"/quote",
+	// Confirmation and unsubscribe links from mailing list emails
+	"/subscribe",
+	// Admin and backend paths
+	"/admin/",
+	"/api/",
+}
 
-	// Encode and write sitemap
-	encoder := xml.NewEncoder(w)
-	encoder.Indent("", "  ")
-	if err := encoder.Encode(sitemap); err != nil {
-		log.Printf("Error encoding sitemap: %v", err)
-		http.Error(w, "Failed to generate sitemap", http.StatusInternalServerError)
-		return
+// GetRobotsTxt serves robots.txt pointing crawlers at the sitemap for this environment
+// GET /robots.txt
+func (h *Handler) GetRobotsTxt(w http.ResponseWriter, r *http.Request) {
+	var b strings.Builder
+	b.WriteString("# Nessie Audio - robots.txt (generated by the backend)\n\n")
+	b.WriteString("User-agent: *\n")
+	if h.config.Env == "staging" {
+		// Keep the staging site out of search results
+		b.WriteString("Disallow: /\n")
+	} else {
+		b.WriteString("Allow: /\n")
+		for _, path := range robotsDisallow {
+			b.WriteString("Disallow: " + path + "\n")
+		}
 	}
+	b.WriteString("\nSitemap: " + h.sitemap.IndexURL() + "\n")
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Write([]byte(b.String()))
 }
 
 // getBaseURL returns the appropriate base URL based on environment
@@ -111,23 +97,3 @@ func (h *Handler) getBaseURL() string {
 		return "http://localhost:5500"
 	}
 }
-
-// getProductsForSitemap fetches all product IDs from the database
-func (h *Handler) getProductsForSitemap() ([]string, error) {
-	rows, err := h.db.Query("SELECT id FROM products WHERE active = 1")
-	if err != nil {
-		return nil, fmt.Errorf("query products: %w", err)
-	}
-	defer rows.Close()
-
-	var productIDs []string
-	for rows.Next() {
-		var id string
-		if err := rows.Scan(&id); err != nil {
-			return nil, fmt.Errorf("scan product id: %w", err)
-		}
-		productIDs = append(productIDs, id)
-	}
-
-	return productIDs, nil
-}
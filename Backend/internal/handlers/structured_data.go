@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nessieaudio/ecommerce-backend/internal/catalog"
+	apierrors "github.com/nessieaudio/ecommerce-backend/internal/errors"
+	"github.com/nessieaudio/ecommerce-backend/internal/middleware"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+	"github.com/nessieaudio/ecommerce-backend/internal/tour"
+)
+
+// brandName is the brand shown on products in structured data
+const brandName = "Nessie Audio"
+
+// schema.org availability values
+const (
+	availabilityInStock    = "https://schema.org/InStock"
+	availabilityOutOfStock = "https://schema.org/OutOfStock"
+	availabilityPreOrder   = "https://schema.org/PreOrder"
+)
+
+// ProductJSONLD is a schema.org Product, for <script type="application/ld+json">
+type ProductJSONLD struct {
+	Context     string        `json:"@context"`
+	Type        string        `json:"@type"`
+	ID          string        `json:"@id"`
+	Name        string        `json:"name"`
+	Description string        `json:"description,omitempty"`
+	SKU         string        `json:"sku"`
+	URL         string        `json:"url"`
+	Image       []string      `json:"image,omitempty"`
+	Category    string        `json:"category,omitempty"`
+	Brand       BrandJSONLD   `json:"brand"`
+	Offers      []OfferJSONLD `json:"offers"`
+
+	// Approved reviews only; omitted until the product has one
+	AggregateRating *AggregateRatingJSONLD `json:"aggregateRating,omitempty"`
+	Review          []ReviewJSONLD         `json:"review,omitempty"`
+}
+
+// AggregateRatingJSONLD is a schema.org AggregateRating
+type AggregateRatingJSONLD struct {
+	Type        string  `json:"@type"`
+	RatingValue float64 `json:"ratingValue"`
+	ReviewCount int     `json:"reviewCount"`
+}
+
+// ReviewJSONLD is a schema.org Review
+type ReviewJSONLD struct {
+	Type          string       `json:"@type"`
+	Author        PersonJSONLD `json:"author"`
+	ReviewRating  RatingJSONLD `json:"reviewRating"`
+	ReviewBody    string       `json:"reviewBody,omitempty"`
+	DatePublished string       `json:"datePublished"`
+}
+
+// PersonJSONLD is a schema.org Person
+type PersonJSONLD struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+// RatingJSONLD is a schema.org Rating on a 1-5 scale
+type RatingJSONLD struct {
+	Type        string `json:"@type"`
+	RatingValue int    `json:"ratingValue"`
+	BestRating  int    `json:"bestRating"`
+}
+
+// BrandJSONLD is a schema.org Brand
+type BrandJSONLD struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+// OfferJSONLD is a schema.org Offer for one variant
+type OfferJSONLD struct {
+	Type          string `json:"@type"`
+	SKU           string `json:"sku"`
+	Name          string `json:"name"`
+	URL           string `json:"url"`
+	Price         string `json:"price"`
+	PriceCurrency string `json:"priceCurrency"`
+	Availability  string `json:"availability"`
+	ItemCondition string `json:"itemCondition"`
+	// Pre-orders: the date the variant is expected to ship
+	AvailabilityStarts string `json:"availabilityStarts,omitempty"`
+}
+
+// GetProductStructuredData returns schema.org Product/Offer/Review JSON-LD for a product page
+// GET /api/v1/products/{id}/structured-data
+//
+// {id} is the product's UUID or slug. Availability follows inventory: untracked or
+// in-stock variants are InStock, pre-orders are PreOrder, the rest OutOfStock, as are
+// tour exclusives outside their sale window.
+func (h *Handler) GetProductStructuredData(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	productID, ok := h.resolveProductRef(w, r, requestID)
+	if !ok {
+		return
+	}
+
+	product, err := h.getProductDetail(productID)
+	if errors.Is(err, sql.ErrNoRows) {
+		apierrors.RespondNotFound(w, "Product", requestID)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to fetch product [request_id: "+requestID+", product_id: "+productID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/ld+json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.productJSONLD(product))
+}
+
+// productJSONLD builds the structured data for a loaded product
+func (h *Handler) productJSONLD(product *ProductResponse) ProductJSONLD {
+	baseURL := h.getBaseURL()
+	pageURL := productPageURL(baseURL, product)
+
+	ld := ProductJSONLD{
+		Context:     "https://schema.org/",
+		Type:        "Product",
+		ID:          pageURL + "#product",
+		Name:        product.Name,
+		Description: product.Description,
+		SKU:         product.ID,
+		URL:         pageURL,
+		Category:    product.Category,
+		Brand:       BrandJSONLD{Type: "Brand", Name: brandName},
+		Offers:      []OfferJSONLD{},
+	}
+
+	for _, img := range product.Images {
+		ld.Image = append(ld.Image, absoluteURL(baseURL, img.URL))
+	}
+	if len(ld.Image) == 0 && product.ImageURL != "" {
+		ld.Image = append(ld.Image, absoluteURL(baseURL, product.ImageURL))
+	}
+
+	for _, v := range product.Variants {
+		offer := OfferJSONLD{
+			Type:          "Offer",
+			SKU:           v.ID,
+			Name:          v.Name,
+			URL:           pageURL,
+			Price:         strconv.FormatFloat(v.Price, 'f', 2, 64),
+			PriceCurrency: strings.ToUpper(product.Currency),
+			Availability:  availabilityOutOfStock,
+			ItemCondition: "https://schema.org/NewCondition",
+		}
+		switch {
+		case !v.Available:
+			// A tour exclusive outside its sale window
+		case v.InStock:
+			offer.Availability = availabilityInStock
+		case v.Preorder:
+			offer.Availability = availabilityPreOrder
+			offer.AvailabilityStarts = v.ExpectedShipDate.Format("2006-01-02")
+		}
+		ld.Offers = append(ld.Offers, offer)
+	}
+
+	if product.Rating != nil {
+		ld.AggregateRating = &AggregateRatingJSONLD{
+			Type:        "AggregateRating",
+			RatingValue: product.Rating.Average,
+			ReviewCount: product.Rating.Count,
+		}
+	}
+	for _, review := range product.Reviews {
+		ld.Review = append(ld.Review, ReviewJSONLD{
+			Type:          "Review",
+			Author:        PersonJSONLD{Type: "Person", Name: review.ReviewerName},
+			ReviewRating:  RatingJSONLD{Type: "Rating", RatingValue: review.Rating, BestRating: 5},
+			ReviewBody:    review.Body,
+			DatePublished: review.CreatedAt.Format("2006-01-02"),
+		})
+	}
+
+	return ld
+}
+
+// productPageURL is the public product page for a product
+func productPageURL(baseURL string, product *ProductResponse) string {
+	return baseURL + catalog.ProductPagePath(product.ID, product.Slug)
+}
+
+// absoluteURL resolves a site-relative path such as "/Product Photos/x.jpg" against baseURL
+// Remote URLs are returned unchanged.
+func absoluteURL(baseURL, ref string) string {
+	if !strings.HasPrefix(ref, "/") {
+		return ref
+	}
+	return baseURL + (&url.URL{Path: ref}).EscapedPath()
+}
+
+// schema.org event status values
+const (
+	eventScheduled = "https://schema.org/EventScheduled"
+	eventCancelled = "https://schema.org/EventCancelled"
+)
+
+// MusicEventJSONLD is a schema.org MusicEvent for one show
+type MusicEventJSONLD struct {
+	Context             string             `json:"@context"`
+	Type                string             `json:"@type"`
+	ID                  string             `json:"@id"`
+	Name                string             `json:"name"`
+	URL                 string             `json:"url"`
+	StartDate           string             `json:"startDate"` // With the venue's UTC offset
+	EventStatus         string             `json:"eventStatus"`
+	EventAttendanceMode string             `json:"eventAttendanceMode"`
+	Location            PlaceJSONLD        `json:"location"`
+	Performer           []MusicGroupJSONLD `json:"performer"` // Headliner first, then support
+	Offers              *EventOfferJSONLD  `json:"offers,omitempty"`
+}
+
+// PlaceJSONLD is a schema.org Place
+type PlaceJSONLD struct {
+	Type    string              `json:"@type"`
+	Name    string              `json:"name"`
+	Address PostalAddressJSONLD `json:"address"`
+	Geo     *GeoJSONLD          `json:"geo,omitempty"`
+}
+
+// PostalAddressJSONLD is a schema.org PostalAddress
+type PostalAddressJSONLD struct {
+	Type            string `json:"@type"`
+	AddressLocality string `json:"addressLocality"`
+	AddressRegion   string `json:"addressRegion,omitempty"`
+	AddressCountry  string `json:"addressCountry"`
+}
+
+// GeoJSONLD is a schema.org GeoCoordinates
+type GeoJSONLD struct {
+	Type      string  `json:"@type"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+// MusicGroupJSONLD is a schema.org MusicGroup
+type MusicGroupJSONLD struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+// EventOfferJSONLD is a schema.org Offer for a show's tickets
+type EventOfferJSONLD struct {
+	Type         string `json:"@type"`
+	URL          string `json:"url"`
+	Availability string `json:"availability"`
+}
+
+// musicEventJSONLD builds the structured data for a show
+func (h *Handler) musicEventJSONLD(show *models.Show) MusicEventJSONLD {
+	pageURL := h.getBaseURL() + "/tour"
+
+	ld := MusicEventJSONLD{
+		Context:             "https://schema.org",
+		Type:                "MusicEvent",
+		ID:                  pageURL + "#show-" + show.ID,
+		Name:                brandName + " at " + show.Venue,
+		URL:                 pageURL,
+		StartDate:           show.StartsAt.Format(time.RFC3339),
+		EventStatus:         eventScheduled,
+		EventAttendanceMode: "https://schema.org/OfflineEventAttendanceMode",
+		Location: PlaceJSONLD{
+			Type: "Place",
+			Name: show.Venue,
+			Address: PostalAddressJSONLD{
+				Type:            "PostalAddress",
+				AddressLocality: show.City,
+				AddressRegion:   show.Region,
+				AddressCountry:  show.Country,
+			},
+		},
+		Performer: []MusicGroupJSONLD{{Type: "MusicGroup", Name: brandName}},
+	}
+	if show.Latitude != nil && show.Longitude != nil {
+		ld.Location.Geo = &GeoJSONLD{Type: "GeoCoordinates", Latitude: *show.Latitude, Longitude: *show.Longitude}
+	}
+	for _, act := range show.SupportActs {
+		ld.Performer = append(ld.Performer, MusicGroupJSONLD{Type: "MusicGroup", Name: act})
+	}
+
+	switch show.Status {
+	case tour.StatusCancelled:
+		ld.EventStatus = eventCancelled
+	case tour.StatusSoldOut:
+		if show.TicketURL != "" {
+			ld.Offers = &EventOfferJSONLD{Type: "Offer", URL: show.TicketURL, Availability: "https://schema.org/SoldOut"}
+		}
+	default:
+		if show.TicketURL != "" {
+			ld.Offers = &EventOfferJSONLD{Type: "Offer", URL: show.TicketURL, Availability: availabilityInStock}
+		}
+	}
+
+	return ld
+}
@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	apierrors "github.com/nessieaudio/ecommerce-backend/internal/errors"
+	"github.com/nessieaudio/ecommerce-backend/internal/middleware"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+	"github.com/nessieaudio/ecommerce-backend/internal/newsletter"
+	"github.com/nessieaudio/ecommerce-backend/internal/services/email"
+)
+
+// SubscribeRequest signs up for the mailing list
+type SubscribeRequest struct {
+	Email  string   `json:"email"`
+	City   string   `json:"city"`   // Optional
+	Genres []string `json:"genres"` // Optional genre interests, e.g. ["Metal", "EDM"]
+
+	// Honeypot: hidden from people, so only bots fill it in
+	Website string `json:"website"`
+}
+
+// subscribeReceived is the answer to every sign-up, whether or not an email was
+// sent, so the form doesn't reveal who is already on the list
+var subscribeReceived = map[string]string{
+	"message": "Thanks! Check your inbox and click the link to confirm your subscription.",
+}
+
+// Subscribe starts a double opt-in sign-up by emailing a confirmation link
+// POST /api/v1/subscribe
+//
+// Request: { "email": "fan@example.com", "city": "Portland", "genres": ["Metal"] }
+func (h *Handler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	var req SubscribeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+
+	if req.Website != "" {
+		log.Printf("Subscribe form honeypot filled; dropping sign-up [request_id: %s]", requestID)
+		apierrors.RespondJSON(w, http.StatusAccepted, subscribeReceived)
+		return
+	}
+
+	req.Email = strings.TrimSpace(req.Email)
+	req.City = strings.TrimSpace(req.City)
+	genres, validationErrors := validateSubscribe(&req)
+	if len(validationErrors) > 0 {
+		apierrors.RespondValidationError(w, validationErrors, requestID)
+		return
+	}
+
+	subscriber, send, err := newsletter.NewService(h.db).Subscribe(req.Email, req.City, genres)
+	if err != nil {
+		h.logger.Error("Failed to subscribe [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+	if send {
+		go h.sendSubscriptionConfirmation(subscriber)
+	}
+
+	apierrors.RespondJSON(w, http.StatusAccepted, subscribeReceived)
+}
+
+// validateSubscribe checks a sign-up and returns its genres trimmed and without duplicates
+func validateSubscribe(req *SubscribeRequest) ([]string, []apierrors.ValidationError) {
+	var validationErrors []apierrors.ValidationError
+	invalid := func(field, message string) {
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: field, Message: message})
+	}
+
+	if len(req.Email) > 254 || !contactEmailPattern.MatchString(req.Email) {
+		invalid("email", "must be a valid email address")
+	}
+	if utf8.RuneCountInString(req.City) > 100 || strings.IndexFunc(req.City, unicode.IsControl) >= 0 {
+		invalid("city", "must be at most 100 characters")
+	}
+
+	if len(req.Genres) > newsletter.MaxGenres {
+		invalid("genres", fmt.Sprintf("must have at most %d genres", newsletter.MaxGenres))
+		return nil, validationErrors
+	}
+	genres := []string{}
+	seen := make(map[string]bool)
+	for _, genre := range req.Genres {
+		genre = strings.TrimSpace(genre)
+		if genre == "" || utf8.RuneCountInString(genre) > 50 || strings.IndexFunc(genre, unicode.IsControl) >= 0 {
+			invalid("genres", "must each be between 1 and 50 characters")
+			break
+		}
+		if key := strings.ToLower(genre); !seen[key] {
+			seen[key] = true
+			genres = append(genres, genre)
+		}
+	}
+
+	return genres, validationErrors
+}
+
+// ConfirmSubscription completes a sign-up from the link in the confirmation email
+// POST /api/v1/subscribe/confirm/{token}
+//
+// The emailed link opens /subscribe, which makes this request, so link scanners
+// that follow the link don't confirm it on the reader's behalf.
+func (h *Handler) ConfirmSubscription(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	subscriber, err := newsletter.NewService(h.db).Confirm(mux.Vars(r)["token"])
+	if errors.Is(err, sql.ErrNoRows) {
+		apierrors.RespondError(w, http.StatusNotFound, "This confirmation link is invalid or has expired", apierrors.ErrCodeNotFound, nil, requestID)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to confirm subscription [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	go h.sendWelcomeEmail(subscriber)
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]string{
+		"status": subscriber.Status,
+		"email":  subscriber.Email,
+	})
+}
+
+// Unsubscribe takes an address off the mailing list
+// POST /api/v1/unsubscribe/{token}
+//
+// This is the one-click List-Unsubscribe URL of RFC 8058: mail clients POST
+// "List-Unsubscribe=One-Click" to it. The unsubscribe link in emails opens
+// /subscribe, which POSTs here too.
+func (h *Handler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	subscriber, err := newsletter.NewService(h.db).Unsubscribe(mux.Vars(r)["token"])
+	if errors.Is(err, sql.ErrNoRows) {
+		apierrors.RespondNotFound(w, "Subscription", requestID)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to unsubscribe [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]string{
+		"status": subscriber.Status,
+		"email":  subscriber.Email,
+	})
+}
+
+// subscribeFromCheckout adds a buyer who ticked the promotional email box in
+// Stripe Checkout, welcoming them if they are new to the list
+func (h *Handler) subscribeFromCheckout(customerEmail, city string) {
+	subscriber, added, err := newsletter.NewService(h.db).SubscribeFromCheckout(customerEmail, city)
+	if err != nil {
+		log.Printf("Failed to subscribe %s from checkout: %v", customerEmail, err)
+		return
+	}
+	if added {
+		log.Printf("Subscribed %s to the mailing list from checkout", customerEmail)
+		h.sendWelcomeEmail(subscriber)
+	}
+}
+
+// subscribePageURL is the page confirmation and unsubscribe links open
+func (h *Handler) subscribePageURL(param, token string) string {
+	return h.getBaseURL() + "/subscribe?" + param + "=" + url.QueryEscape(token)
+}
+
+// sendSubscriptionConfirmation emails the double opt-in link
+func (h *Handler) sendSubscriptionConfirmation(subscriber *models.Subscriber) {
+	contentHTML := fmt.Sprintf(`<p style="font-size:16px;">Thanks for signing up for news from %s. Please confirm it's you by clicking the button below.</p>%s%s`,
+		brandName,
+		email.CTAButton("Confirm subscription", h.subscribePageURL("confirm", subscriber.ConfirmToken)),
+		email.NoteBox(fmt.Sprintf("The link works for %d days. If you didn't sign up, ignore this email and you won't hear from us again.",
+			int(newsletter.ConfirmationValidity.Hours()/24)), false),
+	)
+	htmlBody := email.EmailLayout("Confirm Your Subscription", "&#9993;&#65039;", contentHTML, false)
+
+	if err := h.emailClient.SendHTMLEmail(subscriber.Email, "Confirm your subscription to "+brandName, htmlBody); err != nil {
+		log.Printf("Failed to send subscription confirmation to %s: %v", subscriber.Email, err)
+	}
+}
+
+// sendWelcomeEmail is the first mailing list email, with one-click unsubscribe
+func (h *Handler) sendWelcomeEmail(subscriber *models.Subscriber) {
+	interests := ""
+	if len(subscriber.Genres) > 0 {
+		interests = fmt.Sprintf(" We'll keep you posted on %s.", html.EscapeString(strings.Join(subscriber.Genres, ", ")))
+	}
+
+	contentHTML := fmt.Sprintf(`<p style="font-size:16px;">You're on the %s mailing list: new releases, merch drops and shows near you.%s</p>%s%s`,
+		brandName,
+		interests,
+		email.CTAButton("Visit the site", h.getBaseURL()),
+		email.NoteBox(fmt.Sprintf(`Don't want these emails? <a href="%s">Unsubscribe</a> at any time.`,
+			h.subscribePageURL("unsubscribe", subscriber.UnsubscribeToken)), false),
+	)
+	htmlBody := email.EmailLayout("Welcome to the List", "&#127926;", contentHTML, false)
+	unsubscribeURL := h.getAPIBaseURL() + "/api/v1/unsubscribe/" + url.PathEscape(subscriber.UnsubscribeToken)
+
+	if err := h.emailClient.SendListEmail(subscriber.Email, "You're on the "+brandName+" list", htmlBody, unsubscribeURL); err != nil {
+		log.Printf("Failed to send welcome email to %s: %v", subscriber.Email, err)
+	}
+}
+
+// subscriberSegment reads the segment filters of the admin subscriber endpoints
+// status defaults to subscribed; "all" includes every status.
+func subscriberSegment(r *http.Request) (newsletter.Segment, []apierrors.ValidationError) {
+	query := r.URL.Query()
+	segment := newsletter.Segment{
+		Status: query.Get("status"),
+		Genre:  strings.TrimSpace(query.Get("genre")),
+		City:   strings.TrimSpace(query.Get("city")),
+	}
+	var validationErrors []apierrors.ValidationError
+
+	switch segment.Status {
+	case "":
+		segment.Status = models.SubscriberStatusSubscribed
+	case "all":
+		segment.Status = ""
+	case models.SubscriberStatusPending, models.SubscriberStatusSubscribed, models.SubscriberStatusUnsubscribed:
+	default:
+		validationErrors = append(validationErrors, apierrors.ValidationError{Field: "status", Message: "must be pending, subscribed, unsubscribed or all"})
+	}
+
+	if v := query.Get("purchaser"); v != "" {
+		purchaser, err := strconv.ParseBool(v)
+		if err != nil {
+			validationErrors = append(validationErrors, apierrors.ValidationError{Field: "purchaser", Message: "must be true or false"})
+		}
+		segment.Purchaser = &purchaser
+	}
+
+	return segment, validationErrors
+}
+
+// GetAdminSubscribers lists mailing list subscribers in a segment, newest first
+// GET /api/v1/admin/subscribers?status=subscribed&genre=Metal&city=Portland&purchaser=true
+func (h *Handler) GetAdminSubscribers(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	segment, validationErrors := subscriberSegment(r)
+	if len(validationErrors) > 0 {
+		apierrors.RespondValidationError(w, validationErrors, requestID)
+		return
+	}
+
+	list, err := newsletter.NewService(h.db).List(segment)
+	if err != nil {
+		h.logger.Error("Failed to fetch subscribers [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"subscribers": list,
+		"count":       len(list),
+	})
+}
+
+// ExportSubscribers downloads a segment of the mailing list as CSV
+// GET /api/v1/admin/subscribers/export?status=subscribed&genre=Metal
+//
+// Takes the same filters as GetAdminSubscribers. unsubscribe_url is the one-click
+// URL for the List-Unsubscribe header of mail sent from another tool.
+func (h *Handler) ExportSubscribers(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	segment, validationErrors := subscriberSegment(r)
+	if len(validationErrors) > 0 {
+		apierrors.RespondValidationError(w, validationErrors, requestID)
+		return
+	}
+
+	list, err := newsletter.NewService(h.db).List(segment)
+	if err != nil {
+		h.logger.Error("Failed to export subscribers [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="subscribers-%s.csv"`, time.Now().Format("2006-01-02")))
+	w.Header().Set("Cache-Control", "no-store")
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"email", "status", "source", "city", "genres", "purchaser", "confirmed_at", "created_at", "unsubscribe_url"})
+	for _, s := range list {
+		confirmedAt := ""
+		if s.ConfirmedAt != nil {
+			confirmedAt = s.ConfirmedAt.UTC().Format(time.RFC3339)
+		}
+		out.Write([]string{
+			csvSafe(s.Email),
+			s.Status,
+			s.Source,
+			csvSafe(s.City),
+			csvSafe(strings.Join(s.Genres, ";")),
+			strconv.FormatBool(s.Purchaser),
+			confirmedAt,
+			s.CreatedAt.UTC().Format(time.RFC3339),
+			h.getAPIBaseURL() + "/api/v1/unsubscribe/" + url.PathEscape(s.UnsubscribeToken),
+		})
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		log.Printf("Failed to write subscriber export: %v", err)
+	}
+}
+
+// csvSafe keeps visitor input from being read as a formula when the export is
+// opened in a spreadsheet
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
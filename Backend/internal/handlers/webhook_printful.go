@@ -10,6 +10,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
 	"github.com/nessieaudio/ecommerce-backend/internal/services/email"
 )
 
@@ -171,8 +172,8 @@ func (h *Handler) handlePrintfulOrderFailed(payload PrintfulWebhookPayload) {
 		return
 	}
 
-	// Mark order as failed
-	if err := h.orderService.UpdateOrderStatus(orderID, "failed"); err != nil {
+	// Mark the Printful fulfillment as failed; downloads in the same order are unaffected
+	if err := h.orderService.UpdateFulfillment(orderID, models.FulfillmentChannelPrintful, models.FulfillmentStatusFailed, "Printful reported the order failed"); err != nil {
 		log.Printf("Failed to update order status: %v", err)
 	}
 
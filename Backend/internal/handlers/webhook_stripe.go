@@ -10,9 +10,12 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/nessieaudio/ecommerce-backend/internal/downloads"
+	"github.com/nessieaudio/ecommerce-backend/internal/inventory"
 	"github.com/nessieaudio/ecommerce-backend/internal/logger"
 	"github.com/nessieaudio/ecommerce-backend/internal/models"
 	"github.com/nessieaudio/ecommerce-backend/internal/services/email"
+	"github.com/nessieaudio/ecommerce-backend/internal/services/order"
 	"github.com/nessieaudio/ecommerce-backend/internal/services/stripe"
 	stripeLib "github.com/stripe/stripe-go/v76"
 	"github.com/stripe/stripe-go/v76/webhook"
@@ -68,9 +71,14 @@ func (h *Handler) HandleStripeWebhook(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Process based on event type
+	// Deposits on booking quotes go to their engagement rather than becoming orders
 	switch event.Type {
 	case "checkout.session.completed":
-		h.handleCheckoutSessionCompleted(event)
+		if session, ok := depositSession(event); ok {
+			h.handleDepositCompleted(session)
+		} else {
+			h.handleCheckoutSessionCompleted(event)
+		}
 
 	case "payment_intent.succeeded":
 		log.Printf("PaymentIntent succeeded: %s", event.ID)
@@ -82,7 +90,11 @@ func (h *Handler) HandleStripeWebhook(w http.ResponseWriter, r *http.Request) {
 		h.handlePaymentCanceled(event)
 
 	case "checkout.session.expired":
-		h.handleCheckoutExpired(event)
+		if session, ok := depositSession(event); ok {
+			log.Printf("Deposit checkout %s for quote %s expired", session.ID, session.Metadata["quote_id"])
+		} else {
+			h.handleCheckoutExpired(event)
+		}
 
 	default:
 		log.Printf("Unhandled event type: %s", event.Type)
@@ -91,6 +103,16 @@ func (h *Handler) HandleStripeWebhook(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, map[string]string{"status": "received"})
 }
 
+// depositSession returns the checkout session of a checkout.session event if it
+// pays a quote deposit
+func depositSession(event stripeLib.Event) (*stripeLib.CheckoutSession, bool) {
+	var session stripeLib.CheckoutSession
+	if err := json.Unmarshal(event.Data.Raw, &session); err != nil {
+		return nil, false
+	}
+	return &session, stripe.SessionType(&session) == stripe.SessionTypeDeposit
+}
+
 // handlePaymentFailed processes payment failures and sends admin alert
 func (h *Handler) handlePaymentFailed(event stripeLib.Event) {
 	var paymentIntent stripeLib.PaymentIntent
@@ -304,14 +326,71 @@ func (h *Handler) handleCheckoutSessionCompleted(event stripeLib.Event) {
 
 	log.Printf("Order %s marked as paid", orderID)
 
+	// ====== MAILING LIST ======
+	if stripe.PromotionsOptIn(fullSession) && fullSession.CustomerDetails != nil {
+		city := ""
+		if shipping := stripe.ExtractShippingFromSession(fullSession); shipping != nil {
+			city = shipping.City
+		}
+		go h.subscribeFromCheckout(fullSession.CustomerDetails.Email, city)
+	}
+
+	// One fulfillment per channel the order uses; each goes ahead on its own
+	fulfillments, err := h.orderService.CreateFulfillments(orderID)
+	if err != nil {
+		log.Printf("Failed to create fulfillments for order %s: %v", orderID, err)
+		return
+	}
+	pending := make(map[string]bool)
+	for _, f := range fulfillments {
+		pending[f.Channel] = f.Status == models.FulfillmentStatusPending
+	}
+
+	// ====== ISSUE DOWNLOADS ======
+	// Before the confirmation email, which carries the download links
+	if pending[models.FulfillmentChannelDigital] {
+		h.fulfillDownloads(orderID)
+	}
+
 	// ====== SEND ORDER CONFIRMATION EMAIL ======
 	go h.sendOrderConfirmationEmail(orderID, fullSession)
 
+	if !pending[models.FulfillmentChannelPrintful] {
+		return
+	}
+
+	// ====== HOLD PRE-ORDERS ======
+	// Orders with pre-order items wait for their ship date (or an admin release)
+	if order.FulfillmentHoldUntil != nil {
+		log.Printf("Order %s contains pre-order items - holding Printful submission until %s",
+			orderID, order.FulfillmentHoldUntil.Format("2006-01-02"))
+		return
+	}
+
 	// ====== SUBMIT TO PRINTFUL ======
 	// This is the critical step - only submit after payment is confirmed
 	go h.submitOrderToPrintful(orderID)
 }
 
+// fulfillDownloads issues an order's downloads and records the digital fulfillment
+func (h *Handler) fulfillDownloads(orderID string) {
+	downloadService := downloads.NewService(h.db, h.signer, h.config.DigitalFilesDir)
+	issued, err := downloadService.Issue(orderID)
+	if err != nil {
+		log.Printf("Failed to issue downloads for order %s: %v", orderID, err)
+		if err := h.orderService.UpdateFulfillment(orderID, models.FulfillmentChannelDigital, models.FulfillmentStatusFailed, err.Error()); err != nil {
+			log.Printf("Failed to update digital fulfillment: %v", err)
+		}
+		return
+	}
+	if issued > 0 {
+		log.Printf("Issued %d downloads for order %s", issued, orderID)
+	}
+	if err := h.orderService.UpdateFulfillment(orderID, models.FulfillmentChannelDigital, models.FulfillmentStatusFulfilled, ""); err != nil {
+		log.Printf("Failed to update digital fulfillment: %v", err)
+	}
+}
+
 // submitOrderToPrintful submits a paid order to Printful for fulfillment
 // Runs asynchronously to not block webhook response
 // Implements immediate retry with exponential backoff (3 attempts: 1s, 2s, 4s)
@@ -320,20 +399,26 @@ func (h *Handler) submitOrderToPrintful(orderID string) {
 	backoffDurations := []time.Duration{1 * time.Second, 2 * time.Second, 4 * time.Second}
 
 	for attempt := 1; attempt <= maxImmediateRetries; attempt++ {
-		order, err := h.orderService.GetOrder(orderID)
+		items, err := h.orderService.GetOrderItems(orderID)
 		if err != nil {
-			log.Printf("Failed to get order for Printful: %v", err)
+			log.Printf("Failed to get order items for Printful: %v", err)
 			return
 		}
 
-		items, err := h.orderService.GetOrderItems(orderID)
+		// Orders of digital products only have nothing to ship
+		if !order.NeedsShipping(items) {
+			log.Printf("Order %s has only digital items - nothing to submit to Printful", orderID)
+			return
+		}
+
+		ord, err := h.orderService.GetOrder(orderID)
 		if err != nil {
-			log.Printf("Failed to get order items for Printful: %v", err)
+			log.Printf("Failed to get order for Printful: %v", err)
 			return
 		}
 
 		// Attempt to submit to Printful
-		printfulOrderID, err := h.printfulClient.CreateOrder(order, items)
+		printfulOrderID, err := h.printfulClient.CreateOrder(ord, items)
 		if err != nil {
 			log.Printf("Printful submission attempt %d/%d failed for order %s: %v", attempt, maxImmediateRetries, orderID, err)
 
@@ -382,18 +467,12 @@ func (h *Handler) submitOrderToPrintful(orderID string) {
 			return
 		}
 
-		// Update order with Printful ID
-		if err := h.orderService.UpdateOrderWithPrintful(orderID, printfulOrderID); err != nil {
+		// Record the Printful ID; the Printful fulfillment is done
+		if err := h.orderService.MarkPrintfulSubmitted(orderID, printfulOrderID); err != nil {
 			log.Printf("Failed to update order with Printful ID: %v", err)
 			return
 		}
 
-		// Update status to fulfilled
-		if err := h.orderService.UpdateOrderStatus(orderID, models.OrderStatusFulfilled); err != nil {
-			log.Printf("Failed to update order status: %v", err)
-			return
-		}
-
 		log.Printf("✅ Order %s submitted to Printful successfully (ID: %d) on attempt %d", orderID, printfulOrderID, attempt)
 		return
 	}
@@ -402,17 +481,18 @@ func (h *Handler) submitOrderToPrintful(orderID string) {
 // sendOrderConfirmationEmail sends order confirmation email to customer
 // Runs asynchronously to not block webhook response
 func (h *Handler) sendOrderConfirmationEmail(orderID string, session *stripeLib.CheckoutSession) {
-	// Get order details
-	order, err := h.orderService.GetOrder(orderID)
+	// Get order items
+	items, err := h.orderService.GetOrderItems(orderID)
 	if err != nil {
-		log.Printf("Failed to get order for email: %v", err)
+		log.Printf("Failed to get order items for email: %v", err)
 		return
 	}
+	ships := order.NeedsShipping(items)
 
-	// Get order items
-	items, err := h.orderService.GetOrderItems(orderID)
+	// Get order details
+	ord, err := h.orderService.GetOrder(orderID)
 	if err != nil {
-		log.Printf("Failed to get order items for email: %v", err)
+		log.Printf("Failed to get order for email: %v", err)
 		return
 	}
 
@@ -440,18 +520,18 @@ func (h *Handler) sendOrderConfirmationEmail(orderID string, session *stripeLib.
 	}
 
 	// Extract shipping info from order
-	shippingAddress := order.ShippingAddress1
-	if order.ShippingAddress2 != "" {
-		shippingAddress += ", " + order.ShippingAddress2
+	shippingAddress := ord.ShippingAddress1
+	if ord.ShippingAddress2 != "" {
+		shippingAddress += ", " + ord.ShippingAddress2
 	}
 
 	shippingInfo := email.ShippingInfo{
-		Name:    order.ShippingName,
+		Name:    ord.ShippingName,
 		Address: shippingAddress,
-		City:    order.ShippingCity,
-		State:   order.ShippingState,
-		Zip:     order.ShippingZip,
-		Country: order.ShippingCountry,
+		City:    ord.ShippingCity,
+		State:   ord.ShippingState,
+		Zip:     ord.ShippingZip,
+		Country: ord.ShippingCountry,
 	}
 
 	// If we have session shipping details, use those (more complete)
@@ -481,14 +561,23 @@ func (h *Handler) sendOrderConfirmationEmail(orderID string, session *stripeLib.
 		}
 	}
 
+	downloadService := downloads.NewService(h.db, h.signer, h.config.DigitalFilesDir)
+	orderDownloads, err := downloadService.ForOrder(orderID, h.getAPIBaseURL())
+	if err != nil {
+		log.Printf("Failed to get downloads for email: %v", err)
+	}
+
 	// Prepare email data
 	emailData := email.OrderConfirmationData{
-		OrderID:       orderID,
-		CustomerName:  customerName,
-		CustomerEmail: customerEmail,
-		Items:         items,
-		Total:         order.TotalAmount,
-		ShippingInfo:  shippingInfo,
+		OrderID:          orderID,
+		CustomerName:     customerName,
+		CustomerEmail:    customerEmail,
+		Items:            items,
+		Total:            ord.TotalAmount,
+		ShippingInfo:     shippingInfo,
+		PreorderShipDate: ord.FulfillmentHoldUntil,
+		Downloads:        orderDownloads,
+		Ships:            ships,
 	}
 
 	// Send email
@@ -571,12 +660,15 @@ func (h *Handler) createOrderFromSession(session *stripeLib.CheckoutSession) (*m
 
 	// Create order items — use cart metadata for proper product/variant IDs
 	if len(cartItems) > 0 {
+		inventoryService := inventory.NewService(h.db)
+		var holdUntil *time.Time
+
 		// We have cart metadata — use it to insert items with correct IDs
 		for _, ci := range cartItems {
 			// Look up product name and variant name from the database
-			var productName, variantName string
+			var productName, variantName, productType string
 			var price float64
-			err = h.db.QueryRow("SELECT name FROM products WHERE id = ?", ci.ProductID).Scan(&productName)
+			err = h.db.QueryRow("SELECT name, product_type FROM products WHERE id = ?", ci.ProductID).Scan(&productName, &productType)
 			if err != nil {
 				log.Printf("WARNING: Could not find product %s: %v", ci.ProductID, err)
 				productName = "Unknown Product"
@@ -587,6 +679,20 @@ func (h *Handler) createOrderFromSession(session *stripeLib.CheckoutSession) (*m
 				variantName = ""
 			}
 
+			// Payment is already captured, so stock problems are logged rather than rejected.
+			// Units sold past zero on a pre-order variant hold the order until its ship date.
+			var preorderShipDate *time.Time
+			stockCheck, err := inventoryService.CheckStock(ci.VariantID, int(ci.Quantity))
+			if err != nil {
+				log.Printf("WARNING: Could not check stock for variant %s: %v", ci.VariantID, err)
+			} else if stockCheck.Preorder {
+				preorderShipDate = stockCheck.ExpectedShipDate
+				holdUntil = order.LaterHold(holdUntil, preorderShipDate)
+			}
+			if err := inventoryService.DeductStock(ci.VariantID, int(ci.Quantity)); err != nil {
+				log.Printf("WARNING: Could not deduct stock for variant %s: %v", ci.VariantID, err)
+			}
+
 			itemID := uuid.New().String()
 			totalPrice := price * float64(ci.Quantity)
 
@@ -594,12 +700,24 @@ func (h *Handler) createOrderFromSession(session *stripeLib.CheckoutSession) (*m
 				INSERT INTO order_items (
 					id, order_id, product_id, variant_id,
 					product_name, variant_name,
-					quantity, unit_price, total_price, created_at
-				) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+					quantity, unit_price, total_price, preorder_ship_date, unit_cost, fulfillment_channel, created_at
+				) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, (SELECT printful_cost FROM variants WHERE id = ?), ?, ?)
 			`, itemID, orderID, ci.ProductID, ci.VariantID,
 				productName, variantName,
-				ci.Quantity, price, totalPrice, time.Now())
+				ci.Quantity, price, totalPrice, preorderShipDate, ci.VariantID, order.ChannelForProductType(productType), time.Now())
+
+			if err != nil {
+				return nil, err
+			}
+
+			// Bundles are fulfilled as their components, priced from the bundle price
+			if err := order.SnapshotBundleComponents(h.db, itemID, ci.VariantID, price); err != nil {
+				return nil, err
+			}
+		}
 
+		if holdUntil != nil {
+			_, err = h.db.Exec(`UPDATE orders SET fulfillment_hold_until = ? WHERE id = ?`, holdUntil, orderID)
 			if err != nil {
 				return nil, err
 			}
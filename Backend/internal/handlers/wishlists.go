@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	apierrors "github.com/nessieaudio/ecommerce-backend/internal/errors"
+	"github.com/nessieaudio/ecommerce-backend/internal/middleware"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+	"github.com/nessieaudio/ecommerce-backend/internal/services/email"
+	"github.com/nessieaudio/ecommerce-backend/internal/wishlist"
+)
+
+// WishlistResponse is a wishlist as seen by its owner
+type WishlistResponse struct {
+	Token     string                `json:"token"`     // Keep private; grants edit access
+	ShareURL  string                `json:"share_url"` // Read-only link for friends
+	Email     string                `json:"email,omitempty"`
+	Notify    bool                  `json:"notify"`
+	Items     []models.WishlistItem `json:"items"`
+	UpdatedAt time.Time             `json:"updated_at"`
+}
+
+// SharedWishlistResponse is the read-only view behind a share link
+type SharedWishlistResponse struct {
+	Items     []models.WishlistItem `json:"items"`
+	UpdatedAt time.Time             `json:"updated_at"`
+}
+
+// UpdateWishlistRequest attaches an email to a wishlist
+type UpdateWishlistRequest struct {
+	Email  string `json:"email"`
+	Notify bool   `json:"notify"` // Email when a saved variant is back in stock or cheaper
+}
+
+// AddWishlistItemRequest saves a variant
+type AddWishlistItemRequest struct {
+	VariantID string `json:"variant_id"`
+}
+
+// CreateWishlist starts an anonymous wishlist
+// POST /api/v1/wishlists
+//
+// The response token is the only key to the wishlist; the frontend keeps it in localStorage.
+func (h *Handler) CreateWishlist(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	wishlistService := wishlist.NewService(h.db)
+	list, err := wishlistService.Create()
+	if err != nil {
+		h.logger.Error("Failed to create wishlist [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusCreated, h.wishlistResponse(list))
+}
+
+// GetWishlist returns a wishlist with current prices and availability
+// GET /api/v1/wishlists/{token}
+func (h *Handler) GetWishlist(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	wishlistService := wishlist.NewService(h.db)
+	list, err := wishlistService.Get(mux.Vars(r)["token"])
+	if err != nil {
+		h.respondWishlistError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, h.wishlistResponse(list))
+}
+
+// GetSharedWishlist returns the read-only view of a wishlist
+// GET /api/v1/wishlists/shared/{share_token}
+func (h *Handler) GetSharedWishlist(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	wishlistService := wishlist.NewService(h.db)
+	list, err := wishlistService.GetShared(mux.Vars(r)["share_token"])
+	if err != nil {
+		h.respondWishlistError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, SharedWishlistResponse{
+		Items:     list.Items,
+		UpdatedAt: list.UpdatedAt,
+	})
+}
+
+// UpdateWishlist attaches an email (and so a customer) to a wishlist and sets notifications
+// PUT /api/v1/wishlists/{token}
+//
+// Request: { "email": "fan@example.com", "notify": true }
+func (h *Handler) UpdateWishlist(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	var req UpdateWishlistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+	if _, err := mail.ParseAddress(req.Email); err != nil {
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "email", Message: "must be a valid email address"}}, requestID)
+		return
+	}
+
+	wishlistService := wishlist.NewService(h.db)
+	list, err := wishlistService.Promote(mux.Vars(r)["token"], req.Email, req.Notify)
+	if err != nil {
+		h.respondWishlistError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, h.wishlistResponse(list))
+}
+
+// AddWishlistItem saves a variant to a wishlist
+// POST /api/v1/wishlists/{token}/items
+//
+// Request: { "variant_id": "..." }
+func (h *Handler) AddWishlistItem(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+
+	var req AddWishlistItemRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.ErrCodeBadRequest, nil, requestID)
+		return
+	}
+	if req.VariantID == "" {
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "variant_id", Message: "is required"}}, requestID)
+		return
+	}
+
+	wishlistService := wishlist.NewService(h.db)
+	list, err := wishlistService.AddItem(mux.Vars(r)["token"], req.VariantID)
+	if err != nil {
+		h.respondWishlistError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, h.wishlistResponse(list))
+}
+
+// RemoveWishlistItem removes a variant from a wishlist
+// DELETE /api/v1/wishlists/{token}/items/{variant_id}
+func (h *Handler) RemoveWishlistItem(w http.ResponseWriter, r *http.Request) {
+	requestID := middleware.GetRequestID(r.Context())
+	vars := mux.Vars(r)
+
+	wishlistService := wishlist.NewService(h.db)
+	list, err := wishlistService.RemoveItem(vars["token"], vars["variant_id"])
+	if err != nil {
+		h.respondWishlistError(w, err, requestID)
+		return
+	}
+
+	apierrors.RespondJSON(w, http.StatusOK, h.wishlistResponse(list))
+}
+
+// wishlistResponse is the owner's view of a wishlist
+func (h *Handler) wishlistResponse(list *models.Wishlist) WishlistResponse {
+	return WishlistResponse{
+		Token:     list.Token,
+		ShareURL:  h.getBaseURL() + "/wishlist?share=" + url.QueryEscape(list.ShareToken),
+		Email:     list.Email,
+		Notify:    list.Notify,
+		Items:     list.Items,
+		UpdatedAt: list.UpdatedAt,
+	}
+}
+
+// respondWishlistError maps wishlist service errors to API responses
+func (h *Handler) respondWishlistError(w http.ResponseWriter, err error, requestID string) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		apierrors.RespondNotFound(w, "Wishlist", requestID)
+	case errors.Is(err, wishlist.ErrUnknownVariant):
+		apierrors.RespondValidationError(w, []apierrors.ValidationError{{Field: "variant_id", Message: err.Error()}}, requestID)
+	case errors.Is(err, wishlist.ErrFull):
+		apierrors.RespondError(w, http.StatusConflict, fmt.Sprintf("Wishlists hold at most %d items", wishlist.MaxItems), apierrors.ErrCodeConflict, nil, requestID)
+	default:
+		h.logger.Error("Wishlist operation failed [request_id: "+requestID+"]", err)
+		apierrors.RespondInternalError(w, requestID)
+	}
+}
+
+// StartWishlistNotifier emails wishlist owners about restocks and price drops on a fixed interval
+func (h *Handler) StartWishlistNotifier(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			h.SendWishlistAlerts()
+		}
+	}()
+
+	log.Printf("Wishlist notifier started (every %v)", interval)
+}
+
+// SendWishlistAlerts emails each owner with notifications on about saved variants
+// that came back in stock or dropped in price
+func (h *Handler) SendWishlistAlerts() {
+	wishlistService := wishlist.NewService(h.db)
+	alerts, err := wishlistService.PendingAlerts()
+	if err != nil {
+		log.Printf("Failed to check wishlists for alerts: %v", err)
+		return
+	}
+
+	for _, alert := range alerts {
+		if err := h.sendWishlistAlert(alert); err != nil {
+			log.Printf("Failed to send wishlist alert for wishlist %s: %v", alert.WishlistID, err)
+			continue
+		}
+		if err := wishlistService.MarkAlerted(alert); err != nil {
+			log.Printf("Failed to record wishlist alert for wishlist %s: %v", alert.WishlistID, err)
+		}
+	}
+}
+
+// sendWishlistAlert emails one owner the restocks and price drops on their wishlist
+func (h *Handler) sendWishlistAlert(alert wishlist.Alert) error {
+	var rows strings.Builder
+	for _, item := range alert.Items {
+		name := html.EscapeString(item.ProductName + " - " + item.VariantName)
+		switch item.Kind {
+		case wishlist.AlertBackInStock:
+			rows.WriteString(email.DetailRow(name, fmt.Sprintf("Back in stock - $%.2f", item.Price)))
+		case wishlist.AlertPriceDrop:
+			rows.WriteString(email.DetailRow(name, fmt.Sprintf("Now $%.2f (was $%.2f)", item.Price, item.PreviousPrice)))
+		}
+	}
+
+	subject := "Something on your wishlist is back in stock"
+	if alert.Items[0].Kind == wishlist.AlertPriceDrop {
+		subject = "Something on your wishlist just got cheaper"
+	}
+
+	wishlistURL := h.getBaseURL() + "/wishlist?token=" + url.QueryEscape(alert.Token)
+	contentHTML := fmt.Sprintf(`<p style="font-size:16px;">Good news from your Nessie Audio wishlist:</p>%s%s%s`,
+		email.InfoBox("Wishlist updates", rows.String()),
+		email.CTAButton("View your wishlist", wishlistURL),
+		email.NoteBox("You're getting this because you turned on wishlist alerts. Turn them off from your wishlist page.", false),
+	)
+	htmlBody := email.EmailLayout("Wishlist update", "&#128150;", contentHTML, false)
+
+	return h.emailClient.SendHTMLEmail(alert.Email, subject, htmlBody)
+}
@@ -0,0 +1,380 @@
+package imaging
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chai2010/webp"
+)
+
+// Widths are the only sizes the resize endpoint will produce. Anything else is
+// rejected so arbitrary widths cannot be used to fill the disk cache.
+var Widths = []int{320, 640, 960, 1280, 1920}
+
+// Output formats. By default photos are re-encoded as JPEG and PNG sources stay
+// PNG to keep transparency; WebP (which keeps transparency too) is served to
+// browsers that accept it. There is no AVIF encoder available to us yet.
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+)
+
+// jpegQuality and webpQuality are part of the cache key; changing them
+// invalidates cached files
+const (
+	jpegQuality = 82
+	webpQuality = 80
+)
+
+// maxSourcePixels guards against decompression bombs in uploaded photos
+const maxSourcePixels = 50_000_000
+
+// resizePrefix is the URL prefix of the resize endpoint (see handlers.GetResizedImage)
+const resizePrefix = "/api/v1/images/"
+
+// sourceRoot is the only directory under the static root that can be resized
+const sourceRoot = "Product Photos"
+
+var (
+	// ErrInvalidWidth is returned for widths outside the allowlist
+	ErrInvalidWidth = errors.New("width is not allowed")
+	// ErrInvalidFormat is returned for formats we cannot encode
+	ErrInvalidFormat = errors.New("format is not supported")
+	// ErrSourceNotFound is returned when the source image does not exist or is outside Product Photos
+	ErrSourceNotFound = errors.New("source image not found")
+)
+
+// Resized is a cached, resized rendition ready to be served
+type Resized struct {
+	Path        string    // File in the cache directory
+	ContentType string    // e.g. "image/jpeg"
+	ETag        string    // Strong ETag, quoted
+	ModTime     time.Time // Source modification time
+	Version     string    // Source version the rendition was built from (see Srcset)
+}
+
+// Service resizes product photos and caches the results on disk
+type Service struct {
+	staticDir string
+	cacheDir  string
+
+	encodeSlots chan struct{} // Bounds concurrent decodes, which are CPU and memory heavy
+
+	mu      sync.Mutex
+	configs map[string]sourceInfo // Source path -> dimensions at a given mtime
+}
+
+type sourceInfo struct {
+	modTime time.Time
+	size    int64
+	width   int
+	height  int
+	format  string // Decoder name: "jpeg" or "png"
+}
+
+// NewService creates an image service reading from staticDir and caching into cacheDir
+func NewService(staticDir, cacheDir string) *Service {
+	return &Service{
+		staticDir:   staticDir,
+		cacheDir:    cacheDir,
+		encodeSlots: make(chan struct{}, 2),
+		configs:     make(map[string]sourceInfo),
+	}
+}
+
+// IsAllowedWidth reports whether width is in the allowlist
+func IsAllowedWidth(width int) bool {
+	for _, w := range Widths {
+		if w == width {
+			return true
+		}
+	}
+	return false
+}
+
+// NegotiateFormat picks the output format for a request's Accept header: WebP
+// when the browser lists it, otherwise "" for the source's default format
+func NegotiateFormat(accept string) string {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || mediaType != "image/webp" {
+			continue
+		}
+		if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q <= 0 {
+			continue
+		}
+		return FormatWebP
+	}
+	return ""
+}
+
+// Resize returns the cached rendition of src at width, building it if needed
+// src is a site URL path such as "/Product Photos/Hoodie/front.jpg". format may be
+// "" to keep PNG for PNG sources and use JPEG for everything else.
+func (s *Service) Resize(src string, width int, format string) (*Resized, error) {
+	if !IsAllowedWidth(width) {
+		return nil, ErrInvalidWidth
+	}
+
+	sourcePath, err := s.sourcePath(src)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := s.sourceInfo(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case "":
+		format = FormatJPEG
+		if info.format == "png" {
+			format = FormatPNG
+		}
+	case FormatJPEG, FormatPNG, FormatWebP:
+	default:
+		return nil, ErrInvalidFormat
+	}
+
+	key := cacheKey(sourcePath, info, width, format)
+	resized := &Resized{
+		Path:        filepath.Join(s.cacheDir, key[:2], key+"."+extension(format)),
+		ContentType: "image/" + format,
+		ETag:        `"` + key[:32] + `"`,
+		ModTime:     info.modTime,
+		Version:     version(info),
+	}
+
+	if _, err := os.Stat(resized.Path); err == nil {
+		return resized, nil
+	}
+
+	if err := s.render(sourcePath, resized.Path, width, format); err != nil {
+		return nil, err
+	}
+	return resized, nil
+}
+
+// Srcset returns a srcset attribute value for a product photo URL, or "" if the image
+// is not a local photo (e.g. a Printful mockup) or cannot be read
+// Widths at or above the photo's own width are skipped since we never upscale.
+func (s *Service) Srcset(src string) string {
+	sourcePath, err := s.sourcePath(src)
+	if err != nil {
+		return ""
+	}
+	info, err := s.sourceInfo(sourcePath)
+	if err != nil {
+		return ""
+	}
+
+	var candidates []string
+	for _, w := range Widths {
+		if w >= info.width {
+			break
+		}
+		candidates = append(candidates, fmt.Sprintf("%s %dw", ResizeURL(src, w, version(info)), w))
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	// The original is the largest candidate
+	candidates = append(candidates, fmt.Sprintf("%s %dw", escapePath(src), info.width))
+	return strings.Join(candidates, ", ")
+}
+
+// ResizeURL returns the resize endpoint URL for src at width
+// The version query parameter changes whenever the source file does, which is what
+// lets responses be cached as immutable.
+func ResizeURL(src string, width int, version string) string {
+	u := resizePrefix + strconv.Itoa(width) + escapePath(src)
+	if version != "" {
+		u += "?v=" + version
+	}
+	return u
+}
+
+// sourcePath maps a site URL path to a file under <staticDir>/Product Photos,
+// rejecting anything that escapes it
+func (s *Service) sourcePath(src string) (string, error) {
+	if s.staticDir == "" {
+		return "", ErrSourceNotFound
+	}
+
+	clean := path.Clean("/" + strings.TrimPrefix(src, "/"))
+	if !strings.HasPrefix(clean, "/"+sourceRoot+"/") {
+		return "", ErrSourceNotFound
+	}
+
+	switch strings.ToLower(path.Ext(clean)) {
+	case ".jpg", ".jpeg", ".png":
+	default:
+		return "", ErrSourceNotFound
+	}
+
+	return filepath.Join(s.staticDir, filepath.FromSlash(clean)), nil
+}
+
+// sourceInfo returns the dimensions of a source image, decoding only its header
+// Results are cached until the file's mtime or size changes.
+func (s *Service) sourceInfo(sourcePath string) (sourceInfo, error) {
+	stat, err := os.Stat(sourcePath)
+	if err != nil || stat.IsDir() {
+		return sourceInfo{}, ErrSourceNotFound
+	}
+
+	s.mu.Lock()
+	info, ok := s.configs[sourcePath]
+	s.mu.Unlock()
+	if ok && info.modTime.Equal(stat.ModTime()) && info.size == stat.Size() {
+		return info, nil
+	}
+
+	f, err := os.Open(sourcePath)
+	if err != nil {
+		return sourceInfo{}, ErrSourceNotFound
+	}
+	defer f.Close()
+
+	cfg, format, err := image.DecodeConfig(f)
+	if err != nil {
+		return sourceInfo{}, fmt.Errorf("decode image header: %w", err)
+	}
+
+	info = sourceInfo{
+		modTime: stat.ModTime(),
+		size:    stat.Size(),
+		width:   cfg.Width,
+		height:  cfg.Height,
+		format:  format,
+	}
+
+	s.mu.Lock()
+	s.configs[sourcePath] = info
+	s.mu.Unlock()
+
+	return info, nil
+}
+
+// render decodes, resizes and encodes a source image into the cache
+// The file is written under a temporary name and renamed so readers never see a partial image.
+func (s *Service) render(sourcePath, destPath string, width int, format string) error {
+	s.encodeSlots <- struct{}{}
+	defer func() { <-s.encodeSlots }()
+
+	// Another request may have built it while we waited
+	if _, err := os.Stat(destPath); err == nil {
+		return nil
+	}
+
+	f, err := os.Open(sourcePath)
+	if err != nil {
+		return ErrSourceNotFound
+	}
+	defer f.Close()
+
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return fmt.Errorf("decode image header: %w", err)
+	}
+	if cfg.Width*cfg.Height > maxSourcePixels {
+		return fmt.Errorf("source image too large: %dx%d", cfg.Width, cfg.Height)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("rewind source image: %w", err)
+	}
+
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return fmt.Errorf("decode image: %w", err)
+	}
+
+	dst := resize(src, width)
+
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return fmt.Errorf("create cache directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(destPath), ".resize-*")
+	if err != nil {
+		return fmt.Errorf("create cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	switch format {
+	case FormatPNG:
+		encoder := png.Encoder{CompressionLevel: png.BestCompression}
+		err = encoder.Encode(tmp, dst)
+	case FormatWebP:
+		err = webp.Encode(tmp, dst, &webp.Options{Quality: webpQuality})
+	default:
+		err = jpeg.Encode(tmp, dst, &jpeg.Options{Quality: jpegQuality})
+	}
+	if err != nil {
+		tmp.Close()
+		return fmt.Errorf("encode image: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write cache file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("write cache file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), destPath); err != nil {
+		return fmt.Errorf("store cache file: %w", err)
+	}
+	return nil
+}
+
+// cacheKey identifies a rendition by source file, source version, width and encoder settings
+func cacheKey(sourcePath string, info sourceInfo, width int, format string) string {
+	quality := jpegQuality
+	if format == FormatWebP {
+		quality = webpQuality
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%d|%d|%s|q%d",
+		sourcePath, info.modTime.UnixNano(), info.size, width, format, quality)))
+	return hex.EncodeToString(sum[:])
+}
+
+// version is a short token that changes whenever the source file does
+func version(info sourceInfo) string {
+	return strconv.FormatInt(info.modTime.Unix(), 36) + strconv.FormatInt(info.size, 36)
+}
+
+func extension(format string) string {
+	switch format {
+	case FormatPNG:
+		return "png"
+	case FormatWebP:
+		return "webp"
+	}
+	return "jpg"
+}
+
+// escapePath percent-encodes each segment of a URL path ("Product Photos" -> "Product%20Photos")
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return strings.Join(segments, "/")
+}
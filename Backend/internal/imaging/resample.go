@@ -0,0 +1,65 @@
+package imaging
+
+import (
+	"image"
+	"image/draw"
+)
+
+// resize scales src down to width, keeping its aspect ratio
+// Each output pixel is the average of the source pixels it covers (a box filter),
+// which is sharp enough for photos and avoids the aliasing of nearest-neighbour.
+// Images already narrower than width are only re-encoded, never upscaled.
+func resize(src image.Image, width int) *image.RGBA {
+	bounds := src.Bounds()
+
+	// Premultiplied RGBA so transparent pixels do not bleed colour into their neighbours
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	sw, sh := bounds.Dx(), bounds.Dy()
+	if width >= sw || sw == 0 {
+		return rgba
+	}
+
+	dw := width
+	dh := sh * dw / sw
+	if dh < 1 {
+		dh = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < dh; y++ {
+		y0 := y * sh / dh
+		y1 := (y + 1) * sh / dh
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < dw; x++ {
+			x0 := x * sw / dw
+			x1 := (x + 1) * sw / dw
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride+x0*4 : sy*rgba.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					r += uint64(row[i])
+					g += uint64(row[i+1])
+					b += uint64(row[i+2])
+					a += uint64(row[i+3])
+					n++
+				}
+			}
+
+			o := y*dst.Stride + x*4
+			dst.Pix[o] = uint8(r / n)
+			dst.Pix[o+1] = uint8(g / n)
+			dst.Pix[o+2] = uint8(b / n)
+			dst.Pix[o+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
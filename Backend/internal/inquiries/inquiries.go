@@ -0,0 +1,120 @@
+// Package inquiries stores booking requests sent through the site's contact form
+// and tracks whether they have been answered.
+package inquiries
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+// ErrInvalidStatus is returned for an unknown inquiry status
+var ErrInvalidStatus = errors.New("status must be new, replied or archived")
+
+// Service stores inquiries
+type Service struct {
+	db *sql.DB
+}
+
+// NewService creates an inquiry service
+func NewService(db *sql.DB) *Service {
+	return &Service{db: db}
+}
+
+// Create stores a new inquiry, filling in its ID, status and timestamps
+func (s *Service) Create(inquiry *models.Inquiry) error {
+	now := time.Now()
+	inquiry.ID = uuid.New().String()
+	inquiry.Status = models.InquiryStatusNew
+	inquiry.CreatedAt = now
+	inquiry.UpdatedAt = now
+
+	_, err := s.db.Exec(`
+		INSERT INTO inquiries (id, name, email, project_type, deadline, message, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, inquiry.ID, inquiry.Name, inquiry.Email, inquiry.ProjectType, inquiry.Deadline, inquiry.Message,
+		inquiry.Status, now, now)
+	if err != nil {
+		return fmt.Errorf("insert inquiry: %w", err)
+	}
+	return nil
+}
+
+// List returns inquiries with a status, or all of them for "", newest first
+func (s *Service) List(status string) ([]models.Inquiry, error) {
+	if status == "" {
+		return s.query(`ORDER BY created_at DESC`)
+	}
+	return s.query(`WHERE status = ? ORDER BY created_at DESC`, status)
+}
+
+// Get returns an inquiry by ID
+// Returns sql.ErrNoRows if it does not exist.
+func (s *Service) Get(id string) (*models.Inquiry, error) {
+	list, err := s.query(`WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &list[0], nil
+}
+
+func (s *Service) query(where string, args ...interface{}) ([]models.Inquiry, error) {
+	rows, err := s.db.Query(`
+		SELECT id, name, email, project_type, deadline, message, status, note, replied_at, created_at, updated_at
+		FROM inquiries
+		`+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query inquiries: %w", err)
+	}
+	defer rows.Close()
+
+	list := []models.Inquiry{}
+	for rows.Next() {
+		var i models.Inquiry
+		var repliedAt sql.NullTime
+		if err := rows.Scan(&i.ID, &i.Name, &i.Email, &i.ProjectType, &i.Deadline, &i.Message, &i.Status,
+			&i.Note, &repliedAt, &i.CreatedAt, &i.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan inquiry: %w", err)
+		}
+		if repliedAt.Valid {
+			i.RepliedAt = &repliedAt.Time
+		}
+		list = append(list, i)
+	}
+	return list, rows.Err()
+}
+
+// SetStatus moves an inquiry to a status, with an optional note for other admins.
+// The first move to replied records when it was answered.
+// Returns sql.ErrNoRows if it does not exist, ErrInvalidStatus for an unknown status.
+func (s *Service) SetStatus(id, status, note string) (*models.Inquiry, error) {
+	switch status {
+	case models.InquiryStatusNew, models.InquiryStatusReplied, models.InquiryStatusArchived:
+	default:
+		return nil, ErrInvalidStatus
+	}
+
+	now := time.Now()
+	result, err := s.db.Exec(`
+		UPDATE inquiries
+		SET status = ?, note = ?,
+			replied_at = CASE WHEN ? = 'replied' THEN COALESCE(replied_at, ?) ELSE replied_at END,
+			updated_at = ?
+		WHERE id = ?
+	`, status, note, status, now, now, id)
+	if err != nil {
+		return nil, fmt.Errorf("update inquiry: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return s.Get(id)
+}
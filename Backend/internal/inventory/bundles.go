@@ -0,0 +1,118 @@
+package inventory
+
+import (
+	"fmt"
+	"log"
+)
+
+// bundleComponent is one component of a bundle variant
+type bundleComponent struct {
+	variantID string
+	quantity  int  // per bundle
+	listed    bool // variant and product are both on sale
+}
+
+// bundleComponents returns a bundle variant's components, or none for an ordinary variant
+func (s *Service) bundleComponents(variantID string) ([]bundleComponent, error) {
+	rows, err := s.db.Query(`
+		SELECT bc.component_variant_id, bc.quantity, COALESCE(v.available, 0) = 1 AND COALESCE(p.active, 0) = 1
+		FROM bundle_components bc
+		LEFT JOIN variants v ON v.id = bc.component_variant_id
+		LEFT JOIN products p ON p.id = v.product_id
+		WHERE bc.bundle_variant_id = ?
+		ORDER BY bc.position
+	`, variantID)
+	if err != nil {
+		return nil, fmt.Errorf("query bundle components: %w", err)
+	}
+	defer rows.Close()
+
+	var components []bundleComponent
+	for rows.Next() {
+		var c bundleComponent
+		if err := rows.Scan(&c.variantID, &c.quantity, &c.listed); err != nil {
+			return nil, fmt.Errorf("scan bundle component: %w", err)
+		}
+		components = append(components, c)
+	}
+	return components, rows.Err()
+}
+
+// checkBundleStock checks every component for requestedQty bundles
+//
+// The bundle is available only if all components are on sale and in stock. Its
+// stock is the number of whole bundles the tracked components can make (nil if
+// none are tracked), and it is a pre-order if any component is, shipping with
+// the latest component.
+func (s *Service) checkBundleStock(variantID string, requestedQty int, components []bundleComponent) (*StockCheck, error) {
+	check := &StockCheck{
+		VariantID:    variantID,
+		RequestedQty: requestedQty,
+		Available:    true,
+	}
+
+	for _, c := range components {
+		if !c.listed {
+			check.Available = false
+			continue
+		}
+
+		component, err := s.CheckStock(c.variantID, requestedQty*c.quantity)
+		if err != nil {
+			return nil, fmt.Errorf("check bundle component %s: %w", c.variantID, err)
+		}
+
+		if !component.Available {
+			check.Available = false
+		}
+		if component.TrackInventory {
+			check.TrackInventory = true
+
+			bundles := 0
+			if component.StockQuantity != nil && *component.StockQuantity > 0 {
+				bundles = *component.StockQuantity / c.quantity
+			}
+			if check.StockQuantity == nil || bundles < *check.StockQuantity {
+				check.StockQuantity = &bundles
+			}
+		}
+		if component.Preorder {
+			check.Preorder = true
+			if check.ExpectedShipDate == nil || component.ExpectedShipDate.After(*check.ExpectedShipDate) {
+				check.ExpectedShipDate = component.ExpectedShipDate
+			}
+		}
+	}
+
+	if !check.Available {
+		check.Preorder = false
+		check.ExpectedShipDate = nil
+	}
+	return check, nil
+}
+
+// deductBundleStock deducts quantity bundles' worth of every component, putting
+// back what was already deducted if a component falls short
+func (s *Service) deductBundleStock(variantID string, quantity int, components []bundleComponent) error {
+	for i, c := range components {
+		if err := s.DeductStock(c.variantID, quantity*c.quantity); err != nil {
+			for _, done := range components[:i] {
+				if restoreErr := s.RestoreStock(done.variantID, quantity*done.quantity); restoreErr != nil {
+					log.Printf("WARNING: Could not restore stock for bundle component %s: %v", done.variantID, restoreErr)
+				}
+			}
+			return fmt.Errorf("deduct bundle %s: %w", variantID, err)
+		}
+	}
+	return nil
+}
+
+// restoreBundleStock puts quantity bundles' worth of every component back
+func (s *Service) restoreBundleStock(variantID string, quantity int, components []bundleComponent) error {
+	for _, c := range components {
+		if err := s.RestoreStock(c.variantID, quantity*c.quantity); err != nil {
+			return fmt.Errorf("restore bundle %s: %w", variantID, err)
+		}
+	}
+	return nil
+}
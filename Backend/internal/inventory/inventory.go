@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"time"
 )
 
 // Service handles inventory tracking and management
@@ -18,23 +19,41 @@ func NewService(db *sql.DB) *Service {
 
 // StockCheck represents the result of a stock availability check
 type StockCheck struct {
-	VariantID       string
-	Available       bool
-	StockQuantity   *int // nil = unlimited
-	RequestedQty    int
-	TrackInventory  bool
+	VariantID        string
+	Available        bool
+	StockQuantity    *int // nil = unlimited
+	RequestedQty     int
+	TrackInventory   bool
+	Preorder         bool       // true if some of the requested units would be backordered
+	ExpectedShipDate *time.Time // set when Preorder is true
 }
 
 // CheckStock verifies if requested quantity is available
+//
+// For pre-order variants, quantities beyond on-hand stock are accepted as
+// long as the total backorder stays within preorder_limit. In that case the
+// check is Available with Preorder set and the expected ship date attached.
+// Bundles are checked through their components.
 func (s *Service) CheckStock(variantID string, requestedQty int) (*StockCheck, error) {
+	components, err := s.bundleComponents(variantID)
+	if err != nil {
+		return nil, err
+	}
+	if len(components) > 0 {
+		return s.checkBundleStock(variantID, requestedQty, components)
+	}
+
 	var stockQty sql.NullInt64
 	var trackInventory bool
+	var preorderEnabled sql.NullBool
+	var preorderLimit sql.NullInt64
+	var preorderShipDate sql.NullTime
 
-	err := s.db.QueryRow(`
-		SELECT stock_quantity, track_inventory
+	err = s.db.QueryRow(`
+		SELECT stock_quantity, track_inventory, preorder_enabled, preorder_limit, preorder_ship_date
 		FROM variants
 		WHERE id = ?
-	`, variantID).Scan(&stockQty, &trackInventory)
+	`, variantID).Scan(&stockQty, &trackInventory, &preorderEnabled, &preorderLimit, &preorderShipDate)
 
 	if err != nil {
 		return nil, fmt.Errorf("query variant stock: %w", err)
@@ -64,16 +83,35 @@ func (s *Service) CheckStock(variantID string, requestedQty int) (*StockCheck, e
 	check.StockQuantity = &currentStock
 	check.Available = currentStock >= requestedQty
 
+	// Pre-order variants may dip below zero, down to -preorder_limit.
+	// A ship date is required so held orders know when to release.
+	if !check.Available && preorderEnabled.Bool && preorderShipDate.Valid &&
+		currentStock-requestedQty >= -int(preorderLimit.Int64) {
+		shipDate := preorderShipDate.Time
+		check.Available = true
+		check.Preorder = true
+		check.ExpectedShipDate = &shipDate
+	}
+
 	return check, nil
 }
 
 // DeductStock reduces stock quantity for a variant
+// Deducting a bundle deducts each of its components.
 func (s *Service) DeductStock(variantID string, quantity int) error {
+	components, err := s.bundleComponents(variantID)
+	if err != nil {
+		return err
+	}
+	if len(components) > 0 {
+		return s.deductBundleStock(variantID, quantity, components)
+	}
+
 	// First check if we should track inventory for this variant
 	var trackInventory bool
 	var stockQty sql.NullInt64
 	var lowStockThreshold int
-	err := s.db.QueryRow(`
+	err = s.db.QueryRow(`
 		SELECT track_inventory, stock_quantity, low_stock_threshold FROM variants WHERE id = ?
 	`, variantID).Scan(&trackInventory, &stockQty, &lowStockThreshold)
 
@@ -86,14 +124,19 @@ func (s *Service) DeductStock(variantID string, quantity int) error {
 		return nil
 	}
 
+	// Pre-order variants may go negative, but never past -preorder_limit
 	result, err := s.db.Exec(`
 		UPDATE variants
 		SET stock_quantity = stock_quantity - ?,
 		    updated_at = datetime('now')
 		WHERE id = ?
 		AND track_inventory = 1
-		AND stock_quantity >= ?
-	`, quantity, variantID, quantity)
+		AND (
+			stock_quantity >= ?
+			OR (preorder_enabled = 1 AND preorder_ship_date IS NOT NULL
+				AND stock_quantity - ? >= -COALESCE(preorder_limit, 0))
+		)
+	`, quantity, variantID, quantity, quantity)
 
 	if err != nil {
 		return fmt.Errorf("deduct stock: %w", err)
@@ -123,10 +166,19 @@ func (s *Service) DeductStock(variantID string, quantity int) error {
 }
 
 // RestoreStock adds stock back (e.g., when an order is cancelled)
+// Restoring a bundle restores each of its components.
 func (s *Service) RestoreStock(variantID string, quantity int) error {
+	components, err := s.bundleComponents(variantID)
+	if err != nil {
+		return err
+	}
+	if len(components) > 0 {
+		return s.restoreBundleStock(variantID, quantity, components)
+	}
+
 	// First check if we should track inventory for this variant
 	var trackInventory bool
-	err := s.db.QueryRow(`
+	err = s.db.QueryRow(`
 		SELECT track_inventory FROM variants WHERE id = ?
 	`, variantID).Scan(&trackInventory)
 
@@ -225,3 +277,32 @@ func (s *Service) UpdateStock(variantID string, newQuantity int, threshold int,
 
 	return nil
 }
+
+// UpdatePreorder configures pre-order selling for a variant.
+// limit caps how many units may be backordered; shipDate is the expected
+// date backordered units ship, and the date held orders are released.
+func (s *Service) UpdatePreorder(variantID string, enabled bool, limit int, shipDate *time.Time) error {
+	result, err := s.db.Exec(`
+		UPDATE variants
+		SET preorder_enabled = ?,
+		    preorder_limit = ?,
+		    preorder_ship_date = ?,
+		    updated_at = datetime('now')
+		WHERE id = ?
+	`, enabled, limit, shipDate, variantID)
+
+	if err != nil {
+		return fmt.Errorf("update preorder: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("check rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
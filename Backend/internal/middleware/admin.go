@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strings"
+
+	apierrors "github.com/nessieaudio/ecommerce-backend/internal/errors"
+)
+
+// AdminAuth protects admin endpoints with a shared API key
+// Clients must send: Authorization: Bearer <ADMIN_API_KEY>
+// If no key is configured, every admin request is rejected (fail closed)
+func AdminAuth(apiKey string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := GetRequestID(r.Context())
+
+			if apiKey == "" {
+				log.Printf("Admin request rejected: ADMIN_API_KEY not configured [request_id: %s]", requestID)
+				apierrors.RespondError(w, http.StatusUnauthorized, "Admin API not configured", apierrors.ErrCodeUnauthorized, nil, requestID)
+				return
+			}
+
+			token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+			// Constant-time comparison to avoid leaking the key through timing
+			if subtle.ConstantTimeCompare([]byte(token), []byte(apiKey)) != 1 {
+				log.Printf("Admin request rejected: invalid API key from %s [request_id: %s]", getClientIP(r), requestID)
+				apierrors.RespondError(w, http.StatusUnauthorized, "Invalid admin credentials", apierrors.ErrCodeUnauthorized, nil, requestID)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,16 @@
+-- Pre-order / backorder support for tracked variants
+-- A pre-order variant may sell past zero stock down to -preorder_limit;
+-- negative stock_quantity is the number of units currently backordered.
+
+ALTER TABLE variants ADD COLUMN preorder_enabled BOOLEAN DEFAULT 0;
+ALTER TABLE variants ADD COLUMN preorder_limit INTEGER DEFAULT 0;
+ALTER TABLE variants ADD COLUMN preorder_ship_date DATETIME;
+
+-- Expected ship date snapshot for items bought as pre-orders
+ALTER TABLE order_items ADD COLUMN preorder_ship_date DATETIME;
+
+-- Orders containing pre-order items are held from Printful submission
+-- until this time (or until an admin releases them). NULL = not held.
+ALTER TABLE orders ADD COLUMN fulfillment_hold_until DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_orders_fulfillment_hold ON orders(fulfillment_hold_until);
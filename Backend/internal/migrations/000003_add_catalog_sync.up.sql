@@ -0,0 +1,39 @@
+-- Printful catalog sync
+-- Products and variants are upserted by Printful ID. Items that disappear
+-- from the Printful store are soft-deactivated and stamped with
+-- printful_removed_at so they can be reactivated if they come back.
+
+-- Local price overrides. NULL = use the Printful retail price.
+ALTER TABLE products ADD COLUMN price_override REAL;
+ALTER TABLE variants ADD COLUMN price_override REAL;
+
+-- Last retail price reported by Printful for the sync variant. Still NULL for
+-- variants from before the first sync; that sync keeps their price as an
+-- override where it differs from the retail price.
+ALTER TABLE variants ADD COLUMN retail_price REAL;
+
+ALTER TABLE products ADD COLUMN printful_removed_at DATETIME;
+ALTER TABLE variants ADD COLUMN printful_removed_at DATETIME;
+ALTER TABLE products ADD COLUMN synced_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_products_printful_id ON products(printful_id);
+CREATE INDEX IF NOT EXISTS idx_variants_printful_variant_id ON variants(printful_variant_id);
+
+-- One row per sync run with the JSON diff report
+CREATE TABLE IF NOT EXISTS catalog_sync_runs (
+	id TEXT PRIMARY KEY,
+	trigger TEXT NOT NULL,
+	status TEXT NOT NULL,
+	products_added INTEGER DEFAULT 0,
+	products_updated INTEGER DEFAULT 0,
+	products_deactivated INTEGER DEFAULT 0,
+	variants_added INTEGER DEFAULT 0,
+	variants_updated INTEGER DEFAULT 0,
+	variants_deactivated INTEGER DEFAULT 0,
+	report TEXT,
+	error TEXT,
+	started_at DATETIME NOT NULL,
+	finished_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_catalog_sync_runs_started ON catalog_sync_runs(started_at);
@@ -0,0 +1,7 @@
+-- Rollback pricing rules and margin tracking
+
+DROP TABLE IF EXISTS pricing_rules;
+
+ALTER TABLE order_items DROP COLUMN unit_cost;
+ALTER TABLE variants DROP COLUMN printful_catalog_variant_id;
+ALTER TABLE variants DROP COLUMN printful_cost;
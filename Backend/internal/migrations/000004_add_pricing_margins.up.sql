@@ -0,0 +1,24 @@
+-- Printful cost, markup rules and margin tracking
+
+-- What Printful charges us for the variant (catalog variant base price)
+ALTER TABLE variants ADD COLUMN printful_cost REAL;
+ALTER TABLE variants ADD COLUMN printful_catalog_variant_id INTEGER;
+
+-- Printful cost at the time of the order, so profit reports survive
+-- later Printful price changes. NULL = cost unknown.
+ALTER TABLE order_items ADD COLUMN unit_cost REAL;
+
+-- Markup rules used to price variants without a price_override.
+-- Precedence: product rule, then category rule, then the default rule.
+-- Without any rule the Printful retail price is used.
+CREATE TABLE IF NOT EXISTS pricing_rules (
+	id TEXT PRIMARY KEY,
+	scope TEXT NOT NULL CHECK (scope IN ('product', 'category', 'default')),
+	target TEXT NOT NULL DEFAULT '', -- product ID or category name, '' for default
+	markup_type TEXT NOT NULL CHECK (markup_type IN ('percent', 'fixed')),
+	markup_value REAL NOT NULL,
+	rounding TEXT NOT NULL DEFAULT 'none',
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	UNIQUE (scope, target)
+);
@@ -0,0 +1,5 @@
+-- Rollback product image gallery
+
+DROP INDEX IF EXISTS idx_product_images_variant;
+DROP INDEX IF EXISTS idx_product_images_product;
+DROP TABLE IF EXISTS product_images;
@@ -0,0 +1,23 @@
+-- Product image gallery
+-- Images are shown in sort_order. variant_id ties an image to one variant
+-- (e.g. a Printful mockup of that colour); NULL applies to the whole product.
+-- source: 'local' (Product Photos directory), 'printful' (mockup), 'admin'
+-- The catalog sync fills in local and Printful images on its next run.
+
+CREATE TABLE IF NOT EXISTS product_images (
+	id TEXT PRIMARY KEY,
+	product_id TEXT NOT NULL,
+	variant_id TEXT,
+	url TEXT NOT NULL,
+	alt_text TEXT NOT NULL DEFAULT '',
+	sort_order INTEGER NOT NULL DEFAULT 0,
+	source TEXT NOT NULL DEFAULT 'admin',
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	FOREIGN KEY (product_id) REFERENCES products(id),
+	FOREIGN KEY (variant_id) REFERENCES variants(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_images_product ON product_images(product_id, sort_order);
+CREATE INDEX IF NOT EXISTS idx_product_images_variant ON product_images(variant_id);
+
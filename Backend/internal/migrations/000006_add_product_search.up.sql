@@ -0,0 +1,38 @@
+-- Full-text product search
+-- products_fts mirrors products.name and products.description and is kept in
+-- sync by the triggers below. It stores its own copy of the text rather than
+-- using external content, because products has no INTEGER PRIMARY KEY and its
+-- implicit rowids are not stable across VACUUM.
+-- Requires SQLite built with FTS5 (go build -tags sqlite_fts5).
+
+CREATE VIRTUAL TABLE IF NOT EXISTS products_fts USING fts5(
+	product_id UNINDEXED,
+	name,
+	description,
+	tokenize = 'porter unicode61 remove_diacritics 2'
+);
+
+INSERT INTO products_fts (product_id, name, description)
+SELECT id, name, COALESCE(description, '') FROM products;
+
+CREATE TRIGGER IF NOT EXISTS products_fts_insert AFTER INSERT ON products
+BEGIN
+	INSERT INTO products_fts (product_id, name, description)
+	VALUES (new.id, new.name, COALESCE(new.description, ''));
+END;
+
+CREATE TRIGGER IF NOT EXISTS products_fts_update AFTER UPDATE OF id, name, description ON products
+BEGIN
+	DELETE FROM products_fts WHERE product_id = old.id;
+	INSERT INTO products_fts (product_id, name, description)
+	VALUES (new.id, new.name, COALESCE(new.description, ''));
+END;
+
+CREATE TRIGGER IF NOT EXISTS products_fts_delete AFTER DELETE ON products
+BEGIN
+	DELETE FROM products_fts WHERE product_id = old.id;
+END;
+
+-- Listing filters and sort orders
+CREATE INDEX IF NOT EXISTS idx_products_active_created ON products(active, created_at);
+CREATE INDEX IF NOT EXISTS idx_products_category ON products(category);
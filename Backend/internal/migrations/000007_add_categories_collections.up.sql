@@ -0,0 +1,50 @@
+-- Nested categories and curated collections
+-- products.category holds the category slug, so existing filters and
+-- category pricing rules keep working; parent_id nests categories.
+
+CREATE TABLE IF NOT EXISTS categories (
+	id TEXT PRIMARY KEY,
+	parent_id TEXT,
+	slug TEXT NOT NULL UNIQUE,
+	name TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	sort_order INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	FOREIGN KEY (parent_id) REFERENCES categories(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_categories_parent ON categories(parent_id, sort_order);
+
+-- One category per flat category string already in use (in practice just 'merch')
+INSERT INTO categories (id, slug, name, created_at, updated_at)
+SELECT lower(hex(randomblob(16))), category, upper(substr(category, 1, 1)) || substr(category, 2),
+	CURRENT_TIMESTAMP, CURRENT_TIMESTAMP
+FROM (SELECT DISTINCT category FROM products WHERE category IS NOT NULL AND category != '');
+
+-- Collections are hand-picked product lists ("Tour 2026") that can be limited
+-- to a visibility window. NULL starts_at / ends_at leave that end open.
+CREATE TABLE IF NOT EXISTS collections (
+	id TEXT PRIMARY KEY,
+	slug TEXT NOT NULL UNIQUE,
+	name TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	image_url TEXT NOT NULL DEFAULT '',
+	active BOOLEAN NOT NULL DEFAULT 1,
+	starts_at DATETIME,
+	ends_at DATETIME,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+-- Manual ordering: products are shown by position
+CREATE TABLE IF NOT EXISTS collection_products (
+	collection_id TEXT NOT NULL,
+	product_id TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	PRIMARY KEY (collection_id, product_id),
+	FOREIGN KEY (collection_id) REFERENCES collections(id),
+	FOREIGN KEY (product_id) REFERENCES products(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_collection_products_position ON collection_products(collection_id, position);
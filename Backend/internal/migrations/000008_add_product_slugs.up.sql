@@ -0,0 +1,17 @@
+-- SEO-friendly product slugs
+-- Slugs are derived from the product name and assigned by the catalog sync
+-- (existing products are backfilled at startup). When a rename changes the
+-- slug, the old one is kept in product_slug_redirects so links keep working.
+
+ALTER TABLE products ADD COLUMN slug TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_slug ON products(slug);
+
+CREATE TABLE IF NOT EXISTS product_slug_redirects (
+	slug TEXT PRIMARY KEY,
+	product_id TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	FOREIGN KEY (product_id) REFERENCES products(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_slug_redirects_product ON product_slug_redirects(product_id);
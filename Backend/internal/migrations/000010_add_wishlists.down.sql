@@ -0,0 +1,6 @@
+-- Rollback wishlists
+
+DROP INDEX IF EXISTS idx_wishlist_items_variant;
+DROP TABLE IF EXISTS wishlist_items;
+DROP INDEX IF EXISTS idx_wishlists_customer;
+DROP TABLE IF EXISTS wishlists;
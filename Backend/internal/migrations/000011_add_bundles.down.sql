@@ -0,0 +1,6 @@
+-- Rollback product bundles
+
+DROP TABLE IF EXISTS order_item_components;
+DROP INDEX IF EXISTS idx_bundle_components_component;
+DROP TABLE IF EXISTS bundle_components;
+ALTER TABLE products DROP COLUMN is_bundle;
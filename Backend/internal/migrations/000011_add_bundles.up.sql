@@ -0,0 +1,35 @@
+-- Product bundles
+-- A bundle is a local product (printful_id 0) with a single variant that
+-- stands for a fixed set of component variants. It is sold as one line item;
+-- its stock is derived from the components and it is fulfilled as the
+-- components. The bundle price is pinned with variants.price_override.
+
+ALTER TABLE products ADD COLUMN is_bundle BOOLEAN NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS bundle_components (
+	bundle_variant_id TEXT NOT NULL,
+	component_variant_id TEXT NOT NULL,
+	quantity INTEGER NOT NULL DEFAULT 1 CHECK (quantity > 0), -- per bundle
+	position INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (bundle_variant_id, component_variant_id),
+	FOREIGN KEY (bundle_variant_id) REFERENCES variants(id),
+	FOREIGN KEY (component_variant_id) REFERENCES variants(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_bundle_components_component ON bundle_components(component_variant_id);
+
+-- What one unit of a bundle order item was made of at order time. allocated_price
+-- is this component line's share of the bundle price (the shares of one bundle
+-- add up to its unit_price to the cent), for refunds and reporting.
+CREATE TABLE IF NOT EXISTS order_item_components (
+	order_item_id TEXT NOT NULL,
+	variant_id TEXT NOT NULL,
+	printful_variant_id INTEGER NOT NULL,
+	product_name TEXT NOT NULL,
+	variant_name TEXT NOT NULL,
+	quantity INTEGER NOT NULL, -- per bundle
+	allocated_price REAL NOT NULL,
+	unit_cost REAL, -- Printful cost per component unit; NULL = unknown
+	PRIMARY KEY (order_item_id, variant_id),
+	FOREIGN KEY (order_item_id) REFERENCES order_items(id)
+);
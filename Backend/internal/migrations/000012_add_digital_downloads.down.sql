@@ -0,0 +1,7 @@
+-- Rollback digital download products
+
+DROP INDEX IF EXISTS idx_download_grants_order;
+DROP TABLE IF EXISTS download_grants;
+DROP INDEX IF EXISTS idx_digital_files_product;
+DROP TABLE IF EXISTS digital_files;
+ALTER TABLE products DROP COLUMN product_type;
@@ -0,0 +1,5 @@
+-- Rollback fulfillment channels
+
+DROP INDEX IF EXISTS idx_order_fulfillments_channel_status;
+DROP TABLE IF EXISTS order_fulfillments;
+ALTER TABLE order_items DROP COLUMN fulfillment_channel;
@@ -0,0 +1,40 @@
+-- Fulfillment channels
+-- Each order item is fulfilled through one channel: printful (shipped) or
+-- digital (download links). A paid order gets one fulfillment record per
+-- channel it uses, and orders.status is derived from those records, so one
+-- channel failing or waiting does not hold up the other.
+
+ALTER TABLE order_items ADD COLUMN fulfillment_channel TEXT NOT NULL DEFAULT 'printful'; -- printful, digital
+
+UPDATE order_items SET fulfillment_channel = 'digital'
+WHERE product_id IN (SELECT id FROM products WHERE product_type = 'digital');
+
+CREATE TABLE IF NOT EXISTS order_fulfillments (
+	order_id TEXT NOT NULL,
+	channel TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending', -- pending, fulfilled, shipped, failed, cancelled
+	printful_order_id INTEGER, -- printful channel, once submitted
+	last_error TEXT,
+	completed_at DATETIME, -- When it reached fulfilled
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	PRIMARY KEY (order_id, channel),
+	FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_fulfillments_channel_status ON order_fulfillments(channel, status);
+
+-- Records for orders paid before channels existed, from the order status
+INSERT OR IGNORE INTO order_fulfillments (order_id, channel, status, printful_order_id, created_at, updated_at)
+SELECT DISTINCT o.id, oi.fulfillment_channel,
+	CASE
+		WHEN oi.fulfillment_channel = 'digital' THEN
+			CASE WHEN EXISTS (SELECT 1 FROM download_grants g WHERE g.order_id = o.id) THEN 'fulfilled' ELSE 'pending' END
+		WHEN o.status IN ('fulfilled', 'shipped', 'failed', 'cancelled') THEN o.status
+		ELSE 'pending'
+	END,
+	CASE WHEN oi.fulfillment_channel = 'printful' THEN o.printful_order_id END,
+	o.created_at, o.updated_at
+FROM orders o
+JOIN order_items oi ON oi.order_id = o.id
+WHERE o.status != 'pending';
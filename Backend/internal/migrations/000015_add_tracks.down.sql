@@ -0,0 +1,3 @@
+-- Rollback ingested portfolio tracks
+
+DROP TABLE IF EXISTS tracks;
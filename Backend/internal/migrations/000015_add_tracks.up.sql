@@ -0,0 +1,26 @@
+-- Ingested portfolio tracks
+-- Filled from the music directory by the audio ingest (see internal/audio), which
+-- reads each file's tags and works out its duration and waveform, so the player
+-- does not have to decode audio in the browser.
+
+CREATE TABLE IF NOT EXISTS tracks (
+	id TEXT PRIMARY KEY, -- Same ID as the streaming API (track_previews, track_plays)
+	path TEXT NOT NULL UNIQUE, -- Relative to the music directory
+	title TEXT NOT NULL, -- From the tags, else the file name
+	artist TEXT NOT NULL, -- From the tags, else the artist folder
+	album TEXT,
+	genre TEXT NOT NULL, -- From the genre folder
+	format TEXT NOT NULL, -- mp3, wav, flac, ogg, opus
+	duration_seconds REAL NOT NULL DEFAULT 0,
+	sample_rate INTEGER NOT NULL DEFAULT 0,
+	channels INTEGER NOT NULL DEFAULT 0,
+	waveform TEXT, -- JSON array of peaks from 0 to 1; NULL when it could not be worked out
+	artwork BLOB, -- Embedded picture, if any
+	artwork_type TEXT,
+	artwork_file TEXT, -- Otherwise a cover image near the track, relative to the music directory
+	file_size INTEGER NOT NULL,
+	file_modified_at DATETIME NOT NULL, -- Unchanged files are skipped on the next ingest
+	ingested_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_tracks_genre_artist ON tracks(genre, artist);
@@ -0,0 +1,3 @@
+-- Rollback tour dates
+
+DROP TABLE IF EXISTS shows;
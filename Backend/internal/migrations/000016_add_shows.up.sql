@@ -0,0 +1,24 @@
+-- Tour dates
+-- Shown on /tour and as schema.org MusicEvent structured data. A show is
+-- archived (archived_at set) by the tour archiver once it is over, and from
+-- then on only listed as a past show.
+
+CREATE TABLE IF NOT EXISTS shows (
+	id TEXT PRIMARY KEY,
+	venue TEXT NOT NULL,
+	city TEXT NOT NULL,
+	region TEXT NOT NULL DEFAULT '', -- State or province, e.g. "OR"
+	country TEXT NOT NULL, -- ISO 3166-1 alpha-2, e.g. "US"
+	latitude REAL,
+	longitude REAL,
+	starts_at DATETIME NOT NULL, -- UTC
+	timezone TEXT NOT NULL, -- IANA zone of the venue, e.g. "America/Los_Angeles"
+	ticket_url TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'on_sale' CHECK (status IN ('on_sale', 'sold_out', 'cancelled')),
+	support_acts TEXT NOT NULL DEFAULT '[]', -- JSON array of names, in billing order
+	archived_at DATETIME, -- Set once the show is over
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_shows_archived_starts ON shows(archived_at, starts_at);
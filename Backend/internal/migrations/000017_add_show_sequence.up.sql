@@ -0,0 +1,5 @@
+-- Calendar feed revisions
+-- The iCalendar SEQUENCE of a show: bumped each time the show is edited, so
+-- calendar clients replace their copy when a show moves or is cancelled.
+
+ALTER TABLE shows ADD COLUMN sequence INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,23 @@
+-- Tour-exclusive merch
+-- A drop ties a product, or just one of its variants, to a show. It can only be
+-- bought from opens_hours_before the show starts until closes_hours_after, and
+-- only while the show is not cancelled. The window is stored relative to the
+-- show, so it follows the show if it is moved. A product or variant tied to
+-- several shows (one per tour stop) is on sale whenever any of their windows is open.
+
+CREATE TABLE IF NOT EXISTS show_drops (
+	id TEXT PRIMARY KEY,
+	show_id TEXT NOT NULL,
+	product_id TEXT NOT NULL,
+	variant_id TEXT, -- NULL = the whole product
+	opens_hours_before INTEGER NOT NULL DEFAULT 48 CHECK (opens_hours_before >= 0),
+	closes_hours_after INTEGER NOT NULL DEFAULT 24 CHECK (closes_hours_after >= 0),
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	FOREIGN KEY (show_id) REFERENCES shows(id),
+	FOREIGN KEY (product_id) REFERENCES products(id),
+	FOREIGN KEY (variant_id) REFERENCES variants(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_show_drops_show ON show_drops(show_id);
+CREATE INDEX IF NOT EXISTS idx_show_drops_product ON show_drops(product_id);
@@ -0,0 +1,3 @@
+-- Rollback booking and contact inquiries
+
+DROP TABLE IF EXISTS inquiries;
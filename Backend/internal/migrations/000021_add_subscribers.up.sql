@@ -0,0 +1,32 @@
+-- Mailing list
+-- Fans sign up through POST /api/v1/subscribe and stay pending until they click
+-- the link in the confirmation email (double opt-in). Buyers who tick Stripe's
+-- promotional email checkbox at checkout are subscribed straight away. Every
+-- subscriber has an unsubscribe token for one-click unsubscribe (RFC 8058).
+
+CREATE TABLE IF NOT EXISTS subscribers (
+	id TEXT PRIMARY KEY,
+	email TEXT NOT NULL UNIQUE COLLATE NOCASE,
+	status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'subscribed', 'unsubscribed')),
+	source TEXT NOT NULL CHECK (source IN ('form', 'checkout')),
+	city TEXT NOT NULL DEFAULT '',
+	confirm_token TEXT UNIQUE, -- NULL once confirmed
+	unsubscribe_token TEXT NOT NULL UNIQUE,
+	confirmation_sent_at DATETIME,
+	confirmed_at DATETIME,
+	unsubscribed_at DATETIME,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscribers_status ON subscribers(status);
+
+-- Genres a subscriber is interested in, matching the portfolio's genres
+CREATE TABLE IF NOT EXISTS subscriber_genres (
+	subscriber_id TEXT NOT NULL,
+	genre TEXT NOT NULL COLLATE NOCASE,
+	PRIMARY KEY (subscriber_id, genre),
+	FOREIGN KEY (subscriber_id) REFERENCES subscribers(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscriber_genres_genre ON subscriber_genres(genre);
@@ -0,0 +1,6 @@
+-- Rollback newsletter campaigns
+
+DROP TABLE IF EXISTS campaign_clicks;
+DROP TABLE IF EXISTS campaign_recipients;
+DROP TABLE IF EXISTS campaign_links;
+DROP TABLE IF EXISTS campaigns;
@@ -0,0 +1,3 @@
+-- Rollback email queue
+
+DROP TABLE IF EXISTS email_queue;
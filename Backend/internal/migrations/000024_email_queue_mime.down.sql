@@ -0,0 +1,16 @@
+-- Rollback MIME email queue fields
+
+ALTER TABLE email_queue ADD COLUMN content_type TEXT NOT NULL DEFAULT 'text/html; charset="UTF-8"';
+ALTER TABLE email_queue ADD COLUMN body TEXT NOT NULL DEFAULT '';
+
+UPDATE email_queue SET body = html_body WHERE html_body != '';
+UPDATE email_queue SET content_type = 'text/plain; charset="UTF-8"', body = text_body WHERE html_body = '';
+UPDATE email_queue SET headers = json_set(headers, '$."Reply-To"', reply_to) WHERE reply_to != '';
+
+ALTER TABLE email_queue DROP COLUMN message_id;
+ALTER TABLE email_queue DROP COLUMN attachments;
+ALTER TABLE email_queue DROP COLUMN text_body;
+ALTER TABLE email_queue DROP COLUMN html_body;
+ALTER TABLE email_queue DROP COLUMN reply_to;
+ALTER TABLE email_queue DROP COLUMN bcc_addresses;
+ALTER TABLE email_queue DROP COLUMN cc_addresses;
@@ -0,0 +1,22 @@
+-- MIME email queue fields
+-- Queued emails gain CC and BCC recipients, a Reply-To address, a plain-text
+-- body next to the HTML one, attachments and a Message-ID that is kept across
+-- retries. The single body column is split into html_body and text_body, and
+-- Reply-To moves out of the extra headers into its own column.
+
+ALTER TABLE email_queue ADD COLUMN cc_addresses TEXT NOT NULL DEFAULT '';
+ALTER TABLE email_queue ADD COLUMN bcc_addresses TEXT NOT NULL DEFAULT '';
+ALTER TABLE email_queue ADD COLUMN reply_to TEXT NOT NULL DEFAULT '';
+ALTER TABLE email_queue ADD COLUMN html_body TEXT NOT NULL DEFAULT '';
+ALTER TABLE email_queue ADD COLUMN text_body TEXT NOT NULL DEFAULT '';
+ALTER TABLE email_queue ADD COLUMN attachments TEXT NOT NULL DEFAULT '[]'; -- JSON array of filename, content type and base64 data
+ALTER TABLE email_queue ADD COLUMN message_id TEXT NOT NULL DEFAULT '';
+
+UPDATE email_queue SET html_body = body WHERE content_type LIKE 'text/html%';
+UPDATE email_queue SET text_body = body WHERE content_type NOT LIKE 'text/html%';
+UPDATE email_queue
+SET reply_to = COALESCE(json_extract(headers, '$."Reply-To"'), ''),
+	headers = json_remove(headers, '$."Reply-To"');
+
+ALTER TABLE email_queue DROP COLUMN content_type;
+ALTER TABLE email_queue DROP COLUMN body;
@@ -20,6 +20,10 @@ var migrationFiles embed.FS
 func RunMigrations(db *sql.DB) error {
 	log.Println("🔄 Checking for database migrations...")
 
+	if err := checkFTS5(db); err != nil {
+		return err
+	}
+
 	// Create driver for migrations
 	driver, err := sqlite3.WithInstance(db, &sqlite3.Config{})
 	if err != nil {
@@ -77,6 +81,19 @@ func RunMigrations(db *sql.DB) error {
 	return nil
 }
 
+// checkFTS5 fails early when SQLite was compiled without FTS5, which product search
+// (migration 000006) needs. go-sqlite3 only includes it with the sqlite_fts5 build tag.
+func checkFTS5(db *sql.DB) error {
+	var enabled bool
+	if err := db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&enabled); err != nil {
+		return fmt.Errorf("check sqlite FTS5 support: %w", err)
+	}
+	if !enabled {
+		return errors.New("sqlite was built without FTS5: build and run with `-tags sqlite_fts5`")
+	}
+	return nil
+}
+
 // GetCurrentVersion returns the current migration version
 // Useful for debugging and monitoring
 func GetCurrentVersion(db *sql.DB) (uint, bool, error) {
@@ -18,6 +18,153 @@ type Product struct {
 	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// ProductImage is one image in a product's gallery
+type ProductImage struct {
+	ID        string    `json:"id" db:"id"`
+	ProductID string    `json:"product_id" db:"product_id"`
+	VariantID *string   `json:"variant_id,omitempty" db:"variant_id"` // NULL = applies to all variants
+	URL       string    `json:"url" db:"url"`
+	AltText   string    `json:"alt_text" db:"alt_text"`
+	SortOrder int       `json:"sort_order" db:"sort_order"`
+	Source    string    `json:"source" db:"source"` // local, printful, admin
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Product image sources
+const (
+	ImageSourceLocal    = "local"    // Product Photos directory
+	ImageSourcePrintful = "printful" // Printful mockup
+	ImageSourceAdmin    = "admin"    // Added through the admin API
+)
+
+// Category is a node in the product category tree
+// Products reference categories by slug (products.category).
+type Category struct {
+	ID          string    `json:"id" db:"id"`
+	ParentID    *string   `json:"parent_id" db:"parent_id"` // NULL = top level
+	Slug        string    `json:"slug" db:"slug"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	SortOrder   int       `json:"sort_order" db:"sort_order"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Collection is a curated, manually ordered list of products
+type Collection struct {
+	ID          string     `json:"id" db:"id"`
+	Slug        string     `json:"slug" db:"slug"`
+	Name        string     `json:"name" db:"name"`
+	Description string     `json:"description" db:"description"`
+	ImageURL    string     `json:"image_url" db:"image_url"`
+	Active      bool       `json:"active" db:"active"`
+	StartsAt    *time.Time `json:"starts_at" db:"starts_at"` // NULL = visible immediately
+	EndsAt      *time.Time `json:"ends_at" db:"ends_at"`     // NULL = never expires
+	ProductIDs  []string   `json:"product_ids" db:"-"`       // In display order
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Bundle is a product sold at one price that ships as a fixed set of component variants
+// It is stored as a product (printful_id 0) with a single variant, the one added to carts.
+type Bundle struct {
+	ProductID   string            `json:"product_id" db:"id"`
+	VariantID   string            `json:"variant_id" db:"-"`
+	Slug        string            `json:"slug" db:"slug"`
+	Name        string            `json:"name" db:"name"`
+	Description string            `json:"description" db:"description"`
+	Price       float64           `json:"price" db:"price"`
+	ImageURL    string            `json:"image_url" db:"image_url"`
+	Category    string            `json:"category" db:"category"`
+	Active      bool              `json:"active" db:"active"`
+	Components  []BundleComponent `json:"components" db:"-"` // In display order
+	CreatedAt   time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// BundleComponent is one variant in a bundle
+type BundleComponent struct {
+	VariantID   string  `json:"variant_id" db:"component_variant_id"`
+	ProductID   string  `json:"product_id" db:"product_id"`
+	ProductSlug string  `json:"product_slug" db:"slug"`
+	ProductName string  `json:"product_name" db:"product_name"`
+	VariantName string  `json:"variant_name" db:"variant_name"`
+	Quantity    int     `json:"quantity" db:"quantity"` // Per bundle
+	Price       float64 `json:"price" db:"price"`       // Current price of the variant on its own
+}
+
+// Product types
+const (
+	ProductTypePhysical = "physical" // Fulfilled by Printful (or as a bundle's components)
+	ProductTypeDigital  = "digital"  // Downloaded; never sent to Printful
+)
+
+// DigitalProduct is a product sold as downloadable files
+// Like a bundle it is stored as a product (printful_id 0) with a single variant.
+type DigitalProduct struct {
+	ProductID   string        `json:"product_id" db:"id"`
+	VariantID   string        `json:"variant_id" db:"-"`
+	Slug        string        `json:"slug" db:"slug"`
+	Name        string        `json:"name" db:"name"`
+	Description string        `json:"description" db:"description"`
+	Price       float64       `json:"price" db:"price"`
+	ImageURL    string        `json:"image_url" db:"image_url"`
+	Category    string        `json:"category" db:"category"`
+	Active      bool          `json:"active" db:"active"`
+	Files       []DigitalFile `json:"files" db:"-"` // In display order
+	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// DigitalFile is one downloadable file of a digital product
+type DigitalFile struct {
+	ID          string `json:"id" db:"id"`
+	Path        string `json:"path" db:"file_path"` // Relative to DIGITAL_FILES_DIR
+	FileName    string `json:"file_name" db:"file_name"`
+	ContentType string `json:"content_type" db:"content_type"`
+	SizeBytes   int64  `json:"size_bytes" db:"size_bytes"`
+}
+
+// Download is a purchased file with its signed link, as shown to the buyer
+type Download struct {
+	OrderItemID        string    `json:"order_item_id" db:"order_item_id"`
+	ProductName        string    `json:"product_name" db:"product_name"`
+	FileName           string    `json:"file_name" db:"file_name"`
+	SizeBytes          int64     `json:"size_bytes" db:"size_bytes"`
+	URL                string    `json:"url" db:"-"`
+	DownloadsRemaining int       `json:"downloads_remaining" db:"-"`
+	ExpiresAt          time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// Track is a portfolio track, found on disk under the music directory
+type Track struct {
+	ID              string        `json:"id" db:"id"` // Slug of genre, artist and title
+	Title           string        `json:"title" db:"title"`
+	Artist          string        `json:"artist" db:"artist"`
+	Genre           string        `json:"genre" db:"genre"`
+	Path            string        `json:"path" db:"path"`     // Relative to the music directory
+	Format          string        `json:"format" db:"format"` // mp3 or wav; ingested tracks can also be flac, ogg or opus
+	DurationSeconds float64       `json:"duration_seconds" db:"duration_seconds"`
+	ArtworkURL      string        `json:"artwork_url,omitempty" db:"-"`
+	StreamURL       string        `json:"stream_url,omitempty" db:"-"` // Only MP3 and WAV tracks are streamed
+	Preview         *TrackPreview `json:"preview,omitempty" db:"-"`    // Set for tracks that are for sale
+	Plays           int           `json:"plays" db:"plays"`
+
+	// Read from the file by the ingest (GET /api/v1/tracks only)
+	Album      string    `json:"album,omitempty" db:"album"`
+	SampleRate int       `json:"sample_rate,omitempty" db:"sample_rate"`
+	Channels   int       `json:"channels,omitempty" db:"channels"`
+	Waveform   []float64 `json:"waveform,omitempty" db:"waveform"` // Peaks from 0 to 1, evenly spread over the track
+}
+
+// TrackPreview is the clip streamed in place of a track that is for sale
+type TrackPreview struct {
+	ProductID     string  `json:"product_id,omitempty" db:"product_id"`
+	StartSeconds  float64 `json:"start_seconds" db:"start_seconds"`
+	LengthSeconds float64 `json:"length_seconds" db:"length_seconds"`
+}
+
 // Variant represents a product variant (size, color, etc.)
 type Variant struct {
 	ID                string    `json:"id" db:"id"`
@@ -31,6 +178,9 @@ type Variant struct {
 	StockQuantity     *int      `json:"stock_quantity,omitempty" db:"stock_quantity"` // NULL = unlimited (print-on-demand)
 	LowStockThreshold int       `json:"low_stock_threshold" db:"low_stock_threshold"` // Alert when stock <= this
 	TrackInventory    bool      `json:"track_inventory" db:"track_inventory"` // FALSE for print-on-demand items
+	PreorderEnabled   bool      `json:"preorder_enabled" db:"preorder_enabled"` // Allow selling past zero stock
+	PreorderLimit     int       `json:"preorder_limit" db:"preorder_limit"` // Max units that may be backordered
+	PreorderShipDate  *time.Time `json:"preorder_ship_date,omitempty" db:"preorder_ship_date"` // Expected ship date for pre-orders
 	CreatedAt         time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -40,7 +190,7 @@ type Order struct {
 	ID                    string    `json:"id" db:"id"`
 	CustomerID            string    `json:"customer_id" db:"customer_id"`
 	CustomerEmail         string    `json:"customer_email" db:"customer_email"`
-	Status                string    `json:"status" db:"status"` // pending, paid, fulfilled, shipped, cancelled
+	Status                string    `json:"status" db:"status"` // pending, paid, partially_fulfilled, fulfilled, shipped, failed, cancelled
 	TotalAmount           float64   `json:"total_amount" db:"total_amount"`
 	Currency              string    `json:"currency" db:"currency"`
 	StripeSessionID       string    `json:"stripe_session_id" db:"stripe_session_id"`
@@ -56,6 +206,7 @@ type Order struct {
 	ShippingCountry       string    `json:"shipping_country" db:"shipping_country"`
 	TrackingNumber        string    `json:"tracking_number,omitempty" db:"tracking_number"`
 	TrackingURL           string    `json:"tracking_url,omitempty" db:"tracking_url"`
+	FulfillmentHoldUntil  *time.Time `json:"fulfillment_hold_until,omitempty" db:"fulfillment_hold_until"` // Pre-order hold; NULL = submit immediately
 	CreatedAt             time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt             time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -72,7 +223,24 @@ type OrderItem struct {
 	TotalPrice        float64   `json:"total_price" db:"total_price"`
 	ProductName       string    `json:"product_name" db:"product_name"`     // Snapshot at order time
 	VariantName       string    `json:"variant_name" db:"variant_name"`     // Snapshot
+	PreorderShipDate  *time.Time `json:"preorder_ship_date,omitempty" db:"preorder_ship_date"` // Set when bought as a pre-order
+	UnitCost          *float64  `json:"unit_cost,omitempty" db:"unit_cost"` // Printful cost snapshot at order time
+	FulfillmentChannel string    `json:"fulfillment_channel" db:"fulfillment_channel"` // printful or digital, from the product type at order time
 	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+
+	// Bundle items: what one unit was made of at order time; fulfilled in place of the bundle
+	Components []OrderItemComponent `json:"components,omitempty" db:"-"`
+}
+
+// OrderItemComponent is one component of a bundle order item, snapshotted at order time
+type OrderItemComponent struct {
+	VariantID         string   `json:"variant_id" db:"variant_id"`
+	PrintfulVariantID int64    `json:"printful_variant_id" db:"printful_variant_id"`
+	ProductName       string   `json:"product_name" db:"product_name"`
+	VariantName       string   `json:"variant_name" db:"variant_name"`
+	Quantity          int      `json:"quantity" db:"quantity"`               // Per bundle
+	AllocatedPrice    float64  `json:"allocated_price" db:"allocated_price"` // This line's share of one bundle's price
+	UnitCost          *float64 `json:"unit_cost,omitempty" db:"unit_cost"`   // Printful cost per unit
 }
 
 // Customer represents a customer
@@ -116,10 +284,379 @@ type PrintfulSubmissionFailure struct {
 }
 
 // Order status constants
+// Once an order is paid its status is derived from its fulfillments.
+const (
+	OrderStatusPending            = "pending"
+	OrderStatusPaid               = "paid"
+	OrderStatusPartiallyFulfilled = "partially_fulfilled" // Some channels done, others still pending
+	OrderStatusFulfilled          = "fulfilled"
+	OrderStatusShipped            = "shipped"
+	OrderStatusFailed             = "failed" // A channel failed and needs attention
+	OrderStatusCancelled          = "cancelled"
+)
+
+// Fulfillment channels: how an order item reaches the buyer
+const (
+	FulfillmentChannelPrintful = "printful" // Printed and shipped by Printful
+	FulfillmentChannelDigital  = "digital"  // Download links
+)
+
+// Fulfillment status constants
 const (
-	OrderStatusPending   = "pending"
-	OrderStatusPaid      = "paid"
-	OrderStatusFulfilled = "fulfilled"
-	OrderStatusShipped   = "shipped"
-	OrderStatusCancelled = "cancelled"
+	FulfillmentStatusPending   = "pending"   // Not yet submitted or issued (including pre-order holds)
+	FulfillmentStatusFulfilled = "fulfilled" // Submitted to Printful, or downloads issued
+	FulfillmentStatusShipped   = "shipped"   // Printful shipped it
+	FulfillmentStatusFailed    = "failed"
+	FulfillmentStatusCancelled = "cancelled"
 )
+
+// Fulfillment tracks one channel of a paid order
+type Fulfillment struct {
+	OrderID         string     `json:"order_id" db:"order_id"`
+	Channel         string     `json:"channel" db:"channel"`
+	Status          string     `json:"status" db:"status"`
+	PrintfulOrderID *int64     `json:"printful_order_id,omitempty" db:"printful_order_id"`
+	LastError       string     `json:"last_error,omitempty" db:"last_error"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Review is a verified purchaser's rating of a product
+type Review struct {
+	ID             string     `json:"id" db:"id"`
+	ProductID      string     `json:"product_id" db:"product_id"`
+	OrderID        string     `json:"order_id" db:"order_id"`
+	Email          string     `json:"email" db:"email"`
+	ReviewerName   string     `json:"reviewer_name" db:"reviewer_name"`
+	Rating         int        `json:"rating" db:"rating"` // 1-5
+	Fit            string     `json:"fit" db:"fit"`       // runs_small, true_to_size, runs_large or ""
+	Body           string     `json:"body" db:"body"`
+	Status         string     `json:"status" db:"status"` // pending, approved, rejected
+	ModerationNote string     `json:"moderation_note" db:"moderation_note"`
+	ModeratedAt    *time.Time `json:"moderated_at,omitempty" db:"moderated_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Review status constants
+const (
+	ReviewStatusPending  = "pending"
+	ReviewStatusApproved = "approved"
+	ReviewStatusRejected = "rejected"
+)
+
+// Inquiry is a booking request sent through the contact form
+type Inquiry struct {
+	ID          string     `json:"id" db:"id"`
+	Name        string     `json:"name" db:"name"`
+	Email       string     `json:"email" db:"email"`
+	ProjectType string     `json:"project_type" db:"project_type"`
+	Deadline    string     `json:"deadline" db:"deadline"` // YYYY-MM-DD
+	Message     string     `json:"message" db:"message"`
+	Status      string     `json:"status" db:"status"` // new, replied, archived
+	Note        string     `json:"note" db:"note"`     // Internal
+	RepliedAt   *time.Time `json:"replied_at,omitempty" db:"replied_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Inquiry status constants
+const (
+	InquiryStatusNew      = "new"
+	InquiryStatusReplied  = "replied"
+	InquiryStatusArchived = "archived"
+)
+
+// Engagement is booking work taken on for a client, usually from an inquiry
+type Engagement struct {
+	ID          string    `json:"id" db:"id"`
+	InquiryID   *string   `json:"inquiry_id,omitempty" db:"inquiry_id"`
+	Name        string    `json:"name" db:"name"`
+	Email       string    `json:"email" db:"email"`
+	ProjectType string    `json:"project_type" db:"project_type"`
+	Status      string    `json:"status" db:"status"` // open, quoted, booked, completed, cancelled
+	Quotes      []Quote   `json:"quotes,omitempty" db:"-"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Engagement status constants
+const (
+	EngagementStatusOpen      = "open"
+	EngagementStatusQuoted    = "quoted" // A quote has been sent
+	EngagementStatusBooked    = "booked" // A deposit has been paid
+	EngagementStatusCompleted = "completed"
+	EngagementStatusCancelled = "cancelled"
+)
+
+// Quote prices an engagement; the client pays its deposit through the link to its token
+type Quote struct {
+	ID                    string      `json:"id" db:"id"`
+	EngagementID          string      `json:"engagement_id" db:"engagement_id"`
+	Token                 string      `json:"token" db:"token"`
+	Items                 []QuoteItem `json:"items" db:"-"`
+	Currency              string      `json:"currency" db:"currency"`
+	Total                 float64     `json:"total" db:"total"`
+	Deposit               float64     `json:"deposit" db:"deposit"`
+	Note                  string      `json:"note" db:"note"` // Shown to the client
+	Status                string      `json:"status" db:"-"`  // draft, sent, paid, expired
+	ExpiresAt             time.Time   `json:"expires_at" db:"expires_at"`
+	SentAt                *time.Time  `json:"sent_at,omitempty" db:"sent_at"`
+	StripeSessionID       string      `json:"stripe_session_id,omitempty" db:"stripe_session_id"`
+	StripePaymentIntentID string      `json:"stripe_payment_intent_id,omitempty" db:"stripe_payment_intent_id"`
+	DepositPaidAt         *time.Time  `json:"deposit_paid_at,omitempty" db:"deposit_paid_at"`
+	CreatedAt             time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt             time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+// Quote status constants, worked out from the quote's timestamps
+const (
+	QuoteStatusDraft   = "draft"
+	QuoteStatusSent    = "sent"
+	QuoteStatusPaid    = "paid"
+	QuoteStatusExpired = "expired"
+)
+
+// QuoteItem is a line of a quote
+type QuoteItem struct {
+	Description string  `json:"description" db:"description"`
+	Quantity    int     `json:"quantity" db:"quantity"`
+	UnitPrice   float64 `json:"unit_price" db:"unit_price"`
+}
+
+// Subscriber is someone on the mailing list
+type Subscriber struct {
+	ID               string     `json:"id" db:"id"`
+	Email            string     `json:"email" db:"email"`
+	Status           string     `json:"status" db:"status"` // pending, subscribed, unsubscribed
+	Source           string     `json:"source" db:"source"` // form, checkout
+	City             string     `json:"city" db:"city"`
+	Genres           []string   `json:"genres" db:"-"`
+	Purchaser        bool       `json:"purchaser" db:"-"` // Has a paid order under this email
+	ConfirmToken     string     `json:"-" db:"confirm_token"`
+	UnsubscribeToken string     `json:"-" db:"unsubscribe_token"`
+	ConfirmedAt      *time.Time `json:"confirmed_at,omitempty" db:"confirmed_at"`
+	UnsubscribedAt   *time.Time `json:"unsubscribed_at,omitempty" db:"unsubscribed_at"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Subscriber status and source constants
+const (
+	SubscriberStatusPending      = "pending" // Waiting for the confirmation link to be clicked
+	SubscriberStatusSubscribed   = "subscribed"
+	SubscriberStatusUnsubscribed = "unsubscribed"
+
+	SubscriberSourceForm     = "form"
+	SubscriberSourceCheckout = "checkout"
+)
+
+// Campaign is a newsletter sent to a segment of the mailing list
+type Campaign struct {
+	ID         string          `json:"id" db:"id"`
+	Subject    string          `json:"subject" db:"subject"`
+	Title      string          `json:"title" db:"title"` // Heading of the email layout
+	Icon       string          `json:"icon" db:"icon"`
+	Blocks     []CampaignBlock `json:"blocks" db:"content"`
+	Genre      string          `json:"genre" db:"segment_genre"` // Segment; empty matches everyone
+	City       string          `json:"city" db:"segment_city"`
+	Purchaser  *bool           `json:"purchaser" db:"segment_purchaser"`
+	Status     string          `json:"status" db:"status"` // draft, scheduled, sending, sent, cancelled
+	SendAt     *time.Time      `json:"send_at,omitempty" db:"send_at"`
+	StartedAt  *time.Time      `json:"started_at,omitempty" db:"started_at"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty" db:"finished_at"`
+	Stats      *CampaignStats  `json:"stats,omitempty" db:"-"`
+	Links      []CampaignLink  `json:"links,omitempty" db:"-"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// CampaignBlock is a piece of campaign content, rendered with the email layout helpers
+type CampaignBlock struct {
+	Type  string        `json:"type"`            // text, info, note or button
+	Text  string        `json:"text,omitempty"`  // text, note and button label
+	Title string        `json:"title,omitempty"` // info
+	Rows  []CampaignRow `json:"rows,omitempty"`  // info
+	URL   string        `json:"url,omitempty"`   // button
+}
+
+// CampaignRow is a label/value line of an info block
+type CampaignRow struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// CampaignLink is the target of a button block, with its click count
+type CampaignLink struct {
+	ID       string `json:"id" db:"id"`
+	URL      string `json:"url" db:"url"`
+	Position int    `json:"position" db:"position"` // Index of the button block
+	Clicks   int    `json:"clicks" db:"-"`
+}
+
+// CampaignStats counts a campaign's recipients by delivery status and engagement
+type CampaignStats struct {
+	Recipients int `json:"recipients"`
+	Pending    int `json:"pending"`
+	Sent       int `json:"sent"`
+	Failed     int `json:"failed"`
+	Skipped    int `json:"skipped"` // Unsubscribed before their email went out
+	Opened     int `json:"opened"`
+	Clicked    int `json:"clicked"`
+}
+
+// CampaignRecipient is one subscriber's delivery of a campaign
+type CampaignRecipient struct {
+	ID            string     `json:"id" db:"id"`
+	CampaignID    string     `json:"campaign_id" db:"campaign_id"`
+	SubscriberID  string     `json:"subscriber_id" db:"subscriber_id"`
+	Email         string     `json:"email" db:"email"`
+	Token         string     `json:"-" db:"token"`       // Identifies opens and clicks
+	Status        string     `json:"status" db:"status"` // pending, sent, failed, skipped
+	Attempts      int        `json:"attempts" db:"attempts"`
+	LastError     string     `json:"last_error,omitempty" db:"last_error"`
+	NextAttemptAt time.Time  `json:"next_attempt_at" db:"next_attempt_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty" db:"sent_at"`
+	OpenedAt      *time.Time `json:"opened_at,omitempty" db:"opened_at"`
+	OpenCount     int        `json:"open_count" db:"open_count"`
+	ClickedAt     *time.Time `json:"clicked_at,omitempty" db:"clicked_at"`
+	ClickCount    int        `json:"click_count" db:"click_count"`
+}
+
+// Campaign, block and recipient status constants
+const (
+	CampaignStatusDraft     = "draft"
+	CampaignStatusScheduled = "scheduled"
+	CampaignStatusSending   = "sending"
+	CampaignStatusSent      = "sent"
+	CampaignStatusCancelled = "cancelled"
+
+	CampaignBlockText   = "text"
+	CampaignBlockInfo   = "info"
+	CampaignBlockNote   = "note"
+	CampaignBlockButton = "button"
+
+	RecipientStatusPending = "pending"
+	RecipientStatusSent    = "sent"
+	RecipientStatusFailed  = "failed"
+	RecipientStatusSkipped = "skipped" // Unsubscribed before the email went out
+)
+
+// QueuedEmail is an email waiting in, or delivered from, the email queue
+type QueuedEmail struct {
+	ID            string            `json:"id" db:"id"`
+	MessageID     string            `json:"message_id" db:"message_id"`
+	From          string            `json:"from" db:"from_address"`
+	To            string            `json:"to" db:"to_address"` // Comma-separated, as in the header
+	Cc            string            `json:"cc,omitempty" db:"cc_addresses"`
+	Bcc           string            `json:"bcc,omitempty" db:"bcc_addresses"`
+	ReplyTo       string            `json:"reply_to,omitempty" db:"reply_to"`
+	Subject       string            `json:"subject" db:"subject"`
+	Headers       map[string]string `json:"headers,omitempty" db:"headers"` // Extra headers, e.g. List-Unsubscribe
+	HTMLBody      string            `json:"-" db:"html_body"`
+	TextBody      string            `json:"-" db:"text_body"`
+	Attachments   []EmailAttachment `json:"attachments,omitempty" db:"attachments"`
+	Status        string            `json:"status" db:"status"` // pending, sent, dead
+	Attempts      int               `json:"attempts" db:"attempts"`
+	LastError     string            `json:"last_error,omitempty" db:"last_error"`
+	NextAttemptAt time.Time         `json:"next_attempt_at" db:"next_attempt_at"`
+	SentAt        *time.Time        `json:"sent_at,omitempty" db:"sent_at"`
+	CreatedAt     time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// EmailAttachment is a file attached to an email, such as a PDF invoice
+type EmailAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"-"`
+}
+
+// Email queue status constants
+const (
+	EmailStatusPending = "pending"
+	EmailStatusSent    = "sent"
+	EmailStatusDead    = "dead" // Failed permanently or ran out of attempts
+)
+
+// Wishlist is a list of saved variants, anonymous until an email is attached
+type Wishlist struct {
+	ID         string         `json:"id" db:"id"`
+	Token      string         `json:"-" db:"token"`       // Private; grants edit access
+	ShareToken string         `json:"-" db:"share_token"` // Read-only link
+	CustomerID string         `json:"customer_id,omitempty" db:"customer_id"`
+	Email      string         `json:"email,omitempty" db:"email"`
+	Notify     bool           `json:"notify" db:"notify"` // Restock / price drop emails
+	Items      []WishlistItem `json:"items" db:"-"`
+	CreatedAt  time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// WishlistItem is a saved variant with its current price and availability
+type WishlistItem struct {
+	VariantID        string     `json:"variant_id" db:"variant_id"`
+	ProductID        string     `json:"product_id" db:"product_id"`
+	ProductSlug      string     `json:"product_slug" db:"-"`
+	ProductName      string     `json:"product_name" db:"-"`
+	VariantName      string     `json:"variant_name" db:"-"`
+	ImageURL         string     `json:"image_url" db:"-"`
+	Price            float64    `json:"price" db:"-"` // Current price
+	Currency         string     `json:"currency" db:"-"`
+	AddedPrice       float64    `json:"added_price" db:"added_price"` // Price when saved
+	Available        bool       `json:"available" db:"-"`             // Can be bought now (in stock or pre-order)
+	Preorder         bool       `json:"preorder,omitempty" db:"-"`
+	ExpectedShipDate *time.Time `json:"expected_ship_date,omitempty" db:"-"`
+	AddedAt          time.Time  `json:"added_at" db:"created_at"`
+}
+
+// Show is a tour date
+type Show struct {
+	ID          string     `json:"id" db:"id"`
+	Venue       string     `json:"venue" db:"venue"`
+	City        string     `json:"city" db:"city"`
+	Region      string     `json:"region,omitempty" db:"region"` // State or province
+	Country     string     `json:"country" db:"country"`         // ISO 3166-1 alpha-2
+	Latitude    *float64   `json:"latitude,omitempty" db:"latitude"`
+	Longitude   *float64   `json:"longitude,omitempty" db:"longitude"`
+	StartsAt    time.Time  `json:"starts_at" db:"starts_at"` // In the venue's timezone
+	Timezone    string     `json:"timezone" db:"timezone"`   // IANA, e.g. "America/Los_Angeles"
+	TicketURL   string     `json:"ticket_url,omitempty" db:"ticket_url"`
+	Status      string     `json:"status" db:"status"`                     // on_sale, sold_out or cancelled
+	SupportActs []string   `json:"support_acts" db:"support_acts"`         // In billing order
+	ArchivedAt  *time.Time `json:"archived_at,omitempty" db:"archived_at"` // Set once the show is over
+	Sequence    int        `json:"sequence" db:"sequence"`                 // iCalendar SEQUENCE; bumped on every edit
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Drop ties a tour-exclusive product, or one of its variants, to a show: it is
+// only on sale in a window around the show's start
+type Drop struct {
+	ID               string    `json:"id" db:"id"`
+	ShowID           string    `json:"show_id" db:"show_id"`
+	ProductID        string    `json:"product_id" db:"product_id"`
+	VariantID        string    `json:"variant_id,omitempty" db:"variant_id"` // Empty = the whole product
+	OpensHoursBefore int       `json:"opens_hours_before" db:"opens_hours_before"`
+	ClosesHoursAfter int       `json:"closes_hours_after" db:"closes_hours_after"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// DropStatus is the sale window of a drop as shown to shoppers. Times are in the
+// venue's timezone.
+type DropStatus struct {
+	ShowID       string    `json:"show_id"`
+	Venue        string    `json:"venue"`
+	City         string    `json:"city"`
+	Region       string    `json:"region,omitempty"`
+	Country      string    `json:"country"`
+	Timezone     string    `json:"timezone"`
+	ShowStartsAt time.Time `json:"show_starts_at"`
+	OpensAt      time.Time `json:"opens_at"`
+	ClosesAt     time.Time `json:"closes_at"`
+	Open         bool      `json:"open"`
+	OpensIn      int64     `json:"opens_in_seconds,omitempty"`  // Until the window opens, while it has not
+	TimeLeft     int64     `json:"time_left_seconds,omitempty"` // Until the window closes, while it is open
+	SoldOut      bool      `json:"sold_out"`                    // Nothing left to sell in this window
+}
@@ -0,0 +1,10 @@
+// Package money holds the rounding rules shared by pricing, margins, reports and
+// quotes, so amounts computed in different places always agree to the cent.
+package money
+
+import "math"
+
+// RoundCents rounds an amount to the nearest cent
+func RoundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,589 @@
+package newsletter
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+	"github.com/nessieaudio/ecommerce-backend/internal/services/email"
+)
+
+var (
+	// ErrNotDraft is returned when changing or deleting a campaign that has been scheduled or sent
+	ErrNotDraft = errors.New("only draft campaigns can be changed")
+	// ErrAlreadySent is returned when scheduling or cancelling a campaign that is sent or cancelled
+	ErrAlreadySent = errors.New("campaign has already been sent or cancelled")
+)
+
+// CreateCampaign stores a draft campaign. ID, status and timestamps are filled in.
+func (s *Service) CreateCampaign(c *models.Campaign) error {
+	content, err := json.Marshal(c.Blocks)
+	if err != nil {
+		return fmt.Errorf("encode content: %w", err)
+	}
+
+	now := time.Now()
+	c.ID = uuid.New().String()
+	c.Status = models.CampaignStatusDraft
+	c.CreatedAt = now
+	c.UpdatedAt = now
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO campaigns (id, subject, title, icon, content, segment_genre, segment_city, segment_purchaser,
+			status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, c.ID, c.Subject, c.Title, c.Icon, string(content), c.Genre, c.City, c.Purchaser, c.Status, now, now)
+	if err != nil {
+		return fmt.Errorf("insert campaign: %w", err)
+	}
+	if err := saveLinks(tx, c); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateCampaign replaces the content and segment of a draft campaign
+// Returns sql.ErrNoRows for an unknown campaign or ErrNotDraft.
+func (s *Service) UpdateCampaign(c *models.Campaign) error {
+	content, err := json.Marshal(c.Blocks)
+	if err != nil {
+		return fmt.Errorf("encode content: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := draftIn(tx, c.ID); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		UPDATE campaigns
+		SET subject = ?, title = ?, icon = ?, content = ?, segment_genre = ?, segment_city = ?,
+			segment_purchaser = ?, updated_at = ?
+		WHERE id = ?
+	`, c.Subject, c.Title, c.Icon, string(content), c.Genre, c.City, c.Purchaser, time.Now(), c.ID)
+	if err != nil {
+		return fmt.Errorf("update campaign: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM campaign_links WHERE campaign_id = ?`, c.ID); err != nil {
+		return fmt.Errorf("clear links: %w", err)
+	}
+	if err := saveLinks(tx, c); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteCampaign removes a draft campaign
+// Returns sql.ErrNoRows for an unknown campaign or ErrNotDraft.
+func (s *Service) DeleteCampaign(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := draftIn(tx, id); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM campaign_links WHERE campaign_id = ?`, id); err != nil {
+		return fmt.Errorf("delete links: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM campaigns WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("delete campaign: %w", err)
+	}
+	return tx.Commit()
+}
+
+// draftIn checks that a campaign exists and is still a draft
+func draftIn(tx *sql.Tx, id string) error {
+	var status string
+	if err := tx.QueryRow(`SELECT status FROM campaigns WHERE id = ?`, id).Scan(&status); err != nil {
+		return err
+	}
+	if status != models.CampaignStatusDraft {
+		return ErrNotDraft
+	}
+	return nil
+}
+
+// saveLinks stores a link for each button block of a campaign
+func saveLinks(tx *sql.Tx, c *models.Campaign) error {
+	for i, block := range c.Blocks {
+		if block.Type != models.CampaignBlockButton {
+			continue
+		}
+		_, err := tx.Exec(`INSERT INTO campaign_links (id, campaign_id, url, position) VALUES (?, ?, ?, ?)`,
+			uuid.New().String(), c.ID, block.URL, i)
+		if err != nil {
+			return fmt.Errorf("insert link: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetCampaign returns a campaign with its links and delivery stats
+// Returns sql.ErrNoRows if it doesn't exist.
+func (s *Service) GetCampaign(id string) (*models.Campaign, error) {
+	list, err := s.queryCampaigns("WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	c := &list[0]
+	if c.Links, err = s.links(c.ID); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ListCampaigns returns campaigns with their delivery stats, newest first
+// An empty status lists every campaign.
+func (s *Service) ListCampaigns(status string) ([]models.Campaign, error) {
+	if status == "" {
+		return s.queryCampaigns("ORDER BY created_at DESC")
+	}
+	return s.queryCampaigns("WHERE status = ? ORDER BY created_at DESC", status)
+}
+
+func (s *Service) queryCampaigns(where string, args ...interface{}) ([]models.Campaign, error) {
+	rows, err := s.db.Query(`
+		SELECT id, subject, title, icon, content, segment_genre, segment_city, segment_purchaser,
+			status, send_at, started_at, finished_at, created_at, updated_at
+		FROM campaigns
+		`+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query campaigns: %w", err)
+	}
+
+	list := []models.Campaign{}
+	for rows.Next() {
+		var c models.Campaign
+		var content string
+		var purchaser sql.NullBool
+		var sendAt, startedAt, finishedAt sql.NullTime
+		if err := rows.Scan(&c.ID, &c.Subject, &c.Title, &c.Icon, &content, &c.Genre, &c.City, &purchaser,
+			&c.Status, &sendAt, &startedAt, &finishedAt, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan campaign: %w", err)
+		}
+		if err := json.Unmarshal([]byte(content), &c.Blocks); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("decode content of campaign %s: %w", c.ID, err)
+		}
+		if purchaser.Valid {
+			c.Purchaser = &purchaser.Bool
+		}
+		if sendAt.Valid {
+			c.SendAt = &sendAt.Time
+		}
+		if startedAt.Valid {
+			c.StartedAt = &startedAt.Time
+		}
+		if finishedAt.Valid {
+			c.FinishedAt = &finishedAt.Time
+		}
+		list = append(list, c)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range list {
+		if list[i].Stats, err = s.stats(list[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// stats counts a campaign's recipients by status, and those who opened or clicked
+func (s *Service) stats(campaignID string) (*models.CampaignStats, error) {
+	var st models.CampaignStats
+	err := s.db.QueryRow(`
+		SELECT COUNT(*),
+			COALESCE(SUM(status = ?), 0), COALESCE(SUM(status = ?), 0),
+			COALESCE(SUM(status = ?), 0), COALESCE(SUM(status = ?), 0),
+			COUNT(opened_at), COUNT(clicked_at)
+		FROM campaign_recipients WHERE campaign_id = ?
+	`, models.RecipientStatusPending, models.RecipientStatusSent, models.RecipientStatusFailed,
+		models.RecipientStatusSkipped, campaignID).Scan(
+		&st.Recipients, &st.Pending, &st.Sent, &st.Failed, &st.Skipped, &st.Opened, &st.Clicked)
+	if err != nil {
+		return nil, fmt.Errorf("count recipients: %w", err)
+	}
+	return &st, nil
+}
+
+// links returns a campaign's links in content order with their click counts
+func (s *Service) links(campaignID string) ([]models.CampaignLink, error) {
+	rows, err := s.db.Query(`
+		SELECT l.id, l.url, l.position, COUNT(k.link_id)
+		FROM campaign_links l
+		LEFT JOIN campaign_clicks k ON k.link_id = l.id
+		WHERE l.campaign_id = ?
+		GROUP BY l.id
+		ORDER BY l.position
+	`, campaignID)
+	if err != nil {
+		return nil, fmt.Errorf("query links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []models.CampaignLink{}
+	for rows.Next() {
+		var l models.CampaignLink
+		if err := rows.Scan(&l.ID, &l.URL, &l.Position, &l.Clicks); err != nil {
+			return nil, fmt.Errorf("scan link: %w", err)
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+// Schedule queues a draft or scheduled campaign to start sending at sendAt
+// Returns sql.ErrNoRows for an unknown campaign or ErrAlreadySent once it has started.
+func (s *Service) Schedule(id string, sendAt time.Time) error {
+	result, err := s.db.Exec(`
+		UPDATE campaigns SET status = ?, send_at = ?, updated_at = ?
+		WHERE id = ? AND status IN (?, ?)
+	`, models.CampaignStatusScheduled, sendAt, time.Now(), id, models.CampaignStatusDraft, models.CampaignStatusScheduled)
+	if err != nil {
+		return fmt.Errorf("schedule campaign: %w", err)
+	}
+	return s.checkChanged(result, id, ErrAlreadySent)
+}
+
+// Cancel stops a campaign. A scheduled campaign goes back to being a draft; one
+// that is sending is cancelled and its remaining recipients are skipped.
+// Returns sql.ErrNoRows for an unknown campaign or ErrAlreadySent.
+func (s *Service) Cancel(id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var status string
+	if err := tx.QueryRow(`SELECT status FROM campaigns WHERE id = ?`, id).Scan(&status); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	switch status {
+	case models.CampaignStatusScheduled:
+		_, err = tx.Exec(`UPDATE campaigns SET status = ?, send_at = NULL, updated_at = ? WHERE id = ?`,
+			models.CampaignStatusDraft, now, id)
+	case models.CampaignStatusSending:
+		_, err = tx.Exec(`UPDATE campaigns SET status = ?, finished_at = ?, updated_at = ? WHERE id = ?`,
+			models.CampaignStatusCancelled, now, now, id)
+		if err == nil {
+			_, err = tx.Exec(`UPDATE campaign_recipients SET status = ?, last_error = 'campaign cancelled' WHERE campaign_id = ? AND status = ?`,
+				models.RecipientStatusSkipped, id, models.RecipientStatusPending)
+		}
+	case models.CampaignStatusDraft:
+		return nil
+	default:
+		return ErrAlreadySent
+	}
+	if err != nil {
+		return fmt.Errorf("cancel campaign: %w", err)
+	}
+	return tx.Commit()
+}
+
+// checkChanged turns an update that matched no rows into sql.ErrNoRows for an
+// unknown campaign, or conflict for one in the wrong status
+func (s *Service) checkChanged(result sql.Result, id string, conflict error) error {
+	if n, err := result.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+	var exists bool
+	if err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM campaigns WHERE id = ?)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+	return conflict
+}
+
+// StartDue starts sending every scheduled campaign whose time has come, copying
+// the subscribed members of its segment into its recipients. Returns how many
+// campaigns were started.
+func (s *Service) StartDue(now time.Time) (int, error) {
+	due, err := s.queryCampaigns("WHERE status = ? AND send_at <= ?", models.CampaignStatusScheduled, now)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, c := range due {
+		if err := s.start(&c, now); err != nil {
+			return 0, fmt.Errorf("start campaign %s: %w", c.ID, err)
+		}
+	}
+	return len(due), nil
+}
+
+func (s *Service) start(c *models.Campaign, now time.Time) error {
+	subscribers, err := s.List(Segment{
+		Status:    models.SubscriberStatusSubscribed,
+		Genre:     c.Genre,
+		City:      c.City,
+		Purchaser: c.Purchaser,
+	})
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, sub := range subscribers {
+		_, err := tx.Exec(`
+			INSERT OR IGNORE INTO campaign_recipients (id, campaign_id, subscriber_id, email, token, next_attempt_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, uuid.New().String(), c.ID, sub.ID, sub.Email, uuid.New().String(), now)
+		if err != nil {
+			return fmt.Errorf("insert recipient: %w", err)
+		}
+	}
+
+	// An empty segment has nothing to send
+	status, finishedAt := models.CampaignStatusSending, sql.NullTime{}
+	if len(subscribers) == 0 {
+		status, finishedAt = models.CampaignStatusSent, sql.NullTime{Time: now, Valid: true}
+	}
+	_, err = tx.Exec(`
+		UPDATE campaigns SET status = ?, started_at = ?, finished_at = ?, updated_at = ?
+		WHERE id = ? AND status = ?
+	`, status, now, finishedAt, now, c.ID, models.CampaignStatusScheduled)
+	if err != nil {
+		return fmt.Errorf("update campaign: %w", err)
+	}
+	return tx.Commit()
+}
+
+// Delivery is a campaign email that is due to be sent
+type Delivery struct {
+	Recipient        models.CampaignRecipient
+	UnsubscribeToken string
+	Subscribed       bool // False once the subscriber has unsubscribed
+}
+
+// NextDelivery returns the recipient of a sending campaign that has waited
+// longest for their email, or sql.ErrNoRows if none is due
+func (s *Service) NextDelivery(now time.Time) (*Delivery, error) {
+	var d Delivery
+	r := &d.Recipient
+	var subscriberStatus string
+	err := s.db.QueryRow(`
+		SELECT r.id, r.campaign_id, r.subscriber_id, r.email, r.token, r.status, r.attempts, r.next_attempt_at,
+			s.unsubscribe_token, s.status
+		FROM campaign_recipients r
+		JOIN campaigns c ON c.id = r.campaign_id
+		JOIN subscribers s ON s.id = r.subscriber_id
+		WHERE c.status = ? AND r.status = ? AND r.next_attempt_at <= ?
+		ORDER BY r.next_attempt_at
+		LIMIT 1
+	`, models.CampaignStatusSending, models.RecipientStatusPending, now).Scan(
+		&r.ID, &r.CampaignID, &r.SubscriberID, &r.Email, &r.Token, &r.Status, &r.Attempts, &r.NextAttemptAt,
+		&d.UnsubscribeToken, &subscriberStatus)
+	if err != nil {
+		return nil, err
+	}
+	d.Subscribed = subscriberStatus == models.SubscriberStatusSubscribed
+	return &d, nil
+}
+
+// RecordSent marks a recipient's email as sent
+func (s *Service) RecordSent(recipientID string) error {
+	_, err := s.db.Exec(`
+		UPDATE campaign_recipients SET status = ?, attempts = attempts + 1, last_error = '', sent_at = ?
+		WHERE id = ?
+	`, models.RecipientStatusSent, time.Now(), recipientID)
+	if err != nil {
+		return fmt.Errorf("record sent: %w", err)
+	}
+	return nil
+}
+
+// RecordSkipped marks a recipient who unsubscribed before their email went out
+func (s *Service) RecordSkipped(recipientID string) error {
+	_, err := s.db.Exec(`UPDATE campaign_recipients SET status = ?, last_error = 'unsubscribed' WHERE id = ?`,
+		models.RecipientStatusSkipped, recipientID)
+	if err != nil {
+		return fmt.Errorf("record skipped: %w", err)
+	}
+	return nil
+}
+
+// RecordFailure records a failed send. A transient failure is retried later,
+// following email.NextRetry; anything else marks the recipient failed. retry
+// reports whether another attempt was scheduled.
+func (s *Service) RecordFailure(d *Delivery, sendErr error, transient bool) (retry bool, err error) {
+	attempts := d.Recipient.Attempts + 1
+	delay, retry := email.NextRetry(attempts, transient)
+
+	status, nextAttempt := models.RecipientStatusFailed, time.Now()
+	if retry {
+		status, nextAttempt = models.RecipientStatusPending, nextAttempt.Add(delay)
+	}
+
+	_, err = s.db.Exec(`
+		UPDATE campaign_recipients SET status = ?, attempts = ?, last_error = ?, next_attempt_at = ?
+		WHERE id = ?
+	`, status, attempts, sendErr.Error(), nextAttempt, d.Recipient.ID)
+	if err != nil {
+		return false, fmt.Errorf("record failure: %w", err)
+	}
+	return retry, nil
+}
+
+// FinishIfDone marks a sending campaign sent once no recipient is pending.
+// Returns whether it finished.
+func (s *Service) FinishIfDone(campaignID string) (bool, error) {
+	now := time.Now()
+	result, err := s.db.Exec(`
+		UPDATE campaigns SET status = ?, finished_at = ?, updated_at = ?
+		WHERE id = ? AND status = ?
+			AND NOT EXISTS (SELECT 1 FROM campaign_recipients WHERE campaign_id = ? AND status = ?)
+	`, models.CampaignStatusSent, now, now, campaignID, models.CampaignStatusSending, campaignID, models.RecipientStatusPending)
+	if err != nil {
+		return false, fmt.Errorf("finish campaign: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// Recipients lists a campaign's recipients, optionally only those in one status
+func (s *Service) Recipients(campaignID, status string) ([]models.CampaignRecipient, error) {
+	query := `
+		SELECT id, campaign_id, subscriber_id, email, token, status, attempts, last_error, next_attempt_at,
+			sent_at, opened_at, open_count, clicked_at, click_count
+		FROM campaign_recipients WHERE campaign_id = ?`
+	args := []interface{}{campaignID}
+	if status != "" {
+		query += " AND status = ?"
+		args = append(args, status)
+	}
+	rows, err := s.db.Query(query+" ORDER BY email", args...)
+	if err != nil {
+		return nil, fmt.Errorf("query recipients: %w", err)
+	}
+	defer rows.Close()
+
+	list := []models.CampaignRecipient{}
+	for rows.Next() {
+		var r models.CampaignRecipient
+		var sentAt, openedAt, clickedAt sql.NullTime
+		if err := rows.Scan(&r.ID, &r.CampaignID, &r.SubscriberID, &r.Email, &r.Token, &r.Status, &r.Attempts,
+			&r.LastError, &r.NextAttemptAt, &sentAt, &openedAt, &r.OpenCount, &clickedAt, &r.ClickCount); err != nil {
+			return nil, fmt.Errorf("scan recipient: %w", err)
+		}
+		if sentAt.Valid {
+			r.SentAt = &sentAt.Time
+		}
+		if openedAt.Valid {
+			r.OpenedAt = &openedAt.Time
+		}
+		if clickedAt.Valid {
+			r.ClickedAt = &clickedAt.Time
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+// RecordOpen counts an open from the tracking pixel. Unknown tokens are ignored.
+func (s *Service) RecordOpen(token string) error {
+	now := time.Now()
+	_, err := s.db.Exec(`
+		UPDATE campaign_recipients SET opened_at = COALESCE(opened_at, ?), open_count = open_count + 1
+		WHERE token = ?
+	`, now, token)
+	if err != nil {
+		return fmt.Errorf("record open: %w", err)
+	}
+	return nil
+}
+
+// RecordClick counts a click on a campaign link and returns where it goes. A
+// click also counts as an open, for mail clients that block the pixel. Clicks
+// with an unknown token (a forwarded email, say) still get the link.
+// Returns sql.ErrNoRows for an unknown link.
+func (s *Service) RecordClick(token, linkID string) (string, error) {
+	var target, campaignID string
+	err := s.db.QueryRow(`SELECT url, campaign_id FROM campaign_links WHERE id = ?`, linkID).Scan(&target, &campaignID)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var recipientID string
+	err = tx.QueryRow(`SELECT id FROM campaign_recipients WHERE token = ? AND campaign_id = ?`, token, campaignID).Scan(&recipientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return target, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get recipient: %w", err)
+	}
+
+	now := time.Now()
+	_, err = tx.Exec(`
+		UPDATE campaign_recipients
+		SET clicked_at = COALESCE(clicked_at, ?), click_count = click_count + 1, opened_at = COALESCE(opened_at, ?)
+		WHERE id = ?
+	`, now, now, recipientID)
+	if err != nil {
+		return "", fmt.Errorf("record click: %w", err)
+	}
+	if _, err := tx.Exec(`INSERT INTO campaign_clicks (recipient_id, link_id, clicked_at) VALUES (?, ?, ?)`, recipientID, linkID, now); err != nil {
+		return "", fmt.Errorf("record click: %w", err)
+	}
+	return target, tx.Commit()
+}
+
+// ValidStatus reports whether status is a campaign status
+func ValidStatus(status string) bool {
+	switch status {
+	case models.CampaignStatusDraft, models.CampaignStatusScheduled, models.CampaignStatusSending,
+		models.CampaignStatusSent, models.CampaignStatusCancelled:
+		return true
+	}
+	return false
+}
+
+// ValidRecipientStatus reports whether status is a recipient status
+func ValidRecipientStatus(status string) bool {
+	switch status {
+	case models.RecipientStatusPending, models.RecipientStatusSent, models.RecipientStatusFailed, models.RecipientStatusSkipped:
+		return true
+	}
+	return false
+}
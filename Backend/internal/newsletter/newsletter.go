@@ -0,0 +1,350 @@
+// Package newsletter keeps the mailing list: double opt-in sign-ups, subscribers
+// from checkout, one-click unsubscribes and segments for exports. It also stores
+// campaigns sent to the list, with per-recipient delivery and open/click tracking.
+package newsletter
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+const (
+	// ConfirmationValidity is how long a confirmation link works
+	ConfirmationValidity = 7 * 24 * time.Hour
+	// ResendAfter is how long a pending sign-up waits before another confirmation
+	// email is sent, so the form can't be used to flood an inbox
+	ResendAfter = 10 * time.Minute
+	// MaxGenres caps the genres one subscriber can pick
+	MaxGenres = 20
+)
+
+// Service manages the mailing list
+type Service struct {
+	db *sql.DB
+}
+
+// NewService creates a newsletter service
+func NewService(db *sql.DB) *Service {
+	return &Service{db: db}
+}
+
+// Subscribe signs an email up from the form. New, unsubscribed and stale pending
+// addresses get a fresh confirmation token and send is true: the caller should
+// email the confirmation link. Addresses already subscribed are left alone, so the
+// form can't be used to change someone else's details.
+func (s *Service) Subscribe(email, city string, genres []string) (sub *models.Subscriber, send bool, err error) {
+	now := time.Now()
+	existing, err := s.GetByEmail(email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+
+	if existing == nil {
+		sub = &models.Subscriber{
+			ID:               uuid.New().String(),
+			Email:            email,
+			Status:           models.SubscriberStatusPending,
+			Source:           models.SubscriberSourceForm,
+			City:             city,
+			ConfirmToken:     uuid.New().String(),
+			UnsubscribeToken: uuid.New().String(),
+			CreatedAt:        now,
+			UpdatedAt:        now,
+		}
+		_, err := s.db.Exec(`
+			INSERT INTO subscribers (id, email, status, source, city, confirm_token, unsubscribe_token,
+				confirmation_sent_at, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, sub.ID, sub.Email, sub.Status, sub.Source, sub.City, sub.ConfirmToken, sub.UnsubscribeToken, now, now, now)
+		if err != nil {
+			return nil, false, fmt.Errorf("insert subscriber: %w", err)
+		}
+		if err := s.setGenres(sub.ID, genres); err != nil {
+			return nil, false, err
+		}
+		sub.Genres = genres
+		return sub, true, nil
+	}
+
+	switch existing.Status {
+	case models.SubscriberStatusSubscribed:
+		return existing, false, nil
+	case models.SubscriberStatusPending:
+		var sentAt sql.NullTime
+		if err := s.db.QueryRow(`SELECT confirmation_sent_at FROM subscribers WHERE id = ?`, existing.ID).Scan(&sentAt); err != nil {
+			return nil, false, fmt.Errorf("get subscriber: %w", err)
+		}
+		if sentAt.Valid && now.Sub(sentAt.Time) < ResendAfter {
+			return existing, false, nil
+		}
+	}
+
+	existing.Status = models.SubscriberStatusPending
+	existing.City = city
+	existing.ConfirmToken = uuid.New().String()
+	_, err = s.db.Exec(`
+		UPDATE subscribers
+		SET status = ?, city = ?, confirm_token = ?, confirmation_sent_at = ?, updated_at = ?
+		WHERE id = ?
+	`, existing.Status, existing.City, existing.ConfirmToken, now, now, existing.ID)
+	if err != nil {
+		return nil, false, fmt.Errorf("update subscriber: %w", err)
+	}
+	if err := s.setGenres(existing.ID, genres); err != nil {
+		return nil, false, err
+	}
+	existing.Genres = genres
+	return existing, true, nil
+}
+
+// SubscribeFromCheckout adds a buyer who opted in to promotional email at checkout.
+// The opt-in is their consent, so there is no confirmation step. added is false if
+// they were already subscribed.
+func (s *Service) SubscribeFromCheckout(email, city string) (sub *models.Subscriber, added bool, err error) {
+	now := time.Now()
+	existing, err := s.GetByEmail(email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+
+	if existing == nil {
+		sub = &models.Subscriber{
+			ID:               uuid.New().String(),
+			Email:            email,
+			Status:           models.SubscriberStatusSubscribed,
+			Source:           models.SubscriberSourceCheckout,
+			City:             city,
+			Genres:           []string{},
+			UnsubscribeToken: uuid.New().String(),
+			ConfirmedAt:      &now,
+			CreatedAt:        now,
+			UpdatedAt:        now,
+		}
+		_, err := s.db.Exec(`
+			INSERT INTO subscribers (id, email, status, source, city, unsubscribe_token, confirmed_at, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, sub.ID, sub.Email, sub.Status, sub.Source, sub.City, sub.UnsubscribeToken, now, now, now)
+		if err != nil {
+			return nil, false, fmt.Errorf("insert subscriber: %w", err)
+		}
+		return sub, true, nil
+	}
+
+	if existing.Status == models.SubscriberStatusSubscribed {
+		return existing, false, nil
+	}
+
+	// A city from the sign-up form is kept over the shipping address
+	_, err = s.db.Exec(`
+		UPDATE subscribers
+		SET status = ?, city = CASE WHEN city = '' THEN ? ELSE city END, confirm_token = NULL,
+			confirmed_at = ?, unsubscribed_at = NULL, updated_at = ?
+		WHERE id = ?
+	`, models.SubscriberStatusSubscribed, city, now, now, existing.ID)
+	if err != nil {
+		return nil, false, fmt.Errorf("update subscriber: %w", err)
+	}
+	sub, err = s.GetByEmail(email)
+	return sub, err == nil, err
+}
+
+// Confirm subscribes the pending address a confirmation token was sent to
+// Returns sql.ErrNoRows for an unknown, used or expired token.
+func (s *Service) Confirm(token string) (*models.Subscriber, error) {
+	now := time.Now()
+	var id string
+	err := s.db.QueryRow(`
+		SELECT id FROM subscribers WHERE confirm_token = ? AND status = ? AND confirmation_sent_at > ?
+	`, token, models.SubscriberStatusPending, now.Add(-ConfirmationValidity)).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.db.Exec(`
+		UPDATE subscribers
+		SET status = ?, confirm_token = NULL, confirmed_at = ?, unsubscribed_at = NULL, updated_at = ?
+		WHERE id = ?
+	`, models.SubscriberStatusSubscribed, now, now, id)
+	if err != nil {
+		return nil, fmt.Errorf("confirm subscriber: %w", err)
+	}
+	return s.getBy("s.id = ?", id)
+}
+
+// Unsubscribe takes the address an unsubscribe token belongs to off the list.
+// Unsubscribing twice is not an error.
+// Returns sql.ErrNoRows for an unknown token.
+func (s *Service) Unsubscribe(token string) (*models.Subscriber, error) {
+	now := time.Now()
+	_, err := s.db.Exec(`
+		UPDATE subscribers
+		SET status = ?, confirm_token = NULL, unsubscribed_at = ?, updated_at = ?
+		WHERE unsubscribe_token = ? AND status != ?
+	`, models.SubscriberStatusUnsubscribed, now, now, token, models.SubscriberStatusUnsubscribed)
+	if err != nil {
+		return nil, fmt.Errorf("unsubscribe: %w", err)
+	}
+	return s.getBy("s.unsubscribe_token = ?", token)
+}
+
+// GetByEmail returns the subscriber with an email address
+// Returns sql.ErrNoRows if it is not on the list.
+func (s *Service) GetByEmail(email string) (*models.Subscriber, error) {
+	return s.getBy("s.email = ?", email)
+}
+
+func (s *Service) getBy(where string, args ...interface{}) (*models.Subscriber, error) {
+	list, err := s.query("WHERE "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &list[0], nil
+}
+
+func (s *Service) setGenres(subscriberID string, genres []string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM subscriber_genres WHERE subscriber_id = ?`, subscriberID); err != nil {
+		return fmt.Errorf("clear genres: %w", err)
+	}
+	for _, genre := range genres {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO subscriber_genres (subscriber_id, genre) VALUES (?, ?)`, subscriberID, genre); err != nil {
+			return fmt.Errorf("insert genre: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Segment narrows the list. Empty fields match everyone.
+type Segment struct {
+	Status    string // pending, subscribed or unsubscribed
+	Genre     string // Interested in this genre
+	City      string
+	Purchaser *bool // Has (or hasn't) a paid order under the same email
+}
+
+// purchaserSQL is true for a subscriber with a paid order
+const purchaserSQL = `EXISTS (
+	SELECT 1 FROM orders o JOIN customers c ON c.id = o.customer_id
+	WHERE c.email = s.email COLLATE NOCASE AND o.status NOT IN ('pending', 'cancelled')
+)`
+
+// List returns the subscribers in a segment, newest first
+func (s *Service) List(segment Segment) ([]models.Subscriber, error) {
+	var where []string
+	var args []interface{}
+	if segment.Status != "" {
+		where = append(where, "s.status = ?")
+		args = append(args, segment.Status)
+	}
+	if segment.Genre != "" {
+		where = append(where, "EXISTS (SELECT 1 FROM subscriber_genres g WHERE g.subscriber_id = s.id AND g.genre = ?)")
+		args = append(args, segment.Genre)
+	}
+	if segment.City != "" {
+		where = append(where, "s.city = ? COLLATE NOCASE")
+		args = append(args, segment.City)
+	}
+	if segment.Purchaser != nil {
+		if *segment.Purchaser {
+			where = append(where, purchaserSQL)
+		} else {
+			where = append(where, "NOT "+purchaserSQL)
+		}
+	}
+
+	query := "ORDER BY s.created_at DESC"
+	if len(where) > 0 {
+		query = "WHERE " + strings.Join(where, " AND ") + " " + query
+	}
+	return s.query(query, args...)
+}
+
+func (s *Service) query(where string, args ...interface{}) ([]models.Subscriber, error) {
+	rows, err := s.db.Query(`
+		SELECT s.id, s.email, s.status, s.source, s.city, s.confirm_token, s.unsubscribe_token,
+			s.confirmed_at, s.unsubscribed_at, s.created_at, s.updated_at, `+purchaserSQL+`
+		FROM subscribers s
+		`+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query subscribers: %w", err)
+	}
+
+	list := []models.Subscriber{}
+	for rows.Next() {
+		var sub models.Subscriber
+		var confirmToken sql.NullString
+		var confirmedAt, unsubscribedAt sql.NullTime
+		if err := rows.Scan(&sub.ID, &sub.Email, &sub.Status, &sub.Source, &sub.City, &confirmToken,
+			&sub.UnsubscribeToken, &confirmedAt, &unsubscribedAt, &sub.CreatedAt, &sub.UpdatedAt, &sub.Purchaser); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan subscriber: %w", err)
+		}
+		sub.ConfirmToken = confirmToken.String
+		if confirmedAt.Valid {
+			sub.ConfirmedAt = &confirmedAt.Time
+		}
+		if unsubscribedAt.Valid {
+			sub.UnsubscribedAt = &unsubscribedAt.Time
+		}
+		list = append(list, sub)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, err
+	}
+	rows.Close()
+
+	if err := s.loadGenres(list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// loadGenres fills in the genres of a page of subscribers
+func (s *Service) loadGenres(list []models.Subscriber) error {
+	byID := make(map[string]*models.Subscriber, len(list))
+	for i := range list {
+		list[i].Genres = []string{}
+		byID[list[i].ID] = &list[i]
+	}
+	if len(list) == 0 {
+		return nil
+	}
+
+	// One subscriber is looked up directly; for a list, reading every genre
+	// beats a query per subscriber
+	query, args := `SELECT subscriber_id, genre FROM subscriber_genres ORDER BY genre`, []interface{}{}
+	if len(list) == 1 {
+		query, args = `SELECT subscriber_id, genre FROM subscriber_genres WHERE subscriber_id = ? ORDER BY genre`, []interface{}{list[0].ID}
+	}
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return fmt.Errorf("query genres: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, genre string
+		if err := rows.Scan(&id, &genre); err != nil {
+			return fmt.Errorf("scan genre: %w", err)
+		}
+		if sub, ok := byID[id]; ok {
+			sub.Genres = append(sub.Genres, genre)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,102 @@
+package reviews
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+// PendingInvite is a shipped order whose buyer has not been asked for reviews yet
+type PendingInvite struct {
+	OrderID  string
+	Email    string
+	Name     string // Shipping name
+	Products []InvitedProduct
+}
+
+// InvitedProduct is one product the buyer is invited to review
+type InvitedProduct struct {
+	ID   string
+	Slug string
+	Name string
+}
+
+// DueInvites returns the orders that shipped at least InviteDelay before now and have
+// not been invited yet, with the active products the buyer has not reviewed
+// Shipping times are compared in Go; SQLite comparisons on stored Go times are unreliable.
+func (s *Service) DueInvites(now time.Time) ([]PendingInvite, error) {
+	rows, err := s.db.Query(`
+		SELECT id, customer_email, COALESCE(shipping_name, ''), shipped_at
+		FROM orders
+		WHERE status = ? AND shipped_at IS NOT NULL AND review_requested_at IS NULL
+			AND customer_email IS NOT NULL AND customer_email != ''
+	`, models.OrderStatusShipped)
+	if err != nil {
+		return nil, fmt.Errorf("query shipped orders: %w", err)
+	}
+
+	var due []PendingInvite
+	for rows.Next() {
+		var invite PendingInvite
+		var shippedAt time.Time
+		if err := rows.Scan(&invite.OrderID, &invite.Email, &invite.Name, &shippedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan shipped order: %w", err)
+		}
+		if shippedAt.Add(InviteDelay).After(now) {
+			continue
+		}
+		due = append(due, invite)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range due {
+		products, err := s.invitedProducts(due[i].OrderID, due[i].Email)
+		if err != nil {
+			return nil, err
+		}
+		due[i].Products = products
+	}
+	return due, nil
+}
+
+func (s *Service) invitedProducts(orderID, email string) ([]InvitedProduct, error) {
+	rows, err := s.db.Query(`
+		SELECT DISTINCT p.id, COALESCE(p.slug, ''), p.name
+		FROM order_items i
+		JOIN products p ON p.id = i.product_id
+		WHERE i.order_id = ? AND p.active = 1
+			AND NOT EXISTS (
+				SELECT 1 FROM product_reviews r
+				WHERE r.product_id = p.id AND r.email = lower(?)
+			)
+		ORDER BY p.name
+	`, orderID, email)
+	if err != nil {
+		return nil, fmt.Errorf("query order products: %w", err)
+	}
+	defer rows.Close()
+
+	var products []InvitedProduct
+	for rows.Next() {
+		var p InvitedProduct
+		if err := rows.Scan(&p.ID, &p.Slug, &p.Name); err != nil {
+			return nil, fmt.Errorf("scan order product: %w", err)
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+// MarkInvited records that an order's buyer has been asked for reviews
+func (s *Service) MarkInvited(orderID string) error {
+	_, err := s.db.Exec(`UPDATE orders SET review_requested_at = ? WHERE id = ?`, time.Now(), orderID)
+	if err != nil {
+		return fmt.Errorf("mark review invite sent: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+// Package reviews handles product reviews from verified purchasers: signed invitation
+// links, submission, moderation and the rating summary shown on product pages.
+package reviews
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+	"github.com/nessieaudio/ecommerce-backend/internal/signing"
+)
+
+// invitePurpose binds invitation tokens to reviews so other signed links cannot be reused
+const invitePurpose = "review"
+
+// InviteTTL is how long an invitation link stays valid
+const InviteTTL = 90 * 24 * time.Hour
+
+// InviteDelay is how long after shipping an invitation is sent. Printful does not
+// report deliveries, so this stands in for "after it has arrived".
+const InviteDelay = 10 * 24 * time.Hour
+
+// Fit notes
+const (
+	FitRunsSmall  = "runs_small"
+	FitTrueToSize = "true_to_size"
+	FitRunsLarge  = "runs_large"
+)
+
+var (
+	// ErrNotVerifiedPurchase is returned when the invitation does not match a paid order for the product
+	ErrNotVerifiedPurchase = errors.New("review link does not match a purchase of this product")
+	// ErrAlreadyReviewed is returned when the purchaser has already reviewed the product
+	ErrAlreadyReviewed = errors.New("you have already reviewed this product")
+	// ErrInvalidStatus is returned for an unknown moderation status
+	ErrInvalidStatus = errors.New("status must be pending, approved or rejected")
+)
+
+// ValidFit reports whether fit is a known fit note, or empty
+func ValidFit(fit string) bool {
+	switch fit {
+	case "", FitRunsSmall, FitTrueToSize, FitRunsLarge:
+		return true
+	}
+	return false
+}
+
+// Invite identifies the purchase an invitation link was sent for
+type Invite struct {
+	OrderID   string `json:"o"`
+	ProductID string `json:"p"`
+	Email     string `json:"e"`
+}
+
+// Submission is what a reviewer fills in
+type Submission struct {
+	Rating       int
+	Fit          string
+	Body         string
+	ReviewerName string
+}
+
+// Summary is the aggregate of a product's approved reviews
+type Summary struct {
+	Average float64        `json:"average"` // Rounded to one decimal
+	Count   int            `json:"count"`
+	Fit     map[string]int `json:"fit,omitempty"` // Reviews per fit note
+}
+
+// Service stores and moderates reviews
+type Service struct {
+	db     *sql.DB
+	signer *signing.Signer
+}
+
+// NewService creates a review service
+func NewService(db *sql.DB, signer *signing.Signer) *Service {
+	return &Service{db: db, signer: signer}
+}
+
+// InviteToken signs an invitation for one product of an order
+func (s *Service) InviteToken(invite Invite) (string, error) {
+	return s.signer.Sign(invitePurpose, invite, InviteTTL)
+}
+
+// VerifyInvite checks an invitation token
+// Returns signing.ErrInvalidToken or signing.ErrExpiredToken when it cannot be used.
+func (s *Service) VerifyInvite(token string) (*Invite, error) {
+	var invite Invite
+	if err := s.signer.Verify(invitePurpose, token, &invite); err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// Submit records a review for moderation
+// The token must be an invitation for productID whose email matches a paid order
+// containing the product. Returns ErrNotVerifiedPurchase otherwise, or
+// ErrAlreadyReviewed if this purchaser has reviewed the product before.
+func (s *Service) Submit(productID, token string, sub Submission) (*models.Review, error) {
+	invite, err := s.VerifyInvite(token)
+	if err != nil {
+		return nil, err
+	}
+	if invite.ProductID != productID {
+		return nil, ErrNotVerifiedPurchase
+	}
+
+	var shippingName string
+	err = s.db.QueryRow(`
+		SELECT COALESCE(o.shipping_name, '')
+		FROM orders o
+		JOIN order_items i ON i.order_id = o.id
+		WHERE o.id = ? AND i.product_id = ? AND lower(o.customer_email) = lower(?)
+			AND o.status IN (?, ?, ?, ?)
+		LIMIT 1
+	`, invite.OrderID, productID, invite.Email, models.OrderStatusPaid,
+		models.OrderStatusPartiallyFulfilled, models.OrderStatusFulfilled, models.OrderStatusShipped).Scan(&shippingName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotVerifiedPurchase
+	}
+	if err != nil {
+		return nil, fmt.Errorf("verify purchase: %w", err)
+	}
+
+	name := strings.TrimSpace(sub.ReviewerName)
+	if name == "" {
+		name = firstName(shippingName)
+	}
+
+	now := time.Now()
+	review := &models.Review{
+		ID:           uuid.New().String(),
+		ProductID:    productID,
+		OrderID:      invite.OrderID,
+		Email:        strings.ToLower(invite.Email),
+		ReviewerName: name,
+		Rating:       sub.Rating,
+		Fit:          sub.Fit,
+		Body:         strings.TrimSpace(sub.Body),
+		Status:       models.ReviewStatusPending,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO product_reviews (id, product_id, order_id, email, reviewer_name, rating, fit, body, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, review.ID, review.ProductID, review.OrderID, review.Email, review.ReviewerName, review.Rating,
+		review.Fit, review.Body, review.Status, now, now)
+	if err != nil && strings.Contains(err.Error(), "UNIQUE") {
+		return nil, ErrAlreadyReviewed
+	}
+	if err != nil {
+		return nil, fmt.Errorf("insert review: %w", err)
+	}
+
+	return review, nil
+}
+
+// List returns reviews with the given status (all when empty), oldest first so the
+// moderation queue is worked in order
+func (s *Service) List(status string) ([]models.Review, error) {
+	if status == "" {
+		return s.query(`ORDER BY created_at`)
+	}
+	return s.query(`WHERE status = ? ORDER BY created_at`, status)
+}
+
+// Approved returns a product's approved reviews, newest first
+func (s *Service) Approved(productID string) ([]models.Review, error) {
+	return s.query(`WHERE product_id = ? AND status = ? ORDER BY created_at DESC`, productID, models.ReviewStatusApproved)
+}
+
+// Get returns a review by ID
+// Returns sql.ErrNoRows if it does not exist.
+func (s *Service) Get(id string) (*models.Review, error) {
+	reviews, err := s.query(`WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(reviews) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &reviews[0], nil
+}
+
+// Moderate sets a review's status, with an optional note for other admins
+// Returns sql.ErrNoRows if it does not exist, ErrInvalidStatus for an unknown status.
+func (s *Service) Moderate(id, status, note string) (*models.Review, error) {
+	switch status {
+	case models.ReviewStatusPending, models.ReviewStatusApproved, models.ReviewStatusRejected:
+	default:
+		return nil, ErrInvalidStatus
+	}
+
+	now := time.Now()
+	result, err := s.db.Exec(`
+		UPDATE product_reviews
+		SET status = ?, moderation_note = ?, moderated_at = ?, updated_at = ?
+		WHERE id = ?
+	`, status, note, now, now, id)
+	if err != nil {
+		return nil, fmt.Errorf("moderate review: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return s.Get(id)
+}
+
+// Delete removes a review
+// Returns sql.ErrNoRows if it does not exist.
+func (s *Service) Delete(id string) error {
+	result, err := s.db.Exec(`DELETE FROM product_reviews WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete review: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Summarize aggregates a product's approved reviews
+// Returns nil when the product has none.
+func (s *Service) Summarize(productID string) (*Summary, error) {
+	rows, err := s.db.Query(`
+		SELECT rating, fit FROM product_reviews
+		WHERE product_id = ? AND status = ?
+	`, productID, models.ReviewStatusApproved)
+	if err != nil {
+		return nil, fmt.Errorf("query ratings: %w", err)
+	}
+	defer rows.Close()
+
+	summary := &Summary{}
+	total := 0
+	for rows.Next() {
+		var rating int
+		var fit string
+		if err := rows.Scan(&rating, &fit); err != nil {
+			return nil, fmt.Errorf("scan rating: %w", err)
+		}
+		summary.Count++
+		total += rating
+		if fit != "" {
+			if summary.Fit == nil {
+				summary.Fit = make(map[string]int)
+			}
+			summary.Fit[fit]++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if summary.Count == 0 {
+		return nil, nil
+	}
+	summary.Average = math.Round(float64(total)/float64(summary.Count)*10) / 10
+	return summary, nil
+}
+
+func (s *Service) query(where string, args ...interface{}) ([]models.Review, error) {
+	rows, err := s.db.Query(`
+		SELECT id, product_id, order_id, email, reviewer_name, rating, fit, body, status,
+			moderation_note, moderated_at, created_at, updated_at
+		FROM product_reviews
+		`+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query reviews: %w", err)
+	}
+	defer rows.Close()
+
+	reviews := []models.Review{}
+	for rows.Next() {
+		var r models.Review
+		var moderatedAt sql.NullTime
+		if err := rows.Scan(&r.ID, &r.ProductID, &r.OrderID, &r.Email, &r.ReviewerName, &r.Rating, &r.Fit,
+			&r.Body, &r.Status, &r.ModerationNote, &moderatedAt, &r.CreatedAt, &r.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan review: %w", err)
+		}
+		if moderatedAt.Valid {
+			r.ModeratedAt = &moderatedAt.Time
+		}
+		reviews = append(reviews, r)
+	}
+	return reviews, rows.Err()
+}
+
+// firstName is the name shown on a review when the reviewer gives none
+func firstName(fullName string) string {
+	if fields := strings.Fields(fullName); len(fields) > 0 {
+		return fields[0]
+	}
+	return "Verified buyer"
+}
@@ -0,0 +1,373 @@
+package email
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+// Message is an email ready to be handed to a Transport. Bytes renders it as a
+// MIME message: an HTML body goes out as multipart/alternative with a plain-text
+// part, and attachments wrap that in multipart/mixed.
+type Message struct {
+	From        string // Display name and address, e.g. "Nessie Audio <shop@example.com>"
+	To          []string
+	Cc          []string
+	Bcc         []string // Receive the email without appearing in its headers
+	ReplyTo     string
+	Subject     string
+	Headers     map[string]string // Extra headers, e.g. List-Unsubscribe
+	HTMLBody    string
+	TextBody    string // Generated from HTMLBody when empty
+	Attachments []models.EmailAttachment
+	MessageID   string    // Generated from the From domain when empty
+	Date        time.Time // Now when zero
+}
+
+// reservedHeaders are written from the Message fields and can't be set through Headers
+var reservedHeaders = map[string]bool{
+	"From": true, "To": true, "Cc": true, "Bcc": true, "Reply-To": true, "Subject": true,
+	"Date": true, "Message-Id": true, "Mime-Version": true, "Content-Type": true,
+	"Content-Transfer-Encoding": true,
+}
+
+// Validate checks that the message has a sender, a recipient, a body and
+// well-formed addresses
+func (m *Message) Validate() error {
+	if m.From == "" {
+		return errors.New("email has no sender")
+	}
+	if len(m.To)+len(m.Cc)+len(m.Bcc) == 0 {
+		return errors.New("email has no recipients")
+	}
+	if m.HTMLBody == "" && m.TextBody == "" {
+		return errors.New("email has no body")
+	}
+
+	addresses := append([]string{m.From}, m.To...)
+	addresses = append(addresses, m.Cc...)
+	addresses = append(addresses, m.Bcc...)
+	if m.ReplyTo != "" {
+		addresses = append(addresses, m.ReplyTo)
+	}
+	for _, a := range addresses {
+		if _, err := mail.ParseAddress(a); err != nil {
+			return fmt.Errorf("invalid email address %q: %w", a, err)
+		}
+	}
+	return nil
+}
+
+// Recipients returns the bare addresses the message is delivered to, including Bcc
+func (m *Message) Recipients() []string {
+	var recipients []string
+	for _, list := range [][]string{m.To, m.Cc, m.Bcc} {
+		for _, a := range list {
+			if addr, err := mail.ParseAddress(a); err == nil {
+				recipients = append(recipients, addr.Address)
+			}
+		}
+	}
+	return recipients
+}
+
+// Bytes renders the message as it goes over the wire. Headers are written in a
+// fixed order, non-ASCII text in them is RFC 2047 encoded, and line breaks are
+// removed so that visitor input can't add headers. Part boundaries are derived
+// from the Message-ID, so the same message always renders the same way.
+func (m *Message) Bytes() ([]byte, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	date := m.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	messageID := m.MessageID
+	if messageID == "" {
+		messageID = NewMessageID(m.From)
+	}
+
+	var buf bytes.Buffer
+	writeHeader(&buf, "From", formatAddresses([]string{m.From}))
+	if len(m.To) > 0 {
+		writeHeader(&buf, "To", formatAddresses(m.To))
+	}
+	if len(m.Cc) > 0 {
+		writeHeader(&buf, "Cc", formatAddresses(m.Cc))
+	}
+	if m.ReplyTo != "" {
+		writeHeader(&buf, "Reply-To", formatAddresses([]string{m.ReplyTo}))
+	}
+	writeHeader(&buf, "Subject", encodeHeader(m.Subject))
+	writeHeader(&buf, "Date", date.Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", headerValue.Replace(messageID))
+
+	keys := make([]string, 0, len(m.Headers))
+	for k := range m.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		name := textproto.CanonicalMIMEHeaderKey(headerValue.Replace(k))
+		if v := m.Headers[k]; v != "" && !reservedHeaders[name] {
+			writeHeader(&buf, name, encodeHeader(v))
+		}
+	}
+	writeHeader(&buf, "MIME-Version", "1.0")
+
+	text := m.TextBody
+	if text == "" {
+		text = HTMLToText(m.HTMLBody)
+	}
+	sum := sha1.Sum([]byte(messageID))
+	boundary := hex.EncodeToString(sum[:12])
+
+	header, body, err := bodyPart(text, m.HTMLBody, "alt_"+boundary)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.Attachments) == 0 {
+		writeMIMEHeader(&buf, header)
+		buf.WriteString("\r\n")
+		buf.Write(body)
+		return buf.Bytes(), nil
+	}
+
+	mixed := multipart.NewWriter(&buf)
+	if err := mixed.SetBoundary("mixed_" + boundary); err != nil {
+		return nil, err
+	}
+	writeHeader(&buf, "Content-Type", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": mixed.Boundary()}))
+	buf.WriteString("\r\n")
+
+	part, err := mixed.CreatePart(header)
+	if err != nil {
+		return nil, err
+	}
+	part.Write(body)
+	for _, a := range m.Attachments {
+		if err := writeAttachment(mixed, a); err != nil {
+			return nil, err
+		}
+	}
+	if err := mixed.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// bodyPart renders the body, plain text alone or multipart/alternative with
+// text and HTML parts, and returns its headers and content
+func bodyPart(text, htmlBody, boundary string) (textproto.MIMEHeader, []byte, error) {
+	var buf bytes.Buffer
+	if htmlBody == "" {
+		header := textproto.MIMEHeader{
+			"Content-Type":              {`text/plain; charset="UTF-8"`},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		}
+		if err := writeQuotedPrintable(&buf, text); err != nil {
+			return nil, nil, err
+		}
+		return header, buf.Bytes(), nil
+	}
+
+	alt := multipart.NewWriter(&buf)
+	if err := alt.SetBoundary(boundary); err != nil {
+		return nil, nil, err
+	}
+	for _, p := range []struct{ contentType, content string }{
+		{`text/plain; charset="UTF-8"`, text},
+		{`text/html; charset="UTF-8"`, htmlBody},
+	} {
+		part, err := alt.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := writeQuotedPrintable(part, p.content); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := alt.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	header := textproto.MIMEHeader{
+		"Content-Type": {mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": alt.Boundary()})},
+	}
+	return header, buf.Bytes(), nil
+}
+
+func writeAttachment(w *multipart.Writer, a models.EmailAttachment) error {
+	contentType := "application/octet-stream"
+	if mediaType, params, err := mime.ParseMediaType(a.ContentType); err == nil {
+		contentType = mime.FormatMediaType(mediaType, params)
+	}
+	filename := headerValue.Replace(a.Filename)
+
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": filename})},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Base64 lines are limited to 76 characters (RFC 2045)
+	encoded := base64.StdEncoding.EncodeToString(a.Data)
+	for len(encoded) > 76 {
+		io.WriteString(part, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	_, err = io.WriteString(part, encoded+"\r\n")
+	return err
+}
+
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := io.WriteString(qp, content); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func writeHeader(buf *bytes.Buffer, name, value string) {
+	fmt.Fprintf(buf, "%s: %s\r\n", name, value)
+}
+
+// writeMIMEHeader writes part headers in sorted order
+func writeMIMEHeader(buf *bytes.Buffer, header textproto.MIMEHeader) {
+	keys := make([]string, 0, len(header))
+	for k := range header {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		writeHeader(buf, k, header.Get(k))
+	}
+}
+
+// encodeHeader removes line breaks from a header value and RFC 2047 encodes it
+// when it isn't plain ASCII, e.g. a subject with emojis
+func encodeHeader(value string) string {
+	return mime.QEncoding.Encode("utf-8", headerValue.Replace(value))
+}
+
+// formatAddresses joins addresses for a header, encoding display names that
+// aren't plain ASCII. Addresses are checked by Validate beforehand.
+func formatAddresses(addresses []string) string {
+	formatted := make([]string, 0, len(addresses))
+	for _, a := range addresses {
+		addr, err := mail.ParseAddress(a)
+		if err != nil {
+			continue
+		}
+		if addr.Name == "" {
+			formatted = append(formatted, addr.Address)
+		} else {
+			formatted = append(formatted, addr.String())
+		}
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// splitAddresses parses a comma-separated address list, as stored in the email queue
+func splitAddresses(list string) []string {
+	if list == "" {
+		return nil
+	}
+	addresses, err := mail.ParseAddressList(list)
+	if err != nil {
+		return []string{list}
+	}
+	split := make([]string, len(addresses))
+	for i, addr := range addresses {
+		split[i] = addr.Address
+		if addr.Name != "" {
+			split[i] = addr.String()
+		}
+	}
+	return split
+}
+
+// NewMessageID returns a unique Message-ID in the domain of the from address
+func NewMessageID(from string) string {
+	domain := "nessieaudio.com"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if _, d, ok := strings.Cut(addr.Address, "@"); ok && d != "" {
+			domain = d
+		}
+	}
+	return "<" + uuid.New().String() + "@" + domain + ">"
+}
+
+// Patterns for turning an HTML email into its plain-text alternative
+var (
+	htmlHiddenPattern = regexp.MustCompile(`(?is)<head\b.*?</head>|<style\b.*?</style>|<script\b.*?</script>`)
+	htmlLinkPattern   = regexp.MustCompile(`(?is)<a\b[^>]*?href\s*=\s*"([^"]*)"[^>]*>(.*?)</a>`)
+	htmlBreakPattern  = regexp.MustCompile(`(?i)<br\s*/?>|</?(p|div|h[1-6]|tr|table|ul|ol)\b[^>]*>`)
+	htmlItemPattern   = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+	htmlCellPattern   = regexp.MustCompile(`(?i)</t[dh]>`)
+	htmlTagPattern    = regexp.MustCompile(`<[^>]*>`)
+	spacePattern      = regexp.MustCompile(`[ \t\x{00a0}]+`)
+)
+
+// HTMLToText renders the plain-text alternative of an HTML email: block
+// elements become line breaks, links are followed by their URL in brackets and
+// everything else is reduced to its text
+func HTMLToText(htmlBody string) string {
+	s := htmlHiddenPattern.ReplaceAllString(htmlBody, "")
+	s = htmlLinkPattern.ReplaceAllStringFunc(s, func(link string) string {
+		match := htmlLinkPattern.FindStringSubmatch(link)
+		href := html.UnescapeString(match[1])
+		text := strings.TrimSpace(htmlTagPattern.ReplaceAllString(match[2], ""))
+		if href == "" || strings.HasPrefix(href, "mailto:") || html.UnescapeString(text) == href {
+			return match[2]
+		}
+		return match[2] + " (" + href + ")"
+	})
+	s = htmlBreakPattern.ReplaceAllString(s, "\n")
+	s = htmlItemPattern.ReplaceAllString(s, "\n- ")
+	s = htmlCellPattern.ReplaceAllString(s, " ")
+	s = htmlTagPattern.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+
+	// Collapse the template's indentation and runs of blank lines
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(spacePattern.ReplaceAllString(line, " "))
+		if line == "" {
+			blank = len(lines) > 0
+			continue
+		}
+		if blank {
+			lines = append(lines, "")
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
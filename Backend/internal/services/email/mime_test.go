@@ -0,0 +1,193 @@
+package email
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+// Run `go test ./internal/services/email -update` to rewrite the golden files
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// fixedDate and fixedMessageID keep the rendered output stable
+var fixedDate = time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+const fixedMessageID = "<3f1c2a9e-golden@nessieaudio.com>"
+
+const testHTML = `<html><head><title>Order</title><style>p { color: #fff; }</style></head>
+<body>
+    <h1>Order Confirmed!</h1>
+    <p>Hey Sam,</p>
+    <p>Thanks for your order &amp; your support.</p>
+    <div class="info-box"><h2>Order Details</h2>
+        <div class="detail-row"><span>Total:</span> <strong>$42.00</strong></div>
+    </div>
+    <ul><li>Logo Tee</li><li>Sticker Pack</li></ul>
+    <a href="https://nessieaudio.com/merch" class="cta-button">Continue Shopping</a>
+    <p>Questions? <a href="mailto:nessieaudio@gmail.com">nessieaudio@gmail.com</a></p>
+</body></html>`
+
+func TestMessageBytesGolden(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+	}{
+		{
+			name: "alternative",
+			msg: Message{
+				From:     `"Nessie Audio" <shop@nessieaudio.com>`,
+				To:       []string{"sam@example.com"},
+				Subject:  "Order Confirmation - Nessie Audio #1001",
+				HTMLBody: testHTML,
+			},
+		},
+		{
+			name: "plain",
+			msg: Message{
+				From:     "shop@nessieaudio.com",
+				To:       []string{"admin@example.com"},
+				Subject:  "Low stock",
+				TextBody: "Logo Tee (M) is down to 2 units.\nRestock soon.",
+			},
+		},
+		{
+			name: "encoded_headers",
+			msg: Message{
+				From:     `"Nessie Audio" <shop@nessieaudio.com>`,
+				To:       []string{"José Núñez <jose@example.com>", "fan@example.com"},
+				Cc:       []string{"Zoë <zoe@example.com>"},
+				Bcc:      []string{"archive@nessieaudio.com"},
+				ReplyTo:  "Björk <bjork@example.com>",
+				Subject:  "🚨 Critical: Überweisung fehlgeschlagen",
+				Headers:  map[string]string{"List-Unsubscribe": "<https://nessieaudio.com/api/v1/unsubscribe/abc>", "X-Campaign": "Frühling"},
+				TextBody: "Héllo",
+			},
+		},
+		{
+			name: "attachments",
+			msg: Message{
+				From:     `"Nessie Audio" <shop@nessieaudio.com>`,
+				To:       []string{"sam@example.com"},
+				Subject:  "Your invoice #1001",
+				HTMLBody: `<p>Your invoice is attached.</p>`,
+				Attachments: []models.EmailAttachment{
+					{Filename: "invoice-1001.pdf", ContentType: "application/pdf", Data: []byte("%PDF-1.4\n1 0 obj << /Type /Catalog >> endobj\ntrailer << /Root 1 0 R >>\n%%EOF\n")},
+					{Filename: "süße lieder.txt", ContentType: "", Data: []byte("lyrics")},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := tt.msg
+			msg.Date = fixedDate
+			msg.MessageID = fixedMessageID
+
+			got, err := msg.Bytes()
+			if err != nil {
+				t.Fatalf("Bytes() error: %v", err)
+			}
+
+			golden := filepath.Join("testdata", tt.name+".eml")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("read golden file (run with -update to create it): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Bytes() differs from %s:\n--- got ---\n%s\n--- want ---\n%s", golden, got, want)
+			}
+
+			// Rendering is deterministic for a fixed Date and Message-ID
+			again, _ := msg.Bytes()
+			if !bytes.Equal(got, again) {
+				t.Error("Bytes() is not deterministic")
+			}
+		})
+	}
+}
+
+func TestMessageBccOnlyOnEnvelope(t *testing.T) {
+	msg := Message{
+		From:     "shop@nessieaudio.com",
+		To:       []string{"Sam <sam@example.com>"},
+		Cc:       []string{"cc@example.com"},
+		Bcc:      []string{"hidden@example.com"},
+		Subject:  "Hi",
+		TextBody: "Hi",
+	}
+
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("hidden@example.com")) || bytes.Contains(data, []byte("Bcc:")) {
+		t.Errorf("Bcc leaked into the message:\n%s", data)
+	}
+	if !bytes.Contains(data, []byte("Cc: cc@example.com\r\n")) {
+		t.Errorf("Cc header missing:\n%s", data)
+	}
+
+	want := []string{"sam@example.com", "cc@example.com", "hidden@example.com"}
+	if got := msg.Recipients(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Recipients() = %v, want %v", got, want)
+	}
+}
+
+func TestMessageHeaderInjection(t *testing.T) {
+	msg := Message{
+		From:     "shop@nessieaudio.com",
+		To:       []string{"sam@example.com"},
+		Subject:  "Hello\r\nBcc: victim@example.com",
+		Headers:  map[string]string{"X-Note": "a\nTo: other@example.com", "Content-Type": "text/evil"},
+		TextBody: "Hi",
+	}
+
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, _, _ := strings.Cut(string(data), "\r\n\r\n")
+	for _, line := range strings.Split(header, "\r\n") {
+		if strings.HasPrefix(line, "Bcc:") || strings.HasPrefix(line, "To: other") || strings.Contains(line, "text/evil") {
+			t.Errorf("injected header line %q", line)
+		}
+	}
+}
+
+func TestMessageValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+	}{
+		{"no sender", Message{To: []string{"a@example.com"}, TextBody: "x"}},
+		{"no recipients", Message{From: "shop@nessieaudio.com", TextBody: "x"}},
+		{"no body", Message{From: "shop@nessieaudio.com", To: []string{"a@example.com"}}},
+		{"bad address", Message{From: "shop@nessieaudio.com", To: []string{"not an address"}, TextBody: "x"}},
+	}
+	for _, tt := range tests {
+		if err := tt.msg.Validate(); err == nil {
+			t.Errorf("%s: Validate() = nil, want an error", tt.name)
+		}
+	}
+}
+
+func TestSplitAddressesRoundTrip(t *testing.T) {
+	addresses := []string{"a@example.com", `"Doe, Jane" <jane@example.com>`}
+	got := splitAddresses(strings.Join(addresses, ", "))
+	if !reflect.DeepEqual(got, addresses) {
+		t.Errorf("splitAddresses() = %q, want %q", got, addresses)
+	}
+}
@@ -0,0 +1,346 @@
+package email
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+// MaxAttempts is how many times an email is tried before it is given up on.
+// Only transient errors are retried.
+const MaxAttempts = 6
+
+// retryDelays is the wait before each retry of a transient failure
+var retryDelays = []time.Duration{time.Minute, 5 * time.Minute, 30 * time.Minute, 2 * time.Hour, 6 * time.Hour}
+
+// NextRetry is the retry policy for everything that sends email, queued or not.
+// Given how many attempts have failed, it reports whether to try again and after
+// how long: transient failures are retried with backoff until MaxAttempts.
+func NextRetry(attempts int, transient bool) (delay time.Duration, retry bool) {
+	if !transient || attempts >= MaxAttempts {
+		return 0, false
+	}
+	if attempts-1 < len(retryDelays) {
+		return retryDelays[attempts-1], true
+	}
+	return retryDelays[len(retryDelays)-1], true
+}
+
+// sentRetention is how long delivered emails are kept before they are pruned
+const sentRetention = 30 * 24 * time.Hour
+
+// ErrNotDead is returned when resending an email that has not failed
+var ErrNotDead = errors.New("only dead emails can be resent")
+
+// Queue stores outgoing email in the email_queue table and delivers it in the
+// background, so a slow or failing SMTP server never holds up a request and no
+// email is lost when it fails
+type Queue struct {
+	db        *sql.DB
+	transport Transport
+	wake      chan struct{} // Signals the worker that an email was queued
+}
+
+// NewQueue creates a queue that delivers through transport
+func NewQueue(db *sql.DB, transport Transport) *Queue {
+	return &Queue{db: db, transport: transport, wake: make(chan struct{}, 1)}
+}
+
+// storedAttachment is how attachments are kept in the queue's attachments column
+type storedAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+}
+
+// Enqueue checks and stores a message for delivery and returns its ID. The
+// Message-ID and Date are fixed here, so that retries send the same email.
+func (q *Queue) Enqueue(msg *Message) (string, error) {
+	if err := msg.Validate(); err != nil {
+		return "", err
+	}
+
+	headers, err := json.Marshal(msg.Headers)
+	if err != nil {
+		return "", fmt.Errorf("encode headers: %w", err)
+	}
+	if msg.Headers == nil {
+		headers = []byte("{}")
+	}
+	stored := make([]storedAttachment, len(msg.Attachments))
+	for i, a := range msg.Attachments {
+		stored[i] = storedAttachment{Filename: a.Filename, ContentType: a.ContentType, Data: a.Data}
+	}
+	attachments, err := json.Marshal(stored)
+	if err != nil {
+		return "", fmt.Errorf("encode attachments: %w", err)
+	}
+
+	messageID := msg.MessageID
+	if messageID == "" {
+		messageID = NewMessageID(msg.From)
+	}
+	id := uuid.New().String()
+	now := time.Now()
+	_, err = q.db.Exec(`
+		INSERT INTO email_queue (id, message_id, from_address, to_address, cc_addresses, bcc_addresses, reply_to,
+			subject, headers, html_body, text_body, attachments, status, next_attempt_at, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, id, messageID, msg.From, strings.Join(msg.To, ", "), strings.Join(msg.Cc, ", "), strings.Join(msg.Bcc, ", "),
+		msg.ReplyTo, msg.Subject, string(headers), msg.HTMLBody, msg.TextBody, string(attachments),
+		models.EmailStatusPending, now, now, now)
+	if err != nil {
+		return "", fmt.Errorf("enqueue email: %w", err)
+	}
+
+	q.notify()
+	return id, nil
+}
+
+// notify wakes the worker without waiting for it
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// StartWorker delivers due emails in the background, as soon as they are queued
+// and every interval for retries, and prunes delivered ones once they are older
+// than sentRetention
+func (q *Queue) StartWorker(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		lastPrune := time.Time{}
+		for {
+			select {
+			case <-ticker.C:
+			case <-q.wake:
+			}
+			now := time.Now()
+			q.runDelivery(now)
+
+			if now.Sub(lastPrune) >= 24*time.Hour {
+				if n, err := q.PruneSent(now.Add(-sentRetention)); err != nil {
+					log.Printf("⚠️  Failed to prune sent emails: %v", err)
+				} else if n > 0 {
+					log.Printf("Pruned %d sent email(s) from the queue", n)
+				}
+				lastPrune = now
+			}
+		}
+	}()
+
+	log.Printf("Email queue worker started (%s transport, every %v)", q.transport.Name(), interval)
+}
+
+func (q *Queue) runDelivery(now time.Time) {
+	sent, failed, err := q.DeliverDue(now)
+	if err != nil {
+		log.Printf("⚠️  Failed to deliver queued emails: %v", err)
+	}
+	if sent > 0 || failed > 0 {
+		log.Printf("Email queue: %d sent, %d failed", sent, failed)
+	}
+}
+
+// DeliverDue sends every pending email whose next attempt is due, oldest first.
+// Returns how many were sent and how many failed.
+func (q *Queue) DeliverDue(now time.Time) (sent, failed int, err error) {
+	due, err := q.query(`WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at`,
+		models.EmailStatusPending, now)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for i := range due {
+		e := &due[i]
+		sendErr := q.transport.Send(&Message{
+			From:        e.From,
+			To:          splitAddresses(e.To),
+			Cc:          splitAddresses(e.Cc),
+			Bcc:         splitAddresses(e.Bcc),
+			ReplyTo:     e.ReplyTo,
+			Subject:     e.Subject,
+			Headers:     e.Headers,
+			HTMLBody:    e.HTMLBody,
+			TextBody:    e.TextBody,
+			Attachments: e.Attachments,
+			MessageID:   e.MessageID,
+			Date:        e.CreatedAt,
+		})
+		if sendErr == nil {
+			if err := q.recordSent(e.ID); err != nil {
+				return sent, failed, err
+			}
+			sent++
+			continue
+		}
+
+		failed++
+		retry, err := q.recordFailure(e, sendErr, IsTransient(sendErr))
+		if err != nil {
+			return sent, failed, err
+		}
+		if retry {
+			log.Printf("Email %s to %s failed, will retry: %v", e.ID, e.To, sendErr)
+		} else {
+			log.Printf("⚠️  Email %s to %s is dead after %d attempt(s): %v", e.ID, e.To, e.Attempts+1, sendErr)
+		}
+	}
+	return sent, failed, nil
+}
+
+func (q *Queue) recordSent(id string) error {
+	now := time.Now()
+	_, err := q.db.Exec(`
+		UPDATE email_queue SET status = ?, attempts = attempts + 1, last_error = '', sent_at = ?, updated_at = ?
+		WHERE id = ?
+	`, models.EmailStatusSent, now, now, id)
+	if err != nil {
+		return fmt.Errorf("record sent: %w", err)
+	}
+	return nil
+}
+
+// recordFailure schedules a retry of a transient failure (see NextRetry), and
+// marks the email dead otherwise. retry reports whether another attempt was scheduled.
+func (q *Queue) recordFailure(e *models.QueuedEmail, sendErr error, transient bool) (retry bool, err error) {
+	attempts := e.Attempts + 1
+	delay, retry := NextRetry(attempts, transient)
+
+	now := time.Now()
+	status, nextAttempt := models.EmailStatusDead, now
+	if retry {
+		status, nextAttempt = models.EmailStatusPending, now.Add(delay)
+	}
+
+	_, err = q.db.Exec(`
+		UPDATE email_queue SET status = ?, attempts = ?, last_error = ?, next_attempt_at = ?, updated_at = ?
+		WHERE id = ?
+	`, status, attempts, sendErr.Error(), nextAttempt, now, e.ID)
+	if err != nil {
+		return false, fmt.Errorf("record failure: %w", err)
+	}
+	return retry, nil
+}
+
+// List returns queued emails with the given status, or all of them for "",
+// newest first
+func (q *Queue) List(status string, limit int) ([]models.QueuedEmail, error) {
+	if status == "" {
+		return q.query(`ORDER BY created_at DESC LIMIT ?`, limit)
+	}
+	return q.query(`WHERE status = ? ORDER BY created_at DESC LIMIT ?`, status, limit)
+}
+
+// Counts returns how many emails the queue holds in each status
+func (q *Queue) Counts() (map[string]int, error) {
+	counts := map[string]int{
+		models.EmailStatusPending: 0,
+		models.EmailStatusSent:    0,
+		models.EmailStatusDead:    0,
+	}
+	rows, err := q.db.Query(`SELECT status, COUNT(*) FROM email_queue GROUP BY status`)
+	if err != nil {
+		return nil, fmt.Errorf("count emails: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, fmt.Errorf("scan count: %w", err)
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
+
+// Resend moves a dead email back into the queue with a fresh set of attempts.
+// Returns sql.ErrNoRows for an unknown email and ErrNotDead for one that has not failed.
+func (q *Queue) Resend(id string) error {
+	now := time.Now()
+	result, err := q.db.Exec(`
+		UPDATE email_queue SET status = ?, attempts = 0, next_attempt_at = ?, updated_at = ?
+		WHERE id = ? AND status = ?
+	`, models.EmailStatusPending, now, now, id, models.EmailStatusDead)
+	if err != nil {
+		return fmt.Errorf("resend email: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n > 0 {
+		q.notify()
+		return nil
+	}
+
+	var exists bool
+	if err := q.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM email_queue WHERE id = ?)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+	return ErrNotDead
+}
+
+// PruneSent deletes delivered emails sent before the cutoff. Dead emails are
+// kept until they are resent.
+func (q *Queue) PruneSent(before time.Time) (int64, error) {
+	result, err := q.db.Exec(`DELETE FROM email_queue WHERE status = ? AND sent_at < ?`,
+		models.EmailStatusSent, before)
+	if err != nil {
+		return 0, fmt.Errorf("prune sent emails: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (q *Queue) query(where string, args ...interface{}) ([]models.QueuedEmail, error) {
+	rows, err := q.db.Query(`
+		SELECT id, message_id, from_address, to_address, cc_addresses, bcc_addresses, reply_to, subject,
+			headers, html_body, text_body, attachments, status, attempts, last_error, next_attempt_at,
+			sent_at, created_at, updated_at
+		FROM email_queue `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query email queue: %w", err)
+	}
+	defer rows.Close()
+
+	emails := []models.QueuedEmail{}
+	for rows.Next() {
+		var e models.QueuedEmail
+		var headers, attachments string
+		var sentAt sql.NullTime
+		if err := rows.Scan(&e.ID, &e.MessageID, &e.From, &e.To, &e.Cc, &e.Bcc, &e.ReplyTo, &e.Subject,
+			&headers, &e.HTMLBody, &e.TextBody, &attachments, &e.Status, &e.Attempts, &e.LastError, &e.NextAttemptAt,
+			&sentAt, &e.CreatedAt, &e.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan queued email: %w", err)
+		}
+		if err := json.Unmarshal([]byte(headers), &e.Headers); err != nil {
+			return nil, fmt.Errorf("decode headers of email %s: %w", e.ID, err)
+		}
+		var stored []storedAttachment
+		if err := json.Unmarshal([]byte(attachments), &stored); err != nil {
+			return nil, fmt.Errorf("decode attachments of email %s: %w", e.ID, err)
+		}
+		for _, a := range stored {
+			e.Attachments = append(e.Attachments, models.EmailAttachment{Filename: a.Filename, ContentType: a.ContentType, Data: a.Data})
+		}
+		if sentAt.Valid {
+			e.SentAt = &sentAt.Time
+		}
+		emails = append(emails, e)
+	}
+	return emails, rows.Err()
+}
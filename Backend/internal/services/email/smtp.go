@@ -2,28 +2,51 @@ package email
 
 import (
 	"bytes"
+	"database/sql"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"log"
-	"net/smtp"
+	"net"
+	"net/mail"
+	"net/textproto"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/nessieaudio/ecommerce-backend/internal/config"
 	"github.com/nessieaudio/ecommerce-backend/internal/models"
 )
 
-// Client handles email sending via SMTP
+// Client builds emails and hands them to the queue, or straight to the
+// transport when no queue is attached (as in the command-line tools)
 type Client struct {
-	config *config.Config
+	config    *config.Config
+	transport Transport
+	queue     *Queue
 }
 
-// NewClient creates a new email client
+// NewClient creates a new email client using the transport chosen by EMAIL_TRANSPORT
 func NewClient(cfg *config.Config) *Client {
 	return &Client{
-		config: cfg,
+		config:    cfg,
+		transport: NewTransport(cfg),
 	}
 }
 
+// UseQueue makes every send go through the persistent email queue in db, and
+// returns the queue so its worker can be started
+func (c *Client) UseQueue(db *sql.DB) *Queue {
+	c.queue = NewQueue(db, c.transport)
+	return c.queue
+}
+
+// Queue returns the attached email queue, or nil when sends go straight to the transport
+func (c *Client) Queue() *Queue {
+	return c.queue
+}
+
 // OrderConfirmationData holds data for order confirmation emails
 type OrderConfirmationData struct {
 	OrderID       string
@@ -32,6 +55,16 @@ type OrderConfirmationData struct {
 	Items         []models.OrderItem
 	Total         float64
 	ShippingInfo  ShippingInfo
+
+	// PreorderShipDate is set when the order contains pre-order items;
+	// the whole order ships together once they're available
+	PreorderShipDate *time.Time
+
+	// Downloads are the signed links for digital items
+	Downloads []models.Download
+
+	// Ships is false for orders of digital items only, which have no shipping details
+	Ships bool
 }
 
 // ShippingInfo holds shipping details
@@ -65,44 +98,9 @@ func (c *Client) SendOrderConfirmation(data OrderConfirmationData) error {
 	return nil
 }
 
-// sendEmail sends an email via SMTP
+// sendEmail sends an HTML email
 func (c *Client) sendEmail(to []string, subject, htmlBody string) error {
-	// Check if SMTP is configured
-	if c.config.SMTPUsername == "" || c.config.SMTPPassword == "" {
-		log.Println("WARNING: SMTP not configured, skipping email send")
-		return nil
-	}
-
-	// SMTP authentication
-	auth := smtp.PlainAuth("", c.config.SMTPUsername, c.config.SMTPPassword, c.config.SMTPHost)
-
-	// Build email message
-	from := c.config.SMTPFromEmail
-	fromName := c.config.SMTPFromName
-
-	// Email headers
-	headers := make(map[string]string)
-	headers["From"] = fmt.Sprintf("%s <%s>", fromName, from)
-	headers["To"] = to[0]
-	headers["Subject"] = subject
-	headers["MIME-Version"] = "1.0"
-	headers["Content-Type"] = "text/html; charset=\"UTF-8\""
-
-	// Build message
-	message := ""
-	for k, v := range headers {
-		message += fmt.Sprintf("%s: %s\r\n", k, v)
-	}
-	message += "\r\n" + htmlBody
-
-	// Send email
-	addr := fmt.Sprintf("%s:%s", c.config.SMTPHost, c.config.SMTPPort)
-	err := smtp.SendMail(addr, auth, from, to, []byte(message))
-	if err != nil {
-		return fmt.Errorf("smtp error: %w", err)
-	}
-
-	return nil
+	return c.Send(&Message{To: to, Subject: subject, HTMLBody: htmlBody})
 }
 
 // generateOrderConfirmationHTML generates HTML for order confirmation email
@@ -132,6 +130,7 @@ func (c *Client) generateOrderConfirmationHTML(data OrderConfirmationData) (stri
                         <td>
                             <div class="item-name">{{.ProductName}}</div>
                             {{if .VariantName}}<div class="item-variant">{{.VariantName}}</div>{{end}}
+                            {{if .PreorderShipDate}}<div class="item-variant">Pre-order &mdash; ships by {{.PreorderShipDate.Format "January 2, 2006"}}</div>{{end}}
                         </td>
                         <td style="text-align:center;">{{.Quantity}}</td>
                         <td style="text-align:right;">${{printf "%.2f" .TotalPrice}}</td>
@@ -144,6 +143,17 @@ func (c *Client) generateOrderConfirmationHTML(data OrderConfirmationData) (stri
                 </tbody>
             </table>
 
+            {{if .Downloads}}
+            <div class="info-box">
+                <h2>Your Downloads</h2>
+                {{range .Downloads}}
+                <div class="detail-row"><span>{{.ProductName}} &mdash; {{.FileName}}</span> {{if .URL}}<a href="{{.URL}}" style="color:#fff;">Download</a>{{else}}<strong>Expired</strong>{{end}}</div>
+                {{end}}
+                <div style="color:#c0c0c0;margin-top:10px;font-size:14px;">Each file can be downloaded {{(index .Downloads 0).DownloadsRemaining}} times until {{(index .Downloads 0).ExpiresAt.Format "January 2, 2006"}}. Your links are also in your order details.</div>
+            </div>
+            {{end}}
+
+            {{if .Ships}}
             <div class="info-box">
                 <h2>Shipping To</h2>
                 <div style="color:#c0c0c0;line-height:1.6;">
@@ -154,7 +164,12 @@ func (c *Client) generateOrderConfirmationHTML(data OrderConfirmationData) (stri
                 </div>
             </div>
 
+            {{if .PreorderShipDate}}
+            <div class="note"><strong>Pre-order</strong><br>Your order includes pre-order items. We'll ship everything together once they're available &mdash; expected by <strong>{{.PreorderShipDate.Format "January 2, 2006"}}</strong>. You'll receive a shipping confirmation email with tracking information once your items are on their way.</div>
+            {{else}}
             <div class="note"><strong>What's Next?</strong><br>Your order will be fulfilled by our print-on-demand partner. You'll receive a shipping confirmation email with tracking information once your items are on their way (typically within 2-5 business days).</div>
+            {{end}}
+            {{end}}
 
             <div style="text-align:center;margin:24px 0;"><a href="https://nessieaudio.com/merch" class="cta-button">Continue Shopping</a></div>`
 
@@ -207,96 +222,111 @@ func (c *Client) SendShippingNotification(customerEmail, orderID, trackingNumber
 
 // SendRawEmail sends a plain text email (for admin alerts)
 func (c *Client) SendRawEmail(to, subject, body string) error {
-	// Check if SMTP is configured
-	if c.config.SMTPUsername == "" || c.config.SMTPPassword == "" {
-		log.Println("WARNING: SMTP not configured, skipping email send")
-		return nil
+	return c.Send(&Message{To: recipient(to), Subject: subject, TextBody: body})
+}
+
+// SendHTMLEmail sends an HTML email (for formatted alerts)
+func (c *Client) SendHTMLEmail(to, subject, htmlBody string) error {
+	return c.Send(&Message{To: recipient(to), Subject: subject, HTMLBody: htmlBody})
+}
+
+// SendHTMLEmailReplyTo sends an HTML email whose replies go to replyTo, e.g. the
+// visitor who sent a contact form
+func (c *Client) SendHTMLEmailReplyTo(to, replyTo, subject, htmlBody string) error {
+	return c.Send(&Message{To: recipient(to), ReplyTo: replyTo, Subject: subject, HTMLBody: htmlBody})
+}
+
+// SendListEmail sends a mailing list email with one-click unsubscribe (RFC 8058):
+// mail clients show an unsubscribe button that POSTs to unsubscribeURL
+func (c *Client) SendListEmail(to, subject, htmlBody, unsubscribeURL string) error {
+	return c.Send(&Message{To: recipient(to), Subject: subject, HTMLBody: htmlBody, Headers: ListHeaders(unsubscribeURL)})
+}
+
+// ListHeaders returns the one-click unsubscribe headers for a mailing list email
+func ListHeaders(unsubscribeURL string) map[string]string {
+	return map[string]string{
+		"List-Unsubscribe":      "<" + unsubscribeURL + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
 	}
+}
 
+// Send queues a message, or sends it right away when no queue is attached. Use
+// it for emails the helpers above don't cover, e.g. with CC, BCC or
+// attachments. From defaults to the configured sender.
+func (c *Client) Send(msg *Message) error {
 	// If no recipient specified, skip
-	if to == "" {
+	if len(msg.To)+len(msg.Cc)+len(msg.Bcc) == 0 {
 		log.Println("WARNING: No recipient email specified, skipping email send")
 		return nil
 	}
+	if msg.From == "" {
+		msg.From = c.from()
+	}
+	to := strings.Join(msg.Recipients(), ", ")
 
-	// SMTP authentication
-	auth := smtp.PlainAuth("", c.config.SMTPUsername, c.config.SMTPPassword, c.config.SMTPHost)
-
-	// Build email message
-	from := c.config.SMTPFromEmail
-	fromName := c.config.SMTPFromName
-
-	// Email headers
-	headers := make(map[string]string)
-	headers["From"] = fmt.Sprintf("%s <%s>", fromName, from)
-	headers["To"] = to
-	headers["Subject"] = subject
-	headers["MIME-Version"] = "1.0"
-	headers["Content-Type"] = "text/plain; charset=\"UTF-8\""
-
-	// Build message
-	message := ""
-	for k, v := range headers {
-		message += fmt.Sprintf("%s: %s\r\n", k, v)
+	if c.queue == nil {
+		if err := c.transport.Send(msg); err != nil {
+			return err
+		}
+		log.Printf("Email sent to %s", to)
+		return nil
 	}
-	message += "\r\n" + body
 
-	// Send email
-	addr := fmt.Sprintf("%s:%s", c.config.SMTPHost, c.config.SMTPPort)
-	err := smtp.SendMail(addr, auth, from, []string{to}, []byte(message))
+	id, err := c.queue.Enqueue(msg)
 	if err != nil {
-		return fmt.Errorf("smtp error: %w", err)
+		return err
 	}
-
-	log.Printf("Raw email sent to %s", to)
+	log.Printf("Email %s queued for %s", id, to)
 	return nil
 }
 
-// SendHTMLEmail sends an HTML email (for formatted alerts)
-func (c *Client) SendHTMLEmail(to, subject, htmlBody string) error {
-	// Check if SMTP is configured
-	if c.config.SMTPUsername == "" || c.config.SMTPPassword == "" {
-		log.Println("WARNING: SMTP not configured, skipping email send")
-		return nil
+// SendNow sends an HTML email straight through the transport, bypassing the
+// queue, and returns the transport's error. For senders that track delivery and
+// retries themselves, such as newsletter campaigns and test sends.
+func (c *Client) SendNow(to, subject, htmlBody string, headers map[string]string) error {
+	return c.transport.Send(&Message{
+		From:     c.from(),
+		To:       recipient(to),
+		Subject:  subject,
+		Headers:  headers,
+		HTMLBody: htmlBody,
+	})
+}
+
+// from returns the configured sender. The SMTP username is usually the
+// sender's address, so it stands in when SMTP_FROM_EMAIL is unset.
+func (c *Client) from() string {
+	address := c.config.SMTPFromEmail
+	if address == "" {
+		address = c.config.SMTPUsername
+	}
+	if address == "" {
+		address = "noreply@localhost"
 	}
+	return (&mail.Address{Name: c.config.SMTPFromName, Address: address}).String()
+}
 
-	// If no recipient specified, skip
+// recipient turns a single address into a recipient list, empty for ""
+func recipient(to string) []string {
 	if to == "" {
-		log.Println("WARNING: No recipient email specified, skipping email send")
 		return nil
 	}
+	return []string{to}
+}
 
-	// SMTP authentication
-	auth := smtp.PlainAuth("", c.config.SMTPUsername, c.config.SMTPPassword, c.config.SMTPHost)
-
-	// Build email message
-	from := c.config.SMTPFromEmail
-	fromName := c.config.SMTPFromName
-
-	// Email headers
-	headers := make(map[string]string)
-	headers["From"] = fmt.Sprintf("%s <%s>", fromName, from)
-	headers["To"] = to
-	headers["Subject"] = subject
-	headers["MIME-Version"] = "1.0"
-	headers["Content-Type"] = "text/html; charset=\"UTF-8\""
-
-	// Build message
-	message := ""
-	for k, v := range headers {
-		message += fmt.Sprintf("%s: %s\r\n", k, v)
-	}
-	message += "\r\n" + htmlBody
+// headerValue strips line breaks from a header value
+var headerValue = strings.NewReplacer("\r", "", "\n", " ")
 
-	// Send email
-	addr := fmt.Sprintf("%s:%s", c.config.SMTPHost, c.config.SMTPPort)
-	err := smtp.SendMail(addr, auth, from, []string{to}, []byte(message))
-	if err != nil {
-		return fmt.Errorf("smtp error: %w", err)
+// IsTransient reports whether a send failed for a reason that may clear up on
+// its own: a 4xx reply from the SMTP server (rate limits, greylisting, a full
+// mailbox) or a network error. 5xx replies, such as an unknown mailbox, are permanent.
+func IsTransient(err error) bool {
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		return reply.Code >= 400 && reply.Code < 500
 	}
-
-	log.Printf("HTML email sent to %s", to)
-	return nil
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
 }
 
 // Helper function to format price
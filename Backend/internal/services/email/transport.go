@@ -0,0 +1,144 @@
+package email
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/nessieaudio/ecommerce-backend/internal/config"
+)
+
+// Transport names accepted by EMAIL_TRANSPORT
+const (
+	TransportSMTP = "smtp"
+	TransportFile = "file"
+	TransportNone = "none"
+)
+
+// Transport delivers messages: over SMTP, into a local maildir, or nowhere
+type Transport interface {
+	Send(msg *Message) error
+	Name() string
+}
+
+// NewTransport returns the transport chosen by EMAIL_TRANSPORT (checked by
+// config.Validate). Left unset, it is SMTP when credentials are configured and
+// none otherwise.
+func NewTransport(cfg *config.Config) Transport {
+	name := cfg.EmailTransport
+	if name == "" {
+		name = TransportSMTP
+		if cfg.SMTPUsername == "" || cfg.SMTPPassword == "" {
+			name = TransportNone
+		}
+	}
+
+	switch name {
+	case TransportFile:
+		return NewFileTransport(cfg.EmailCaptureDir)
+	case TransportNone:
+		if cfg.EmailTransport == "" {
+			log.Println("WARNING: SMTP not configured, emails will be discarded (set EMAIL_TRANSPORT=file to capture them)")
+		}
+		return NopTransport{}
+	default:
+		return &SMTPTransport{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFromEmail,
+		}
+	}
+}
+
+// SMTPTransport sends messages through an SMTP server
+type SMTPTransport struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	From     string // Envelope sender
+}
+
+// Send delivers the message to the SMTP server
+func (t *SMTPTransport) Send(msg *Message) error {
+	auth := smtp.PlainAuth("", t.Username, t.Password, t.Host)
+	addr := fmt.Sprintf("%s:%s", t.Host, t.Port)
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+	if err := smtp.SendMail(addr, auth, t.From, msg.Recipients(), data); err != nil {
+		return fmt.Errorf("smtp error: %w", err)
+	}
+	return nil
+}
+
+// Name returns "smtp"
+func (t *SMTPTransport) Name() string { return TransportSMTP }
+
+// FileTransport captures messages in a maildir instead of sending them, for
+// development and testing. Any mail client that reads maildirs can open it.
+type FileTransport struct {
+	Dir string
+}
+
+// NewFileTransport returns a transport that writes messages into dir
+func NewFileTransport(dir string) *FileTransport {
+	return &FileTransport{Dir: dir}
+}
+
+// captureCount keeps capture file names unique within a process
+var captureCount atomic.Int64
+
+// Send writes the message into the maildir's tmp directory, then moves it into
+// new so that readers never see a partial file
+func (t *FileTransport) Send(msg *Message) error {
+	data, err := msg.Bytes()
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(t.Dir, sub), 0o755); err != nil {
+			return fmt.Errorf("create maildir: %w", err)
+		}
+	}
+
+	host, _ := os.Hostname()
+	if host == "" {
+		host = "localhost"
+	}
+	name := fmt.Sprintf("%d.%d_%d.%s", time.Now().UnixNano(), os.Getpid(), captureCount.Add(1), host)
+
+	tmpPath := filepath.Join(t.Dir, "tmp", name)
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("write message: %w", err)
+	}
+	if err := os.Rename(tmpPath, filepath.Join(t.Dir, "new", name)); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("deliver message: %w", err)
+	}
+	return nil
+}
+
+// Name returns "file"
+func (t *FileTransport) Name() string { return TransportFile }
+
+// NopTransport discards every message
+type NopTransport struct{}
+
+// Send logs and drops the message
+func (NopTransport) Send(msg *Message) error {
+	log.Printf("Email transport disabled, discarded %q to %s", msg.Subject, strings.Join(msg.Recipients(), ", "))
+	return nil
+}
+
+// Name returns "none"
+func (NopTransport) Name() string { return TransportNone }
@@ -0,0 +1,142 @@
+package order
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+	"github.com/nessieaudio/ecommerce-backend/internal/money"
+)
+
+// execQuerier is satisfied by both *sql.DB and *sql.Tx
+type execQuerier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// SnapshotBundleComponents records what a bundle order item is made of, with the bundle
+// price allocated across the components, and sets the item's unit cost to the components'
+// total cost. Items that are not bundles are left alone.
+func SnapshotBundleComponents(q execQuerier, orderItemID, variantID string, unitPrice float64) error {
+	rows, err := q.Query(`
+		SELECT bc.component_variant_id, v.printful_variant_id, p.name, v.name, bc.quantity, v.price, v.printful_cost
+		FROM bundle_components bc
+		JOIN variants v ON v.id = bc.component_variant_id
+		JOIN products p ON p.id = v.product_id
+		WHERE bc.bundle_variant_id = ?
+		ORDER BY bc.position
+	`, variantID)
+	if err != nil {
+		return fmt.Errorf("query bundle components: %w", err)
+	}
+
+	var components []models.OrderItemComponent
+	var weights []float64
+	for rows.Next() {
+		var c models.OrderItemComponent
+		var price float64
+		var cost sql.NullFloat64
+		if err := rows.Scan(&c.VariantID, &c.PrintfulVariantID, &c.ProductName, &c.VariantName,
+			&c.Quantity, &price, &cost); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan bundle component: %w", err)
+		}
+		if cost.Valid {
+			c.UnitCost = &cost.Float64
+		}
+		components = append(components, c)
+		weights = append(weights, price*float64(c.Quantity))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(components) == 0 {
+		return nil
+	}
+
+	// Unit cost is only known if every component's cost is
+	shares := AllocateBundlePrice(unitPrice, weights)
+	var unitCost sql.NullFloat64
+	unitCost.Valid = true
+	for i := range components {
+		components[i].AllocatedPrice = shares[i]
+		if components[i].UnitCost == nil {
+			unitCost.Valid = false
+		} else {
+			unitCost.Float64 += *components[i].UnitCost * float64(components[i].Quantity)
+		}
+
+		c := components[i]
+		if _, err := q.Exec(`
+			INSERT INTO order_item_components (
+				order_item_id, variant_id, printful_variant_id, product_name, variant_name,
+				quantity, allocated_price, unit_cost
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, orderItemID, c.VariantID, c.PrintfulVariantID, c.ProductName, c.VariantName,
+			c.Quantity, c.AllocatedPrice, c.UnitCost); err != nil {
+			return fmt.Errorf("insert order item component: %w", err)
+		}
+	}
+	if unitCost.Valid {
+		unitCost.Float64 = money.RoundCents(unitCost.Float64)
+	}
+
+	if _, err := q.Exec(`UPDATE order_items SET unit_cost = ? WHERE id = ?`, unitCost, orderItemID); err != nil {
+		return fmt.Errorf("update bundle unit cost: %w", err)
+	}
+	return nil
+}
+
+// AllocateBundlePrice splits a bundle price across its components in proportion to
+// weights (each component's own price times its quantity). The shares are whole cents
+// and add up to price exactly; leftover cents go to the largest remainders. Without
+// usable weights the price is split evenly.
+func AllocateBundlePrice(price float64, weights []float64) []float64 {
+	shares := make([]float64, len(weights))
+	if len(weights) == 0 {
+		return shares
+	}
+
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	if total <= 0 {
+		weights = make([]float64, len(weights))
+		for i := range weights {
+			weights[i] = 1
+		}
+		total = float64(len(weights))
+	}
+
+	cents := int64(math.Round(price * 100))
+	allocated := int64(0)
+	whole := make([]int64, len(weights))
+	remainders := make([]float64, len(weights))
+	for i, w := range weights {
+		exact := float64(cents) * w / total
+		whole[i] = int64(math.Floor(exact))
+		remainders[i] = exact - float64(whole[i])
+		allocated += whole[i]
+	}
+
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return remainders[order[a]] > remainders[order[b]]
+	})
+	for i := 0; allocated < cents; i++ {
+		whole[order[i%len(order)]]++
+		allocated++
+	}
+
+	for i, c := range whole {
+		shares[i] = float64(c) / 100
+	}
+	return shares
+}
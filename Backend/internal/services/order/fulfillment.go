@@ -0,0 +1,180 @@
+package order
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+// ChannelForProductType returns the fulfillment channel for an item of a product type
+func ChannelForProductType(productType string) string {
+	if productType == models.ProductTypeDigital {
+		return models.FulfillmentChannelDigital
+	}
+	return models.FulfillmentChannelPrintful
+}
+
+// NeedsShipping reports whether any item is shipped rather than downloaded
+func NeedsShipping(items []models.OrderItem) bool {
+	for _, item := range items {
+		if item.FulfillmentChannel != models.FulfillmentChannelDigital {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateFulfillments adds a pending fulfillment for each channel the order's items use
+// and derives the order status from them. Existing fulfillments are left alone, so this
+// is safe to call again.
+func (s *Service) CreateFulfillments(orderID string) ([]models.Fulfillment, error) {
+	now := time.Now()
+	_, err := s.db.Exec(`
+		INSERT OR IGNORE INTO order_fulfillments (order_id, channel, status, created_at, updated_at)
+		SELECT DISTINCT order_id, fulfillment_channel, ?, ?, ?
+		FROM order_items
+		WHERE order_id = ?
+	`, models.FulfillmentStatusPending, now, now, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("create fulfillments: %w", err)
+	}
+	if err := s.RefreshOrderStatus(orderID); err != nil {
+		return nil, err
+	}
+	return s.GetFulfillments(orderID)
+}
+
+// GetFulfillments returns an order's fulfillments by channel
+func (s *Service) GetFulfillments(orderID string) ([]models.Fulfillment, error) {
+	rows, err := s.db.Query(`
+		SELECT order_id, channel, status, printful_order_id, COALESCE(last_error, ''),
+			completed_at, created_at, updated_at
+		FROM order_fulfillments
+		WHERE order_id = ?
+		ORDER BY channel
+	`, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("query fulfillments: %w", err)
+	}
+	defer rows.Close()
+
+	fulfillments := []models.Fulfillment{}
+	for rows.Next() {
+		var f models.Fulfillment
+		var printfulOrderID sql.NullInt64
+		var completedAt sql.NullTime
+		if err := rows.Scan(&f.OrderID, &f.Channel, &f.Status, &printfulOrderID, &f.LastError,
+			&completedAt, &f.CreatedAt, &f.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan fulfillment: %w", err)
+		}
+		if printfulOrderID.Valid {
+			f.PrintfulOrderID = &printfulOrderID.Int64
+		}
+		if completedAt.Valid {
+			f.CompletedAt = &completedAt.Time
+		}
+		fulfillments = append(fulfillments, f)
+	}
+	return fulfillments, rows.Err()
+}
+
+// GetFulfillment returns one channel's fulfillment of an order.
+// Returns sql.ErrNoRows if the order has nothing in that channel.
+func (s *Service) GetFulfillment(orderID, channel string) (*models.Fulfillment, error) {
+	fulfillments, err := s.GetFulfillments(orderID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range fulfillments {
+		if fulfillments[i].Channel == channel {
+			return &fulfillments[i], nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// UpdateFulfillment sets a channel's status and re-derives the order status. lastError
+// is kept with failed fulfillments and cleared otherwise.
+func (s *Service) UpdateFulfillment(orderID, channel, status, lastError string) error {
+	now := time.Now()
+	var completedAt *time.Time
+	if status == models.FulfillmentStatusFulfilled || status == models.FulfillmentStatusShipped {
+		completedAt = &now
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO order_fulfillments (order_id, channel, status, last_error, completed_at, created_at, updated_at)
+		VALUES (?, ?, ?, NULLIF(?, ''), ?, ?, ?)
+		ON CONFLICT (order_id, channel) DO UPDATE SET
+			status = excluded.status,
+			last_error = excluded.last_error,
+			completed_at = COALESCE(order_fulfillments.completed_at, excluded.completed_at),
+			updated_at = excluded.updated_at
+	`, orderID, channel, status, lastError, completedAt, now, now)
+	if err != nil {
+		return fmt.Errorf("update fulfillment: %w", err)
+	}
+	return s.RefreshOrderStatus(orderID)
+}
+
+// MarkPrintfulSubmitted records that Printful accepted the order for fulfillment
+func (s *Service) MarkPrintfulSubmitted(orderID string, printfulOrderID int64) error {
+	if err := s.UpdateOrderWithPrintful(orderID, printfulOrderID); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`
+		UPDATE order_fulfillments SET printful_order_id = ? WHERE order_id = ? AND channel = ?
+	`, printfulOrderID, orderID, models.FulfillmentChannelPrintful); err != nil {
+		return fmt.Errorf("update printful fulfillment: %w", err)
+	}
+	return s.UpdateFulfillment(orderID, models.FulfillmentChannelPrintful, models.FulfillmentStatusFulfilled, "")
+}
+
+// RefreshOrderStatus sets the order's status from its fulfillments. Orders without
+// fulfillments keep their status.
+func (s *Service) RefreshOrderStatus(orderID string) error {
+	fulfillments, err := s.GetFulfillments(orderID)
+	if err != nil {
+		return err
+	}
+	if len(fulfillments) == 0 {
+		return nil
+	}
+	return s.UpdateOrderStatus(orderID, DeriveOrderStatus(fulfillments))
+}
+
+// DeriveOrderStatus works out a paid order's status from its fulfillments. A failed
+// channel fails the order so it gets attention; cancelled channels are otherwise ignored.
+// An order whose Printful channel has shipped is shipped once every channel is done.
+func DeriveOrderStatus(fulfillments []models.Fulfillment) string {
+	active, done := 0, 0
+	shipped := false
+	for _, f := range fulfillments {
+		switch f.Status {
+		case models.FulfillmentStatusCancelled:
+			continue
+		case models.FulfillmentStatusFailed:
+			return models.OrderStatusFailed
+		case models.FulfillmentStatusShipped:
+			shipped = true
+			done++
+		case models.FulfillmentStatusFulfilled:
+			done++
+		}
+		active++
+	}
+
+	switch {
+	case active == 0:
+		return models.OrderStatusCancelled
+	case done == 0:
+		return models.OrderStatusPaid
+	case done < active:
+		return models.OrderStatusPartiallyFulfilled
+	case shipped:
+		return models.OrderStatusShipped
+	default:
+		return models.OrderStatusFulfilled
+	}
+}
@@ -3,16 +3,18 @@ package order
 import (
 	"database/sql"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/nessieaudio/ecommerce-backend/internal/inventory"
 	"github.com/nessieaudio/ecommerce-backend/internal/models"
+	"github.com/nessieaudio/ecommerce-backend/internal/money"
 )
 
 // Service handles order business logic
 type Service struct {
-	db                *sql.DB
-	inventoryService  *inventory.Service
+	db               *sql.DB
+	inventoryService *inventory.Service
 }
 
 // NewService creates a new order service
@@ -26,7 +28,7 @@ func NewService(db *sql.DB) *Service {
 // CreateOrder creates a new pending order
 func (s *Service) CreateOrder(order *models.Order, items []models.OrderItem) error {
 	// First, check stock availability for all items
-	for _, item := range items {
+	for i, item := range items {
 		stockCheck, err := s.inventoryService.CheckStock(item.VariantID, item.Quantity)
 		if err != nil {
 			return fmt.Errorf("check stock for variant %s: %w", item.VariantID, err)
@@ -39,6 +41,12 @@ func (s *Service) CreateOrder(order *models.Order, items []models.OrderItem) err
 			}
 			return fmt.Errorf("item %s is out of stock", item.VariantName)
 		}
+
+		// Pre-order items hold the whole order until the latest ship date
+		if stockCheck.Preorder {
+			items[i].PreorderShipDate = stockCheck.ExpectedShipDate
+			order.FulfillmentHoldUntil = LaterHold(order.FulfillmentHoldUntil, stockCheck.ExpectedShipDate)
+		}
 	}
 
 	tx, err := s.db.Begin()
@@ -53,12 +61,12 @@ func (s *Service) CreateOrder(order *models.Order, items []models.OrderItem) err
 			id, customer_id, customer_email, status, total_amount, currency,
 			shipping_name, shipping_address1, shipping_address2,
 			shipping_city, shipping_state, shipping_zip, shipping_country,
-			created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			fulfillment_hold_until, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, order.ID, order.CustomerID, order.CustomerEmail, order.Status, order.TotalAmount, order.Currency,
 		order.ShippingName, order.ShippingAddress1, order.ShippingAddress2,
 		order.ShippingCity, order.ShippingState, order.ShippingZip, order.ShippingCountry,
-		order.CreatedAt, order.UpdatedAt)
+		order.FulfillmentHoldUntil, order.CreatedAt, order.UpdatedAt)
 
 	if err != nil {
 		return fmt.Errorf("insert order: %w", err)
@@ -66,17 +74,26 @@ func (s *Service) CreateOrder(order *models.Order, items []models.OrderItem) err
 
 	// Insert order items and deduct stock
 	for _, item := range items {
+		channel := item.FulfillmentChannel
+		if channel == "" {
+			channel = models.FulfillmentChannelPrintful
+		}
 		_, err = tx.Exec(`
 			INSERT INTO order_items (
 				id, order_id, product_id, variant_id, quantity,
-				unit_price, total_price, product_name, variant_name, created_at
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				unit_price, total_price, product_name, variant_name,
+				preorder_ship_date, unit_cost, fulfillment_channel, created_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, (SELECT printful_cost FROM variants WHERE id = ?), ?, ?)
 		`, item.ID, item.OrderID, item.ProductID, item.VariantID, item.Quantity,
-			item.UnitPrice, item.TotalPrice, item.ProductName, item.VariantName, item.CreatedAt)
+			item.UnitPrice, item.TotalPrice, item.ProductName, item.VariantName,
+			item.PreorderShipDate, item.VariantID, channel, item.CreatedAt)
 
 		if err != nil {
 			return fmt.Errorf("insert order item: %w", err)
 		}
+		if err := SnapshotBundleComponents(tx, item.ID, item.VariantID, item.UnitPrice); err != nil {
+			return err
+		}
 
 		// Deduct stock for this item
 		if err := s.inventoryService.DeductStock(item.VariantID, item.Quantity); err != nil {
@@ -98,6 +115,7 @@ func (s *Service) GetOrder(id string) (*models.Order, error) {
 	var printfulRetryCount sql.NullInt64
 	var trackingNumber sql.NullString
 	var trackingURL sql.NullString
+	var holdUntil sql.NullTime
 
 	err := s.db.QueryRow(`
 		SELECT id, customer_id, customer_email, status, total_amount, currency,
@@ -105,7 +123,7 @@ func (s *Service) GetOrder(id string) (*models.Order, error) {
 			COALESCE(printful_retry_count, 0) as printful_retry_count,
 			shipping_name, shipping_address1, shipping_address2,
 			shipping_city, shipping_state, shipping_zip, shipping_country,
-			tracking_number, tracking_url, created_at, updated_at
+			tracking_number, tracking_url, fulfillment_hold_until, created_at, updated_at
 		FROM orders WHERE id = ?
 	`, id).Scan(
 		&order.ID, &order.CustomerID, &order.CustomerEmail, &order.Status, &order.TotalAmount, &order.Currency,
@@ -113,7 +131,7 @@ func (s *Service) GetOrder(id string) (*models.Order, error) {
 		&printfulRetryCount,
 		&order.ShippingName, &order.ShippingAddress1, &order.ShippingAddress2,
 		&order.ShippingCity, &order.ShippingState, &order.ShippingZip, &order.ShippingCountry,
-		&trackingNumber, &trackingURL, &order.CreatedAt, &order.UpdatedAt,
+		&trackingNumber, &trackingURL, &holdUntil, &order.CreatedAt, &order.UpdatedAt,
 	)
 
 	if err != nil {
@@ -133,6 +151,9 @@ func (s *Service) GetOrder(id string) (*models.Order, error) {
 	if trackingURL.Valid {
 		order.TrackingURL = trackingURL.String
 	}
+	if holdUntil.Valid {
+		order.FulfillmentHoldUntil = &holdUntil.Time
+	}
 
 	return order, nil
 }
@@ -143,7 +164,8 @@ func (s *Service) GetOrderItems(orderID string) ([]models.OrderItem, error) {
 		SELECT oi.id, oi.order_id, oi.product_id, oi.variant_id,
 			COALESCE(v.printful_variant_id, 0) as printful_variant_id,
 			oi.quantity, oi.unit_price, oi.total_price,
-			oi.product_name, oi.variant_name, oi.created_at
+			oi.product_name, oi.variant_name, oi.preorder_ship_date, oi.unit_cost,
+			oi.fulfillment_channel, oi.created_at
 		FROM order_items oi
 		LEFT JOIN variants v ON oi.variant_id = v.id
 		WHERE oi.order_id = ?
@@ -156,20 +178,70 @@ func (s *Service) GetOrderItems(orderID string) ([]models.OrderItem, error) {
 	var items []models.OrderItem
 	for rows.Next() {
 		var item models.OrderItem
+		var preorderShipDate sql.NullTime
+		var unitCost sql.NullFloat64
 		if err := rows.Scan(
 			&item.ID, &item.OrderID, &item.ProductID, &item.VariantID,
 			&item.PrintfulVariantID,
 			&item.Quantity, &item.UnitPrice, &item.TotalPrice,
-			&item.ProductName, &item.VariantName, &item.CreatedAt,
+			&item.ProductName, &item.VariantName, &preorderShipDate, &unitCost,
+			&item.FulfillmentChannel, &item.CreatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("scan order item: %w", err)
 		}
+		if preorderShipDate.Valid {
+			item.PreorderShipDate = &preorderShipDate.Time
+		}
+		if unitCost.Valid {
+			item.UnitCost = &unitCost.Float64
+		}
 		items = append(items, item)
 	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate order items: %w", err)
+	}
+
+	for i := range items {
+		components, err := s.getOrderItemComponents(items[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		items[i].Components = components
+	}
 
 	return items, nil
 }
 
+// getOrderItemComponents returns the components of a bundle order item, or none for other items
+func (s *Service) getOrderItemComponents(orderItemID string) ([]models.OrderItemComponent, error) {
+	rows, err := s.db.Query(`
+		SELECT variant_id, printful_variant_id, product_name, variant_name, quantity, allocated_price, unit_cost
+		FROM order_item_components
+		WHERE order_item_id = ?
+		ORDER BY rowid
+	`, orderItemID)
+	if err != nil {
+		return nil, fmt.Errorf("query order item components: %w", err)
+	}
+	defer rows.Close()
+
+	var components []models.OrderItemComponent
+	for rows.Next() {
+		var c models.OrderItemComponent
+		var unitCost sql.NullFloat64
+		if err := rows.Scan(&c.VariantID, &c.PrintfulVariantID, &c.ProductName, &c.VariantName,
+			&c.Quantity, &c.AllocatedPrice, &unitCost); err != nil {
+			return nil, fmt.Errorf("scan order item component: %w", err)
+		}
+		if unitCost.Valid {
+			c.UnitCost = &unitCost.Float64
+		}
+		components = append(components, c)
+	}
+	return components, rows.Err()
+}
+
 // UpdateOrderStatus updates the order status
 func (s *Service) UpdateOrderStatus(orderID, status string) error {
 	_, err := s.db.Exec(`
@@ -224,22 +296,23 @@ func (s *Service) UpdateOrderWithPrintful(orderID string, printfulOrderID int64)
 	return nil
 }
 
-// UpdateOrderTracking updates tracking information
+// UpdateOrderTracking updates tracking information and marks the Printful fulfillment shipped
 func (s *Service) UpdateOrderTracking(orderID, trackingNumber, trackingURL string) error {
+	now := time.Now()
 	_, err := s.db.Exec(`
 		UPDATE orders SET
 			tracking_number = ?,
 			tracking_url = ?,
-			status = ?,
+			shipped_at = COALESCE(shipped_at, ?),
 			updated_at = ?
 		WHERE id = ?
-	`, trackingNumber, trackingURL, models.OrderStatusShipped, time.Now(), orderID)
+	`, trackingNumber, trackingURL, now, now, orderID)
 
 	if err != nil {
 		return fmt.Errorf("update order tracking: %w", err)
 	}
 
-	return nil
+	return s.UpdateFulfillment(orderID, models.FulfillmentChannelPrintful, models.FulfillmentStatusShipped, "")
 }
 
 // IncrementPrintfulRetryCount increments the retry count for an order
@@ -270,30 +343,39 @@ func (s *Service) RecordPrintfulFailure(orderID string, attemptNumber int, error
 		return fmt.Errorf("record printful failure: %w", err)
 	}
 
+	// The fulfillment stays pending for the retry job; the error shows what is holding it up
+	_, err = s.db.Exec(`
+		UPDATE order_fulfillments SET last_error = ?, updated_at = ? WHERE order_id = ? AND channel = ?
+	`, errorMsg, time.Now(), orderID, models.FulfillmentChannelPrintful)
+	if err != nil {
+		return fmt.Errorf("record printful fulfillment error: %w", err)
+	}
+
 	return nil
 }
 
 // GetFailedPrintfulOrders returns orders that failed Printful submission and are eligible for retry
 func (s *Service) GetFailedPrintfulOrders() ([]models.Order, error) {
 	// Find orders that:
-	// 1. Have status = 'paid' (payment confirmed)
-	// 2. Have NULL printful_order_id (not yet submitted)
+	// 1. Have a pending Printful fulfillment (paid, not yet submitted)
+	// 2. Are not held as pre-orders
 	// 3. Were created less than 24 hours ago
 	// 4. Have at least one retry attempt (failed at least once)
 	rows, err := s.db.Query(`
-		SELECT id, customer_id, customer_email, status, total_amount, currency,
-			stripe_session_id, stripe_payment_intent_id, printful_order_id,
-			printful_retry_count,
-			shipping_name, shipping_address1, shipping_address2,
-			shipping_city, shipping_state, shipping_zip, shipping_country,
-			tracking_number, tracking_url, created_at, updated_at
-		FROM orders
-		WHERE status = ?
-			AND printful_order_id IS NULL
-			AND created_at > datetime('now', '-24 hours')
-			AND printful_retry_count > 0
-		ORDER BY created_at ASC
-	`, models.OrderStatusPaid)
+		SELECT o.id, o.customer_id, o.customer_email, o.status, o.total_amount, o.currency,
+			o.stripe_session_id, o.stripe_payment_intent_id, o.printful_order_id,
+			o.printful_retry_count,
+			o.shipping_name, o.shipping_address1, o.shipping_address2,
+			o.shipping_city, o.shipping_state, o.shipping_zip, o.shipping_country,
+			o.tracking_number, o.tracking_url, o.created_at, o.updated_at
+		FROM orders o
+		JOIN order_fulfillments f ON f.order_id = o.id AND f.channel = ?
+		WHERE f.status = ?
+			AND o.fulfillment_hold_until IS NULL
+			AND o.created_at > datetime('now', '-24 hours')
+			AND o.printful_retry_count > 0
+		ORDER BY o.created_at ASC
+	`, models.FulfillmentChannelPrintful, models.FulfillmentStatusPending)
 
 	if err != nil {
 		return nil, fmt.Errorf("query failed orders: %w", err)
@@ -346,3 +428,181 @@ func (s *Service) GetFailedPrintfulOrders() ([]models.Order, error) {
 
 	return orders, nil
 }
+
+// HeldOrder summarises a paid order waiting on a pre-order release
+type HeldOrder struct {
+	OrderID       string    `json:"order_id"`
+	CustomerEmail string    `json:"customer_email"`
+	TotalAmount   float64   `json:"total_amount"`
+	HoldUntil     time.Time `json:"hold_until"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// GetHeldOrders returns paid orders held from Printful submission, soonest release first
+func (s *Service) GetHeldOrders() ([]HeldOrder, error) {
+	rows, err := s.db.Query(`
+		SELECT o.id, COALESCE(o.customer_email, ''), o.total_amount, o.fulfillment_hold_until, o.created_at
+		FROM orders o
+		JOIN order_fulfillments f ON f.order_id = o.id AND f.channel = ?
+		WHERE f.status = ?
+			AND o.fulfillment_hold_until IS NOT NULL
+		ORDER BY o.fulfillment_hold_until ASC
+	`, models.FulfillmentChannelPrintful, models.FulfillmentStatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("query held orders: %w", err)
+	}
+	defer rows.Close()
+
+	var held []HeldOrder
+	for rows.Next() {
+		var h HeldOrder
+		if err := rows.Scan(&h.OrderID, &h.CustomerEmail, &h.TotalAmount, &h.HoldUntil, &h.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan held order: %w", err)
+		}
+		held = append(held, h)
+	}
+
+	return held, rows.Err()
+}
+
+// GetReleasableOrders returns held orders whose release date has arrived
+func (s *Service) GetReleasableOrders(now time.Time) ([]HeldOrder, error) {
+	held, err := s.GetHeldOrders()
+	if err != nil {
+		return nil, err
+	}
+
+	var due []HeldOrder
+	for _, h := range held {
+		if !h.HoldUntil.After(now) {
+			due = append(due, h)
+		}
+	}
+
+	return due, nil
+}
+
+// ReleaseFulfillmentHold clears the pre-order hold so the order can be submitted to Printful
+func (s *Service) ReleaseFulfillmentHold(orderID string) error {
+	result, err := s.db.Exec(`
+		UPDATE orders SET fulfillment_hold_until = NULL, updated_at = ?
+		WHERE id = ? AND fulfillment_hold_until IS NOT NULL
+	`, time.Now(), orderID)
+	if err != nil {
+		return fmt.Errorf("release fulfillment hold: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("check rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// LaterHold returns whichever of the current hold and a candidate ship date is later
+func LaterHold(current, candidate *time.Time) *time.Time {
+	if candidate == nil {
+		return current
+	}
+	if current == nil || candidate.After(*current) {
+		return candidate
+	}
+	return current
+}
+
+// ProfitReport summarises revenue against Printful cost for paid orders in a date range
+// Costs come from the order-time snapshot, so later Printful price changes don't rewrite history.
+type ProfitReport struct {
+	From             time.Time       `json:"from"`
+	To               time.Time       `json:"to"`
+	Orders           int             `json:"orders"`
+	Revenue          float64         `json:"revenue"` // Item totals (excludes shipping and tax)
+	Cost             float64         `json:"cost"`
+	Profit           float64         `json:"profit"`
+	ItemsMissingCost int             `json:"items_missing_cost"` // Items sold before cost tracking, excluded from Cost
+	Products         []ProductProfit `json:"products"`
+}
+
+// ProductProfit is one product's line in a ProfitReport
+type ProductProfit struct {
+	ProductName string  `json:"product_name"`
+	Units       int     `json:"units"`
+	Revenue     float64 `json:"revenue"`
+	Cost        float64 `json:"cost"`
+	Profit      float64 `json:"profit"`
+}
+
+// GetProfitReport builds a profit report for paid, fulfilled and shipped orders created in [from, to)
+func (s *Service) GetProfitReport(from, to time.Time) (*ProfitReport, error) {
+	// created_at is stored as text with the host's UTC offset, so compare instants
+	// with julianday rather than the strings themselves
+	rows, err := s.db.Query(`
+		SELECT o.id, oi.product_name, oi.quantity, oi.total_price, oi.unit_cost
+		FROM orders o
+		JOIN order_items oi ON oi.order_id = o.id
+		WHERE o.status IN (?, ?, ?, ?)
+			AND julianday(o.created_at) >= julianday(?) AND julianday(o.created_at) < julianday(?)
+	`, models.OrderStatusPaid, models.OrderStatusPartiallyFulfilled, models.OrderStatusFulfilled, models.OrderStatusShipped,
+		from, to)
+	if err != nil {
+		return nil, fmt.Errorf("query profit report: %w", err)
+	}
+	defer rows.Close()
+
+	report := &ProfitReport{From: from, To: to, Products: []ProductProfit{}}
+	orders := make(map[string]bool)
+	byProduct := make(map[string]*ProductProfit)
+
+	for rows.Next() {
+		var orderID, productName string
+		var quantity int
+		var totalPrice float64
+		var unitCost sql.NullFloat64
+		if err := rows.Scan(&orderID, &productName, &quantity, &totalPrice, &unitCost); err != nil {
+			return nil, fmt.Errorf("scan profit row: %w", err)
+		}
+
+		orders[orderID] = true
+		line, ok := byProduct[productName]
+		if !ok {
+			line = &ProductProfit{ProductName: productName}
+			byProduct[productName] = line
+		}
+
+		line.Units += quantity
+		line.Revenue += totalPrice
+		report.Revenue += totalPrice
+		if unitCost.Valid {
+			cost := unitCost.Float64 * float64(quantity)
+			line.Cost += cost
+			report.Cost += cost
+		} else {
+			report.ItemsMissingCost++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate profit rows: %w", err)
+	}
+
+	for _, line := range byProduct {
+		line.Revenue = money.RoundCents(line.Revenue)
+		line.Cost = money.RoundCents(line.Cost)
+		line.Profit = money.RoundCents(line.Revenue - line.Cost)
+		report.Products = append(report.Products, *line)
+	}
+	sort.Slice(report.Products, func(i, j int) bool {
+		return report.Products[i].Profit > report.Products[j].Profit
+	})
+
+	report.Orders = len(orders)
+	report.Revenue = money.RoundCents(report.Revenue)
+	report.Cost = money.RoundCents(report.Cost)
+	report.Profit = money.RoundCents(report.Revenue - report.Cost)
+
+	return report, nil
+}
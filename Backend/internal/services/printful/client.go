@@ -37,48 +37,88 @@ func NewClient(apiKey, baseURL string) *Client {
 	}
 }
 
-// PrintfulProduct represents a product from Printful API
-type PrintfulProduct struct {
-	ID          int64  `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	ImageURL    string `json:"image"`
-	Variants    []PrintfulVariant `json:"variants"`
+// SyncProduct represents a store product from the Printful Store Products API
+type SyncProduct struct {
+	ID           int64  `json:"id"`
+	ExternalID   string `json:"external_id"`
+	Name         string `json:"name"`
+	Variants     int    `json:"variants"`
+	Synced       int    `json:"synced"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	IsIgnored    bool   `json:"is_ignored"`
+}
+
+// SyncVariant represents a store variant (sync variant) of a Printful store product
+type SyncVariant struct {
+	ID                 int64  `json:"id"` // sync_variant_id used when submitting orders
+	ExternalID         string `json:"external_id"`
+	SyncProductID      int64  `json:"sync_product_id"`
+	Name               string `json:"name"`
+	Synced             bool   `json:"synced"`
+	VariantID          int64  `json:"variant_id"` // Printful catalog variant ID
+	RetailPrice        string `json:"retail_price"`
+	Currency           string `json:"currency"`
+	IsIgnored          bool   `json:"is_ignored"`
+	Size               string `json:"size"`
+	Color              string `json:"color"`
+	AvailabilityStatus string `json:"availability_status"`
+	Product            struct {
+		VariantID int64  `json:"variant_id"`
+		ProductID int64  `json:"product_id"`
+		Image     string `json:"image"`
+		Name      string `json:"name"`
+	} `json:"product"`
+	Files []SyncFile `json:"files"`
+}
+
+// SyncFile represents a print or preview file attached to a sync variant
+type SyncFile struct {
+	ID           int64  `json:"id"`
+	Type         string `json:"type"` // "default" (print file), "preview" (mockup), etc.
+	PreviewURL   string `json:"preview_url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+}
+
+// SyncProductDetail is a store product together with its sync variants
+type SyncProductDetail struct {
+	SyncProduct  SyncProduct   `json:"sync_product"`
+	SyncVariants []SyncVariant `json:"sync_variants"`
 }
 
-// PrintfulVariant represents a variant from Printful API
-type PrintfulVariant struct {
-	ID    int64  `json:"id"`
-	Name  string `json:"name"`
-	Size  string `json:"size"`
-	Color string `json:"color"`
-	Price string `json:"price"`
-	Available bool `json:"in_stock"`
+// Paging describes the pagination block returned by Printful list endpoints
+type Paging struct {
+	Total  int `json:"total"`
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
 }
 
+// storeProductsPageSize is the maximum page size accepted by /store/products
+const storeProductsPageSize = 100
+
 // PrintfulOrderRequest represents an order submission to Printful
 type PrintfulOrderRequest struct {
-	Recipient PrintfulRecipient `json:"recipient"`
+	Recipient PrintfulRecipient   `json:"recipient"`
 	Items     []PrintfulOrderItem `json:"items"`
 }
 
 // PrintfulRecipient represents shipping details
 type PrintfulRecipient struct {
-	Name     string `json:"name"`
-	Address1 string `json:"address1"`
-	Address2 string `json:"address2,omitempty"`
-	City     string `json:"city"`
-	StateCode string `json:"state_code,omitempty"`
+	Name        string `json:"name"`
+	Address1    string `json:"address1"`
+	Address2    string `json:"address2,omitempty"`
+	City        string `json:"city"`
+	StateCode   string `json:"state_code,omitempty"`
 	CountryCode string `json:"country_code"`
-	Zip      string `json:"zip"`
-	Email    string `json:"email,omitempty"`
-	Phone    string `json:"phone,omitempty"`
+	Zip         string `json:"zip"`
+	Email       string `json:"email,omitempty"`
+	Phone       string `json:"phone,omitempty"`
 }
 
 // PrintfulOrderItem represents an item in a Printful order
 type PrintfulOrderItem struct {
-	SyncVariantID int64 `json:"sync_variant_id"` // Store product sync variant ID
-	Quantity      int   `json:"quantity"`
+	SyncVariantID int64  `json:"sync_variant_id"` // Store product sync variant ID
+	Quantity      int    `json:"quantity"`
+	RetailPrice   string `json:"retail_price,omitempty"` // Per unit; set for bundle components
 }
 
 // PrintfulOrderResponse represents Printful's order creation response
@@ -90,37 +130,53 @@ type PrintfulOrderResponse struct {
 	} `json:"result"`
 }
 
-// GetProducts fetches products from Printful
-// TODO: In production, cache this data in your database
-func (c *Client) GetProducts() ([]PrintfulProduct, error) {
-	// TODO: Implement actual Printful API call
-	// Endpoint: GET /store/products
-	// Documentation: https://developers.printful.com/docs/#tag/Store-Products-API
-	
-	resp, err := c.makeRequest("GET", "/store/products", nil)
+// GetProducts fetches every store product from Printful, following pagination
+// Endpoint: GET /store/products?offset={n}&limit={n}
+func (c *Client) GetProducts() ([]SyncProduct, error) {
+	products := make([]SyncProduct, 0)
+
+	for offset := 0; ; {
+		page, paging, err := c.getProductsPage(offset, storeProductsPageSize)
+		if err != nil {
+			return nil, err
+		}
+
+		products = append(products, page...)
+		offset += len(page)
+
+		if len(page) == 0 || offset >= paging.Total {
+			break
+		}
+	}
+
+	return products, nil
+}
+
+// getProductsPage fetches a single page of store products
+func (c *Client) getProductsPage(offset, limit int) ([]SyncProduct, Paging, error) {
+	endpoint := fmt.Sprintf("/store/products?offset=%d&limit=%d", offset, limit)
+	resp, err := c.makeRequest("GET", endpoint, nil)
 	if err != nil {
-		return nil, err
+		return nil, Paging{}, err
 	}
 	defer resp.Body.Close()
 
 	var result struct {
-		Code   int `json:"code"`
-		Result []PrintfulProduct `json:"result"`
+		Code   int           `json:"code"`
+		Result []SyncProduct `json:"result"`
+		Paging Paging        `json:"paging"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, fmt.Errorf("decode products: %w", err)
+		return nil, Paging{}, fmt.Errorf("decode products: %w", err)
 	}
 
-	return result.Result, nil
+	return result.Result, result.Paging, nil
 }
 
-// GetProduct fetches a single product with variants
-// TODO: Store in database and refresh periodically
-func (c *Client) GetProduct(productID int64) (*PrintfulProduct, error) {
-	// TODO: Implement actual call
-	// Endpoint: GET /store/products/{id}
-	
+// GetProduct fetches a single store product with its sync variants
+// Endpoint: GET /store/products/{id}
+func (c *Client) GetProduct(productID int64) (*SyncProductDetail, error) {
 	endpoint := fmt.Sprintf("/store/products/%d", productID)
 	resp, err := c.makeRequest("GET", endpoint, nil)
 	if err != nil {
@@ -129,8 +185,8 @@ func (c *Client) GetProduct(productID int64) (*PrintfulProduct, error) {
 	defer resp.Body.Close()
 
 	var result struct {
-		Code   int `json:"code"`
-		Result PrintfulProduct `json:"result"`
+		Code   int               `json:"code"`
+		Result SyncProductDetail `json:"result"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
@@ -140,6 +196,41 @@ func (c *Client) GetProduct(productID int64) (*PrintfulProduct, error) {
 	return &result.Result, nil
 }
 
+// CatalogVariant is a Printful catalog variant; Price is what Printful charges us for it
+type CatalogVariant struct {
+	ID        int64  `json:"id"`
+	ProductID int64  `json:"product_id"`
+	Name      string `json:"name"`
+	Size      string `json:"size"`
+	Color     string `json:"color"`
+	Price     string `json:"price"`
+	InStock   bool   `json:"in_stock"`
+}
+
+// GetCatalogVariant fetches a catalog variant, including its base cost
+// Endpoint: GET /products/variant/{id}
+func (c *Client) GetCatalogVariant(variantID int64) (*CatalogVariant, error) {
+	endpoint := fmt.Sprintf("/products/variant/%d", variantID)
+	resp, err := c.makeRequest("GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Code   int `json:"code"`
+		Result struct {
+			Variant CatalogVariant `json:"variant"`
+		} `json:"result"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("decode catalog variant: %w", err)
+	}
+
+	return &result.Result.Variant, nil
+}
+
 // CreateOrder submits an order to Printful for fulfillment
 // This should ONLY be called after payment is confirmed
 func (c *Client) CreateOrder(order *models.Order, items []models.OrderItem) (int64, error) {
@@ -155,15 +246,36 @@ func (c *Client) CreateOrder(order *models.Order, items []models.OrderItem) (int
 			Zip:         order.ShippingZip,
 			Email:       order.CustomerEmail,
 		},
-		Items: make([]PrintfulOrderItem, len(items)),
+		Items: make([]PrintfulOrderItem, 0, len(items)),
 	}
 
 	// Map OrderItems to Printful items using stored variant IDs
-	for i, item := range items {
-		req.Items[i] = PrintfulOrderItem{
-			SyncVariantID: item.PrintfulVariantID, // Now populated from database
-			Quantity:      item.Quantity,
+	for _, item := range items {
+		// Digital items are downloaded, not shipped
+		if item.FulfillmentChannel == models.FulfillmentChannelDigital {
+			continue
 		}
+		if len(item.Components) == 0 {
+			req.Items = append(req.Items, PrintfulOrderItem{
+				SyncVariantID: item.PrintfulVariantID, // Now populated from database
+				Quantity:      item.Quantity,
+			})
+			continue
+		}
+
+		// Bundles ship as their components; the allocated retail prices keep the
+		// packing slip and customs value in line with what the customer paid
+		for _, component := range item.Components {
+			req.Items = append(req.Items, PrintfulOrderItem{
+				SyncVariantID: component.PrintfulVariantID,
+				Quantity:      item.Quantity * component.Quantity,
+				RetailPrice:   fmt.Sprintf("%.2f", component.AllocatedPrice/float64(component.Quantity)),
+			})
+		}
+	}
+
+	if len(req.Items) == 0 {
+		return 0, fmt.Errorf("order has no items for Printful to fulfill")
 	}
 
 	// Submit to Printful
@@ -238,8 +350,8 @@ func (c *Client) GetWebhook() (*WebhookInfo, error) {
 	defer resp.Body.Close()
 
 	var result struct {
-		Code   int          `json:"code"`
-		Result WebhookInfo  `json:"result"`
+		Code   int         `json:"code"`
+		Result WebhookInfo `json:"result"`
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
@@ -13,6 +13,15 @@ import (
 	"github.com/stripe/stripe-go/v76/client"
 )
 
+// Checkout sessions record what they pay for under the "type" metadata key, so
+// the webhook can tell merch orders from engagement deposits. Sessions created
+// before the key was added are orders.
+const (
+	MetadataType       = "type"
+	SessionTypeOrder   = "order"
+	SessionTypeDeposit = "deposit" // Deposit on a booking quote; metadata has quote_id
+)
+
 // CartItemMeta is the compact representation stored in Stripe session metadata
 // so we can recover product/variant IDs when the webhook fires.
 type CartItemMeta struct {
@@ -72,6 +81,18 @@ type CheckoutSessionRequest struct {
 	CustomerEmail string
 	LineItems     []CheckoutLineItem
 	ShippingAddress *ShippingAddress
+	DigitalOnly   bool // Nothing to ship, so no shipping address is collected
+
+	// PromotionsConsent shows Stripe's opt-in checkbox for promotional email, where
+	// the customer's locale allows it; the answer is in the session's Consent
+	PromotionsConsent bool
+
+	// Type is SessionTypeOrder when empty. Other types carry their own Metadata
+	// and return URLs instead of an order ID.
+	Type       string
+	Metadata   map[string]string
+	SuccessURL string // Defaults to the cart success page
+	CancelURL  string // Defaults to the cart cancel page
 }
 
 // CheckoutLineItem represents a product in the checkout
@@ -104,11 +125,15 @@ func (c *Client) CreateCheckoutSession(req *CheckoutSessionRequest) (string, err
 		// Build line items for Stripe
 		var lineItems []*stripe_lib.CheckoutSessionLineItemParams
 		for _, item := range req.LineItems {
+			name := item.ProductName
+			if item.VariantName != "" {
+				name = fmt.Sprintf("%s - %s", item.ProductName, item.VariantName)
+			}
 			lineItems = append(lineItems, &stripe_lib.CheckoutSessionLineItemParams{
 				PriceData: &stripe_lib.CheckoutSessionLineItemPriceDataParams{
 					Currency: stripe_lib.String("usd"),
 					ProductData: &stripe_lib.CheckoutSessionLineItemPriceDataProductDataParams{
-						Name: stripe_lib.String(fmt.Sprintf("%s - %s", item.ProductName, item.VariantName)),
+						Name: stripe_lib.String(name),
 					},
 					UnitAmount: stripe_lib.Int64(item.UnitPrice), // Price in cents
 				},
@@ -116,9 +141,19 @@ func (c *Client) CreateCheckoutSession(req *CheckoutSessionRequest) (string, err
 			})
 		}
 
-		// Build metadata — always include order_id
+		sessionType := req.Type
+		if sessionType == "" {
+			sessionType = SessionTypeOrder
+		}
 		metadata := map[string]string{
-			"order_id": req.OrderID,
+			MetadataType: sessionType,
+		}
+		for key, value := range req.Metadata {
+			metadata[key] = value
+		}
+		if sessionType == SessionTypeOrder {
+			// Orders always include order_id (empty for cart checkouts)
+			metadata["order_id"] = req.OrderID
 		}
 
 		// For cart checkouts, store product/variant IDs so the webhook
@@ -140,19 +175,35 @@ func (c *Client) CreateCheckoutSession(req *CheckoutSessionRequest) (string, err
 			}
 		}
 
+		successURL := c.successURL + "?session_id={CHECKOUT_SESSION_ID}"
+		if req.SuccessURL != "" {
+			successURL = req.SuccessURL
+		}
+		cancelURL := c.cancelURL
+		if req.CancelURL != "" {
+			cancelURL = req.CancelURL
+		}
+
 		// Create Stripe checkout session
 		params := &stripe_lib.CheckoutSessionParams{
 			Mode:       stripe_lib.String(string(stripe_lib.CheckoutSessionModePayment)),
-			SuccessURL: stripe_lib.String(c.successURL + "?session_id={CHECKOUT_SESSION_ID}"),
-			CancelURL:  stripe_lib.String(c.cancelURL),
+			SuccessURL: stripe_lib.String(successURL),
+			CancelURL:  stripe_lib.String(cancelURL),
 			LineItems:  lineItems,
 			PaymentMethodTypes: stripe_lib.StringSlice([]string{
 				"card",
 			}),
 			Metadata: metadata,
-			ShippingAddressCollection: &stripe_lib.CheckoutSessionShippingAddressCollectionParams{
+		}
+		if req.PromotionsConsent {
+			params.ConsentCollection = &stripe_lib.CheckoutSessionConsentCollectionParams{
+				Promotions: stripe_lib.String(string(stripe_lib.CheckoutSessionConsentCollectionPromotionsAuto)),
+			}
+		}
+		if !req.DigitalOnly {
+			params.ShippingAddressCollection = &stripe_lib.CheckoutSessionShippingAddressCollectionParams{
 				AllowedCountries: stripe_lib.StringSlice(worldwideCountries),
-			},
+			}
 		}
 
 		// Set customer email if provided, otherwise tell Stripe to collect it
@@ -202,6 +253,19 @@ func (c *Client) GetSession(sessionID string) (*stripe_lib.CheckoutSession, erro
 	return sess, nil
 }
 
+// SessionType returns what a checkout session pays for: a SessionType constant
+func SessionType(sess *stripe_lib.CheckoutSession) string {
+	if t := sess.Metadata[MetadataType]; t != "" {
+		return t
+	}
+	return SessionTypeOrder
+}
+
+// PromotionsOptIn reports whether the customer ticked the promotional email box
+func PromotionsOptIn(sess *stripe_lib.CheckoutSession) bool {
+	return sess.Consent != nil && sess.Consent.Promotions == stripe_lib.CheckoutSessionConsentPromotionsOptIn
+}
+
 // ExtractShippingFromSession extracts shipping details from completed session
 func ExtractShippingFromSession(sess *stripe_lib.CheckoutSession) *ShippingAddress {
 	if sess.ShippingDetails == nil || sess.ShippingDetails.Address == nil {
@@ -0,0 +1,328 @@
+// Package sitemap builds the site's XML sitemaps from the catalog and the static pages
+// on disk, and caches the result between crawls.
+package sitemap
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nessieaudio/ecommerce-backend/internal/catalog"
+	"github.com/nessieaudio/ecommerce-backend/internal/tour"
+)
+
+// MaxURLsPerFile is the sitemap protocol's limit on URLs in one file. Past it the
+// sitemap is split into numbered parts listed by a sitemap index.
+const MaxURLsPerFile = 50000
+
+// cacheTTL is how long a generated sitemap is served before it is rebuilt
+const cacheTTL = 15 * time.Minute
+
+const (
+	sitemapNS = "http://www.sitemaps.org/schemas/sitemap/0.9"
+	imageNS   = "http://www.google.com/schemas/sitemap-image/1.1"
+)
+
+// ErrNotFound is returned for a sitemap part that does not exist
+var ErrNotFound = errors.New("sitemap not found")
+
+// Page is a static page of the site
+type Page struct {
+	Path       string // URL path, e.g. "/merch"
+	File       string // HTML file under the static root, for lastmod
+	ChangeFreq string
+	Priority   float64
+}
+
+// Pages are the static pages listed in the sitemap
+var Pages = []Page{
+	{"/home", "home.html", "monthly", 1.0},                     // Homepage - highest priority
+	{"/portfolio", "portfolio.html", "weekly", 0.9},            // Portfolio - high priority
+	{"/merch", "merch.html", "daily", 0.9},                     // Merch/shop - high priority, changes often
+	{"/tour", "tour.html", "weekly", 0.8},                      // Tour dates - medium-high priority
+	{"/about", "about.html", "monthly", 0.7},                   // About page
+	{"/gallery", "gallery.html", "monthly", 0.7},               // Gallery
+	{"/nessie-digital", "nessie-digital.html", "monthly", 0.7}, // Nessie Digital
+	{"/contact", "contact.html", "monthly", 0.6},               // Contact page
+}
+
+// URL is a single URL entry in a sitemap
+type URL struct {
+	Loc        string  `xml:"loc"`
+	LastMod    string  `xml:"lastmod,omitempty"`
+	ChangeFreq string  `xml:"changefreq,omitempty"`
+	Priority   float64 `xml:"priority,omitempty"`
+	Images     []Image `xml:"image:image,omitempty"`
+
+	modTime time.Time
+}
+
+// Image is an image sitemap entry for the page it belongs to
+type Image struct {
+	Loc string `xml:"image:loc"`
+}
+
+// URLSet is the root element of a sitemap
+type URLSet struct {
+	XMLName xml.Name `xml:"urlset"`
+	XMLNS   string   `xml:"xmlns,attr"`
+	ImageNS string   `xml:"xmlns:image,attr"`
+	URLs    []URL    `xml:"url"`
+}
+
+// Index is the root element of a sitemap index
+type Index struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	XMLNS    string       `xml:"xmlns,attr"`
+	Sitemaps []IndexEntry `xml:"sitemap"`
+}
+
+// IndexEntry points at one sitemap part
+type IndexEntry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// File is a generated sitemap document ready to serve
+type File struct {
+	Body    []byte
+	ETag    string    // Strong ETag of Body, quoted
+	ModTime time.Time // Newest lastmod in the document
+}
+
+// Generator builds and caches the sitemap
+type Generator struct {
+	db        *sql.DB
+	staticDir string
+	baseURL   string
+
+	mu      sync.Mutex
+	files   map[string]*File // "sitemap.xml", "sitemap-1.xml", ...
+	builtAt time.Time
+}
+
+// NewGenerator creates a sitemap generator
+// staticDir is the site root (HTML pages and Product Photos); baseURL has no trailing slash.
+func NewGenerator(db *sql.DB, staticDir, baseURL string) *Generator {
+	return &Generator{db: db, staticDir: staticDir, baseURL: baseURL}
+}
+
+// Get returns a sitemap document by file name: "sitemap.xml" is the sitemap itself,
+// or the index when the site has more than MaxURLsPerFile URLs, in which case the
+// parts are "sitemap-1.xml", "sitemap-2.xml", ...
+func (g *Generator) Get(name string) (*File, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.files == nil || time.Since(g.builtAt) > cacheTTL {
+		files, err := g.build()
+		if err != nil {
+			return nil, err
+		}
+		g.files = files
+		g.builtAt = time.Now()
+	}
+
+	file, ok := g.files[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return file, nil
+}
+
+// IndexURL is the absolute URL of the top-level sitemap, for robots.txt
+func (g *Generator) IndexURL() string {
+	return g.baseURL + "/sitemap.xml"
+}
+
+// build generates every sitemap document
+func (g *Generator) build() (map[string]*File, error) {
+	urls, err := g.collect()
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]*File)
+	if len(urls) <= MaxURLsPerFile {
+		file, err := encodeURLSet(urls)
+		if err != nil {
+			return nil, err
+		}
+		files["sitemap.xml"] = file
+		return files, nil
+	}
+
+	index := Index{XMLNS: sitemapNS}
+	for part := 1; len(urls) > 0; part++ {
+		n := min(len(urls), MaxURLsPerFile)
+		file, err := encodeURLSet(urls[:n])
+		if err != nil {
+			return nil, err
+		}
+		urls = urls[n:]
+
+		name := "sitemap-" + strconv.Itoa(part) + ".xml"
+		files[name] = file
+
+		entry := IndexEntry{Loc: g.baseURL + "/" + name}
+		if !file.ModTime.IsZero() {
+			entry.LastMod = file.ModTime.Format(time.RFC3339)
+		}
+		index.Sitemaps = append(index.Sitemaps, entry)
+	}
+
+	file, err := encode(index, latest(files))
+	if err != nil {
+		return nil, err
+	}
+	files["sitemap.xml"] = file
+	return files, nil
+}
+
+// collect lists every URL on the site: static pages, products and visible collections.
+// Shows have no pages of their own; they are on /tour, with MusicEvent JSON-LD.
+func (g *Generator) collect() ([]URL, error) {
+	var urls []URL
+
+	// The tour page lists the shows, so it changes whenever they do
+	showsModified, err := tour.NewService(g.db).LastModified()
+	if err != nil {
+		return nil, fmt.Errorf("tour last modified: %w", err)
+	}
+
+	for _, page := range Pages {
+		u := URL{
+			Loc:        g.baseURL + page.Path,
+			ChangeFreq: page.ChangeFreq,
+			Priority:   page.Priority,
+		}
+		if info, err := os.Stat(filepath.Join(g.staticDir, page.File)); err == nil {
+			u.setModTime(info.ModTime())
+		}
+		if page.Path == "/tour" && showsModified.After(u.modTime) {
+			u.setModTime(showsModified)
+		}
+		urls = append(urls, u)
+	}
+
+	catalogService := catalog.NewService(g.db, nil, g.staticDir)
+
+	products, err := g.collectProducts(catalogService)
+	if err != nil {
+		return nil, err
+	}
+	urls = append(urls, products...)
+
+	collections, err := catalogService.ListVisibleCollections()
+	if err != nil {
+		return nil, fmt.Errorf("list collections: %w", err)
+	}
+	for _, c := range collections {
+		u := URL{
+			Loc:        g.baseURL + "/merch?collection=" + url.QueryEscape(c.Slug),
+			ChangeFreq: "weekly",
+			Priority:   0.7,
+		}
+		u.setModTime(c.UpdatedAt)
+		urls = append(urls, u)
+	}
+
+	return urls, nil
+}
+
+// collectProducts lists active product pages with their photos from Product Photos
+func (g *Generator) collectProducts(catalogService *catalog.Service) ([]URL, error) {
+	rows, err := g.db.Query(`
+		SELECT id, COALESCE(slug, ''), name, COALESCE(image_url, ''), updated_at
+		FROM products
+		WHERE active = 1
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("query products: %w", err)
+	}
+	defer rows.Close()
+
+	var urls []URL
+	for rows.Next() {
+		var id, slug, name, imageURL string
+		var updatedAt time.Time
+		if err := rows.Scan(&id, &slug, &name, &imageURL, &updatedAt); err != nil {
+			return nil, fmt.Errorf("scan product: %w", err)
+		}
+
+		u := URL{
+			Loc:        g.baseURL + catalog.ProductPagePath(id, slug),
+			ChangeFreq: "weekly",
+			Priority:   0.8, // Product pages are important for e-commerce
+		}
+		u.setModTime(updatedAt)
+
+		photos := catalogService.LocalImages(name)
+		if len(photos) == 0 && strings.HasPrefix(imageURL, "/") {
+			photos = []string{imageURL}
+		}
+		for _, photo := range photos {
+			u.Images = append(u.Images, Image{Loc: g.baseURL + (&url.URL{Path: photo}).EscapedPath()})
+		}
+
+		urls = append(urls, u)
+	}
+
+	return urls, rows.Err()
+}
+
+func (u *URL) setModTime(t time.Time) {
+	u.modTime = t
+	u.LastMod = t.UTC().Format(time.RFC3339)
+}
+
+func encodeURLSet(urls []URL) (*File, error) {
+	var newest time.Time
+	for _, u := range urls {
+		if u.modTime.After(newest) {
+			newest = u.modTime
+		}
+	}
+	return encode(URLSet{XMLNS: sitemapNS, ImageNS: imageNS, URLs: urls}, newest)
+}
+
+func encode(doc interface{}, modTime time.Time) (*File, error) {
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return nil, fmt.Errorf("encode sitemap: %w", err)
+	}
+	buf.WriteByte('\n')
+
+	sum := sha256.Sum256(buf.Bytes())
+	return &File{
+		Body:    buf.Bytes(),
+		ETag:    `"` + hex.EncodeToString(sum[:16]) + `"`,
+		ModTime: modTime,
+	}, nil
+}
+
+// latest returns the newest modification time among files
+func latest(files map[string]*File) time.Time {
+	var newest time.Time
+	for _, f := range files {
+		if f.ModTime.After(newest) {
+			newest = f.ModTime
+		}
+	}
+	return newest
+}
@@ -0,0 +1,348 @@
+package tour
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+// Default drop window: from two days before a show until a day after it starts
+const (
+	DefaultOpensHoursBefore = 48
+	DefaultClosesHoursAfter = 24
+)
+
+var (
+	// ErrShowNotFound is returned for a drop tied to a show that does not exist
+	ErrShowNotFound = errors.New("show not found")
+	// ErrProductNotFound is returned for a drop of a product that does not exist
+	ErrProductNotFound = errors.New("product not found")
+	// ErrVariantNotInProduct is returned for a drop of a variant of another product
+	ErrVariantNotInProduct = errors.New("variant does not belong to product")
+	// ErrInvalidDropWindow is returned for negative window hours
+	ErrInvalidDropWindow = errors.New("opens_hours_before and closes_hours_after must not be negative")
+	// ErrShowHasDrops is returned when deleting a show that still has merch drops.
+	// Deleting it would put its tour-exclusive merch on sale everywhere.
+	ErrShowHasDrops = errors.New("show still has merch drops; remove them first")
+)
+
+// DropWindow returns when a drop goes on and off sale, in the venue's timezone
+func DropWindow(show *models.Show, drop *models.Drop) (opensAt, closesAt time.Time) {
+	opensAt = show.StartsAt.Add(-time.Duration(drop.OpensHoursBefore) * time.Hour)
+	closesAt = show.StartsAt.Add(time.Duration(drop.ClosesHoursAfter) * time.Hour)
+	return opensAt, closesAt
+}
+
+// DropOpen reports whether a drop is on sale at now; a cancelled show's never is
+func DropOpen(show *models.Show, drop *models.Drop, now time.Time) bool {
+	opensAt, closesAt := DropWindow(show, drop)
+	return show.Status != StatusCancelled && !now.Before(opensAt) && now.Before(closesAt)
+}
+
+// ListDrops returns every drop, for the admin, newest first
+func (s *Service) ListDrops() ([]models.Drop, error) {
+	return s.queryDrops(`ORDER BY created_at DESC`)
+}
+
+// GetDrop returns a drop by ID
+// Returns sql.ErrNoRows if there is none.
+func (s *Service) GetDrop(id string) (*models.Drop, error) {
+	drops, err := s.queryDrops(`WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(drops) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &drops[0], nil
+}
+
+func (s *Service) queryDrops(where string, args ...interface{}) ([]models.Drop, error) {
+	rows, err := s.db.Query(`
+		SELECT id, show_id, product_id, COALESCE(variant_id, ''), opens_hours_before, closes_hours_after,
+			created_at, updated_at
+		FROM show_drops
+		`+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query drops: %w", err)
+	}
+	defer rows.Close()
+
+	drops := []models.Drop{}
+	for rows.Next() {
+		var d models.Drop
+		if err := rows.Scan(&d.ID, &d.ShowID, &d.ProductID, &d.VariantID, &d.OpensHoursBefore,
+			&d.ClosesHoursAfter, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan drop: %w", err)
+		}
+		drops = append(drops, d)
+	}
+	return drops, rows.Err()
+}
+
+// SaveDrop creates a drop (empty ID) or replaces an existing one
+// Returns ErrShowNotFound, ErrProductNotFound, ErrVariantNotInProduct or
+// ErrInvalidDropWindow for a bad drop, and sql.ErrNoRows when updating a drop
+// that does not exist.
+func (s *Service) SaveDrop(drop *models.Drop) error {
+	if drop.OpensHoursBefore < 0 || drop.ClosesHoursAfter < 0 {
+		return ErrInvalidDropWindow
+	}
+
+	var exists bool
+	if err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM shows WHERE id = ?)`, drop.ShowID).Scan(&exists); err != nil {
+		return fmt.Errorf("check show: %w", err)
+	}
+	if !exists {
+		return ErrShowNotFound
+	}
+	if err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM products WHERE id = ?)`, drop.ProductID).Scan(&exists); err != nil {
+		return fmt.Errorf("check product: %w", err)
+	}
+	if !exists {
+		return ErrProductNotFound
+	}
+	if drop.VariantID != "" {
+		err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM variants WHERE id = ? AND product_id = ?)`,
+			drop.VariantID, drop.ProductID).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("check variant: %w", err)
+		}
+		if !exists {
+			return ErrVariantNotInProduct
+		}
+	}
+
+	var variantID interface{}
+	if drop.VariantID != "" {
+		variantID = drop.VariantID
+	}
+
+	now := time.Now()
+	drop.UpdatedAt = now
+
+	if drop.ID == "" {
+		drop.ID = uuid.New().String()
+		drop.CreatedAt = now
+		_, err := s.db.Exec(`
+			INSERT INTO show_drops (id, show_id, product_id, variant_id, opens_hours_before, closes_hours_after,
+				created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, drop.ID, drop.ShowID, drop.ProductID, variantID, drop.OpensHoursBefore, drop.ClosesHoursAfter, now, now)
+		if err != nil {
+			return fmt.Errorf("insert drop: %w", err)
+		}
+		return nil
+	}
+
+	result, err := s.db.Exec(`
+		UPDATE show_drops
+		SET show_id = ?, product_id = ?, variant_id = ?, opens_hours_before = ?, closes_hours_after = ?, updated_at = ?
+		WHERE id = ?
+	`, drop.ShowID, drop.ProductID, variantID, drop.OpensHoursBefore, drop.ClosesHoursAfter, now, drop.ID)
+	if err != nil {
+		return fmt.Errorf("update drop: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+
+	saved, err := s.GetDrop(drop.ID)
+	if err != nil {
+		return err
+	}
+	*drop = *saved
+	return nil
+}
+
+// DeleteDrop removes a drop. Once a product or variant has no drops left it is on
+// sale like any other.
+// Returns sql.ErrNoRows if it does not exist.
+func (s *Service) DeleteDrop(id string) error {
+	result, err := s.db.Exec(`DELETE FROM show_drops WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete drop: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Availability is what tour-exclusive merch can be bought at one moment
+// Products and variants without drops are always available as far as it is concerned.
+type Availability struct {
+	now      time.Time
+	products map[string][]dropWindow // Whole-product drops, by product ID
+	variants map[string][]dropWindow // Single-variant drops, by variant ID
+}
+
+// dropWindow is one drop resolved against its show
+type dropWindow struct {
+	show              *models.Show
+	drop              *models.Drop
+	opensAt, closesAt time.Time
+}
+
+func (w *dropWindow) open(now time.Time) bool {
+	return DropOpen(w.show, w.drop, now)
+}
+
+// Availability loads every drop and works out which are open at now
+func (s *Service) Availability(now time.Time) (*Availability, error) {
+	drops, err := s.queryDrops(``)
+	if err != nil {
+		return nil, err
+	}
+	shows, err := s.query(`WHERE id IN (SELECT show_id FROM show_drops)`)
+	if err != nil {
+		return nil, err
+	}
+	showsByID := make(map[string]*models.Show, len(shows))
+	for i := range shows {
+		showsByID[shows[i].ID] = &shows[i]
+	}
+
+	a := &Availability{
+		now:      now,
+		products: make(map[string][]dropWindow),
+		variants: make(map[string][]dropWindow),
+	}
+	for i := range drops {
+		drop := &drops[i]
+		show, ok := showsByID[drop.ShowID]
+		if !ok {
+			continue
+		}
+		window := dropWindow{show: show, drop: drop}
+		window.opensAt, window.closesAt = DropWindow(show, drop)
+		if drop.VariantID == "" {
+			a.products[drop.ProductID] = append(a.products[drop.ProductID], window)
+		} else {
+			a.variants[drop.VariantID] = append(a.variants[drop.VariantID], window)
+		}
+	}
+	return a, nil
+}
+
+// ProductOpen reports whether a product can be bought as a whole: it has no
+// product drops, or one of them is open
+func (a *Availability) ProductOpen(productID string) bool {
+	return a.anyOpen(a.products[productID])
+}
+
+// VariantOpen reports whether a variant's own drops allow it to be bought
+func (a *Availability) VariantOpen(variantID string) bool {
+	return a.anyOpen(a.variants[variantID])
+}
+
+// CanBuy reports whether a variant of a product is on sale now
+func (a *Availability) CanBuy(productID, variantID string) bool {
+	return a.ProductOpen(productID) && a.VariantOpen(variantID)
+}
+
+func (a *Availability) anyOpen(windows []dropWindow) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	for i := range windows {
+		if windows[i].open(a.now) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClosedProducts returns the products that have drops but none open now
+func (a *Availability) ClosedProducts() []string {
+	return closed(a.products, a.now)
+}
+
+// ClosedVariants returns the variants that have drops but none open now
+func (a *Availability) ClosedVariants() []string {
+	return closed(a.variants, a.now)
+}
+
+func closed(windows map[string][]dropWindow, now time.Time) []string {
+	var ids []string
+	for id, ws := range windows {
+		open := false
+		for i := range ws {
+			open = open || ws[i].open(now)
+		}
+		if !open {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// ProductStatus returns the drop status of a product, or nil if it has no product drops
+func (a *Availability) ProductStatus(productID string) *models.DropStatus {
+	return a.status(a.products[productID])
+}
+
+// VariantStatus returns the drop status of a variant, or nil if it has no drops of its own
+func (a *Availability) VariantStatus(variantID string) *models.DropStatus {
+	return a.status(a.variants[variantID])
+}
+
+// status describes the window that matters most to a shopper: the open one with
+// the most time left, else the next to open, else the last to close
+func (a *Availability) status(windows []dropWindow) *models.DropStatus {
+	var best *dropWindow
+	rank := func(w *dropWindow) int {
+		switch {
+		case w.open(a.now):
+			return 2
+		case w.show.Status != StatusCancelled && a.now.Before(w.opensAt):
+			return 1
+		}
+		return 0
+	}
+	for i := range windows {
+		w := &windows[i]
+		if best == nil {
+			best = w
+			continue
+		}
+		r, bestRank := rank(w), rank(best)
+		switch {
+		case r > bestRank:
+			best = w
+		case r < bestRank:
+		case r == 2 && w.closesAt.After(best.closesAt),
+			r == 1 && w.opensAt.Before(best.opensAt),
+			r == 0 && w.closesAt.After(best.closesAt):
+			best = w
+		}
+	}
+	if best == nil {
+		return nil
+	}
+
+	show := best.show
+	status := &models.DropStatus{
+		ShowID:       show.ID,
+		Venue:        show.Venue,
+		City:         show.City,
+		Region:       show.Region,
+		Country:      show.Country,
+		Timezone:     show.Timezone,
+		ShowStartsAt: show.StartsAt,
+		OpensAt:      best.opensAt,
+		ClosesAt:     best.closesAt,
+		Open:         best.open(a.now),
+	}
+	switch rank(best) {
+	case 2:
+		status.TimeLeft = int64(best.closesAt.Sub(a.now).Seconds())
+	case 1:
+		status.OpensIn = int64(best.opensAt.Sub(a.now).Seconds())
+	}
+	return status
+}
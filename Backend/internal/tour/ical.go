@@ -0,0 +1,268 @@
+package tour
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+// EventLength is how long a show blocks out in a calendar
+const EventLength = 3 * time.Hour
+
+// uidDomain makes show UIDs globally unique (RFC 5545 3.8.4.7). It is fixed so
+// UIDs stay the same whichever host the feed is fetched from.
+const uidDomain = "nessieaudio.com"
+
+// refreshInterval is how often calendar clients are asked to poll the feed
+const refreshInterval = "PT6H"
+
+// Calendar describes an iCalendar document of shows
+type Calendar struct {
+	Name    string // X-WR-CALNAME, e.g. "Nessie Audio Tour"
+	PageURL string // The tour page, linked from every event
+}
+
+// ICS renders shows as an RFC 5545 calendar, with a VTIMEZONE for every venue
+// timezone. Each show keeps its UID across edits, and its SEQUENCE goes up with
+// each one, so subscribed calendars move or cancel it in place.
+func (c Calendar) ICS(shows []models.Show) []byte {
+	var b icsWriter
+	b.line("BEGIN:VCALENDAR")
+	b.line("VERSION:2.0")
+	b.line("PRODID:-//Nessie Audio//Tour Dates//EN")
+	b.line("CALSCALE:GREGORIAN")
+	b.line("METHOD:PUBLISH")
+	if c.Name != "" {
+		b.prop("X-WR-CALNAME", c.Name)
+		b.prop("NAME", c.Name)
+	}
+	b.line("REFRESH-INTERVAL;VALUE=DURATION:" + refreshInterval)
+	b.line("X-PUBLISHED-TTL:" + refreshInterval)
+
+	for _, tz := range calendarTimezones(shows) {
+		b.vtimezone(tz.loc, tz.from, tz.to)
+	}
+	for i := range shows {
+		c.vevent(&b, &shows[i])
+	}
+
+	b.line("END:VCALENDAR")
+	return []byte(b.String())
+}
+
+// vevent writes one show. DTSTAMP is the last edit rather than the time of the
+// request, so an unchanged feed is byte-for-byte the same and its ETag holds.
+func (c Calendar) vevent(b *icsWriter, show *models.Show) {
+	start := show.StartsAt
+	end := start.Add(EventLength)
+
+	b.line("BEGIN:VEVENT")
+	b.line("UID:" + show.ID + "@" + uidDomain)
+	b.line("DTSTAMP:" + show.UpdatedAt.UTC().Format(icsUTC))
+	b.line("DTSTART;TZID=" + show.Timezone + ":" + start.Format(icsLocal))
+	b.line("DTEND;TZID=" + show.Timezone + ":" + end.In(start.Location()).Format(icsLocal))
+	b.line(fmt.Sprintf("SEQUENCE:%d", show.Sequence))
+	b.line("CREATED:" + show.CreatedAt.UTC().Format(icsUTC))
+	b.line("LAST-MODIFIED:" + show.UpdatedAt.UTC().Format(icsUTC))
+	b.prop("SUMMARY", ShowTitle(show))
+	b.prop("LOCATION", show.Venue+", "+ShowPlace(show))
+	if show.Latitude != nil && show.Longitude != nil {
+		b.line(fmt.Sprintf("GEO:%.6f;%.6f", *show.Latitude, *show.Longitude))
+	}
+	if description := ShowDescription(show); description != "" {
+		b.prop("DESCRIPTION", description)
+	}
+	if show.TicketURL != "" {
+		b.line("URL;VALUE=URI:" + show.TicketURL)
+	} else if c.PageURL != "" {
+		b.line("URL;VALUE=URI:" + c.PageURL + "#show-" + show.ID)
+	}
+	if show.Status == StatusCancelled {
+		b.line("STATUS:CANCELLED")
+	} else {
+		b.line("STATUS:CONFIRMED")
+	}
+	b.line("TRANSP:OPAQUE")
+	b.line("END:VEVENT")
+}
+
+// ShowTitle is a show's one-line title, e.g. "Nessie Audio at The Rusty Stage"
+func ShowTitle(show *models.Show) string {
+	title := "Nessie Audio at " + show.Venue
+	switch show.Status {
+	case StatusCancelled:
+		title = "CANCELLED: " + title
+	case StatusSoldOut:
+		title += " (sold out)"
+	}
+	return title
+}
+
+// ShowPlace is the city, region if any, and country, e.g. "Portland, OR, US"
+func ShowPlace(show *models.Show) string {
+	if show.Region != "" {
+		return show.City + ", " + show.Region + ", " + show.Country
+	}
+	return show.City + ", " + show.Country
+}
+
+// ShowDescription lists the support acts and where to get tickets
+func ShowDescription(show *models.Show) string {
+	var lines []string
+	if len(show.SupportActs) > 0 {
+		lines = append(lines, "With "+strings.Join(show.SupportActs, ", "))
+	}
+	switch {
+	case show.Status == StatusCancelled:
+		lines = append(lines, "This show has been cancelled.")
+	case show.Status == StatusSoldOut:
+		lines = append(lines, "Sold out.")
+	case show.TicketURL != "":
+		lines = append(lines, "Tickets: "+show.TicketURL)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// iCalendar date-time forms
+const (
+	icsUTC   = "20060102T150405Z"
+	icsLocal = "20060102T150405"
+)
+
+// calendarTimezone is a venue timezone and the span of shows in it
+type calendarTimezone struct {
+	loc      *time.Location
+	from, to time.Time
+}
+
+// calendarTimezones returns the timezones of shows, by name
+func calendarTimezones(shows []models.Show) []calendarTimezone {
+	byName := make(map[string]*calendarTimezone)
+	var names []string
+	for _, show := range shows {
+		loc := show.StartsAt.Location()
+		if loc.String() != show.Timezone {
+			continue // Unknown zone; the event still has the TZID
+		}
+		tz, ok := byName[show.Timezone]
+		if !ok {
+			tz = &calendarTimezone{loc: loc, from: show.StartsAt, to: show.StartsAt}
+			byName[show.Timezone] = tz
+			names = append(names, show.Timezone)
+		}
+		if show.StartsAt.Before(tz.from) {
+			tz.from = show.StartsAt
+		}
+		if show.StartsAt.After(tz.to) {
+			tz.to = show.StartsAt
+		}
+	}
+
+	sort.Strings(names)
+	timezones := make([]calendarTimezone, 0, len(names))
+	for _, name := range names {
+		timezones = append(timezones, *byName[name])
+	}
+	return timezones
+}
+
+// vtimezone writes a timezone's observances covering from..to. Each change of offset
+// is listed as its own STANDARD or DAYLIGHT observance, taken from the Go zone
+// database, rather than as recurrence rules that might not match it.
+func (b *icsWriter) vtimezone(loc *time.Location, from, to time.Time) {
+	b.line("BEGIN:VTIMEZONE")
+	b.line("TZID:" + loc.String())
+
+	// Start with the period in effect at from, and go on to the end of to's year
+	_, offset := from.In(loc).Zone()
+	start, _ := from.In(loc).ZoneBounds()
+	until := time.Date(to.In(loc).Year()+1, 1, 1, 0, 0, 0, 0, loc)
+
+	if start.IsZero() {
+		// The zone has had this offset for as long as the database knows
+		b.observance(time.Date(1970, 1, 1, 0, 0, 0, 0, loc), offset, offset, from.In(loc).IsDST())
+	} else {
+		_, prevOffset := start.Add(-time.Second).In(loc).Zone()
+		b.observance(start.In(loc), prevOffset, offset, start.In(loc).IsDST())
+	}
+
+	t := from.In(loc)
+	for {
+		_, end := t.ZoneBounds()
+		if end.IsZero() || !end.Before(until) {
+			break
+		}
+		_, prevOffset := t.Zone()
+		t = end.In(loc)
+		_, offset := t.Zone()
+		b.observance(t, prevOffset, offset, t.IsDST())
+	}
+
+	b.line("END:VTIMEZONE")
+}
+
+// observance writes one STANDARD or DAYLIGHT period starting at onset. DTSTART is
+// the local time of the onset under the offset it replaces.
+func (b *icsWriter) observance(onset time.Time, fromOffset, toOffset int, dst bool) {
+	kind := "STANDARD"
+	if dst {
+		kind = "DAYLIGHT"
+	}
+	name, _ := onset.Zone()
+
+	b.line("BEGIN:" + kind)
+	b.line("DTSTART:" + onset.UTC().Add(time.Duration(fromOffset)*time.Second).Format(icsLocal))
+	b.line("TZOFFSETFROM:" + utcOffset(fromOffset))
+	b.line("TZOFFSETTO:" + utcOffset(toOffset))
+	if name != "" && !strings.ContainsAny(name, "+-") {
+		b.line("TZNAME:" + name)
+	}
+	b.line("END:" + kind)
+}
+
+// utcOffset formats seconds east of UTC as an iCalendar UTC-OFFSET, e.g. "-0700"
+func utcOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	s := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds%3600/60)
+	if seconds%60 != 0 {
+		s += fmt.Sprintf("%02d", seconds%60)
+	}
+	return s
+}
+
+// icsWriter builds an iCalendar document: CRLF line endings, and lines folded at
+// 75 octets without splitting a UTF-8 sequence
+type icsWriter struct {
+	strings.Builder
+}
+
+func (b *icsWriter) line(s string) {
+	limit := 75
+	for len(s) > limit {
+		cut := limit
+		for s[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = 74 // The leading space of a continuation line counts
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+}
+
+// prop writes a TEXT property, escaped
+func (b *icsWriter) prop(name, value string) {
+	b.line(name + ":" + icsEscaper.Replace(value))
+}
+
+// icsEscaper escapes TEXT values (RFC 5545 3.3.11)
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
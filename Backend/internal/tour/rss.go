@@ -0,0 +1,112 @@
+package tour
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+// Feed describes an RSS 2.0 feed of shows
+type Feed struct {
+	Title       string
+	Description string
+	PageURL     string // The tour page
+	FeedURL     string // Where the feed itself is served, for atom:link rel="self"
+}
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	TTL           int       `xml:"ttl"` // Minutes
+	Self          rssLink   `xml:"atom:link"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Category    string  `xml:"category,omitempty"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// RSS renders shows as an RSS 2.0 feed, one item per show. Items keep their GUID
+// when a show is edited; the title and description say when it moved or was
+// cancelled.
+func (f Feed) RSS(shows []models.Show) ([]byte, error) {
+	channel := rssChannel{
+		Title:       f.Title,
+		Link:        f.PageURL,
+		Description: f.Description,
+		Language:    "en-us",
+		TTL:         360,
+		Self:        rssLink{Href: f.FeedURL, Rel: "self", Type: "application/rss+xml"},
+		Items:       []rssItem{},
+	}
+
+	var lastBuild time.Time
+	for i := range shows {
+		show := &shows[i]
+		if show.UpdatedAt.After(lastBuild) {
+			lastBuild = show.UpdatedAt
+		}
+
+		description := show.StartsAt.Format("Monday, January 2, 2006 at 3:04 PM MST") + " at " +
+			show.Venue + ", " + ShowPlace(show) + "."
+		if extra := ShowDescription(show); extra != "" {
+			description += "\n" + extra
+		}
+
+		channel.Items = append(channel.Items, rssItem{
+			Title:       show.StartsAt.Format("Jan 2, 2006") + " — " + ShowTitle(show) + ", " + ShowPlace(show),
+			Link:        f.PageURL + "#show-" + show.ID,
+			Description: description,
+			GUID:        rssGUID{Value: show.ID + "@" + uidDomain},
+			PubDate:     show.CreatedAt.UTC().Format(rfc822),
+			Category:    show.Status,
+		})
+	}
+
+	if !lastBuild.IsZero() {
+		channel.LastBuildDate = lastBuild.UTC().Format(rfc822)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	encoder := xml.NewEncoder(&buf)
+	encoder.Indent("", "  ")
+	doc := rssDocument{Version: "2.0", AtomNS: "http://www.w3.org/2005/Atom", Channel: channel}
+	if err := encoder.Encode(doc); err != nil {
+		return nil, fmt.Errorf("encode rss: %w", err)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+// rfc822 is the RSS date format (RFC 822 with a four-digit year)
+const rfc822 = "Mon, 02 Jan 2006 15:04:05 -0700"
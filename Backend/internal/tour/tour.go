@@ -0,0 +1,311 @@
+// Package tour stores the band's tour dates, archives them once they are over and
+// works out when the tour page last changed.
+package tour
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	_ "time/tzdata" // The runtime image has no zoneinfo
+
+	"github.com/google/uuid"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+// Show statuses
+const (
+	StatusOnSale    = "on_sale"
+	StatusSoldOut   = "sold_out"
+	StatusCancelled = "cancelled"
+)
+
+// ShowLength is how long after its start a show counts as over and is archived
+const ShowLength = 6 * time.Hour
+
+// ErrInvalidTimezone is returned for a timezone that is not an IANA zone name
+var ErrInvalidTimezone = errors.New("timezone must be an IANA zone such as America/Chicago")
+
+// startLayouts are the accepted wall-clock start times, read in the venue's timezone
+var startLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// ValidStatus reports whether status is one of the show statuses
+func ValidStatus(status string) bool {
+	switch status {
+	case StatusOnSale, StatusSoldOut, StatusCancelled:
+		return true
+	}
+	return false
+}
+
+// ParseStart reads a show's start time in its venue's timezone. value is either a
+// wall-clock time ("2026-03-14T20:00") or RFC 3339 with an offset, which is
+// converted to the timezone. Returns ErrInvalidTimezone for an unknown zone.
+func ParseStart(value, timezone string) (time.Time, error) {
+	loc, err := loadLocation(timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(loc), nil
+	}
+	for _, layout := range startLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid start time %q", value)
+}
+
+// loadLocation is time.LoadLocation without the local zone, which would differ
+// between servers
+func loadLocation(timezone string) (*time.Location, error) {
+	if timezone == "" || timezone == "Local" {
+		return nil, ErrInvalidTimezone
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, ErrInvalidTimezone
+	}
+	return loc, nil
+}
+
+// Over reports whether a show starting at startsAt has finished by now
+func Over(startsAt, now time.Time) bool {
+	return !now.Before(startsAt.Add(ShowLength))
+}
+
+// Service manages tour dates
+type Service struct {
+	db *sql.DB
+}
+
+// NewService creates a tour service
+func NewService(db *sql.DB) *Service {
+	return &Service{db: db}
+}
+
+// ListUpcoming returns the shows that are not over yet, soonest first, cancelled ones included
+func (s *Service) ListUpcoming() ([]models.Show, error) {
+	return s.query(`WHERE archived_at IS NULL AND starts_at > ? ORDER BY starts_at`,
+		time.Now().Add(-ShowLength).UTC())
+}
+
+// ListPast returns the shows that are over, most recent first
+func (s *Service) ListPast() ([]models.Show, error) {
+	return s.query(`WHERE archived_at IS NOT NULL OR starts_at <= ? ORDER BY starts_at DESC`,
+		time.Now().Add(-ShowLength).UTC())
+}
+
+// ListSince returns the shows starting from a time on, archived or not, in date order
+func (s *Service) ListSince(from time.Time) ([]models.Show, error) {
+	return s.query(`WHERE starts_at >= ? ORDER BY starts_at`, from.UTC())
+}
+
+// ListAll returns every show in date order
+func (s *Service) ListAll() ([]models.Show, error) {
+	return s.query(`ORDER BY starts_at`)
+}
+
+// Get returns a show by ID
+// Returns sql.ErrNoRows if there is none.
+func (s *Service) Get(id string) (*models.Show, error) {
+	shows, err := s.query(`WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(shows) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return &shows[0], nil
+}
+
+func (s *Service) query(where string, args ...interface{}) ([]models.Show, error) {
+	rows, err := s.db.Query(`
+		SELECT id, venue, city, region, country, latitude, longitude, starts_at, timezone,
+			ticket_url, status, support_acts, archived_at, sequence, created_at, updated_at
+		FROM shows
+		`+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query shows: %w", err)
+	}
+	defer rows.Close()
+
+	shows := []models.Show{}
+	for rows.Next() {
+		var show models.Show
+		var latitude, longitude sql.NullFloat64
+		var supportActs string
+		var archivedAt sql.NullTime
+		if err := rows.Scan(&show.ID, &show.Venue, &show.City, &show.Region, &show.Country, &latitude, &longitude,
+			&show.StartsAt, &show.Timezone, &show.TicketURL, &show.Status, &supportActs, &archivedAt,
+			&show.Sequence, &show.CreatedAt, &show.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan show: %w", err)
+		}
+		if latitude.Valid && longitude.Valid {
+			show.Latitude, show.Longitude = &latitude.Float64, &longitude.Float64
+		}
+		if archivedAt.Valid {
+			show.ArchivedAt = &archivedAt.Time
+		}
+		if err := json.Unmarshal([]byte(supportActs), &show.SupportActs); err != nil {
+			return nil, fmt.Errorf("decode support acts of show %s: %w", show.ID, err)
+		}
+		if show.SupportActs == nil {
+			show.SupportActs = []string{}
+		}
+		if loc, err := loadLocation(show.Timezone); err == nil {
+			show.StartsAt = show.StartsAt.In(loc)
+		}
+		shows = append(shows, show)
+	}
+	return shows, rows.Err()
+}
+
+// Save creates a show (empty ID) or replaces an existing one, bumping its sequence.
+// A show that is already over is saved archived; moving an archived show to a later
+// date brings it back.
+// Returns sql.ErrNoRows when updating a show that does not exist.
+func (s *Service) Save(show *models.Show) error {
+	if show.Status == "" {
+		show.Status = StatusOnSale
+	}
+	if show.SupportActs == nil {
+		show.SupportActs = []string{}
+	}
+	supportActs, err := json.Marshal(show.SupportActs)
+	if err != nil {
+		return fmt.Errorf("encode support acts: %w", err)
+	}
+
+	now := time.Now()
+	show.UpdatedAt = now
+	show.ArchivedAt = nil
+	if Over(show.StartsAt, now) {
+		show.ArchivedAt = &now
+	}
+
+	if show.ID == "" {
+		show.ID = uuid.New().String()
+		show.CreatedAt = now
+		_, err = s.db.Exec(`
+			INSERT INTO shows (id, venue, city, region, country, latitude, longitude, starts_at, timezone,
+				ticket_url, status, support_acts, archived_at, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, show.ID, show.Venue, show.City, show.Region, show.Country, show.Latitude, show.Longitude,
+			show.StartsAt.UTC(), show.Timezone, show.TicketURL, show.Status, string(supportActs), show.ArchivedAt, now, now)
+		if err != nil {
+			return fmt.Errorf("insert show: %w", err)
+		}
+		return nil
+	}
+
+	// An archived show that stays over keeps its original archive time
+	result, err := s.db.Exec(`
+		UPDATE shows
+		SET venue = ?, city = ?, region = ?, country = ?, latitude = ?, longitude = ?, starts_at = ?, timezone = ?,
+			ticket_url = ?, status = ?, support_acts = ?,
+			archived_at = CASE WHEN ? IS NULL THEN NULL ELSE COALESCE(archived_at, ?) END,
+			sequence = sequence + 1, updated_at = ?
+		WHERE id = ?
+	`, show.Venue, show.City, show.Region, show.Country, show.Latitude, show.Longitude, show.StartsAt.UTC(),
+		show.Timezone, show.TicketURL, show.Status, string(supportActs), show.ArchivedAt, show.ArchivedAt, now, show.ID)
+	if err != nil {
+		return fmt.Errorf("update show: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+
+	saved, err := s.Get(show.ID)
+	if err != nil {
+		return err
+	}
+	*show = *saved
+	return nil
+}
+
+// Delete removes a show
+// Returns sql.ErrNoRows if it does not exist, ErrShowHasDrops if merch is still tied to it.
+func (s *Service) Delete(id string) error {
+	var hasDrops bool
+	if err := s.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM show_drops WHERE show_id = ?)`, id).Scan(&hasDrops); err != nil {
+		return fmt.Errorf("check drops: %w", err)
+	}
+	if hasDrops {
+		return ErrShowHasDrops
+	}
+
+	result, err := s.db.Exec(`DELETE FROM shows WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("delete show: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ArchivePast archives the shows that are over, returning how many it archived
+func (s *Service) ArchivePast() (int, error) {
+	now := time.Now()
+	result, err := s.db.Exec(`
+		UPDATE shows SET archived_at = ?, updated_at = ?
+		WHERE archived_at IS NULL AND starts_at <= ?
+	`, now, now, now.Add(-ShowLength).UTC())
+	if err != nil {
+		return 0, fmt.Errorf("archive shows: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return int(n), nil
+}
+
+// StartScheduledArchive archives past shows now and then every interval in the background
+func (s *Service) StartScheduledArchive(interval time.Duration) {
+	go func() {
+		s.runArchive()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			s.runArchive()
+		}
+	}()
+
+	log.Printf("Tour archiver started (every %v)", interval)
+}
+
+func (s *Service) runArchive() {
+	n, err := s.ArchivePast()
+	if err != nil {
+		log.Printf("⚠️  Failed to archive past shows: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("Archived %d past show(s)", n)
+	}
+}
+
+// LastModified returns when a show was last added, changed or archived; zero if
+// there are no shows
+func (s *Service) LastModified() (time.Time, error) {
+	var updatedAt time.Time
+	err := s.db.QueryRow(`SELECT updated_at FROM shows ORDER BY updated_at DESC LIMIT 1`).Scan(&updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("query shows: %w", err)
+	}
+	return updatedAt, nil
+}
@@ -0,0 +1,89 @@
+package wishlist
+
+import (
+	"fmt"
+
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+// Alert kinds
+const (
+	AlertBackInStock = "back_in_stock"
+	AlertPriceDrop   = "price_drop"
+)
+
+// Alert is the restocks and price drops to tell one wishlist owner about
+type Alert struct {
+	WishlistID string
+	Email      string
+	Token      string
+	Items      []AlertItem
+}
+
+// AlertItem is one saved variant that came back in stock or got cheaper
+type AlertItem struct {
+	models.WishlistItem
+	Kind          string  // AlertBackInStock or AlertPriceDrop
+	PreviousPrice float64 // Last price the owner was told about
+}
+
+// PendingAlerts returns the saved variants that came back in stock or dropped in
+// price since their owners were last told, for wishlists with notifications on.
+// Other changes (price rises, sell-outs, wishlists without notifications) are
+// recorded silently, so turning notifications on later doesn't replay old news.
+func (s *Service) PendingAlerts() ([]Alert, error) {
+	tracked, err := s.queryItems(`ORDER BY i.wishlist_id, i.created_at`)
+	if err != nil {
+		return nil, err
+	}
+
+	var alerts []Alert
+	for _, t := range tracked {
+		item := t.item
+		var kind string
+		switch {
+		case item.Available && !t.lastAvailable:
+			kind = AlertBackInStock
+		case item.Available && item.Price < t.lastPrice-0.005:
+			kind = AlertPriceDrop
+		}
+
+		if kind == "" || !t.notify || t.email == "" {
+			if item.Price != t.lastPrice || item.Available != t.lastAvailable {
+				if err := s.record(t.wishlistID, item); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		if len(alerts) == 0 || alerts[len(alerts)-1].WishlistID != t.wishlistID {
+			alerts = append(alerts, Alert{WishlistID: t.wishlistID, Email: t.email, Token: t.token})
+		}
+		last := &alerts[len(alerts)-1]
+		last.Items = append(last.Items, AlertItem{WishlistItem: item, Kind: kind, PreviousPrice: t.lastPrice})
+	}
+	return alerts, nil
+}
+
+// MarkAlerted records that an alert's owner has been told, so it is not sent again
+func (s *Service) MarkAlerted(alert Alert) error {
+	for _, item := range alert.Items {
+		if err := s.record(alert.WishlistID, item.WishlistItem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// record stores an item's current price and availability as what its owner last saw
+func (s *Service) record(wishlistID string, item models.WishlistItem) error {
+	_, err := s.db.Exec(`
+		UPDATE wishlist_items SET last_price = ?, last_available = ?
+		WHERE wishlist_id = ? AND variant_id = ?
+	`, item.Price, item.Available, wishlistID, item.VariantID)
+	if err != nil {
+		return fmt.Errorf("record wishlist item state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,288 @@
+// Package wishlist stores saved variants under anonymous tokens, reports their
+// current price and availability, and finds the restocks and price drops to email about.
+package wishlist
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nessieaudio/ecommerce-backend/internal/inventory"
+	"github.com/nessieaudio/ecommerce-backend/internal/models"
+)
+
+// MaxItems caps the size of one wishlist
+const MaxItems = 100
+
+var (
+	// ErrUnknownVariant is returned when adding a variant that does not exist
+	ErrUnknownVariant = errors.New("variant not found")
+	// ErrFull is returned when a wishlist already holds MaxItems variants
+	ErrFull = errors.New("wishlist is full")
+)
+
+// Service manages wishlists
+type Service struct {
+	db        *sql.DB
+	inventory *inventory.Service
+}
+
+// NewService creates a wishlist service
+func NewService(db *sql.DB) *Service {
+	return &Service{db: db, inventory: inventory.NewService(db)}
+}
+
+// Create starts an empty anonymous wishlist
+func (s *Service) Create() (*models.Wishlist, error) {
+	now := time.Now()
+	w := &models.Wishlist{
+		ID:         uuid.New().String(),
+		Token:      uuid.New().String(),
+		ShareToken: uuid.New().String(),
+		Items:      []models.WishlistItem{},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	_, err := s.db.Exec(`
+		INSERT INTO wishlists (id, token, share_token, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, w.ID, w.Token, w.ShareToken, now, now)
+	if err != nil {
+		return nil, fmt.Errorf("insert wishlist: %w", err)
+	}
+	return w, nil
+}
+
+// Get returns the wishlist for a private token, with its items
+// Returns sql.ErrNoRows if there is none.
+func (s *Service) Get(token string) (*models.Wishlist, error) {
+	return s.get("token", token)
+}
+
+// GetShared returns the wishlist for a share token, with its items
+// Returns sql.ErrNoRows if there is none.
+func (s *Service) GetShared(shareToken string) (*models.Wishlist, error) {
+	return s.get("share_token", shareToken)
+}
+
+func (s *Service) get(column, value string) (*models.Wishlist, error) {
+	var w models.Wishlist
+	var customerID sql.NullString
+	err := s.db.QueryRow(`
+		SELECT id, token, share_token, customer_id, email, notify, created_at, updated_at
+		FROM wishlists WHERE `+column+` = ?
+	`, value).Scan(&w.ID, &w.Token, &w.ShareToken, &customerID, &w.Email, &w.Notify, &w.CreatedAt, &w.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	w.CustomerID = customerID.String
+
+	tracked, err := s.queryItems(`WHERE i.wishlist_id = ? ORDER BY i.created_at DESC`, w.ID)
+	if err != nil {
+		return nil, err
+	}
+	w.Items = []models.WishlistItem{}
+	for _, t := range tracked {
+		w.Items = append(w.Items, t.item)
+	}
+	return &w, nil
+}
+
+// AddItem saves a variant to a wishlist; saving it again keeps the original entry
+// Returns sql.ErrNoRows for an unknown token, ErrUnknownVariant or ErrFull.
+func (s *Service) AddItem(token, variantID string) (*models.Wishlist, error) {
+	w, err := s.Get(token)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range w.Items {
+		if item.VariantID == variantID {
+			return w, nil
+		}
+	}
+	if len(w.Items) >= MaxItems {
+		return nil, ErrFull
+	}
+
+	var price float64
+	err = s.db.QueryRow(`SELECT price FROM variants WHERE id = ?`, variantID).Scan(&price)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUnknownVariant
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query variant: %w", err)
+	}
+	available, err := s.available(variantID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	_, err = s.db.Exec(`
+		INSERT OR IGNORE INTO wishlist_items (wishlist_id, variant_id, added_price, last_price, last_available, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, w.ID, variantID, price, price, available, now)
+	if err != nil {
+		return nil, fmt.Errorf("insert wishlist item: %w", err)
+	}
+	if err := s.touch(w.ID, now); err != nil {
+		return nil, err
+	}
+
+	return s.Get(token)
+}
+
+// RemoveItem drops a variant from a wishlist; removing one that isn't there is not an error
+// Returns sql.ErrNoRows for an unknown token.
+func (s *Service) RemoveItem(token, variantID string) (*models.Wishlist, error) {
+	var wishlistID string
+	if err := s.db.QueryRow(`SELECT id FROM wishlists WHERE token = ?`, token).Scan(&wishlistID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.db.Exec(`DELETE FROM wishlist_items WHERE wishlist_id = ? AND variant_id = ?`, wishlistID, variantID); err != nil {
+		return nil, fmt.Errorf("delete wishlist item: %w", err)
+	}
+	if err := s.touch(wishlistID, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return s.Get(token)
+}
+
+// Promote attaches a wishlist to the customer with this email, creating the
+// customer if needed, and sets whether restock / price drop emails are sent
+// Returns sql.ErrNoRows for an unknown token.
+func (s *Service) Promote(token, email string, notify bool) (*models.Wishlist, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	now := time.Now()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var customerID string
+	err = tx.QueryRow(`SELECT id FROM customers WHERE email = ? COLLATE NOCASE`, email).Scan(&customerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		customerID = uuid.New().String()
+		_, err = tx.Exec(`
+			INSERT INTO customers (id, email, created_at, updated_at)
+			VALUES (?, ?, ?, ?)
+		`, customerID, email, now, now)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find or create customer: %w", err)
+	}
+
+	result, err := tx.Exec(`
+		UPDATE wishlists SET customer_id = ?, email = ?, notify = ?, updated_at = ?
+		WHERE token = ?
+	`, customerID, email, notify, now, token)
+	if err != nil {
+		return nil, fmt.Errorf("update wishlist: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit wishlist: %w", err)
+	}
+	return s.Get(token)
+}
+
+func (s *Service) touch(wishlistID string, now time.Time) error {
+	if _, err := s.db.Exec(`UPDATE wishlists SET updated_at = ? WHERE id = ?`, now, wishlistID); err != nil {
+		return fmt.Errorf("update wishlist: %w", err)
+	}
+	return nil
+}
+
+// trackedItem is a wishlist item with what its owner was last told about it
+type trackedItem struct {
+	wishlistID    string
+	email         string
+	token         string
+	notify        bool
+	lastPrice     float64
+	lastAvailable bool
+	item          models.WishlistItem
+}
+
+func (s *Service) queryItems(where string, args ...interface{}) ([]trackedItem, error) {
+	rows, err := s.db.Query(`
+		SELECT i.wishlist_id, w.email, w.token, w.notify, i.last_price, i.last_available,
+			i.variant_id, p.id, COALESCE(p.slug, ''), p.name, v.name, COALESCE(p.image_url, ''),
+			v.price, COALESCE(p.currency, 'USD'), i.added_price, v.available = 1 AND p.active = 1, i.created_at
+		FROM wishlist_items i
+		JOIN wishlists w ON w.id = i.wishlist_id
+		JOIN variants v ON v.id = i.variant_id
+		JOIN products p ON p.id = v.product_id
+		`+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query wishlist items: %w", err)
+	}
+
+	var tracked []trackedItem
+	for rows.Next() {
+		var t trackedItem
+		var listed bool
+		if err := rows.Scan(&t.wishlistID, &t.email, &t.token, &t.notify, &t.lastPrice, &t.lastAvailable,
+			&t.item.VariantID, &t.item.ProductID, &t.item.ProductSlug, &t.item.ProductName, &t.item.VariantName,
+			&t.item.ImageURL, &t.item.Price, &t.item.Currency, &t.item.AddedPrice, &listed, &t.item.AddedAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan wishlist item: %w", err)
+		}
+		// Unlisted variants are unavailable regardless of stock
+		t.item.Available = listed
+		tracked = append(tracked, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Pre-orders and tracked stock come from the inventory service
+	for i := range tracked {
+		item := &tracked[i].item
+		if !item.Available {
+			continue
+		}
+		check, err := s.inventory.CheckStock(item.VariantID, 1)
+		if err != nil {
+			return nil, err
+		}
+		item.Available = check.Available
+		item.Preorder = check.Preorder
+		item.ExpectedShipDate = check.ExpectedShipDate
+	}
+	return tracked, nil
+}
+
+// available reports whether one unit of a listed variant can be bought now
+func (s *Service) available(variantID string) (bool, error) {
+	var listed bool
+	err := s.db.QueryRow(`
+		SELECT v.available = 1 AND p.active = 1
+		FROM variants v JOIN products p ON p.id = v.product_id
+		WHERE v.id = ?
+	`, variantID).Scan(&listed)
+	if err != nil {
+		return false, fmt.Errorf("query variant: %w", err)
+	}
+	if !listed {
+		return false, nil
+	}
+
+	check, err := s.inventory.CheckStock(variantID, 1)
+	if err != nil {
+		return false, err
+	}
+	return check.Available, nil
+}
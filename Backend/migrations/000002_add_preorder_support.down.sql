@@ -0,0 +1,9 @@
+-- Rollback pre-order support
+
+DROP INDEX IF EXISTS idx_orders_fulfillment_hold;
+
+ALTER TABLE orders DROP COLUMN fulfillment_hold_until;
+ALTER TABLE order_items DROP COLUMN preorder_ship_date;
+ALTER TABLE variants DROP COLUMN preorder_ship_date;
+ALTER TABLE variants DROP COLUMN preorder_limit;
+ALTER TABLE variants DROP COLUMN preorder_enabled;
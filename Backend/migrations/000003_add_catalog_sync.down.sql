@@ -0,0 +1,14 @@
+-- Rollback Printful catalog sync
+
+DROP INDEX IF EXISTS idx_catalog_sync_runs_started;
+DROP TABLE IF EXISTS catalog_sync_runs;
+
+DROP INDEX IF EXISTS idx_variants_printful_variant_id;
+DROP INDEX IF EXISTS idx_products_printful_id;
+
+ALTER TABLE products DROP COLUMN synced_at;
+ALTER TABLE variants DROP COLUMN printful_removed_at;
+ALTER TABLE products DROP COLUMN printful_removed_at;
+ALTER TABLE variants DROP COLUMN retail_price;
+ALTER TABLE variants DROP COLUMN price_override;
+ALTER TABLE products DROP COLUMN price_override;
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_products_category;
+DROP INDEX IF EXISTS idx_products_active_created;
+
+DROP TRIGGER IF EXISTS products_fts_delete;
+DROP TRIGGER IF EXISTS products_fts_update;
+DROP TRIGGER IF EXISTS products_fts_insert;
+
+DROP TABLE IF EXISTS products_fts;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_collection_products_position;
+DROP TABLE IF EXISTS collection_products;
+DROP TABLE IF EXISTS collections;
+
+DROP INDEX IF EXISTS idx_categories_parent;
+DROP TABLE IF EXISTS categories;
@@ -0,0 +1,7 @@
+-- Rollback product slugs
+
+DROP INDEX IF EXISTS idx_product_slug_redirects_product;
+DROP TABLE IF EXISTS product_slug_redirects;
+
+DROP INDEX IF EXISTS idx_products_slug;
+ALTER TABLE products DROP COLUMN slug;
@@ -0,0 +1,8 @@
+-- Rollback product reviews
+
+DROP INDEX IF EXISTS idx_product_reviews_status;
+DROP INDEX IF EXISTS idx_product_reviews_product_status;
+DROP TABLE IF EXISTS product_reviews;
+
+ALTER TABLE orders DROP COLUMN review_requested_at;
+ALTER TABLE orders DROP COLUMN shipped_at;
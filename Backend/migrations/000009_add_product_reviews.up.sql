@@ -0,0 +1,31 @@
+-- Product reviews from verified purchasers
+-- Reviewers are invited by a signed link emailed some days after their order
+-- ships; reviews wait in moderation (status 'pending') until approved.
+
+ALTER TABLE orders ADD COLUMN shipped_at DATETIME;
+ALTER TABLE orders ADD COLUMN review_requested_at DATETIME;
+
+-- Orders that shipped before reviews existed are not invited retroactively
+UPDATE orders SET shipped_at = updated_at, review_requested_at = CURRENT_TIMESTAMP WHERE status = 'shipped';
+
+CREATE TABLE IF NOT EXISTS product_reviews (
+	id TEXT PRIMARY KEY,
+	product_id TEXT NOT NULL,
+	order_id TEXT NOT NULL,
+	email TEXT NOT NULL,
+	reviewer_name TEXT NOT NULL DEFAULT '',
+	rating INTEGER NOT NULL CHECK (rating BETWEEN 1 AND 5),
+	fit TEXT NOT NULL DEFAULT '', -- runs_small, true_to_size, runs_large or '' (not apparel / not given)
+	body TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'pending', -- pending, approved, rejected
+	moderation_note TEXT NOT NULL DEFAULT '',
+	moderated_at DATETIME,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	UNIQUE (product_id, email),
+	FOREIGN KEY (product_id) REFERENCES products(id),
+	FOREIGN KEY (order_id) REFERENCES orders(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_reviews_product_status ON product_reviews(product_id, status);
+CREATE INDEX IF NOT EXISTS idx_product_reviews_status ON product_reviews(status, created_at);
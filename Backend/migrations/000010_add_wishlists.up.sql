@@ -0,0 +1,33 @@
+-- Wishlists of saved variants
+-- A wishlist starts out anonymous, known only by its token (kept in the
+-- browser); it is attached to a customer once an email is given. share_token
+-- opens a read-only view. last_price / last_available are what the buyer was
+-- last told, so price drops and restocks are alerted once each.
+
+CREATE TABLE IF NOT EXISTS wishlists (
+	id TEXT PRIMARY KEY,
+	token TEXT NOT NULL UNIQUE,
+	share_token TEXT NOT NULL UNIQUE,
+	customer_id TEXT,
+	email TEXT NOT NULL DEFAULT '',
+	notify BOOLEAN NOT NULL DEFAULT 0, -- email on restock / price drop
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	FOREIGN KEY (customer_id) REFERENCES customers(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_wishlists_customer ON wishlists(customer_id);
+
+CREATE TABLE IF NOT EXISTS wishlist_items (
+	wishlist_id TEXT NOT NULL,
+	variant_id TEXT NOT NULL,
+	added_price REAL NOT NULL,
+	last_price REAL NOT NULL,
+	last_available BOOLEAN NOT NULL,
+	created_at DATETIME NOT NULL,
+	PRIMARY KEY (wishlist_id, variant_id),
+	FOREIGN KEY (wishlist_id) REFERENCES wishlists(id),
+	FOREIGN KEY (variant_id) REFERENCES variants(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_wishlist_items_variant ON wishlist_items(variant_id);
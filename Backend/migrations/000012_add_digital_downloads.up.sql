@@ -0,0 +1,42 @@
+-- Digital download products
+-- A digital product is a local product (printful_id 0) with a single variant,
+-- like a bundle, whose files are streamed from DIGITAL_FILES_DIR instead of
+-- being fulfilled by Printful. Paying for one issues a download grant per file.
+
+ALTER TABLE products ADD COLUMN product_type TEXT NOT NULL DEFAULT 'physical'; -- physical, digital
+
+CREATE TABLE IF NOT EXISTS digital_files (
+	id TEXT PRIMARY KEY,
+	product_id TEXT NOT NULL,
+	file_path TEXT NOT NULL, -- Relative to DIGITAL_FILES_DIR
+	file_name TEXT NOT NULL, -- Offered to the browser when downloading
+	content_type TEXT NOT NULL,
+	size_bytes INTEGER NOT NULL,
+	position INTEGER NOT NULL DEFAULT 0,
+	removed_at DATETIME, -- No longer sold; past buyers keep their downloads
+	created_at DATETIME NOT NULL,
+	FOREIGN KEY (product_id) REFERENCES products(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_digital_files_product ON digital_files(product_id);
+
+-- One grant per purchased file. Links are signed tokens naming the grant; the
+-- grant holds the expiry and how many downloads are left.
+CREATE TABLE IF NOT EXISTS download_grants (
+	id TEXT PRIMARY KEY,
+	order_id TEXT NOT NULL,
+	order_item_id TEXT NOT NULL,
+	file_id TEXT NOT NULL,
+	email TEXT NOT NULL,
+	downloads INTEGER NOT NULL DEFAULT 0,
+	max_downloads INTEGER NOT NULL,
+	expires_at DATETIME NOT NULL,
+	last_downloaded_at DATETIME,
+	created_at DATETIME NOT NULL,
+	UNIQUE (order_item_id, file_id),
+	FOREIGN KEY (order_id) REFERENCES orders(id),
+	FOREIGN KEY (order_item_id) REFERENCES order_items(id),
+	FOREIGN KEY (file_id) REFERENCES digital_files(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_download_grants_order ON download_grants(order_id);
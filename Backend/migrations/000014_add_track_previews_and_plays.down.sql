@@ -0,0 +1,4 @@
+-- Rollback portfolio audio
+
+DROP TABLE IF EXISTS track_plays;
+DROP TABLE IF EXISTS track_previews;
@@ -0,0 +1,20 @@
+-- Portfolio audio
+-- Tracks themselves are found on disk under the music directory (see internal/audio);
+-- these tables key off the track ID derived from genre, artist and title.
+
+-- Tracks that are for sale only stream a preview clip
+CREATE TABLE IF NOT EXISTS track_previews (
+	track_id TEXT PRIMARY KEY,
+	product_id TEXT, -- What the track is sold as, if anything in the catalog
+	start_seconds REAL NOT NULL DEFAULT 0,
+	length_seconds REAL, -- NULL = the default preview length
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	FOREIGN KEY (product_id) REFERENCES products(id)
+);
+
+CREATE TABLE IF NOT EXISTS track_plays (
+	track_id TEXT PRIMARY KEY,
+	plays INTEGER NOT NULL DEFAULT 0,
+	last_played_at DATETIME
+);
@@ -0,0 +1,3 @@
+-- Rollback calendar feed revisions
+
+ALTER TABLE shows DROP COLUMN sequence;
@@ -0,0 +1,3 @@
+-- Rollback tour-exclusive merch
+
+DROP TABLE IF EXISTS show_drops;
@@ -0,0 +1,19 @@
+-- Booking and contact inquiries
+-- Submitted through POST /api/v1/contact (previously sent to Formspree). New
+-- inquiries are emailed to the admin, who marks them replied or archived.
+
+CREATE TABLE IF NOT EXISTS inquiries (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	email TEXT NOT NULL,
+	project_type TEXT NOT NULL,
+	deadline TEXT NOT NULL, -- YYYY-MM-DD, as picked by the sender
+	message TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'new' CHECK (status IN ('new', 'replied', 'archived')),
+	note TEXT NOT NULL DEFAULT '', -- Internal
+	replied_at DATETIME,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_inquiries_status_created ON inquiries(status, created_at);
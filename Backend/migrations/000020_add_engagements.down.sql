@@ -0,0 +1,5 @@
+-- Rollback booking engagements, quotes and deposits
+
+DROP TABLE IF EXISTS quote_items;
+DROP TABLE IF EXISTS quotes;
+DROP TABLE IF EXISTS engagements;
@@ -0,0 +1,51 @@
+-- Booking engagements, quotes and deposits
+-- An inquiry the studio takes on becomes an engagement. The admin prices it in a
+-- quote (line items, a total, the deposit due and an expiry) and emails the
+-- client a link to it; the link is the quote's token. Paying the deposit through
+-- Stripe Checkout books the engagement.
+
+CREATE TABLE IF NOT EXISTS engagements (
+	id TEXT PRIMARY KEY,
+	inquiry_id TEXT UNIQUE, -- NULL when created without an inquiry
+	name TEXT NOT NULL,
+	email TEXT NOT NULL,
+	project_type TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'quoted', 'booked', 'completed', 'cancelled')),
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	FOREIGN KEY (inquiry_id) REFERENCES inquiries(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_engagements_status_created ON engagements(status, created_at);
+
+CREATE TABLE IF NOT EXISTS quotes (
+	id TEXT PRIMARY KEY,
+	engagement_id TEXT NOT NULL,
+	token TEXT NOT NULL UNIQUE,
+	currency TEXT NOT NULL DEFAULT 'USD',
+	total REAL NOT NULL CHECK (total > 0),
+	deposit REAL NOT NULL CHECK (deposit > 0 AND deposit <= total),
+	note TEXT NOT NULL DEFAULT '', -- Shown to the client
+	expires_at DATETIME NOT NULL,
+	sent_at DATETIME,
+	stripe_session_id TEXT,
+	stripe_payment_intent_id TEXT,
+	deposit_paid_at DATETIME,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	FOREIGN KEY (engagement_id) REFERENCES engagements(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_quotes_engagement ON quotes(engagement_id);
+
+CREATE TABLE IF NOT EXISTS quote_items (
+	id TEXT PRIMARY KEY,
+	quote_id TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	description TEXT NOT NULL,
+	quantity INTEGER NOT NULL CHECK (quantity > 0),
+	unit_price REAL NOT NULL CHECK (unit_price >= 0),
+	FOREIGN KEY (quote_id) REFERENCES quotes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_quote_items_quote ON quote_items(quote_id, position);
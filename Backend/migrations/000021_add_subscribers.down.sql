@@ -0,0 +1,4 @@
+-- Rollback mailing list
+
+DROP TABLE IF EXISTS subscriber_genres;
+DROP TABLE IF EXISTS subscribers;
@@ -0,0 +1,69 @@
+-- Newsletter campaigns
+-- A campaign is composed from content blocks that are rendered with the email
+-- layout helpers, previewed and test-sent, then scheduled. When its send time
+-- comes, the subscribed members of its segment are copied into
+-- campaign_recipients and a background sender works through them at a
+-- throttled rate, retrying transient SMTP errors. Each recipient has a token for
+-- the open pixel and the click redirects.
+
+CREATE TABLE IF NOT EXISTS campaigns (
+	id TEXT PRIMARY KEY,
+	subject TEXT NOT NULL,
+	title TEXT NOT NULL, -- Heading of the email layout
+	icon TEXT NOT NULL DEFAULT '',
+	content TEXT NOT NULL, -- JSON array of content blocks
+	segment_genre TEXT NOT NULL DEFAULT '',
+	segment_city TEXT NOT NULL DEFAULT '',
+	segment_purchaser INTEGER, -- NULL for everyone
+	status TEXT NOT NULL DEFAULT 'draft' CHECK (status IN ('draft', 'scheduled', 'sending', 'sent', 'cancelled')),
+	send_at DATETIME,
+	started_at DATETIME,
+	finished_at DATETIME,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaigns_status_send_at ON campaigns(status, send_at);
+
+-- Links from button blocks; emails point at a redirect that counts the click
+CREATE TABLE IF NOT EXISTS campaign_links (
+	id TEXT PRIMARY KEY,
+	campaign_id TEXT NOT NULL,
+	url TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	FOREIGN KEY (campaign_id) REFERENCES campaigns(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaign_links_campaign ON campaign_links(campaign_id);
+
+CREATE TABLE IF NOT EXISTS campaign_recipients (
+	id TEXT PRIMARY KEY,
+	campaign_id TEXT NOT NULL,
+	subscriber_id TEXT NOT NULL,
+	email TEXT NOT NULL,
+	token TEXT NOT NULL UNIQUE,
+	status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed', 'skipped')),
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	next_attempt_at DATETIME NOT NULL,
+	sent_at DATETIME,
+	opened_at DATETIME, -- First open
+	open_count INTEGER NOT NULL DEFAULT 0,
+	clicked_at DATETIME, -- First click
+	click_count INTEGER NOT NULL DEFAULT 0,
+	UNIQUE (campaign_id, subscriber_id),
+	FOREIGN KEY (campaign_id) REFERENCES campaigns(id) ON DELETE CASCADE,
+	FOREIGN KEY (subscriber_id) REFERENCES subscribers(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaign_recipients_due ON campaign_recipients(status, next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS campaign_clicks (
+	recipient_id TEXT NOT NULL,
+	link_id TEXT NOT NULL,
+	clicked_at DATETIME NOT NULL,
+	FOREIGN KEY (recipient_id) REFERENCES campaign_recipients(id) ON DELETE CASCADE,
+	FOREIGN KEY (link_id) REFERENCES campaign_links(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaign_clicks_link ON campaign_clicks(link_id);
@@ -0,0 +1,26 @@
+-- Email queue
+-- Every email the server sends is stored here first and delivered by a
+-- background worker through the configured transport (SMTP, a local maildir or
+-- nothing). Transient failures are retried with backoff; an email that fails
+-- permanently or runs out of attempts is marked dead, and stays listed for
+-- admins until it is resent.
+
+CREATE TABLE IF NOT EXISTS email_queue (
+	id TEXT PRIMARY KEY,
+	from_address TEXT NOT NULL,
+	to_address TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	headers TEXT NOT NULL DEFAULT '{}', -- JSON object of extra headers, e.g. Reply-To
+	content_type TEXT NOT NULL,
+	body TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'dead')),
+	attempts INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	next_attempt_at DATETIME NOT NULL,
+	sent_at DATETIME,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_queue_due ON email_queue(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_email_queue_created ON email_queue(created_at);
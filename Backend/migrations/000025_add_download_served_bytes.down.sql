@@ -0,0 +1,3 @@
+-- Rollback download progress tracking
+
+ALTER TABLE download_grants DROP COLUMN served_bytes;
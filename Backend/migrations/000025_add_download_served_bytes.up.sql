@@ -0,0 +1,6 @@
+-- Track how far the counted download of a grant got
+-- A ranged request is a free resume only when it continues from where the last
+-- counted download stopped. served_bytes is the furthest byte offset sent since
+-- that download was counted.
+
+ALTER TABLE download_grants ADD COLUMN served_bytes INTEGER NOT NULL DEFAULT 0;
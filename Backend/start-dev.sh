@@ -53,19 +53,16 @@ RETRY_PID=$!
 # Wait a moment for Stripe to start
 sleep 2
 
-# Sync products and Printful IDs
+# Sync products from Printful (the server also syncs on startup)
 echo -e "${BLUE}[Database Sync]${NC} Syncing products from Printful..."
-go run cmd/sync-products/main.go 2>&1 | grep -E "✓|✅|Total|Found" | sed 's/^/[Sync] /'
-
-echo -e "${BLUE}[Database Sync]${NC} Syncing Printful variant IDs..."
-go run cmd/sync-printful-ids/main.go 2>&1 | grep -E "✓|✅|Sync complete|Updated" | sed 's/^/[Sync] /'
+go run -tags sqlite_fts5 cmd/sync-products/main.go 2>&1 | grep -E "✓|✅|Total|⚠️" | sed 's/^/[Sync] /'
 
 echo ""
 
 # Start the Go server
 echo -e "${BLUE}[Backend Server]${NC} Starting Go server..."
 echo ""
-go run cmd/server/main.go 2>&1 | sed 's/^/[Server] /' &
+go run -tags sqlite_fts5 cmd/server/main.go 2>&1 | sed 's/^/[Server] /' &
 SERVER_PID=$!
 
 echo ""
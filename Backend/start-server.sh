@@ -2,4 +2,4 @@
 cd "$(dirname "$0")"
 export PATH="/opt/homebrew/bin:$PATH"
 echo "Starting Nessie Audio Backend Server..."
-go run cmd/server/main.go
+go run -tags sqlite_fts5 cmd/server/main.go
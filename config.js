@@ -31,10 +31,48 @@ function resolveAssetUrl(url) {
   return url;
 }
 
+// Point the resized-image entries of a srcset at the API server
+// (same origin in production, :8080 in local dev)
+function resolveSrcset(srcset) {
+  if (!srcset) return '';
+  var apiOrigin = getApiBaseUrl().replace(/\/api\/v1$/, '');
+  return srcset.split(', ').map(function (candidate) {
+    return candidate.indexOf('/api/') === 0 ? apiOrigin + candidate : candidate;
+  }).join(', ');
+}
+
+// One-line state of a tour-exclusive drop, e.g. "Tour exclusive · Portland · 5h left"
+function describeDrop(drop) {
+  if (!drop) return '';
+  function duration(seconds) {
+    var hours = Math.floor(seconds / 3600);
+    if (hours >= 48) return Math.floor(hours / 24) + ' days';
+    if (hours >= 1) return hours + 'h';
+    return Math.max(1, Math.floor(seconds / 60)) + 'm';
+  }
+  var parts = ['Tour exclusive', drop.city];
+  if (drop.open && drop.sold_out) {
+    parts.push('sold out');
+  } else if (drop.open) {
+    parts.push(duration(drop.time_left_seconds) + ' left');
+  } else if (drop.opens_in_seconds) {
+    parts.push('on sale in ' + duration(drop.opens_in_seconds));
+  } else {
+    parts.push('no longer on sale');
+  }
+  return parts.join(' · ');
+}
+
 const API_CONFIG = {
   BASE_URL: getApiBaseUrl(),
   PRODUCTS_ENDPOINT: `${getApiBaseUrl()}/products`,
   ORDERS_ENDPOINT: `${getApiBaseUrl()}/orders`,
   CHECKOUT_ENDPOINT: `${getApiBaseUrl()}/cart/checkout`,
+  WISHLISTS_ENDPOINT: `${getApiBaseUrl()}/wishlists`,
+  SHOWS_ENDPOINT: `${getApiBaseUrl()}/shows`,
+  CONTACT_ENDPOINT: `${getApiBaseUrl()}/contact`,
+  QUOTES_ENDPOINT: `${getApiBaseUrl()}/quotes`,
+  SUBSCRIBE_ENDPOINT: `${getApiBaseUrl()}/subscribe`,
+  UNSUBSCRIBE_ENDPOINT: `${getApiBaseUrl()}/unsubscribe`,
   CONFIG_ENDPOINT: `${getApiBaseUrl()}/config`
 };
@@ -60,26 +60,23 @@
   // Initialize booking form validation
   const bookingForm = document.querySelector('.booking-form');
   if (bookingForm) {
-    // Add honeypot field for spam protection (hidden from users)
+    // Add honeypot field for spam protection (hidden from users); the API
+    // silently drops submissions that fill it in
     const honeypot = document.createElement('input');
     honeypot.type = 'text';
-    honeypot.name = '_gotcha';
+    honeypot.name = 'website';
     honeypot.style.display = 'none';
     honeypot.tabIndex = -1;
     honeypot.autocomplete = 'off';
     honeypot.setAttribute('aria-hidden', 'true');
     bookingForm.appendChild(honeypot);
 
-    // Track submission to prevent double-submit
-    let isSubmitting = false;
-
     // Get form elements
     const nameInput = bookingForm.querySelector('#bk-name');
     const emailInput = bookingForm.querySelector('#bk-email');
     const projectTypeInput = bookingForm.querySelector('#bk-project-type');
     const deadlineInput = bookingForm.querySelector('#bk-deadline');
     const messageInput = bookingForm.querySelector('#bk-message');
-    const submitButton = bookingForm.querySelector('button[type="submit"]');
 
     // Add input length limits
     if (nameInput) nameInput.maxLength = 100;
@@ -207,21 +204,18 @@
       }
     });
 
-    // Form submission handler
+    // Form submission handler. It runs in the capture phase, ahead of the
+    // handler in script.js that sends the form, and stops invalid submissions
+    // from reaching it. Input is sent as typed; the API escapes it where shown.
     bookingForm.addEventListener('submit', function(e) {
       // Check honeypot field - if filled, it's likely a bot
       if (honeypot.value) {
         e.preventDefault();
+        e.stopImmediatePropagation();
         console.log('Spam detected - honeypot filled');
         return false;
       }
 
-      // Prevent double submission
-      if (isSubmitting) {
-        e.preventDefault();
-        return false;
-      }
-
       // Validate all fields
       let isValid = true;
       const fields = [nameInput, emailInput, projectTypeInput, deadlineInput, messageInput];
@@ -234,6 +228,7 @@
 
       if (!isValid) {
         e.preventDefault();
+        e.stopImmediatePropagation();
 
         // Focus on first error field
         const firstError = bookingForm.querySelector('.input-error');
@@ -244,31 +239,8 @@
         return false;
       }
 
-      // Sanitize all inputs before submission
-      if (nameInput) nameInput.value = FormValidator.sanitizeInput(nameInput.value);
-      if (emailInput) emailInput.value = FormValidator.sanitizeInput(emailInput.value);
-      if (projectTypeInput) projectTypeInput.value = FormValidator.sanitizeInput(projectTypeInput.value);
-      if (messageInput) messageInput.value = FormValidator.sanitizeInput(messageInput.value);
-
-      // Mark as submitting and disable button
-      isSubmitting = true;
-      if (submitButton) {
-        submitButton.disabled = true;
-        submitButton.textContent = 'Sending...';
-      }
-
-      // Form will submit naturally to Formspree
-      // Re-enable after a delay in case of network error
-      setTimeout(() => {
-        isSubmitting = false;
-        if (submitButton) {
-          submitButton.disabled = false;
-          submitButton.textContent = 'Send Booking Request';
-        }
-      }, 5000);
-
       return true;
-    });
+    }, true);
   }
 
   // Export utilities for use in other scripts if needed
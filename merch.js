@@ -4,20 +4,35 @@
 const API_BASE_URL = API_CONFIG.BASE_URL;
 const PRODUCTS_ENDPOINT = API_CONFIG.PRODUCTS_ENDPOINT;
 
+// The API returns products a page at a time; follow next_cursor to load the whole catalog
 async function fetchProducts() {
   try {
-    const response = await fetch(PRODUCTS_ENDPOINT);
-    if (!response.ok) {
-      throw new Error(`HTTP error! status: ${response.status}`);
-    }
-    const data = await response.json();
-    return data.products || [];
+    const products = [];
+    let cursor = '';
+    do {
+      const url = cursor ? `${PRODUCTS_ENDPOINT}?cursor=${encodeURIComponent(cursor)}` : PRODUCTS_ENDPOINT;
+      const response = await fetch(url);
+      if (!response.ok) {
+        throw new Error(`HTTP error! status: ${response.status}`);
+      }
+      const data = await response.json();
+      products.push(...(data.products || []));
+      cursor = data.next_cursor || '';
+    } while (cursor);
+    return products;
   } catch (error) {
     console.error('Error fetching products:', error);
     return [];
   }
 }
 
+// Product pages are addressed by slug; the id form still works for older links
+function productPageUrl(product) {
+  return product.slug
+    ? `/product-detail?slug=${encodeURIComponent(product.slug)}`
+    : `/product-detail?id=${product.id}`;
+}
+
 function createProductHTML(product) {
   // Show price range if variants have different prices
   let priceDisplay;
@@ -33,11 +48,12 @@ function createProductHTML(product) {
 
   return `
     <article class="merch-item">
-      <a href="/product-detail?id=${product.id}"
+      <a href="${productPageUrl(product)}"
          class="merch-item-link"
          aria-label="View details for ${product.name}, ${priceDisplay}">
         <div class="merch-image-container">
           <img src="${resolveAssetUrl(product.image_url || product.imageUrl)}"
+               ${product.image_srcset ? `srcset="${resolveSrcset(product.image_srcset)}" sizes="(max-width: 768px) 50vw, 320px"` : ''}
                alt="${altText}"
                class="merch-image"
                loading="eager">
@@ -45,6 +61,7 @@ function createProductHTML(product) {
         <div class="merch-details">
           <h3 class="merch-title">${product.name}</h3>
           <p class="merch-price">${priceDisplay}</p>
+          ${product.drop ? `<p class="merch-drop">${describeDrop(product.drop)}</p>` : ''}
         </div>
       </a>
     </article>
@@ -67,7 +84,44 @@ function renderProducts(products) {
   grid.innerHTML = products.map(createProductHTML).join('');
 }
 
+// A curated collection, e.g. /merch?collection=tour-2026; null if it is missing or not live
+async function fetchCollection(slug) {
+  try {
+    const response = await fetch(`${API_BASE_URL}/collections/${encodeURIComponent(slug)}`);
+    if (!response.ok) {
+      return null;
+    }
+    return await response.json();
+  } catch (error) {
+    console.error('Error fetching collection:', error);
+    return null;
+  }
+}
+
+function renderCollectionIntro(collection) {
+  const intro = document.querySelector('.merch-intro');
+  if (!intro) return;
+
+  intro.textContent = collection.description || collection.name;
+  const heading = document.createElement('h2');
+  heading.className = 'merch-collection-title';
+  heading.textContent = collection.name;
+  intro.parentNode.insertBefore(heading, intro);
+  document.title = `${collection.name} | Nessie Audio Merch`;
+}
+
 async function initMerchPage() {
+  const collectionSlug = new URLSearchParams(window.location.search).get('collection');
+  if (collectionSlug) {
+    const collection = await fetchCollection(collectionSlug);
+    if (collection) {
+      // Collections keep the order they were curated in
+      renderCollectionIntro(collection);
+      renderProducts(collection.products || []);
+      return;
+    }
+  }
+
   const products = await fetchProducts();
 
   // Display products in curated order
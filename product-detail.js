@@ -4,9 +4,10 @@
 const API_BASE_URL = API_CONFIG.BASE_URL;
 const PRODUCTS_ENDPOINT = API_CONFIG.PRODUCTS_ENDPOINT;
 
+// Accepts ?slug= (current links) or ?id= (older links); the API resolves either
 function getProductIdFromURL() {
   const urlParams = new URLSearchParams(window.location.search);
-  return urlParams.get('id');
+  return urlParams.get('slug') || urlParams.get('id');
 }
 
 async function fetchProduct(productId) {
@@ -32,7 +33,7 @@ async function fetchProductFromList(productId) {
     }
     const data = await response.json();
     const products = data.products || [];
-    return products.find(p => p.id === productId) || null;
+    return products.find(p => p.id === productId || p.slug === productId) || null;
   } catch (error) {
     console.error('Error fetching products:', error);
     return null;
@@ -94,13 +95,20 @@ function renderProductDetail(product) {
     return;
   }
 
+  const images = getGalleryImages(product);
+  const mainImage = images[0] || { url: product.image_url || product.imageUrl, alt_text: product.name };
+
   const html = `
     <div class="product-detail">
       <div class="product-detail-image">
-        <img src="${resolveAssetUrl(product.image_url || product.imageUrl)}"
-             alt="${product.name}"
+        <img src="${resolveAssetUrl(mainImage.url)}"
+             srcset="${resolveSrcset(mainImage.srcset || product.image_srcset)}"
+             sizes="(max-width: 768px) 100vw, 50vw"
+             alt="${escapeAttr(mainImage.alt_text || product.name)}"
              class="product-main-image"
+             id="product-main-image"
              loading="eager">
+        ${renderGalleryThumbnails(images)}
       </div>
 
       <div class="product-detail-info">
@@ -119,7 +127,7 @@ function renderProductDetail(product) {
         </div>
 
         <div class="product-availability">
-          <span class="availability-badge in-stock">In Stock</span>
+          ${renderAvailability(product)}
         </div>
 
         <div class="product-actions">
@@ -129,6 +137,9 @@ function renderProductDetail(product) {
           <button class="btn-buy-now" id="buy-now-btn" data-product-id="${product.id}">
             Buy Now
           </button>
+          <button class="btn-save-for-later" id="save-for-later-btn" type="button">
+            Save for later
+          </button>
         </div>
 
         <div class="product-meta">
@@ -137,10 +148,215 @@ function renderProductDetail(product) {
         </div>
       </div>
     </div>
+
+    ${renderReviewsSection(product)}
   `;
 
   container.innerHTML = html;
   attachProductDetailListeners(product);
+  attachReviewFormListener(product);
+
+  // Invitation links end in #reviews, which didn't exist until now
+  const reviewsSection = document.getElementById('reviews');
+  if (window.location.hash === '#reviews' && reviewsSection) {
+    reviewsSection.scrollIntoView();
+  }
+}
+
+// Tour exclusives say which show they are sold around and how long is left
+function renderAvailability(product) {
+  const drop = product.drop || (product.variants || []).map(v => v.drop).find(Boolean);
+  if (!drop) {
+    return '<span class="availability-badge in-stock">In Stock</span>';
+  }
+  const state = drop.open && !drop.sold_out ? 'in-stock' : 'out-of-stock';
+  return `<span class="availability-badge ${state}">${describeDrop(drop)}</span>
+    <p class="drop-show">Sold around the show at ${escapeAttr(drop.venue)}, ${escapeAttr(drop.city)} on ${new Date(drop.show_starts_at).toLocaleDateString(undefined, { timeZone: drop.timezone, month: 'long', day: 'numeric', year: 'numeric' })}</p>`;
+}
+
+// Gallery images from the API; older responses only carry image_url
+function getGalleryImages(product) {
+  if (Array.isArray(product.images) && product.images.length > 0) {
+    return product.images.filter(img => img && img.url);
+  }
+  const url = product.image_url || product.imageUrl;
+  return url ? [{ id: '', url: url, alt_text: product.name, srcset: product.image_srcset }] : [];
+}
+
+const FIT_LABELS = {
+  runs_small: 'Runs small',
+  true_to_size: 'True to size',
+  runs_large: 'Runs large'
+};
+
+function renderStars(rating) {
+  const full = Math.round(rating);
+  return `<span class="review-stars" aria-label="${rating} out of 5 stars">${'★'.repeat(full)}${'☆'.repeat(5 - full)}</span>`;
+}
+
+// Approved reviews, plus the review form when arriving from an invitation email (?review=<token>)
+function renderReviewsSection(product) {
+  const reviews = Array.isArray(product.reviews) ? product.reviews : [];
+  const rating = product.rating;
+
+  let summary = '<p class="reviews-empty">No reviews yet.</p>';
+  if (rating && rating.count > 0) {
+    const fits = Object.entries(rating.fit || {})
+      .sort((a, b) => b[1] - a[1])
+      .map(([fit, count]) => `${FIT_LABELS[fit] || fit} (${count})`)
+      .join(' · ');
+    summary = `
+      <div class="reviews-summary">
+        ${renderStars(rating.average)}
+        <span class="reviews-average">${rating.average.toFixed(1)} out of 5</span>
+        <span class="reviews-count">${rating.count} review${rating.count === 1 ? '' : 's'}</span>
+        ${fits ? `<p class="reviews-fit"><strong>Fit:</strong> ${fits}</p>` : ''}
+      </div>
+    `;
+  }
+
+  const list = reviews.map(review => `
+    <li class="review">
+      <div class="review-header">
+        ${renderStars(review.rating)}
+        <span class="review-author">${escapeAttr(review.reviewer_name)}</span>
+        <span class="review-date">${new Date(review.created_at).toLocaleDateString()}</span>
+      </div>
+      ${review.fit ? `<p class="review-fit">${FIT_LABELS[review.fit] || ''}</p>` : ''}
+      ${review.body ? `<p class="review-body">${escapeAttr(review.body)}</p>` : ''}
+    </li>
+  `).join('');
+
+  return `
+    <section class="product-reviews" id="reviews" aria-labelledby="reviews-heading">
+      <h2 id="reviews-heading">Reviews</h2>
+      ${summary}
+      ${renderReviewForm()}
+      ${list ? `<ul class="review-list">${list}</ul>` : ''}
+    </section>
+  `;
+}
+
+function getReviewTokenFromURL() {
+  return new URLSearchParams(window.location.search).get('review');
+}
+
+function renderReviewForm() {
+  if (!getReviewTokenFromURL()) return '';
+
+  const stars = [5, 4, 3, 2, 1].map(n => `
+    <label><input type="radio" name="rating" value="${n}" required> ${n} ★</label>
+  `).join('');
+
+  return `
+    <form class="review-form" id="review-form">
+      <h3>Write a review</h3>
+      <fieldset>
+        <legend>Rating</legend>
+        ${stars}
+      </fieldset>
+      <label for="review-fit">How does it fit?</label>
+      <select id="review-fit" name="fit">
+        <option value="">Not applicable</option>
+        <option value="runs_small">Runs small</option>
+        <option value="true_to_size">True to size</option>
+        <option value="runs_large">Runs large</option>
+      </select>
+      <label for="review-body">Your review</label>
+      <textarea id="review-body" name="body" rows="4" maxlength="5000"></textarea>
+      <label for="review-name">Name shown with your review (optional)</label>
+      <input type="text" id="review-name" name="reviewer_name" maxlength="100">
+      <button type="submit" class="btn-add-to-cart">Submit review</button>
+    </form>
+  `;
+}
+
+function attachReviewFormListener(product) {
+  const form = document.getElementById('review-form');
+  if (!form) return;
+
+  form.addEventListener('submit', async (e) => {
+    e.preventDefault();
+    const data = new FormData(form);
+    const button = form.querySelector('button[type="submit"]');
+    button.disabled = true;
+
+    try {
+      const response = await fetch(`${PRODUCTS_ENDPOINT}/${product.id}/reviews`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({
+          token: getReviewTokenFromURL(),
+          rating: parseInt(data.get('rating'), 10),
+          fit: data.get('fit'),
+          body: data.get('body'),
+          reviewer_name: data.get('reviewer_name')
+        })
+      });
+      const result = await response.json();
+      if (!response.ok) {
+        throw new Error(result.error || `HTTP error! status: ${response.status}`);
+      }
+      form.innerHTML = `<p class="review-thanks">${escapeAttr(result.message)}</p>`;
+    } catch (error) {
+      console.error('Error submitting review:', error);
+      showNotification(error.message || 'Could not submit your review', 'error');
+      button.disabled = false;
+    }
+  });
+}
+
+function escapeAttr(value) {
+  return String(value)
+    .replace(/&/g, '&amp;')
+    .replace(/"/g, '&quot;')
+    .replace(/</g, '&lt;')
+    .replace(/>/g, '&gt;');
+}
+
+function renderGalleryThumbnails(images) {
+  if (images.length < 2) return '';
+
+  const thumbs = images.map((img, i) => `
+    <button type="button"
+            class="product-thumbnail${i === 0 ? ' active' : ''}"
+            data-image-index="${i}"
+            aria-label="Show image ${i + 1} of ${images.length}: ${escapeAttr(img.alt_text)}"
+            aria-pressed="${i === 0}">
+      <img src="${resolveAssetUrl(img.url)}" srcset="${resolveSrcset(img.srcset)}" sizes="80px" alt="" loading="lazy">
+    </button>
+  `).join('');
+
+  return `<div class="product-thumbnails" role="group" aria-label="Product images">${thumbs}</div>`;
+}
+
+function showGalleryImage(images, index) {
+  const image = images[index];
+  const mainImage = document.getElementById('product-main-image');
+  if (!image || !mainImage) return;
+
+  // Set srcset first so the browser does not fetch the full-size src in between
+  mainImage.srcset = resolveSrcset(image.srcset);
+  mainImage.src = resolveAssetUrl(image.url);
+  mainImage.alt = image.alt_text || '';
+
+  document.querySelectorAll('.product-thumbnail').forEach(thumb => {
+    const active = parseInt(thumb.getAttribute('data-image-index')) === index;
+    thumb.classList.toggle('active', active);
+    thumb.setAttribute('aria-pressed', active);
+  });
+}
+
+// Switch to the image tied to the selected variant (e.g. its colour mockup)
+function showVariantImage(product, variantId) {
+  const variant = (product.variants || []).find(v => v.id === variantId);
+  if (!variant || !variant.image_id) return;
+
+  const images = getGalleryImages(product);
+  const index = images.findIndex(img => img.id === variant.image_id);
+  if (index !== -1) {
+    showGalleryImage(images, index);
+  }
 }
 
 function sortVariantsBySize(variants) {
@@ -175,7 +391,63 @@ function sortVariantsBySize(variants) {
   });
 }
 
+// Bundles have a single variant; list what's in the box instead of a size picker
+function renderBundleSection(product) {
+  const items = product.bundle.map(item => {
+    const name = item.quantity > 1 ? `${item.quantity} × ${item.product_name}` : item.product_name;
+    const href = item.product_slug
+      ? `/product-detail?slug=${encodeURIComponent(item.product_slug)}`
+      : `/product-detail?id=${encodeURIComponent(item.product_id)}`;
+    return `<li><a href="${href}">${escapeAttr(name)}</a> <span class="bundle-variant">${escapeAttr(item.variant_name)}</span></li>`;
+  }).join('');
+
+  const separately = product.bundle.reduce((sum, item) => sum + item.price * item.quantity, 0);
+  const saving = separately - product.price;
+  const variant = product.variants[0];
+
+  return `
+    <div class="product-bundle">
+      <h3>In this bundle</h3>
+      <ul class="bundle-items">${items}</ul>
+      ${saving >= 0.01 ? `<p class="bundle-saving">Save $${saving.toFixed(2)} vs. buying separately</p>` : ''}
+      <select id="variant-select" class="variant-selector" hidden>
+        <option value="${variant.id}" data-price="${variant.price}">${escapeAttr(variant.name)}</option>
+      </select>
+    </div>
+  `;
+}
+
+function formatFileSize(bytes) {
+  if (bytes >= 1024 * 1024) return `${(bytes / (1024 * 1024)).toFixed(1)} MB`;
+  return `${Math.max(1, Math.round(bytes / 1024))} KB`;
+}
+
+function renderDigitalSection(product) {
+  const files = product.files.map(file =>
+    `<li>${escapeAttr(file.file_name)} <span class="bundle-variant">${formatFileSize(file.size_bytes)}</span></li>`
+  ).join('');
+  const variant = product.variants[0];
+
+  return `
+    <div class="product-bundle product-digital">
+      <h3>Digital download</h3>
+      <ul class="bundle-items">${files}</ul>
+      <p class="bundle-saving">Download links are emailed as soon as your payment goes through</p>
+      <select id="variant-select" class="variant-selector" hidden>
+        <option value="${variant.id}" data-price="${variant.price}">${escapeAttr(variant.name)}</option>
+      </select>
+    </div>
+  `;
+}
+
 function renderVariantsSection(product) {
+  if (Array.isArray(product.bundle) && product.bundle.length > 0 && product.variants && product.variants.length > 0) {
+    return renderBundleSection(product);
+  }
+  if (product.digital && Array.isArray(product.files) && product.variants && product.variants.length > 0) {
+    return renderDigitalSection(product);
+  }
+
   if (!product.variants || product.variants.length === 0) {
     return `
       <div class="product-variants">
@@ -207,7 +479,7 @@ function renderVariantsSection(product) {
   const sortedOptions = sortVariantsBySize(sizeOptions);
 
   const optionsHTML = sortedOptions.map(opt =>
-    `<option value="${opt.id}" data-price="${opt.price}">${opt.size}</option>`
+    `<option value="${opt.id}" data-price="${opt.price}"${opt.available ? '' : ' disabled'}>${opt.size}</option>`
   ).join('');
 
   return `
@@ -235,6 +507,13 @@ function attachProductDetailListeners(product) {
     });
   }
 
+  const saveBtn = document.getElementById('save-for-later-btn');
+  if (saveBtn) {
+    saveBtn.addEventListener('click', () => {
+      handleSaveForLater(product);
+    });
+  }
+
   const variantSelect = document.getElementById('variant-select');
   if (variantSelect) {
     variantSelect.addEventListener('change', (e) => {
@@ -248,8 +527,17 @@ function attachProductDetailListeners(product) {
         }
         console.log('Size selected:', selectedOption.text, 'Price:', newPrice);
       }
+
+      showVariantImage(product, e.target.value);
     });
   }
+
+  const images = getGalleryImages(product);
+  document.querySelectorAll('.product-thumbnail').forEach(thumb => {
+    thumb.addEventListener('click', () => {
+      showGalleryImage(images, parseInt(thumb.getAttribute('data-image-index')));
+    });
+  });
 }
 
 function handleAddToCart(product) {
@@ -275,7 +563,7 @@ function handleAddToCart(product) {
   const quantity = quantityInput ? parseInt(quantityInput.value) : 1;
 
   // API returns either image_url or imageUrl depending on source
-  const productImage = resolveAssetUrl(product.image_url || product.imageUrl || (product.images && product.images[0]) || '');
+  const productImage = resolveAssetUrl(product.image_url || product.imageUrl || (getGalleryImages(product)[0] || {}).url || '');
 
   if (window.cart) {
     cart.addItem({
@@ -314,7 +602,7 @@ function handleBuyNow(product) {
   const quantity = quantityInput ? parseInt(quantityInput.value) : 1;
 
   // API returns either image_url or imageUrl depending on source
-  const productImage = resolveAssetUrl(product.image_url || product.imageUrl || (product.images && product.images[0]) || '');
+  const productImage = resolveAssetUrl(product.image_url || product.imageUrl || (getGalleryImages(product)[0] || {}).url || '');
 
   // Skip notification since redirect provides immediate feedback
   if (window.cart) {
@@ -335,6 +623,53 @@ function handleBuyNow(product) {
   }
 }
 
+// Wishlists are anonymous until an email is added on /wishlist; the token is the only key
+async function getWishlistToken() {
+  const saved = localStorage.getItem('nessie_audio_wishlist');
+  if (saved) return saved;
+
+  const response = await fetch(API_CONFIG.WISHLISTS_ENDPOINT, { method: 'POST' });
+  if (!response.ok) {
+    throw new Error(`HTTP error! status: ${response.status}`);
+  }
+  const wishlist = await response.json();
+  localStorage.setItem('nessie_audio_wishlist', wishlist.token);
+  return wishlist.token;
+}
+
+function addToWishlist(token, variantId) {
+  return fetch(`${API_CONFIG.WISHLISTS_ENDPOINT}/${encodeURIComponent(token)}/items`, {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json' },
+    body: JSON.stringify({ variant_id: variantId })
+  });
+}
+
+async function handleSaveForLater(product) {
+  const variantSelect = document.getElementById('variant-select');
+  const variantId = variantSelect ? variantSelect.value : (product.variants && product.variants[0] && product.variants[0].id);
+  if (!variantId) {
+    showNotification('Please select a size', 'error');
+    return;
+  }
+
+  try {
+    let response = await addToWishlist(await getWishlistToken(), variantId);
+    // Stale token (e.g. a reset database): start a fresh wishlist once
+    if (response.status === 404) {
+      localStorage.removeItem('nessie_audio_wishlist');
+      response = await addToWishlist(await getWishlistToken(), variantId);
+    }
+    if (!response.ok) {
+      throw new Error(`HTTP error! status: ${response.status}`);
+    }
+    showNotification('Saved to your wishlist');
+  } catch (error) {
+    console.error('Error saving to wishlist:', error);
+    showNotification('Could not save to your wishlist', 'error');
+  }
+}
+
 function showNotification(message, type = 'success') {
   let notificationContainer = document.querySelector('.notification-container');
 
@@ -440,12 +775,20 @@ async function initProductDetailPage() {
   renderProductDetail(product);
   document.title = `${product.name} - Nessie Audio`;
   updateMetaTags(product);
+  updateCanonicalURL(product);
+  loadStructuredData(product);
 
   console.log('Product Detail page initialized');
 }
 
+function productPageURL(product) {
+  return product.slug
+    ? `https://nessieaudio.com/product-detail?slug=${encodeURIComponent(product.slug)}`
+    : `https://nessieaudio.com/product-detail?id=${product.id}`;
+}
+
 function updateMetaTags(product) {
-  const productUrl = `https://nessieaudio.com/product-detail?id=${product.id}`;
+  const productUrl = productPageURL(product);
   const productImage = product.image_url || product.imageUrl || 'https://nessieaudio.com/Nessie Audio 2026.jpg';
   const productDescription = product.description
     ? product.description.substring(0, 150).replace(/\n/g, ' ').trim() + '...'
@@ -477,6 +820,43 @@ function updateMetaTag(attribute, value, content) {
   }
 }
 
+// Point the address bar and canonical link at the current slug (old slugs and
+// UUID links redirect here)
+function updateCanonicalURL(product) {
+  if (product.slug) {
+    const params = new URLSearchParams(window.location.search);
+    if (params.get('slug') !== product.slug) {
+      params.delete('id');
+      params.set('slug', product.slug);
+      history.replaceState(null, '', `${window.location.pathname}?${params}`);
+    }
+  }
+
+  let link = document.querySelector('link[rel="canonical"]');
+  if (!link) {
+    link = document.createElement('link');
+    link.setAttribute('rel', 'canonical');
+    document.head.appendChild(link);
+  }
+  link.setAttribute('href', productPageURL(product));
+}
+
+// Embed schema.org Product/Offer JSON-LD for rich results
+async function loadStructuredData(product) {
+  try {
+    const response = await fetch(`${PRODUCTS_ENDPOINT}/${product.id}/structured-data`);
+    if (!response.ok) {
+      throw new Error(`HTTP error! status: ${response.status}`);
+    }
+    const script = document.createElement('script');
+    script.type = 'application/ld+json';
+    script.textContent = JSON.stringify(await response.json());
+    document.head.appendChild(script);
+  } catch (error) {
+    console.error('Error loading structured data:', error);
+  }
+}
+
 // Wait for DOM before initializing
 if (document.readyState === 'loading') {
   document.addEventListener('DOMContentLoaded', initProductDetailPage);
@@ -0,0 +1,120 @@
+// quote.js
+// Requires config.js (and Stripe.js) to be loaded first
+//
+// /quote?token=...          - a booking quote from its email, with a button to pay the deposit
+// /quote?token=...&paid=1   - back from Stripe Checkout after paying
+
+const QUOTES_ENDPOINT = API_CONFIG.QUOTES_ENDPOINT;
+
+function escapeHTML(value) {
+  return String(value)
+    .replace(/&/g, '&amp;')
+    .replace(/"/g, '&quot;')
+    .replace(/</g, '&lt;')
+    .replace(/>/g, '&gt;');
+}
+
+function formatMoney(amount) {
+  return '$' + amount.toFixed(2);
+}
+
+function showQuoteMessage(text) {
+  const message = document.getElementById('quote-message');
+  message.textContent = text;
+  message.hidden = !text;
+}
+
+function renderQuote(quote, justPaid) {
+  const expires = new Date(quote.expires_at).toLocaleDateString(undefined, { year: 'numeric', month: 'long', day: 'numeric' });
+
+  document.getElementById('quote-title').textContent = `Quote: ${quote.project_type}`;
+  document.getElementById('quote-meta').textContent = `For ${quote.name} · valid until ${expires}`;
+  document.getElementById('quote-items').innerHTML = quote.items.map(item => `
+    <tr>
+      <td>${escapeHTML(item.description)}</td>
+      <td>${item.quantity}</td>
+      <td>${formatMoney(item.unit_price)}</td>
+      <td>${formatMoney(item.quantity * item.unit_price)}</td>
+    </tr>
+  `).join('');
+  document.getElementById('quote-total').textContent = formatMoney(quote.total);
+  document.getElementById('quote-deposit').textContent = formatMoney(quote.deposit);
+  document.getElementById('quote-balance').textContent = formatMoney(quote.balance_due);
+
+  const note = document.getElementById('quote-note');
+  note.textContent = quote.note || '';
+  note.hidden = !quote.note;
+
+  const payButton = document.getElementById('quote-pay-btn');
+  payButton.textContent = `Pay ${formatMoney(quote.deposit)} deposit`;
+  payButton.hidden = quote.status !== 'sent' && quote.status !== 'draft';
+
+  if (quote.status === 'paid') {
+    showQuoteMessage('Your deposit has been paid and your booking is confirmed. Thank you!');
+  } else if (justPaid) {
+    // The webhook can land a moment after Stripe sends the client back
+    payButton.hidden = true;
+    showQuoteMessage('Thanks! Your payment is being confirmed. You\'ll get an email receipt shortly.');
+  } else if (quote.status === 'expired') {
+    showQuoteMessage('This quote has expired. Get in touch and we\'ll send you a new one.');
+  } else {
+    showQuoteMessage('');
+  }
+
+  document.getElementById('quote-details').hidden = false;
+}
+
+async function payDeposit(token, stripeInstance) {
+  const payButton = document.getElementById('quote-pay-btn');
+  const label = payButton.textContent;
+  payButton.disabled = true;
+  payButton.textContent = 'Redirecting to checkout...';
+
+  try {
+    const response = await fetch(`${QUOTES_ENDPOINT}/${encodeURIComponent(token)}/checkout`, { method: 'POST' });
+    const data = await response.json();
+    if (!response.ok) {
+      throw new Error(data.error || `Checkout failed: ${response.status}`);
+    }
+    await stripeInstance.redirectToCheckout({ sessionId: data.session_id });
+  } catch (error) {
+    console.error('Deposit checkout error:', error);
+    showQuoteMessage(error.message || 'Failed to proceed to checkout. Please try again.');
+    payButton.disabled = false;
+    payButton.textContent = label;
+  }
+}
+
+document.addEventListener('DOMContentLoaded', async () => {
+  const params = new URLSearchParams(window.location.search);
+  const token = params.get('token');
+  if (!token) {
+    showQuoteMessage('This link is missing its quote. Please use the link from your email.');
+    return;
+  }
+
+  try {
+    const response = await fetch(`${QUOTES_ENDPOINT}/${encodeURIComponent(token)}`);
+    if (response.status === 404) {
+      showQuoteMessage('We couldn\'t find this quote. Please use the link from your email.');
+      return;
+    }
+    if (!response.ok) {
+      throw new Error(`HTTP error! status: ${response.status}`);
+    }
+    renderQuote(await response.json(), params.get('paid') === '1');
+  } catch (error) {
+    console.error('Failed to load quote:', error);
+    showQuoteMessage('We couldn\'t load your quote. Please try again later.');
+    return;
+  }
+
+  try {
+    const configResponse = await fetch(API_CONFIG.CONFIG_ENDPOINT);
+    const config = await configResponse.json();
+    const stripeInstance = Stripe(config.stripe_publishable_key);
+    document.getElementById('quote-pay-btn').addEventListener('click', () => payDeposit(token, stripeInstance));
+  } catch (error) {
+    console.error('Failed to initialize Stripe:', error);
+  }
+});
@@ -141,7 +141,8 @@
     });
   }
 
-  // Booking form — submit via fetch so user stays on page
+  // Booking form — submit via fetch so user stays on page. form-validation.js
+  // checks the fields first and stops invalid submissions before they get here.
   const bookingForm = $('.booking-form');
   if(bookingForm){
     bookingForm.addEventListener('submit', (e)=>{
@@ -151,17 +152,78 @@
       btn.textContent = 'Sending...';
       btn.disabled = true;
 
-      fetch(bookingForm.action, {
+      const endpoint = (typeof API_CONFIG !== 'undefined' && API_CONFIG.CONTACT_ENDPOINT) || bookingForm.action;
+      const data = new FormData(bookingForm);
+      const payload = {};
+      ['name', 'email', 'project_type', 'deadline', 'message', 'website'].forEach(field => {
+        payload[field] = (data.get(field) || '').toString();
+      });
+
+      fetch(endpoint, {
         method: 'POST',
-        body: new FormData(bookingForm),
-        headers: { 'Accept': 'application/json' }
+        body: JSON.stringify(payload),
+        headers: { 'Content-Type': 'application/json', 'Accept': 'application/json' }
       })
       .then(res => {
         if(res.ok){
           showBookingNotification('✓ Message sent!');
           bookingForm.reset();
+        } else if(res.status === 429){
+          showBookingNotification('Too many requests. Please try again in a few minutes.', true);
+        } else {
+          return res.json().catch(() => ({})).then(body => {
+            const first = body.details && body.details[0];
+            const field = first ? first.field.replace('_', ' ') : '';
+            showBookingNotification(first ? `${field.charAt(0).toUpperCase()}${field.slice(1)} ${first.message}` : 'Something went wrong. Please try again.', true);
+          });
+        }
+      })
+      .catch(()=>{
+        showBookingNotification('Network error. Please try again.', true);
+      })
+      .finally(()=>{
+        btn.textContent = origText;
+        btn.disabled = false;
+      });
+    });
+  }
+
+  // Mailing list sign-up — the API always answers 202 and emails a link to
+  // confirm the address, so the same message shows whether or not it's new
+  const newsletterForm = $('.newsletter-form');
+  if(newsletterForm){
+    newsletterForm.addEventListener('submit', (e)=>{
+      e.preventDefault();
+      const btn = newsletterForm.querySelector('button[type="submit"]');
+      const origText = btn.textContent;
+      btn.textContent = 'Subscribing...';
+      btn.disabled = true;
+
+      const endpoint = (typeof API_CONFIG !== 'undefined' && API_CONFIG.SUBSCRIBE_ENDPOINT) || newsletterForm.action;
+      const data = new FormData(newsletterForm);
+      const payload = {
+        email: (data.get('email') || '').toString(),
+        city: (data.get('city') || '').toString(),
+        genres: data.getAll('genres').map(String),
+        website: (data.get('website') || '').toString()
+      };
+
+      fetch(endpoint, {
+        method: 'POST',
+        body: JSON.stringify(payload),
+        headers: { 'Content-Type': 'application/json', 'Accept': 'application/json' }
+      })
+      .then(res => {
+        if(res.ok){
+          showBookingNotification('✓ Check your inbox to confirm your subscription!');
+          newsletterForm.reset();
+        } else if(res.status === 429){
+          showBookingNotification('Too many requests. Please try again in a few minutes.', true);
         } else {
-          showBookingNotification('Something went wrong. Please try again.', true);
+          return res.json().catch(() => ({})).then(body => {
+            const first = body.details && body.details[0];
+            showBookingNotification(first ? `${first.field.charAt(0).toUpperCase()}${first.field.slice(1)} ${first.message}` : 'Something went wrong. Please try again.', true);
+          });
         }
       })
       .catch(()=>{
@@ -0,0 +1,76 @@
+// subscribe.js
+// Requires config.js to be loaded first
+//
+// /subscribe?confirm=...      - the link from the confirmation email; confirms the sign-up
+// /subscribe?unsubscribe=...  - the unsubscribe link from mailing list emails
+//
+// Both act through a POST from this page rather than on the GET, so mail
+// scanners that open links don't confirm or unsubscribe anyone.
+
+function showSubscribeMessage(title, text) {
+  document.getElementById('subscribe-title').textContent = title;
+  document.getElementById('subscribe-message').textContent = text;
+}
+
+async function postToken(url) {
+  const response = await fetch(url, { method: 'POST', headers: { 'Accept': 'application/json' } });
+  if (response.status === 404) {
+    return null;
+  }
+  if (!response.ok) {
+    throw new Error(`HTTP error! status: ${response.status}`);
+  }
+  return response.json();
+}
+
+async function confirmSubscription(token) {
+  showSubscribeMessage('Confirming...', 'Confirming your subscription...');
+  try {
+    const result = await postToken(`${API_CONFIG.SUBSCRIBE_ENDPOINT}/confirm/${encodeURIComponent(token)}`);
+    if (!result) {
+      showSubscribeMessage('Link expired', 'This confirmation link is invalid or has expired. Sign up again from the home page to get a new one.');
+    } else {
+      showSubscribeMessage('You\'re on the list!', `Thanks for confirming. We'll send news to ${result.email}.`);
+    }
+  } catch (error) {
+    console.error('Failed to confirm subscription:', error);
+    showSubscribeMessage('Something went wrong', 'We couldn\'t confirm your subscription. Please try again later.');
+  }
+  document.getElementById('subscribe-home-link').hidden = false;
+}
+
+function offerUnsubscribe(token) {
+  const button = document.getElementById('unsubscribe-btn');
+  showSubscribeMessage('Unsubscribe', 'Click below to stop receiving emails from Nessie Audio.');
+  button.hidden = false;
+
+  button.addEventListener('click', async () => {
+    button.disabled = true;
+    try {
+      const result = await postToken(`${API_CONFIG.UNSUBSCRIBE_ENDPOINT}/${encodeURIComponent(token)}`);
+      if (!result) {
+        showSubscribeMessage('Link not found', 'We couldn\'t find this subscription. Please use the link from one of our emails.');
+      } else {
+        showSubscribeMessage('Unsubscribed', `${result.email} won't get any more emails from us. Sorry to see you go!`);
+      }
+      button.hidden = true;
+      document.getElementById('subscribe-home-link').hidden = false;
+    } catch (error) {
+      console.error('Failed to unsubscribe:', error);
+      showSubscribeMessage('Something went wrong', 'We couldn\'t unsubscribe you. Please try again.');
+      button.disabled = false;
+    }
+  });
+}
+
+document.addEventListener('DOMContentLoaded', () => {
+  const params = new URLSearchParams(window.location.search);
+  if (params.get('confirm')) {
+    confirmSubscription(params.get('confirm'));
+  } else if (params.get('unsubscribe')) {
+    offerUnsubscribe(params.get('unsubscribe'));
+  } else {
+    showSubscribeMessage('Mailing List', 'This link is incomplete. Please use the link from your email.');
+    document.getElementById('subscribe-home-link').hidden = false;
+  }
+});
@@ -0,0 +1,128 @@
+// tour.js
+// Requires config.js to be loaded first
+//
+// Lists the upcoming shows from the API, links the calendar and RSS feeds, and
+// embeds the shows as schema.org MusicEvent JSON-LD for search results.
+
+const SHOWS_ENDPOINT = API_CONFIG.SHOWS_ENDPOINT;
+
+// The calendar and RSS feeds are served from the API server's root
+const TOUR_FEEDS_ORIGIN = getApiBaseUrl().replace(/\/api\/v1$/, '');
+
+const SHOW_STATUS_LABELS = {
+  sold_out: 'Sold out',
+  cancelled: 'Cancelled'
+};
+
+function escapeHTML(value) {
+  return String(value)
+    .replace(/&/g, '&amp;')
+    .replace(/"/g, '&quot;')
+    .replace(/</g, '&lt;')
+    .replace(/>/g, '&gt;');
+}
+
+// Dates are shown in the venue's timezone, as printed on the ticket
+function formatShowDate(show) {
+  return new Date(show.starts_at).toLocaleDateString('en-US', {
+    month: 'short', day: 'numeric', year: 'numeric', timeZone: show.timezone
+  });
+}
+
+function formatShowTime(show) {
+  return new Date(show.starts_at).toLocaleTimeString('en-US', {
+    hour: 'numeric', minute: '2-digit', timeZone: show.timezone, timeZoneName: 'short'
+  });
+}
+
+function showPlace(show) {
+  return [show.city, show.region || show.country].map(escapeHTML).join(', ');
+}
+
+function renderShows(shows) {
+  const list = document.getElementById('events-list');
+  document.getElementById('events-empty').hidden = shows.length > 0;
+
+  list.innerHTML = shows.map(show => {
+    const support = show.support_acts.length
+      ? `<span class="event-support">with ${show.support_acts.map(escapeHTML).join(', ')}</span>`
+      : '';
+    let action = '';
+    if (SHOW_STATUS_LABELS[show.status]) {
+      action = `<span class="event-status ${show.status}">${SHOW_STATUS_LABELS[show.status]}</span>`;
+    } else if (show.ticket_url) {
+      action = `<a class="btn small" href="${escapeHTML(show.ticket_url)}" target="_blank" rel="noopener">Tickets</a>`;
+    }
+
+    const calendar = show.status === 'cancelled'
+      ? ''
+      : `<a class="event-calendar" href="${SHOWS_ENDPOINT}/${encodeURIComponent(show.id)}.ics" download>Add to calendar</a>`;
+
+    return `
+      <li id="show-${show.id}"${show.status === 'cancelled' ? ' class="cancelled"' : ''}>
+        <time datetime="${escapeHTML(show.starts_at)}" title="${escapeHTML(formatShowTime(show))}"><strong>${formatShowDate(show)}</strong></time>
+        <strong>— ${escapeHTML(show.venue)}</strong> — ${showPlace(show)}
+        ${support}
+        ${calendar}
+        ${action}
+      </li>
+    `;
+  }).join('');
+}
+
+async function loadShows() {
+  try {
+    const response = await fetch(`${SHOWS_ENDPOINT}?upcoming=true`);
+    if (!response.ok) {
+      throw new Error(`HTTP error! status: ${response.status}`);
+    }
+    const data = await response.json();
+    renderShows(data.shows || []);
+  } catch (error) {
+    console.error('Error loading shows:', error);
+    document.getElementById('events-empty').hidden = false;
+  }
+}
+
+// Embed schema.org MusicEvent JSON-LD for rich results
+async function loadStructuredData() {
+  try {
+    const response = await fetch(`${SHOWS_ENDPOINT}/structured-data`);
+    if (!response.ok) {
+      throw new Error(`HTTP error! status: ${response.status}`);
+    }
+    const events = await response.json();
+    if (events.length === 0) return;
+    const script = document.createElement('script');
+    script.type = 'application/ld+json';
+    script.textContent = JSON.stringify(events);
+    document.head.appendChild(script);
+  } catch (error) {
+    console.error('Error loading structured data:', error);
+  }
+}
+
+// Point the subscribe links at the feeds (webcal:// opens calendar apps directly)
+function initFeedLinks() {
+  const calendarLink = document.getElementById('tour-calendar-link');
+  if (calendarLink) {
+    calendarLink.href = `${TOUR_FEEDS_ORIGIN}/tour.ics`.replace(/^https?:/, 'webcal:');
+  }
+  const rssLink = document.getElementById('tour-rss-link');
+  if (rssLink) {
+    rssLink.href = `${TOUR_FEEDS_ORIGIN}/tour.rss`;
+  }
+}
+
+function initTourPage() {
+  initFeedLinks();
+  loadShows();
+  loadStructuredData();
+}
+
+// Wait for DOM before initializing
+if (document.readyState === 'loading') {
+  document.addEventListener('DOMContentLoaded', initTourPage);
+} else {
+  initTourPage();
+}
@@ -0,0 +1,171 @@
+// wishlist.js
+// Requires config.js to be loaded first
+//
+// /wishlist              - the visitor's own wishlist (token kept in localStorage)
+// /wishlist?token=...    - the owner's wishlist from an alert email
+// /wishlist?share=...    - someone else's wishlist, read-only
+
+const WISHLISTS_ENDPOINT = API_CONFIG.WISHLISTS_ENDPOINT;
+const WISHLIST_STORAGE_KEY = 'nessie_audio_wishlist';
+
+function escapeHTML(value) {
+  return String(value)
+    .replace(/&/g, '&amp;')
+    .replace(/"/g, '&quot;')
+    .replace(/</g, '&lt;')
+    .replace(/>/g, '&gt;');
+}
+
+function wishlistProductURL(item) {
+  return item.product_slug
+    ? `/product-detail?slug=${encodeURIComponent(item.product_slug)}`
+    : `/product-detail?id=${item.product_id}`;
+}
+
+async function fetchWishlist(url) {
+  const response = await fetch(url);
+  if (response.status === 404) return null;
+  if (!response.ok) {
+    throw new Error(`HTTP error! status: ${response.status}`);
+  }
+  return response.json();
+}
+
+function renderWishlistItems(items, editable) {
+  const list = document.getElementById('wishlist-items');
+  document.getElementById('wishlist-empty').hidden = items.length > 0;
+
+  list.innerHTML = items.map(item => {
+    const dropped = item.price < item.added_price;
+    let availability = '<span class="availability-badge out-of-stock">Out of stock</span>';
+    if (item.available && item.preorder) {
+      availability = '<span class="availability-badge preorder">Pre-order</span>';
+    } else if (item.available) {
+      availability = '<span class="availability-badge in-stock">In stock</span>';
+    }
+
+    return `
+      <li class="wishlist-item">
+        <a href="${wishlistProductURL(item)}" class="wishlist-item-image">
+          <img src="${resolveAssetUrl(item.image_url)}" alt="${escapeHTML(item.product_name)}" loading="lazy">
+        </a>
+        <div class="wishlist-item-info">
+          <a href="${wishlistProductURL(item)}" class="wishlist-item-name">${escapeHTML(item.product_name)}</a>
+          <span class="wishlist-item-variant">${escapeHTML(item.variant_name)}</span>
+          <span class="wishlist-item-price">
+            $${item.price.toFixed(2)}
+            ${dropped ? `<s>$${item.added_price.toFixed(2)}</s>` : ''}
+          </span>
+          ${availability}
+        </div>
+        ${editable ? `<button type="button" class="wishlist-remove btn small" data-variant-id="${escapeHTML(item.variant_id)}">Remove</button>` : ''}
+      </li>
+    `;
+  }).join('');
+}
+
+async function initWishlistPage() {
+  const params = new URLSearchParams(window.location.search);
+  const shareToken = params.get('share');
+
+  try {
+    if (shareToken) {
+      const shared = await fetchWishlist(`${WISHLISTS_ENDPOINT}/shared/${encodeURIComponent(shareToken)}`);
+      document.getElementById('wishlist-title').textContent = 'Shared Wishlist';
+      renderWishlistItems(shared ? shared.items : [], false);
+      return;
+    }
+
+    // A token from an alert email wins over (and replaces) the stored one
+    const token = params.get('token') || localStorage.getItem(WISHLIST_STORAGE_KEY);
+    const wishlist = token ? await fetchWishlist(`${WISHLISTS_ENDPOINT}/${encodeURIComponent(token)}`) : null;
+    if (!wishlist) {
+      renderWishlistItems([], false);
+      return;
+    }
+    localStorage.setItem(WISHLIST_STORAGE_KEY, wishlist.token);
+    renderOwnerWishlist(wishlist);
+  } catch (error) {
+    console.error('Error loading wishlist:', error);
+    renderWishlistItems([], false);
+  }
+}
+
+function renderOwnerWishlist(wishlist) {
+  renderWishlistItems(wishlist.items, true);
+
+  document.getElementById('wishlist-owner').hidden = false;
+  document.getElementById('wishlist-share-url').value = wishlist.share_url;
+  document.getElementById('wishlist-email').value = wishlist.email || '';
+  document.getElementById('wishlist-notify').checked = wishlist.notify;
+
+  document.querySelectorAll('.wishlist-remove').forEach(button => {
+    button.addEventListener('click', () => removeWishlistItem(wishlist.token, button.dataset.variantId));
+  });
+}
+
+async function removeWishlistItem(token, variantId) {
+  try {
+    const response = await fetch(`${WISHLISTS_ENDPOINT}/${encodeURIComponent(token)}/items/${encodeURIComponent(variantId)}`, {
+      method: 'DELETE'
+    });
+    if (!response.ok) {
+      throw new Error(`HTTP error! status: ${response.status}`);
+    }
+    renderOwnerWishlist(await response.json());
+  } catch (error) {
+    console.error('Error removing wishlist item:', error);
+  }
+}
+
+function attachWishlistFormListeners() {
+  const copyBtn = document.getElementById('wishlist-copy-btn');
+  copyBtn.addEventListener('click', async () => {
+    const input = document.getElementById('wishlist-share-url');
+    try {
+      await navigator.clipboard.writeText(input.value);
+      copyBtn.textContent = 'Copied';
+    } catch (error) {
+      input.select();
+    }
+  });
+
+  const form = document.getElementById('wishlist-notify-form');
+  form.addEventListener('submit', async (e) => {
+    e.preventDefault();
+    const token = localStorage.getItem(WISHLIST_STORAGE_KEY);
+    const button = form.querySelector('button[type="submit"]');
+    button.disabled = true;
+
+    try {
+      const response = await fetch(`${WISHLISTS_ENDPOINT}/${encodeURIComponent(token)}`, {
+        method: 'PUT',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({
+          email: document.getElementById('wishlist-email').value,
+          notify: document.getElementById('wishlist-notify').checked
+        })
+      });
+      if (!response.ok) {
+        throw new Error(`HTTP error! status: ${response.status}`);
+      }
+      renderOwnerWishlist(await response.json());
+      button.textContent = 'Saved';
+    } catch (error) {
+      console.error('Error saving wishlist settings:', error);
+    } finally {
+      button.disabled = false;
+    }
+  });
+}
+
+// Wait for DOM before initializing
+if (document.readyState === 'loading') {
+  document.addEventListener('DOMContentLoaded', () => {
+    attachWishlistFormListeners();
+    initWishlistPage();
+  });
+} else {
+  attachWishlistFormListeners();
+  initWishlistPage();
+}